@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// isolation starts code under the configured limits. Memory and process
+// limits are enforced by a cgroup per execution if the server may manage
+// cgroup v2, and by setrlimit otherwise. The CPU time limit is always a
+// setrlimit. The limits are applied by the server binary itself, started
+// as a launcher that sets them and then executes the code's interpreter.
+type isolation struct {
+	limits   limits
+	launcher string // the server binary
+	cgroup   string // cgroup v2 directory for execution cgroups, if usable
+	groups   atomic.Int64
+}
+
+func newIsolation(l *limits) (*isolation, error) {
+	launcher, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locating launcher: %w", err)
+	}
+	iso := &isolation{limits: *l, launcher: launcher}
+
+	if l.memoryBytes > 0 || l.processes > 0 {
+		dir, err := setupCgroup()
+		if err != nil {
+			slog.Info("cgroup v2 not usable, limiting memory and processes with setrlimit", "reason", err)
+		} else {
+			iso.cgroup = dir
+		}
+	}
+
+	// Start a launcher without code to check that the kernel permits the
+	// cgroup and network namespace.
+	if err := iso.probe(); err != nil && iso.cgroup != "" {
+		slog.Warn("cannot start processes in cgroups, limiting memory and processes with setrlimit", "error", err)
+		iso.cgroup = ""
+		err = iso.probe()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("starting isolated processes: %w", err)
+	}
+	return iso, nil
+}
+
+// probe runs the launcher without a command.
+func (iso *isolation) probe() error {
+	cmd, group, err := iso.command(context.Background(), false, "")
+	if err != nil {
+		return err
+	}
+	defer iso.release(cmd, group)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	return nil
+}
+
+// cgroups reports whether executions get their own cgroup.
+func (iso *isolation) cgroups() bool {
+	return iso.cgroup != ""
+}
+
+// command returns a command that runs name with args under the limits,
+// in a new process group and, with cgroups, a new cgroup. With cpu, the
+// CPU time limit applies. Cancelling ctx kills all processes of the
+// command. The caller must call release once the command has exited.
+func (iso *isolation) command(ctx context.Context, cpu bool, name string, args ...string) (*exec.Cmd, *execGroup, error) {
+	launch := []string{launcherArg}
+	if iso.cgroup == "" {
+		if iso.limits.memoryBytes > 0 {
+			launch = append(launch, fmt.Sprintf("-data=%d", iso.limits.memoryBytes))
+		}
+		if iso.limits.processes > 0 {
+			launch = append(launch, fmt.Sprintf("-nproc=%d", iso.limits.processes))
+		}
+	}
+	if cpu && iso.limits.cpuSeconds > 0 {
+		launch = append(launch, fmt.Sprintf("-cpu=%d", iso.limits.cpuSeconds))
+	}
+	if name != "" {
+		launch = append(append(launch, "--", name), args...)
+	}
+
+	attr := &syscall.SysProcAttr{Setpgid: true, Pdeathsig: syscall.SIGKILL}
+	if iso.limits.noNetwork {
+		attr.Cloneflags = syscall.CLONE_NEWNET
+		if uid, gid := os.Getuid(), os.Getgid(); uid != 0 {
+			// Unprivileged, the network namespace needs a user namespace.
+			attr.Cloneflags |= syscall.CLONE_NEWUSER
+			attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+			attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+		}
+	}
+
+	var group *execGroup
+	if iso.cgroup != "" {
+		var err error
+		group, err = iso.newGroup()
+		if err != nil {
+			return nil, nil, err
+		}
+		attr.UseCgroupFD = true
+		attr.CgroupFD = group.fd
+	}
+
+	cmd := exec.CommandContext(ctx, iso.launcher, launch...)
+	cmd.SysProcAttr = attr
+	cmd.Cancel = func() error {
+		group.kill()
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// Background processes may keep stdout open after the code exits.
+	cmd.WaitDelay = time.Second
+	return cmd, group, nil
+}
+
+// release kills the processes the command left behind and removes its
+// cgroup.
+func (iso *isolation) release(cmd *exec.Cmd, group *execGroup) {
+	if cmd.Process != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	group.close()
+}
+
+// runLauncher sets the limits given as flags on the process and executes
+// the command that follows them, which inherits the limits. Without a
+// command it exits successfully.
+func runLauncher(args []string) int {
+	fs := flag.NewFlagSet(launcherArg, flag.ContinueOnError)
+	data := fs.Int64("data", 0, "data segment limit in bytes")
+	cpu := fs.Int("cpu", 0, "CPU time limit in seconds")
+	nproc := fs.Int("nproc", 0, "process limit")
+	if err := fs.Parse(args); err != nil {
+		return 127
+	}
+
+	// RLIMIT_DATA rather than RLIMIT_AS: runtimes such as Node.js and Go
+	// reserve far more address space than they use.
+	set := []struct {
+		resource int
+		cur, max uint64
+	}{
+		{unix.RLIMIT_DATA, uint64(*data), uint64(*data)},
+		// SIGXCPU at the limit, SIGKILL a second later.
+		{unix.RLIMIT_CPU, uint64(*cpu), uint64(*cpu) + 1},
+		{unix.RLIMIT_NPROC, uint64(*nproc), uint64(*nproc)},
+	}
+	for _, l := range set {
+		if l.cur == 0 {
+			continue
+		}
+		if err := unix.Setrlimit(l.resource, &unix.Rlimit{Cur: l.cur, Max: l.max}); err != nil {
+			fmt.Fprintf(os.Stderr, "sandbox: setting resource limit: %v\n", err)
+			return 127
+		}
+	}
+
+	if fs.NArg() == 0 {
+		return 0
+	}
+	path, err := exec.LookPath(fs.Arg(0))
+	if err == nil {
+		err = unix.Exec(path, fs.Args(), os.Environ())
+	}
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	return 127
+}
+
+// setupCgroup prepares the server's cgroup v2 for execution cgroups and
+// returns its directory. Once controllers are enabled for children, a
+// cgroup may not contain processes itself, so the server first moves
+// into a child cgroup of its own.
+func setupCgroup() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	var path string
+	for _, line := range strings.Split(string(data), "\n") {
+		if p, ok := strings.CutPrefix(line, "0::"); ok {
+			path = p
+		}
+	}
+	if path == "" {
+		return "", errors.New("not in a cgroup v2 hierarchy")
+	}
+
+	dir := filepath.Join("/sys/fs/cgroup", path)
+	controllers, err := os.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		return "", fmt.Errorf("cgroup v2 not mounted: %w", err)
+	}
+	for _, c := range []string{"memory", "pids"} {
+		if !slices.Contains(strings.Fields(string(controllers)), c) {
+			return "", fmt.Errorf("%s controller not delegated", c)
+		}
+	}
+
+	server := filepath.Join(dir, "server")
+	if err := os.Mkdir(server, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(server, "cgroup.procs"), []byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+		return "", fmt.Errorf("moving server into its cgroup: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+memory +pids"), 0); err != nil {
+		return "", fmt.Errorf("enabling controllers: %w", err)
+	}
+	return dir, nil
+}
+
+// execGroup is the cgroup of an execution or of a persistent kernel. A
+// nil execGroup stands for no cgroup.
+type execGroup struct {
+	dir string
+	fd  int
+}
+
+// newGroup creates a cgroup with the memory and process limits.
+func (iso *isolation) newGroup() (*execGroup, error) {
+	dir := filepath.Join(iso.cgroup, fmt.Sprintf("exec-%d-%d", os.Getpid(), iso.groups.Add(1)))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating cgroup: %w", err)
+	}
+
+	settings := map[string]string{}
+	if iso.limits.memoryBytes > 0 {
+		settings["memory.max"] = strconv.FormatInt(iso.limits.memoryBytes, 10)
+		settings["memory.oom.group"] = "1"
+	}
+	if iso.limits.processes > 0 {
+		settings["pids.max"] = strconv.Itoa(iso.limits.processes)
+	}
+	for file, value := range settings {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0); err != nil {
+			os.Remove(dir)
+			return nil, fmt.Errorf("setting %s: %w", file, err)
+		}
+	}
+	if iso.limits.memoryBytes > 0 {
+		// Not every kernel accounts swap; without swap there is nothing to limit.
+		os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0)
+	}
+
+	fd, err := unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		os.Remove(dir)
+		return nil, fmt.Errorf("opening cgroup: %w", err)
+	}
+	return &execGroup{dir: dir, fd: fd}, nil
+}
+
+// events returns the limit breaches in the cgroup so far.
+func (g *execGroup) events() groupEvents {
+	if g == nil {
+		return groupEvents{}
+	}
+	return groupEvents{
+		oomKills:   readEvent(filepath.Join(g.dir, "memory.events"), "oom_kill"),
+		processMax: readEvent(filepath.Join(g.dir, "pids.events"), "max"),
+	}
+}
+
+// readEvent returns the counter key of a cgroup events file.
+func readEvent(path, key string) int64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if name, value, ok := strings.Cut(scanner.Text(), " "); ok && name == key {
+			n, _ := strconv.ParseInt(value, 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// kill kills all processes in the cgroup.
+func (g *execGroup) kill() {
+	if g != nil {
+		os.WriteFile(filepath.Join(g.dir, "cgroup.kill"), []byte("1"), 0)
+	}
+}
+
+// close kills the processes in the cgroup and removes it.
+func (g *execGroup) close() {
+	if g == nil {
+		return
+	}
+	g.kill()
+	unix.Close(g.fd)
+	// Killed processes leave the cgroup asynchronously.
+	for range 50 {
+		if err := os.Remove(g.dir); err == nil || errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	slog.Warn("removing cgroup failed", "cgroup", g.dir)
+}
@@ -0,0 +1,57 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// isolation starts code with the output limits only; memory, CPU time
+// and process limits and network isolation need Linux.
+type isolation struct{}
+
+func newIsolation(l *limits) (*isolation, error) {
+	if l.noNetwork {
+		return nil, errors.New("network isolation requires Linux")
+	}
+	if l.memoryBytes > 0 || l.cpuSeconds > 0 || l.processes > 0 {
+		slog.Warn("memory, CPU time and process limits require Linux, running code without them")
+		l.memoryBytes, l.cpuSeconds, l.processes = 0, 0, 0
+	}
+	return &isolation{}, nil
+}
+
+// cgroups reports whether executions get their own cgroup.
+func (iso *isolation) cgroups() bool {
+	return false
+}
+
+// command returns a command that runs name with args.
+func (iso *isolation) command(ctx context.Context, _ bool, name string, args ...string) (*exec.Cmd, *execGroup, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	// Background processes may keep stdout open after the code exits.
+	cmd.WaitDelay = time.Second
+	return cmd, nil, nil
+}
+
+// release does nothing without process groups.
+func (iso *isolation) release(*exec.Cmd, *execGroup) {}
+
+// runLauncher is only used on Linux.
+func runLauncher([]string) int {
+	fmt.Fprintln(os.Stderr, "sandbox: the launcher requires Linux")
+	return 127
+}
+
+// execGroup stands for the cgroup of an execution, which needs Linux.
+type execGroup struct{}
+
+func (g *execGroup) events() groupEvents {
+	return groupEvents{}
+}
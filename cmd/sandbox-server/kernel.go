@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// kernelDriver is the Python program of a persistent kernel. It reads one
+// JSON request per line from fd 3 and executes the code in a namespace
+// kept across requests. To fd 4 it writes a JSON message per line: the
+// output of the code, line by line as it is printed, and finally the
+// exit code. Output is sent this way rather than written to the
+// process's stdout.
+const kernelDriver = `
+import contextlib, importlib, io, json, os, sys, traceback
+
+requests = os.fdopen(3, "r")
+replies = os.fdopen(4, "w")
+namespace = {"__name__": "__main__"}
+
+def send(message):
+    replies.write(json.dumps(message) + "\n")
+    replies.flush()
+
+class Output(io.TextIOBase):
+    """Line-buffered stream that sends its output to the server."""
+
+    def __init__(self, name):
+        self.name, self.buffer = name, []
+
+    def writable(self):
+        return True
+
+    def write(self, s):
+        self.buffer.append(s)
+        if "\n" in s or sum(map(len, self.buffer)) >= 8192:
+            self.flush()
+        return len(s)
+
+    def flush(self):
+        data = "".join(self.buffer)
+        self.buffer = []
+        if data:
+            send({"stream": self.name, "data": data})
+
+for line in requests:
+    code = json.loads(line)["code"]
+    out, err = Output("stdout"), Output("stderr")
+    exit_code = 0
+    importlib.invalidate_caches()
+    with contextlib.redirect_stdout(out), contextlib.redirect_stderr(err):
+        try:
+            exec(compile(code, "<cell>", "exec"), namespace)
+        except SystemExit as e:
+            if e.code is None:
+                exit_code = 0
+            elif isinstance(e.code, int):
+                exit_code = e.code
+            else:
+                print(e.code, file=err)
+                exit_code = 1
+        except BaseException:
+            # Leave out the driver's own frame.
+            kind, value, tb = sys.exc_info()
+            traceback.print_exception(kind, value, tb.tb_next)
+            exit_code = 1
+    out.flush()
+    err.flush()
+    send({"exit_code": exit_code})
+`
+
+// errKernelExited reports that a kernel died while running code. Its
+// state is lost; the next execution starts a new kernel.
+var errKernelExited = errors.New("python kernel exited unexpectedly, its state was lost")
+
+// pythonKernel is a Python process that keeps variables, imports and
+// function definitions between executions of a session.
+type pythonKernel struct {
+	cmd      *exec.Cmd
+	group    *execGroup // cgroup of the kernel, if any
+	release  func()     // cleans up after the process has exited
+	requests io.WriteCloser
+	replies  *bufio.Reader
+	exited   chan struct{} // closed when the process has exited
+}
+
+// kernelMessage is output of the code, or the exit code once it is done.
+type kernelMessage struct {
+	Stream   string `json:"stream,omitempty"` // "stdout" or "stderr"
+	Data     string `json:"data,omitempty"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// startKernel starts a kernel with cmd, which runs kernelDriver in group.
+// release is called when the kernel is stopped or fails to start.
+func startKernel(cmd *exec.Cmd, group *execGroup, release func()) (*pythonKernel, error) {
+	reqR, reqW, err := os.Pipe()
+	if err != nil {
+		release()
+		return nil, err
+	}
+	replyR, replyW, err := os.Pipe()
+	if err != nil {
+		reqR.Close()
+		reqW.Close()
+		release()
+		return nil, err
+	}
+
+	cmd.Stderr = os.Stderr // Driver failures end up in the server log.
+	cmd.ExtraFiles = []*os.File{reqR, replyW}
+
+	err = cmd.Start()
+	// The child has its own copies of these ends.
+	reqR.Close()
+	replyW.Close()
+	if err != nil {
+		reqW.Close()
+		replyR.Close()
+		release()
+		return nil, fmt.Errorf("starting python kernel: %w", err)
+	}
+
+	k := &pythonKernel{
+		cmd:      cmd,
+		group:    group,
+		release:  release,
+		requests: reqW,
+		replies:  bufio.NewReader(replyR),
+		exited:   make(chan struct{}),
+	}
+	go func() {
+		cmd.Wait()
+		replyR.Close()
+		close(k.exited)
+	}()
+	return k, nil
+}
+
+// execute runs code in the kernel, writes its output to stdout and
+// stderr as it arrives and returns its exit code. If ctx ends first, the
+// kernel is stopped and ctx.Err() is returned.
+func (k *pythonKernel) execute(ctx context.Context, code string, stdout, stderr io.Writer) (int, error) {
+	line, _ := json.Marshal(map[string]string{"code": code})
+	if _, err := k.requests.Write(append(line, '\n')); err != nil {
+		return -1, errKernelExited
+	}
+
+	type result struct {
+		exitCode int
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		for {
+			data, err := k.replies.ReadBytes('\n')
+			if err != nil {
+				done <- result{err: errKernelExited}
+				return
+			}
+			var msg kernelMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				done <- result{err: fmt.Errorf("invalid kernel reply: %w", err)}
+				return
+			}
+			switch msg.Stream {
+			case "stdout":
+				stdout.Write([]byte(msg.Data))
+			case "stderr":
+				stderr.Write([]byte(msg.Data))
+			default:
+				done <- result{exitCode: msg.ExitCode}
+				return
+			}
+		}
+	}()
+
+	select {
+	case r := <-done:
+		return r.exitCode, r.err
+	case <-ctx.Done():
+		k.stop()
+		<-done
+		return -1, ctx.Err()
+	}
+}
+
+// alive reports whether the kernel process is still running.
+func (k *pythonKernel) alive() bool {
+	select {
+	case <-k.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// stop kills the kernel and the processes it started.
+func (k *pythonKernel) stop() {
+	k.requests.Close()
+	k.cmd.Process.Kill()
+	<-k.exited
+	k.release()
+}
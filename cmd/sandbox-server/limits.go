@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Execution statuses. Besides success and error, each limit an execution
+// can breach has its own status.
+const (
+	statusSuccess      = "success"
+	statusError        = "error"
+	statusTimeout      = "timeout"
+	statusMemoryLimit  = "memory_limit_exceeded"
+	statusCPULimit     = "cpu_limit_exceeded"
+	statusProcessLimit = "process_limit_exceeded"
+	statusOutputLimit  = "output_limit_exceeded"
+	statusFileLimit    = "file_limit_exceeded"
+)
+
+// launcherArg as the first argument makes the server binary act as the
+// launcher of code: it applies the limits and executes the interpreter.
+const launcherArg = "__sandbox_launch"
+
+// limits are the resource limits of code executions. Zero disables a
+// limit.
+type limits struct {
+	memoryBytes int64 // memory of all processes of an execution
+	cpuSeconds  int   // CPU time of each process
+	processes   int   // processes and threads
+	outputBytes int   // stdout and stderr, each
+	outputFiles int   // files reported from the output directory
+	noNetwork   bool  // run code in an empty network namespace
+}
+
+// loadLimits reads the limits from the environment.
+func loadLimits() limits {
+	return limits{
+		memoryBytes: int64(envOrInt("SANDBOX_MEMORY_LIMIT_MB", 1024)) * 1024 * 1024,
+		cpuSeconds:  envOrInt("SANDBOX_CPU_LIMIT_SECONDS", 60),
+		processes:   envOrInt("SANDBOX_MAX_PROCESSES", 128),
+		outputBytes: envOrInt("SANDBOX_MAX_OUTPUT_BYTES", 1024*1024),
+		outputFiles: envOrInt("SANDBOX_MAX_OUTPUT_FILES", 20),
+		noNetwork:   envOr("SANDBOX_NETWORK", "true") == "false",
+	}
+}
+
+// groupEvents counts limit breaches in an execution's cgroup.
+type groupEvents struct {
+	oomKills   int64 // processes killed for exceeding memory.max
+	processMax int64 // forks that failed at pids.max
+}
+
+// sub returns the events that happened since before.
+func (e groupEvents) sub(before groupEvents) groupEvents {
+	return groupEvents{oomKills: e.oomKills - before.oomKills, processMax: e.processMax - before.processMax}
+}
+
+// execResult is the outcome of running code.
+type execResult struct {
+	stdout, stderr  string
+	stderrTail      string // end of stderr, even if stderr was truncated
+	exitCode        int
+	err             error       // the code failed or could not be run
+	cpuExceeded     bool        // the process used up its CPU time
+	events          groupEvents // cgroup limit breaches
+	outputTruncated bool        // stdout or stderr hit the output limit
+}
+
+// baseEnvVars are the server's environment variables that code may see.
+var baseEnvVars = []string{"PATH", "HOME", "LANG", "TZ", "TMPDIR"}
+
+// environment returns the environment of processes the server starts:
+// baseEnvVars, the variables named in SANDBOX_ENV_PASSTHROUGH and extra,
+// but none of the server's other variables, which may hold credentials.
+func environment(extra ...string) []string {
+	names := append([]string{}, baseEnvVars...)
+	for _, name := range strings.Split(os.Getenv("SANDBOX_ENV_PASSTHROUGH"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	var env []string
+	for _, name := range names {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return append(env, extra...)
+}
+
+// tailSize is how much of the end of stderr is kept to recognize errors.
+const tailSize = 1024
+
+// cappedBuffer keeps the first limit bytes written to it and drops the
+// rest, but remembers the last tailSize bytes. Writes never fail, so that
+// the writing process is not disturbed.
+type cappedBuffer struct {
+	limit     int // zero keeps everything
+	buf       strings.Builder
+	tail      []byte
+	truncated bool
+	forward   func([]byte) // if set, receives the bytes kept as they are written
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.tail = append(b.tail, p[max(len(p)-tailSize, 0):]...)
+	b.tail = b.tail[max(len(b.tail)-tailSize, 0):]
+	if b.limit > 0 {
+		room := b.limit - b.buf.Len()
+		if room < len(p) {
+			b.truncated = true
+			p = p[:max(room, 0)]
+		}
+	}
+	b.buf.Write(p)
+	if b.forward != nil && len(p) > 0 {
+		b.forward(p)
+	}
+	return n, nil
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
+// Tail returns the last bytes written.
+func (b *cappedBuffer) Tail() string {
+	return string(b.tail)
+}
+
+// Messages of runtimes that failed to get memory or start a process,
+// used to recognize limit breaches when no cgroup reports them.
+var (
+	memoryErrorMessages  = []string{"MemoryError", "Cannot allocate memory", "out of memory"}
+	processErrorMessages = []string{"Resource temporarily unavailable", "can't start new thread"}
+)
+
+// status determines the status of an execution and returns it with a
+// message explaining a limit breach that made it fail, if any. Failures
+// caused by a limit take precedence over plain errors, which take
+// precedence over output that was cut off.
+func (s *sandboxServer) status(res *execResult, timedOut bool, timeoutSeconds int) (string, string) {
+	failed := res.err != nil || res.exitCode != 0
+	stderrHas := func(messages []string) bool {
+		for _, m := range messages {
+			if strings.Contains(res.stderrTail, m) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case timedOut:
+		return statusTimeout, fmt.Sprintf("execution timed out after %d seconds", timeoutSeconds)
+	case res.events.oomKills > 0,
+		failed && !s.isolation.cgroups() && s.limits.memoryBytes > 0 && stderrHas(memoryErrorMessages):
+		return statusMemoryLimit, fmt.Sprintf("memory limit of %d MiB exceeded", s.limits.memoryBytes/(1024*1024))
+	case res.cpuExceeded:
+		return statusCPULimit, fmt.Sprintf("CPU time limit of %d seconds exceeded", s.limits.cpuSeconds)
+	case res.events.processMax > 0,
+		failed && !s.isolation.cgroups() && s.limits.processes > 0 && stderrHas(processErrorMessages):
+		return statusProcessLimit, fmt.Sprintf("process limit of %d exceeded", s.limits.processes)
+	case failed:
+		return statusError, ""
+	case res.outputTruncated:
+		return statusOutputLimit, ""
+	default:
+		return statusSuccess, ""
+	}
+}
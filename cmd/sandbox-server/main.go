@@ -8,12 +8,38 @@
 //	SANDBOX_MAX_CONCURRENT - Max concurrent executions (default: 3)
 //	SANDBOX_PYTHON_INDEX - Python package index URL (default: https://pypi.org/simple/)
 //	SANDBOX_OUTPUT_DIR   - Output directory name within temp dir (default: output)
+//	SANDBOX_SESSION_DIR  - Directory for session working dirs (default: <tmp>/sandbox-sessions)
+//	SANDBOX_SESSION_IDLE_TIMEOUT - Seconds after which an idle session is removed (default: 86400)
+//	SANDBOX_PERSISTENT_KERNEL - Keep a Python process per session (python mode only, default: false)
+//	SANDBOX_MEMORY_LIMIT_MB   - Memory per execution in MiB (default: 1024)
+//	SANDBOX_CPU_LIMIT_SECONDS - CPU time per process in seconds (default: 60)
+//	SANDBOX_MAX_PROCESSES     - Processes and threads per execution (default: 128)
+//	SANDBOX_MAX_OUTPUT_BYTES  - Bytes kept of stdout and stderr, each (default: 1048576)
+//	SANDBOX_MAX_OUTPUT_FILES  - Output files returned per execution (default: 20)
+//	SANDBOX_NETWORK           - Set to false to run code without network access (default: true)
+//	SANDBOX_ENV_PASSTHROUGH   - Comma-separated server variables code may see (default: none)
+//
+// Executions that name a session_id share a persistent working directory,
+// including installed packages. With SANDBOX_PERSISTENT_KERNEL=true they
+// also share the Python interpreter state (variables, imports).
+//
+// POST /execute returns the result when the code has finished. POST
+// /execute/stream takes the same request and streams the output while
+// the code runs, as newline-delimited JSON: lines of type "stdout" and
+// "stderr" with the output in data, then a line of type "result" with
+// the response of POST /execute in result. Closing the connection kills
+// the code.
+//
+// Code runs in its own process group with a minimal environment. Memory
+// and process limits use a cgroup per execution when the server may
+// manage cgroup v2, and setrlimit otherwise. A zero limit disables it.
 package main
 
 import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -28,11 +54,18 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == launcherArg {
+		os.Exit(runLauncher(os.Args[2:]))
+	}
+
 	port := envOr("SANDBOX_PORT", "8080")
 	mode := envOr("SANDBOX_MODE", "")
 	maxConcurrent := envOrInt("SANDBOX_MAX_CONCURRENT", 3)
 	pythonIndex := envOr("SANDBOX_PYTHON_INDEX", "https://pypi.org/simple/")
 	outputDirName := envOr("SANDBOX_OUTPUT_DIR", "output")
+	sessionDir := envOr("SANDBOX_SESSION_DIR", filepath.Join(os.TempDir(), "sandbox-sessions"))
+	sessionIdleTimeout := envOrInt("SANDBOX_SESSION_IDLE_TIMEOUT", 86400)
+	persistentKernel := envOr("SANDBOX_PERSISTENT_KERNEL", "false") == "true"
 
 	// Resolve mode: explicit or auto-detect.
 	if mode == "" {
@@ -50,21 +83,49 @@ func main() {
 		}
 	}
 
+	if persistentKernel && mode != "python" {
+		slog.Warn("persistent kernel is only supported in python mode, ignoring", "mode", mode)
+		persistentKernel = false
+	}
+
 	// Detect runtime version.
 	runtimeVersion := detectRuntimeVersion(mode)
 
+	limits := loadLimits()
+	isolation, err := newIsolation(&limits)
+	if err != nil {
+		slog.Error("failed to set up isolation", "error", err)
+		os.Exit(1)
+	}
+
+	sessions, err := newSessionStore(sessionDir, time.Duration(sessionIdleTimeout)*time.Second)
+	if err != nil {
+		slog.Error("failed to set up sessions", "error", err)
+		os.Exit(1)
+	}
+
 	srv := &sandboxServer{
-		mode:           mode,
-		runtimeVersion: runtimeVersion,
-		maxConcurrent:  int32(maxConcurrent),
-		pythonIndex:    pythonIndex,
-		outputDirName:  outputDirName,
-		startTime:      time.Now(),
+		mode:             mode,
+		runtimeVersion:   runtimeVersion,
+		maxConcurrent:    int32(maxConcurrent),
+		pythonIndex:      pythonIndex,
+		outputDirName:    outputDirName,
+		sessions:         sessions,
+		persistentKernel: persistentKernel,
+		limits:           limits,
+		isolation:        isolation,
+		startTime:        time.Now(),
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /execute", srv.handleExecute)
+	mux.HandleFunc("POST /execute/stream", srv.handleExecuteStream)
 	mux.HandleFunc("GET /health", srv.handleHealth)
+	mux.HandleFunc("GET /sessions/{id}/files", srv.handleListFiles)
+	mux.HandleFunc("GET /sessions/{id}/files/{path...}", srv.handleGetFile)
+	mux.HandleFunc("PUT /sessions/{id}/files/{path...}", srv.handlePutFile)
+	mux.HandleFunc("DELETE /sessions/{id}/files/{path...}", srv.handleDeleteFile)
+	mux.HandleFunc("DELETE /sessions/{id}", srv.handleDeleteSession)
 
 	httpSrv := &http.Server{
 		Addr:         ":" + port,
@@ -76,8 +137,12 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	go sessions.run(ctx.Done())
+
 	go func() {
-		slog.Info("sandbox server starting", "port", port, "mode", mode, "runtime", runtimeVersion, "max_concurrent", maxConcurrent)
+		slog.Info("sandbox server starting", "port", port, "mode", mode, "runtime", runtimeVersion, "max_concurrent", maxConcurrent,
+			"memory_limit_mb", limits.memoryBytes/(1024*1024), "cpu_limit_seconds", limits.cpuSeconds, "max_processes", limits.processes,
+			"cgroups", isolation.cgroups(), "network", !limits.noNetwork)
 		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			slog.Error("server failed", "error", err)
 			os.Exit(1)
@@ -93,6 +158,10 @@ func main() {
 
 // --- Server ---
 
+// maxExecuteRequestSize limits execute requests, which carry input files
+// base64-encoded.
+const maxExecuteRequestSize = 64 * 1024 * 1024
+
 type sandboxServer struct {
 	mode           string // python, golang, node, shell
 	runtimeVersion string // e.g., "Python 3.12.12", "go1.25", "v22.0.0"
@@ -101,6 +170,12 @@ type sandboxServer struct {
 	pythonIndex    string
 	outputDirName  string
 	startTime      time.Time
+
+	sessions         *sessionStore
+	persistentKernel bool // run session code in a persistent Python kernel
+
+	limits    limits
+	isolation *isolation
 }
 
 // modeConfig returns the interpreter command, file extension, and extra
@@ -111,7 +186,8 @@ func (s *sandboxServer) modeConfig(tmpDir, outputDir string) (cmd []string, ext
 	switch s.mode {
 	case "python":
 		pyLibs := filepath.Join(tmpDir, ".pylibs")
-		return []string{"python3"}, ".py", append(env, "PYTHONPATH="+pyLibs)
+		// Unbuffered, output can be streamed as it is printed.
+		return []string{"python3"}, ".py", append(env, "PYTHONPATH="+pyLibs, "PYTHONUNBUFFERED=1")
 	case "golang":
 		return []string{"go", "run"}, ".go", env
 	case "node":
@@ -130,6 +206,10 @@ type executeRequest struct {
 	TimeoutSeconds int               `json:"timeout_seconds"`
 	Requirements   []string          `json:"requirements,omitempty"`
 	Files          map[string]string `json:"files,omitempty"`
+
+	// SessionID runs the code in the session's persistent working
+	// directory instead of a fresh temporary one.
+	SessionID string `json:"session_id,omitempty"`
 }
 
 type executeResponse struct {
@@ -142,6 +222,18 @@ type executeResponse struct {
 }
 
 func (s *sandboxServer) handleExecute(w http.ResponseWriter, r *http.Request) {
+	s.execute(w, r, nil)
+}
+
+// handleExecuteStream executes code like handleExecute, but streams its
+// output while it runs.
+func (s *sandboxServer) handleExecuteStream(w http.ResponseWriter, r *http.Request) {
+	s.execute(w, r, newOutputStream(w))
+}
+
+// execute runs the code of an execute request and writes the response,
+// or, with a stream, the output followed by the response.
+func (s *sandboxServer) execute(w http.ResponseWriter, r *http.Request, stream *outputStream) {
 	// Check capacity.
 	current := s.currentLoad.Add(1)
 	defer s.currentLoad.Add(-1)
@@ -157,7 +249,7 @@ func (s *sandboxServer) handleExecute(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request.
 	var req executeRequest
-	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 10*1024*1024)).Decode(&req); err != nil {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxExecuteRequestSize)).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
 		return
 	}
@@ -183,16 +275,31 @@ func (s *sandboxServer) handleExecute(w http.ResponseWriter, r *http.Request) {
 		req.TimeoutSeconds = 30 // Default timeout.
 	}
 
-	// Create temporary working directory.
-	tmpDir, err := os.MkdirTemp("", "sandbox-exec-*")
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create temp dir: "+err.Error())
-		return
+	// Use the session's working directory, or a temporary one.
+	var sess *session
+	var workDir string
+	if req.SessionID != "" {
+		var err error
+		sess, err = s.sessions.open(req.SessionID, true)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		workDir = sess.dir
+	} else {
+		tmpDir, err := os.MkdirTemp("", "sandbox-exec-*")
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to create temp dir: "+err.Error())
+			return
+		}
+		defer os.RemoveAll(tmpDir)
+		workDir = tmpDir
 	}
-	defer os.RemoveAll(tmpDir)
 
 	// Create output directory.
-	outputDir := filepath.Join(tmpDir, s.outputDirName)
+	outputDir := filepath.Join(workDir, s.outputDirName)
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to create output dir: "+err.Error())
 		return
@@ -205,7 +312,7 @@ func (s *sandboxServer) handleExecute(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode file %q: %v", name, err))
 			return
 		}
-		filePath := filepath.Join(tmpDir, filepath.Base(name)) // Prevent path traversal.
+		filePath := filepath.Join(workDir, filepath.Base(name)) // Prevent path traversal.
 		if err := os.WriteFile(filePath, content, 0644); err != nil {
 			writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to write file %q: %v", name, err))
 			return
@@ -214,11 +321,9 @@ func (s *sandboxServer) handleExecute(w http.ResponseWriter, r *http.Request) {
 
 	// Install requirements if specified and mode supports it.
 	if len(req.Requirements) > 0 {
-		installErr := s.installRequirements(r.Context(), tmpDir, req.Requirements, req.TimeoutSeconds)
+		installErr := s.installRequirements(r.Context(), workDir, req.Requirements, req.TimeoutSeconds)
 		if installErr != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(executeResponse{
+			writeResult(w, stream, &executeResponse{
 				Status:   "error",
 				Stderr:   "package installation failed: " + installErr.Error(),
 				ExitCode: -1,
@@ -227,81 +332,178 @@ func (s *sandboxServer) handleExecute(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Get mode-specific interpreter, file extension, and env.
-	interpreter, fileExt, extraEnv := s.modeConfig(tmpDir, outputDir)
-
-	// Write the code to a file.
-	codePath := filepath.Join(tmpDir, "script"+fileExt)
-	if err := os.WriteFile(codePath, []byte(req.Code), 0644); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to write code: "+err.Error())
-		return
-	}
+	// Files already in a session's output directory are not reported
+	// again unless the code changes them.
+	before := outputDirState(outputDir)
 
 	// Execute with timeout.
 	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(req.TimeoutSeconds)*time.Second)
 	defer cancel()
 
 	startTime := time.Now()
-	cmdArgs := append(interpreter[1:], codePath)
-	cmd := exec.CommandContext(ctx, interpreter[0], cmdArgs...)
-	cmd.Dir = tmpDir
-	cmd.Env = append(os.Environ(), extraEnv...)
-
-	var stdoutBuf, stderrBuf strings.Builder
-	cmd.Stdout = &stdoutBuf
-	cmd.Stderr = &stderrBuf
-
-	execErr := cmd.Run()
+	var res *execResult
+	if sess != nil && s.persistentKernel {
+		res = s.runInKernel(ctx, sess, outputDir, req.Code, stream)
+	} else {
+		res = s.runScript(ctx, workDir, outputDir, req.Code, stream)
+	}
 	duration := time.Since(startTime)
 
-	// Determine exit code.
-	exitCode := 0
-	status := "success"
-	if execErr != nil {
-		status = "error"
-		// Check timeout first (context deadline takes precedence over exit error).
-		if ctx.Err() == context.DeadlineExceeded {
-			exitCode = -1
-			if stderrBuf.Len() == 0 {
-				stderrBuf.WriteString(fmt.Sprintf("execution timed out after %d seconds", req.TimeoutSeconds))
-			}
-		} else if exitErr, ok := execErr.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		} else {
-			exitCode = -1
-		}
+	// Determine status. A failure caused by a limit is explained in stderr.
+	timedOut := ctx.Err() == context.DeadlineExceeded
+	status, message := s.status(res, timedOut, req.TimeoutSeconds)
+	if errors.Is(res.err, errKernelExited) {
+		res.exitCode = -1
+		message = strings.TrimSpace(message + "\n" + res.err.Error())
+	}
+	if res.outputTruncated {
+		message = strings.TrimSpace(message + fmt.Sprintf("\noutput truncated after %d bytes", s.limits.outputBytes))
 	}
 
 	// Collect output files.
-	filesProduced := collectOutputFiles(outputDir)
+	filesProduced, skipped := collectOutputFiles(outputDir, before, s.limits.outputFiles)
+	if skipped > 0 {
+		if status == statusSuccess {
+			status = statusFileLimit
+		}
+		message = strings.TrimSpace(message + fmt.Sprintf("\n%d output files not returned, the limit is %d", skipped, s.limits.outputFiles))
+	}
+	if message != "" {
+		if res.stderr != "" && !strings.HasSuffix(res.stderr, "\n") {
+			res.stderr += "\n"
+		}
+		res.stderr += message
+	}
 
 	// Log completion.
-	stdoutPreview := stdoutBuf.String()
+	stdoutPreview := res.stdout
 	if len(stdoutPreview) > 200 {
 		stdoutPreview = stdoutPreview[:200] + "..."
 	}
 	fileCount := len(filesProduced)
 	slog.Info("execute complete",
 		"status", status,
-		"exit_code", exitCode,
+		"session", req.SessionID,
+		"exit_code", res.exitCode,
 		"duration_ms", duration.Milliseconds(),
-		"stdout_len", stdoutBuf.Len(),
+		"stdout_len", len(res.stdout),
 		"stdout", stdoutPreview,
 		"files_produced", fileCount,
 	)
 
 	// Return response.
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(executeResponse{
+	writeResult(w, stream, &executeResponse{
 		Status:          status,
-		Stdout:          stdoutBuf.String(),
-		Stderr:          stderrBuf.String(),
-		ExitCode:        exitCode,
+		Stdout:          res.stdout,
+		Stderr:          res.stderr,
+		ExitCode:        res.exitCode,
 		ExecutionTimeMs: duration.Milliseconds(),
 		FilesProduced:   filesProduced,
 	})
 }
 
+// writeResult writes the response of an execution, as the last line of
+// the stream if there is one.
+func writeResult(w http.ResponseWriter, stream *outputStream, resp *executeResponse) {
+	if stream != nil {
+		stream.result(resp)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// runScript writes the code to a hidden script file in workDir and runs
+// it with the mode's interpreter under the limits. Output is streamed to
+// stream, if not nil.
+func (s *sandboxServer) runScript(ctx context.Context, workDir, outputDir, code string, stream *outputStream) *execResult {
+	// Get mode-specific interpreter, file extension, and env.
+	interpreter, fileExt, extraEnv := s.modeConfig(workDir, outputDir)
+
+	// Write the code to a file.
+	codePath := filepath.Join(workDir, ".script"+fileExt)
+	if err := os.WriteFile(codePath, []byte(code), 0644); err != nil {
+		return &execResult{stderr: "failed to write code: " + err.Error(), exitCode: -1, err: err}
+	}
+
+	cmdArgs := append(interpreter[1:], codePath)
+	cmd, group, err := s.isolation.command(ctx, true, interpreter[0], cmdArgs...)
+	if err != nil {
+		return &execResult{stderr: err.Error(), exitCode: -1, err: err}
+	}
+	defer s.isolation.release(cmd, group)
+	cmd.Dir = workDir
+	cmd.Env = environment(extraEnv...)
+
+	stdout := &cappedBuffer{limit: s.limits.outputBytes, forward: stream.output("stdout")}
+	stderr := &cappedBuffer{limit: s.limits.outputBytes, forward: stream.output("stderr")}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	res := &execResult{err: cmd.Run()}
+	if errors.Is(res.err, exec.ErrWaitDelay) {
+		// The code exited, but left processes behind that held its output
+		// open. They are killed on release.
+		res.err = nil
+		if !cmd.ProcessState.Success() {
+			res.err = &exec.ExitError{ProcessState: cmd.ProcessState}
+		}
+	}
+	if res.err != nil {
+		res.exitCode = -1
+		if exitErr, ok := res.err.(*exec.ExitError); ok {
+			res.exitCode = exitErr.ExitCode()
+		}
+	}
+	if ps := cmd.ProcessState; ps != nil && res.err != nil && s.limits.cpuSeconds > 0 {
+		res.cpuExceeded = ps.UserTime()+ps.SystemTime() >= time.Duration(s.limits.cpuSeconds)*time.Second
+	}
+	res.events = group.events()
+	res.stdout, res.stderr, res.stderrTail = stdout.String(), stderr.String(), stderr.Tail()
+	res.outputTruncated = stdout.truncated || stderr.truncated
+	return res
+}
+
+// runInKernel runs the code in the session's persistent Python kernel,
+// starting one if needed. A kernel that times out or dies is discarded.
+// The CPU time limit does not apply to kernels, which outlive executions.
+// Output is streamed to stream, if not nil.
+func (s *sandboxServer) runInKernel(ctx context.Context, sess *session, outputDir, code string, stream *outputStream) *execResult {
+	if sess.kernel != nil && !sess.kernel.alive() {
+		sess.stopKernel()
+	}
+	if sess.kernel == nil {
+		_, _, env := s.modeConfig(sess.dir, outputDir)
+		cmd, group, err := s.isolation.command(context.Background(), false, "python3", "-c", kernelDriver)
+		if err == nil {
+			cmd.Dir = sess.dir
+			cmd.Env = environment(env...)
+			sess.kernel, err = startKernel(cmd, group, func() { s.isolation.release(cmd, group) })
+		}
+		if err != nil {
+			return &execResult{stderr: err.Error(), exitCode: -1, err: err}
+		}
+	}
+
+	stdout := &cappedBuffer{limit: s.limits.outputBytes, forward: stream.output("stdout")}
+	stderr := &cappedBuffer{limit: s.limits.outputBytes, forward: stream.output("stderr")}
+	before := sess.kernel.group.events()
+	exitCode, err := sess.kernel.execute(ctx, code, stdout, stderr)
+	res := &execResult{
+		stdout:          stdout.String(),
+		stderr:          stderr.String(),
+		stderrTail:      stderr.Tail(),
+		exitCode:        exitCode,
+		events:          sess.kernel.group.events().sub(before),
+		outputTruncated: stdout.truncated || stderr.truncated,
+	}
+	if err != nil {
+		sess.stopKernel()
+		res.exitCode, res.err = -1, err
+	}
+	return res
+}
+
 // installRequirements installs packages based on the active mode.
 // Python: uv pip install. Node: npm install. Go/shell: skip.
 func (s *sandboxServer) installRequirements(ctx context.Context, workDir string, requirements []string, timeoutSecs int) error {
@@ -326,6 +528,7 @@ func (s *sandboxServer) installPythonRequirements(ctx context.Context, workDir s
 
 	cmd := exec.CommandContext(installCtx, "uv", args...)
 	cmd.Dir = workDir
+	cmd.Env = environment()
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -343,6 +546,7 @@ func (s *sandboxServer) installNodeRequirements(ctx context.Context, workDir str
 
 	cmd := exec.CommandContext(installCtx, "npm", args...)
 	cmd.Dir = workDir
+	cmd.Env = environment()
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -351,18 +555,52 @@ func (s *sandboxServer) installNodeRequirements(ctx context.Context, workDir str
 	return nil
 }
 
-// collectOutputFiles reads files from the output directory and encodes them as base64.
-func collectOutputFiles(outputDir string) map[string]string {
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// outputDirState records the files in the output directory.
+func outputDirState(outputDir string) map[string]fileStamp {
 	entries, err := os.ReadDir(outputDir)
-	if err != nil || len(entries) == 0 {
+	if err != nil {
 		return nil
 	}
+	state := make(map[string]fileStamp, len(entries))
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && !entry.IsDir() {
+			state[entry.Name()] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	return state
+}
+
+// collectOutputFiles reads files from the output directory and encodes
+// them as base64. Files that are unchanged since before were not produced
+// by this execution and are skipped. At most limit files are returned,
+// in name order; the number of files left out is returned as well.
+func collectOutputFiles(outputDir string, before map[string]fileStamp, limit int) (map[string]string, int) {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil || len(entries) == 0 {
+		return nil, 0
+	}
 
 	files := make(map[string]string, len(entries))
+	skipped := 0
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
+		if stamp, ok := before[entry.Name()]; ok {
+			if info, err := entry.Info(); err == nil && info.Size() == stamp.size && info.ModTime().Equal(stamp.modTime) {
+				continue
+			}
+		}
+		if limit > 0 && len(files) == limit {
+			skipped++
+			continue
+		}
 		content, err := os.ReadFile(filepath.Join(outputDir, entry.Name()))
 		if err != nil {
 			continue
@@ -371,9 +609,9 @@ func collectOutputFiles(outputDir string) map[string]string {
 	}
 
 	if len(files) == 0 {
-		return nil
+		return nil, skipped
 	}
-	return files
+	return files, skipped
 }
 
 // --- Health handler ---
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSessionFileSize limits files uploaded into a session.
+const maxSessionFileSize = 100 * 1024 * 1024
+
+// sessionIDPattern restricts session IDs to names that are safe as
+// directory names.
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+var errSessionNotFound = errors.New("session not found")
+
+// session is a persistent working directory shared by the executions
+// that name it. Executions in a session run one at a time.
+type session struct {
+	id  string
+	dir string
+
+	mu     sync.Mutex    // held while code runs in the session
+	kernel *pythonKernel // persistent interpreter, if enabled and started
+
+	lastUsed time.Time // guarded by sessionStore.mu
+}
+
+// sessionStore manages the sessions below a root directory. Sessions
+// idle for longer than idleTimeout are removed.
+type sessionStore struct {
+	root        string
+	idleTimeout time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+func newSessionStore(root string, idleTimeout time.Duration) (*sessionStore, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("creating session dir: %w", err)
+	}
+	return &sessionStore{
+		root:        root,
+		idleTimeout: idleTimeout,
+		sessions:    make(map[string]*session),
+	}, nil
+}
+
+// open returns the session with the given ID. A missing session is
+// created if create is set; otherwise errSessionNotFound is returned.
+// Session directories left by an earlier server process are adopted.
+func (st *sessionStore) open(id string, create bool) (*session, error) {
+	if !sessionIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid session id %q", id)
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if s, ok := st.sessions[id]; ok {
+		s.lastUsed = time.Now()
+		return s, nil
+	}
+
+	dir := filepath.Join(st.root, id)
+	if _, err := os.Stat(dir); err != nil {
+		if !create {
+			return nil, errSessionNotFound
+		}
+		// .pylibs exists from the start so that a persistent kernel
+		// finds packages installed after it started.
+		if err := os.MkdirAll(filepath.Join(dir, ".pylibs"), 0755); err != nil {
+			return nil, fmt.Errorf("creating session: %w", err)
+		}
+		slog.Info("session created", "session", id)
+	}
+
+	s := &session{id: id, dir: dir, lastUsed: time.Now()}
+	st.sessions[id] = s
+	return s, nil
+}
+
+// remove deletes a session, waiting for a running execution to finish.
+// Removing a missing session is not an error.
+func (st *sessionStore) remove(id string) error {
+	if !sessionIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid session id %q", id)
+	}
+
+	st.mu.Lock()
+	s, ok := st.sessions[id]
+	delete(st.sessions, id)
+	st.mu.Unlock()
+
+	if ok {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.stopKernel()
+	}
+	if err := os.RemoveAll(filepath.Join(st.root, id)); err != nil {
+		return fmt.Errorf("removing session: %w", err)
+	}
+	slog.Info("session removed", "session", id)
+	return nil
+}
+
+// expire removes the sessions that have been idle for too long. Sessions
+// with a running execution are skipped.
+func (st *sessionStore) expire() {
+	cutoff := time.Now().Add(-st.idleTimeout)
+
+	st.mu.Lock()
+	var idle []*session
+	for id, s := range st.sessions {
+		if s.lastUsed.Before(cutoff) && s.mu.TryLock() {
+			delete(st.sessions, id)
+			idle = append(idle, s)
+		}
+	}
+	st.mu.Unlock()
+
+	for _, s := range idle {
+		s.stopKernel()
+		os.RemoveAll(s.dir)
+		s.mu.Unlock()
+		slog.Info("session expired", "session", s.id)
+	}
+}
+
+// run calls expire periodically until stop is closed.
+func (st *sessionStore) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			st.expire()
+		}
+	}
+}
+
+// stopKernel stops the session's kernel. The caller holds s.mu.
+func (s *session) stopKernel() {
+	if s.kernel != nil {
+		s.kernel.stop()
+		s.kernel = nil
+	}
+}
+
+// sessionFilePath resolves a slash-separated path within the session
+// directory. Absolute paths, ".." and hidden components are rejected, so
+// clients cannot reach outside the session or its internal files.
+func sessionFilePath(dir, name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("invalid path %q", name)
+	}
+	clean := path.Clean(name)
+	for _, part := range strings.Split(clean, "/") {
+		if part == ".." || strings.HasPrefix(part, ".") {
+			return "", fmt.Errorf("invalid path %q", name)
+		}
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
+
+// --- Session handlers ---
+
+type sessionFile struct {
+	Path       string `json:"path"`
+	Bytes      int64  `json:"bytes"`
+	ModifiedAt int64  `json:"modified_at"`
+}
+
+type sessionFileList struct {
+	Files []sessionFile `json:"files"`
+}
+
+// handleListFiles lists the files of a session, sorted by path. Hidden
+// files and directories are left out. An unknown session has no files.
+func (s *sandboxServer) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	files := []sessionFile{}
+
+	sess, err := s.sessions.open(r.PathValue("id"), false)
+	switch {
+	case errors.Is(err, errSessionNotFound):
+		writeJSON(w, sessionFileList{Files: files})
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = filepath.WalkDir(sess.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == sess.dir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // Removed while walking.
+		}
+		rel, _ := filepath.Rel(sess.dir, p)
+		files = append(files, sessionFile{
+			Path:       filepath.ToSlash(rel),
+			Bytes:      info.Size(),
+			ModifiedAt: info.ModTime().Unix(),
+		})
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list files: "+err.Error())
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	writeJSON(w, sessionFileList{Files: files})
+}
+
+// handleGetFile returns the content of a session file.
+func (s *sandboxServer) handleGetFile(w http.ResponseWriter, r *http.Request) {
+	filePath, ok := s.resolveSessionFile(w, r, false)
+	if !ok {
+		return
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		writeFileError(w, err)
+		return
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || !info.Mode().IsRegular() {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	io.Copy(w, f)
+}
+
+// handlePutFile writes the request body to a session file, creating the
+// session and parent directories as needed.
+func (s *sandboxServer) handlePutFile(w http.ResponseWriter, r *http.Request) {
+	filePath, ok := s.resolveSessionFile(w, r, true)
+	if !ok {
+		return
+	}
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSessionFileSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "file too large: "+err.Error())
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create directory: "+err.Error())
+		return
+	}
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to write file: "+err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteFile deletes a session file.
+func (s *sandboxServer) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
+	filePath, ok := s.resolveSessionFile(w, r, false)
+	if !ok {
+		return
+	}
+	if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	if err := os.Remove(filePath); err != nil {
+		writeFileError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteSession deletes a session with its files and kernel.
+func (s *sandboxServer) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	if err := s.sessions.remove(r.PathValue("id")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resolveSessionFile opens the session of the request and resolves the
+// file path in it. On failure it writes the error response.
+func (s *sandboxServer) resolveSessionFile(w http.ResponseWriter, r *http.Request, create bool) (string, bool) {
+	sess, err := s.sessions.open(r.PathValue("id"), create)
+	if errors.Is(err, errSessionNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return "", false
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	filePath, err := sessionFilePath(sess.dir, r.PathValue("path"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return filePath, true
+}
+
+func writeFileError(w http.ResponseWriter, err error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"unicode/utf8"
+)
+
+// streamLine is a line of a streamed execution. Output lines carry what
+// the code wrote to stdout or stderr; the last line carries the result,
+// whose stdout and stderr repeat the output in full.
+type streamLine struct {
+	Type   string           `json:"type"` // "stdout", "stderr" or "result"
+	Data   string           `json:"data,omitempty"`
+	Result *executeResponse `json:"result,omitempty"`
+}
+
+// outputStream writes an execution as newline-delimited JSON while the
+// code runs. The response status is sent with the first line, so errors
+// before the code runs can still be reported as plain HTTP errors.
+type outputStream struct {
+	w http.ResponseWriter
+
+	mu      sync.Mutex // stdout and stderr are copied concurrently
+	started bool
+	pending map[string][]byte // incomplete UTF-8 sequences per stream
+}
+
+func newOutputStream(w http.ResponseWriter) *outputStream {
+	return &outputStream{w: w, pending: make(map[string][]byte)}
+}
+
+// output returns a function that streams the output of the named stream,
+// or nil for a nil outputStream. Characters split across writes are held
+// back until they are complete.
+func (o *outputStream) output(name string) func([]byte) {
+	if o == nil {
+		return nil
+	}
+	return func(p []byte) {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		p = append(o.pending[name], p...)
+		n := completeUTF8(p)
+		o.pending[name] = append([]byte(nil), p[n:]...)
+		if n > 0 {
+			o.writeLine(streamLine{Type: name, Data: string(p[:n])})
+		}
+	}
+}
+
+// result writes the final line.
+func (o *outputStream) result(resp *executeResponse) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.writeLine(streamLine{Type: "result", Result: resp})
+}
+
+func (o *outputStream) writeLine(line streamLine) {
+	if !o.started {
+		o.w.Header().Set("Content-Type", "application/x-ndjson")
+		o.w.WriteHeader(http.StatusOK)
+		o.started = true
+	}
+	json.NewEncoder(o.w).Encode(line)
+	http.NewResponseController(o.w).Flush()
+}
+
+// completeUTF8 returns the length of p without a trailing incomplete
+// UTF-8 sequence.
+func completeUTF8(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if !utf8.FullRune(p[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(p)
+}
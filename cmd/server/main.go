@@ -10,20 +10,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rhuss/antwort/pkg/api"
 	"github.com/rhuss/antwort/pkg/audit"
 	"github.com/rhuss/antwort/pkg/auth"
 	"github.com/rhuss/antwort/pkg/auth/apikey"
 	authjwt "github.com/rhuss/antwort/pkg/auth/jwt"
+	kubeauth "github.com/rhuss/antwort/pkg/auth/kubernetes"
 	"github.com/rhuss/antwort/pkg/auth/noop"
 	"github.com/rhuss/antwort/pkg/auth/scope"
 	"github.com/rhuss/antwort/pkg/config"
@@ -34,6 +38,8 @@ import (
 	"github.com/rhuss/antwort/pkg/provider/litellm"
 	"github.com/rhuss/antwort/pkg/provider/resilience"
 	"github.com/rhuss/antwort/pkg/provider/responses"
+	"github.com/rhuss/antwort/pkg/provider/router"
+	"github.com/rhuss/antwort/pkg/provider/tracing"
 	"github.com/rhuss/antwort/pkg/provider/vllm"
 	"github.com/rhuss/antwort/pkg/storage/memory"
 	"github.com/rhuss/antwort/pkg/storage/postgres"
@@ -47,6 +53,7 @@ import (
 	"github.com/rhuss/antwort/pkg/tools/registry"
 	"github.com/rhuss/antwort/pkg/transport"
 	transporthttp "github.com/rhuss/antwort/pkg/transport/http"
+	k8sconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
 )
 
 func main() {
@@ -76,6 +83,28 @@ func run() error {
 	// Initialize debug logging from config (env overrides config).
 	debug.Init(cfg.Logging.Debug, cfg.Logging.Level)
 
+	// Install the tracer provider (no-op unless tracing is enabled).
+	shutdownTracing, err := observability.InitTracing(context.Background(), observability.TracingConfig{
+		Enabled:     cfg.Observability.Tracing.Enabled,
+		Endpoint:    cfg.Observability.Tracing.Endpoint,
+		Insecure:    cfg.Observability.Tracing.Insecure,
+		ServiceName: cfg.Observability.Tracing.ServiceName,
+		SampleRate:  cfg.Observability.Tracing.SampleRate,
+	})
+	if err != nil {
+		return fmt.Errorf("initializing tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("failed to flush traces", "error", err)
+		}
+	}()
+	if cfg.Observability.Tracing.Enabled {
+		slog.Info("tracing enabled", "endpoint", cfg.Observability.Tracing.Endpoint, "sample_rate", cfg.Observability.Tracing.SampleRate)
+	}
+
 	// Create audit logger from config.
 	auditLogger, err := audit.New(cfg.Audit)
 	if err != nil {
@@ -90,15 +119,23 @@ func run() error {
 	}
 	defer prov.Close()
 
-	// Wrap provider with resilience (circuit breaker + retry) if enabled.
-	prov = resilience.Wrap(prov, cfg.Resilience)
-
 	// Create storage from config.
 	store, err := createStore(cfg)
 	if err != nil {
 		return fmt.Errorf("creating store: %w", err)
 	}
 
+	// Create the conversation store if storage is available. The PostgreSQL
+	// conversation store shares the response store's connection pool.
+	var convStore transport.ConversationStore
+	if store != nil {
+		if pgStore, ok := store.(*postgres.Store); ok {
+			convStore = postgres.NewConversationStore(pgStore)
+		} else {
+			convStore = memory.NewConversationStore()
+		}
+	}
+
 	// Create MCP executor if configured.
 	var executors []tools.ToolExecutor
 	mcpExecutor, err := createMCPExecutor(cfg)
@@ -110,6 +147,17 @@ func run() error {
 		defer mcpExecutor.Close()
 	}
 
+	// Allow requests to declare MCP servers from the configured allowlist.
+	var mcpPool *mcptools.Pool
+	if len(cfg.MCP.AllowedServerURLs) > 0 {
+		mcpPool = mcptools.NewPool(mcptools.PoolConfig{
+			AllowedURLs: cfg.MCP.AllowedServerURLs,
+			IdleTimeout: cfg.MCP.IdleTimeout,
+		})
+		defer mcpPool.Close()
+		slog.Info("per-request MCP servers enabled", "allowed_urls", len(cfg.MCP.AllowedServerURLs))
+	}
+
 	// Create builtin function provider registry.
 	funcRegistry := createFunctionRegistry(cfg)
 	if funcRegistry.HasProviders() {
@@ -128,13 +176,23 @@ func run() error {
 		slog.Info("agent profiles loaded", "count", len(cfg.Agents))
 	}
 
+	// Create the rate limiter if tiers are configured. The engine charges
+	// token usage to it after each response.
+	limiter := createRateLimiter(cfg, store)
+	if pgLimiter, ok := limiter.(*postgres.RateLimiter); ok {
+		defer pgLimiter.Close()
+	}
+
 	// Create engine.
 	eng, err := engine.New(prov, store, engine.Config{
 		DefaultModel:    cfg.Engine.DefaultModel,
 		MaxAgenticTurns: cfg.Engine.MaxTurns,
 		Executors:       executors,
+		MCPPool:         mcpPool,
 		ProfileResolver: profileResolver,
+		Conversations:   convStore,
 		AuditLogger:     auditLogger,
+		UsageRecorder:   limiter,
 	})
 	if err != nil {
 		return fmt.Errorf("creating engine: %w", err)
@@ -144,8 +202,7 @@ func run() error {
 	adapter := transporthttp.NewAdapter(eng, store, transporthttp.DefaultConfig())
 
 	// Enable conversations if storage is available.
-	if store != nil {
-		convStore := memory.NewConversationStore()
+	if convStore != nil {
 		adapter.SetConversationStore(convStore)
 	}
 
@@ -154,6 +211,18 @@ func run() error {
 		adapter.SetProfileResolver(profileResolver)
 	}
 
+	// Serve GET /v1/models from the provider, optionally with agent
+	// profiles as virtual models.
+	adapter.SetModelLister(prov, cfg.Models.CacheTTL)
+	adapter.SetAgentModels(cfg.Models.IncludeAgents)
+
+	// Buffer streaming events so clients can resume dropped streams.
+	var streams *transport.StreamRegistry
+	if cfg.Server.Streaming.BufferSize > 0 {
+		streams = transport.NewStreamRegistry(cfg.Server.Streaming.BufferSize, cfg.Server.Streaming.Retention)
+		adapter.SetStreamRegistry(streams)
+	}
+
 	// Wire audit logger to resource handlers.
 	adapter.SetAuditLogger(auditLogger)
 	for _, p := range funcRegistry.Providers() {
@@ -165,8 +234,17 @@ func run() error {
 		}
 	}
 
+	// Manage API keys through the admin API, stored alongside responses.
+	keyStore := createKeyStore(cfg, store)
+	if keyStore != nil {
+		adapter.SetKeyStore(keyStore)
+	}
+
 	// Build auth chain from config.
-	authChain := buildAuthChain(cfg)
+	authChain, err := buildAuthChain(cfg, keyStore)
+	if err != nil {
+		return fmt.Errorf("building auth chain: %w", err)
+	}
 
 	// Build HTTP mux with health endpoint.
 	mux := http.NewServeMux()
@@ -184,6 +262,17 @@ func run() error {
 		w.Write([]byte("ok\n"))
 	})
 
+	// Report the connection state of the configured MCP servers.
+	if mcpExecutor != nil {
+		mux.HandleFunc("GET /v1/mcp/servers", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{
+				"object": "list",
+				"data":   mcpExecutor.Health(),
+			})
+		})
+	}
+
 	// Register Prometheus metrics endpoint if enabled.
 	if cfg.Observability.Metrics.Enabled {
 		metricsPath := cfg.Observability.Metrics.Path
@@ -212,10 +301,14 @@ func run() error {
 
 	// Wrap with auth middleware.
 	if authChain != nil {
-		authMiddleware := auth.Middleware(authChain, nil, auth.DefaultBypassEndpoints, auditLogger, cfg.Auth.Authorization.AdminRole)
+		authMiddleware := auth.Middleware(authChain, limiter, auth.DefaultBypassEndpoints, auditLogger, cfg.Auth.Authorization.AdminRole)
 		handler = authMiddleware(handler)
 	}
 
+	// Join incoming W3C trace context (outermost, so every span of the
+	// request belongs to the caller's trace).
+	handler = observability.TracingMiddleware(handler)
+
 	// Emit audit startup event after all components are initialized.
 	if auditLogger != nil {
 		auditLogger.Log(context.Background(), "config.startup",
@@ -244,9 +337,13 @@ func run() error {
 
 	if mode == "worker" || mode == "integrated" {
 		bgWorker = engine.NewWorker(eng, cfg.Engine.Background)
-		// Register for in-process cancellation in integrated mode.
+		// Register for in-process cancellation in integrated mode, and
+		// let clients follow background responses as streams.
 		if mode == "integrated" {
 			adapter.SetBackgroundCanceller(bgWorker)
+			if streams != nil {
+				bgWorker.SetStreamRegistry(streams)
+			}
 		}
 	}
 
@@ -368,6 +465,23 @@ func createFunctionRegistry(cfg *config.Config) *registry.FunctionRegistry {
 		}
 	}
 
+	// The code interpreter reads attached files from and saves produced
+	// files to the Files API.
+	var ciProvider *codeinterpreter.CodeInterpreterProvider
+	var filesProvider *files.FilesProvider
+	for _, p := range reg.Providers() {
+		switch p := p.(type) {
+		case *codeinterpreter.CodeInterpreterProvider:
+			ciProvider = p
+		case *files.FilesProvider:
+			filesProvider = p
+		}
+	}
+	if ciProvider != nil && filesProvider != nil {
+		ciProvider.SetFileSaver(filesProvider)
+		ciProvider.SetFileSource(filesProvider)
+	}
+
 	return reg
 }
 
@@ -381,32 +495,93 @@ func findFileSearchProvider(reg *registry.FunctionRegistry) *filesearch.FileSear
 	return nil
 }
 
-// createProvider creates a provider.Provider from the config.
+// createProvider creates a provider.Provider from the config, wrapped with
+// resilience (circuit breaker + retry) if enabled. When engine.backends is
+// set, a router is returned that dispatches requests by model, with each
+// backend wrapped using its own resilience settings.
 func createProvider(cfg *config.Config) (provider.Provider, error) {
-	switch cfg.Engine.Provider {
+	if len(cfg.Engine.Backends) == 0 {
+		prov, err := newBackendProvider(cfg.Engine.Provider, cfg.Engine.BackendURL, cfg.Engine.APIKey, cfg.Engine.MaxContextWindow, cfg.Server.WriteTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return wrapProvider(cfg, prov, cfg.Resilience), nil
+	}
+
+	backends := make([]router.Backend, 0, len(cfg.Engine.Backends))
+	closeAll := func() {
+		for _, b := range backends {
+			b.Provider.Close()
+		}
+	}
+	for _, bc := range cfg.Engine.Backends {
+		prov, err := newBackendProvider(bc.Provider, bc.URL, bc.APIKey, bc.MaxContextWindow, cfg.Server.WriteTimeout)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("backend %q: %w", bc.Name, err)
+		}
+		resCfg := cfg.Resilience
+		if bc.Resilience != nil {
+			resCfg = *bc.Resilience
+		}
+		backends = append(backends, router.Backend{
+			Name:     bc.Name,
+			Models:   bc.Models,
+			Provider: wrapProvider(cfg, prov, resCfg),
+		})
+		slog.Info("backend configured", "name", bc.Name, "provider", bc.Provider, "url", bc.URL, "models", bc.Models)
+	}
+
+	r, err := router.New(backends)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return r, nil
+}
+
+// wrapProvider adds resilience and, if enabled, tracing to a backend
+// provider. Tracing wraps resilience so a span covers all retry attempts.
+func wrapProvider(cfg *config.Config, prov provider.Provider, resCfg config.ResilienceConfig) provider.Provider {
+	prov = resilience.Wrap(prov, resCfg)
+	if cfg.Observability.Tracing.Enabled {
+		prov = tracing.Wrap(prov)
+	}
+	return prov
+}
+
+// newBackendProvider creates a single backend provider of the given type.
+func newBackendProvider(kind, baseURL, apiKey string, maxContextWindow int, timeout time.Duration) (provider.Provider, error) {
+	switch kind {
 	case "vllm", "":
 		return vllm.New(vllm.Config{
-			BaseURL: cfg.Engine.BackendURL,
-			APIKey:  cfg.Engine.APIKey,
-			Timeout: cfg.Server.WriteTimeout,
+			BaseURL: baseURL,
+			APIKey:  apiKey,
+			Timeout: timeout,
+
+			MaxContextWindow: maxContextWindow,
 		})
 
 	case "litellm":
 		return litellm.New(litellm.Config{
-			BaseURL: cfg.Engine.BackendURL,
-			APIKey:  cfg.Engine.APIKey,
-			Timeout: cfg.Server.WriteTimeout,
+			BaseURL: baseURL,
+			APIKey:  apiKey,
+			Timeout: timeout,
+
+			MaxContextWindow: maxContextWindow,
 		})
 
 	case "vllm-responses":
 		return responses.New(responses.Config{
-			BaseURL: cfg.Engine.BackendURL,
-			APIKey:  cfg.Engine.APIKey,
-			Timeout: cfg.Server.WriteTimeout,
+			BaseURL: baseURL,
+			APIKey:  apiKey,
+			Timeout: timeout,
+
+			MaxContextWindow: maxContextWindow,
 		})
 
 	default:
-		return nil, fmt.Errorf("unknown provider type %q (supported: vllm, litellm, vllm-responses)", cfg.Engine.Provider)
+		return nil, fmt.Errorf("unknown provider type %q (supported: vllm, litellm, vllm-responses)", kind)
 	}
 }
 
@@ -434,14 +609,15 @@ func createStore(cfg *config.Config) (transport.ResponseStore, error) {
 	}
 }
 
-// createMCPExecutor creates an MCP executor from the config.
-// Returns nil if no MCP servers are configured.
+// createMCPExecutor creates an MCP executor from the config and starts
+// connecting to its servers in the background, so that an unreachable
+// server does not prevent startup. Returns nil if no MCP servers are
+// configured.
 func createMCPExecutor(cfg *config.Config) (*mcptools.MCPExecutor, error) {
 	if len(cfg.MCP.Servers) == 0 {
 		return nil, nil
 	}
 
-	ctx := context.Background()
 	clients := make(map[string]*mcptools.MCPClient, len(cfg.MCP.Servers))
 
 	for _, serverCfg := range cfg.MCP.Servers {
@@ -461,25 +637,35 @@ func createMCPExecutor(cfg *config.Config) (*mcptools.MCPExecutor, error) {
 
 		// Configure auth provider based on auth type.
 		mcpCfg.Auth = buildMCPAuthConfig(serverCfg.Auth)
+		mcpCfg.RequireApproval = buildMCPApprovalPolicy(serverCfg.RequireApproval)
 
-		client := mcptools.NewMCPClient(mcpCfg)
-		if err := client.Connect(ctx); err != nil {
-			// Close already-connected clients on failure.
-			for _, c := range clients {
-				_ = c.Close()
-			}
-			return nil, fmt.Errorf("connecting to MCP server %q: %w", serverCfg.Name, err)
-		}
-
-		clients[serverCfg.Name] = client
+		clients[serverCfg.Name] = mcptools.NewMCPClient(mcpCfg)
 		authType := serverCfg.Auth.Type
 		if authType == "" {
 			authType = "none"
 		}
-		slog.Info("MCP server connected", "name", serverCfg.Name, "url", serverCfg.URL, "transport", serverCfg.Transport, "auth", authType)
+		slog.Info("MCP server configured", "name", serverCfg.Name, "url", serverCfg.URL, "transport", serverCfg.Transport, "auth", authType)
 	}
 
-	return mcptools.NewMCPExecutor(clients), nil
+	executor := mcptools.NewMCPExecutor(clients)
+	executor.Start(mcptools.HealthConfig{
+		CheckInterval:   cfg.MCP.HealthCheckInterval,
+		RefreshInterval: cfg.MCP.ToolRefreshInterval,
+	})
+	return executor, nil
+}
+
+// buildMCPApprovalPolicy converts a config.MCPApprovalConfig to the policy
+// checked before a tool call runs. Returns nil if no approval is configured.
+func buildMCPApprovalPolicy(approvalCfg config.MCPApprovalConfig) *api.MCPApprovalPolicy {
+	if approvalCfg.Mode == "" && len(approvalCfg.Always) == 0 && len(approvalCfg.Never) == 0 {
+		return nil
+	}
+	return &api.MCPApprovalPolicy{
+		Mode:   approvalCfg.Mode,
+		Always: approvalCfg.Always,
+		Never:  approvalCfg.Never,
+	}
 }
 
 // buildMCPAuthConfig converts a config.MCPAuthConfig to the MCP package's MCPAuthConfig.
@@ -492,75 +678,216 @@ func buildMCPAuthConfig(authCfg config.MCPAuthConfig) mcptools.MCPAuthConfig {
 		ClientSecret:     authCfg.ClientSecret,
 		ClientSecretFile: authCfg.ClientSecretFile,
 		Scopes:           authCfg.Scopes,
+		Audience:         authCfg.Audience,
 	}
 }
 
 // buildAuthChain creates an auth chain from config.
 // Returns nil when auth is disabled (type=none).
-func buildAuthChain(cfg *config.Config) *auth.AuthChain {
+func buildAuthChain(cfg *config.Config, keyStore apikey.Store) (*auth.AuthChain, error) {
 	switch cfg.Auth.Type {
 	case "apikey":
 		keys := convertAPIKeys(cfg.Auth.APIKeys)
-		if len(keys) == 0 {
+		if len(keys) == 0 && keyStore == nil {
 			slog.Warn("auth.type=apikey but no api_keys configured")
-			return nil
+			return nil, nil
 		}
-		slog.Info("auth enabled", "type", "apikey", "keys", len(keys))
+		slog.Info("auth enabled", "type", "apikey", "keys", len(keys), "key_management", keyStore != nil)
 		return &auth.AuthChain{
-			Authenticators:  []auth.Authenticator{apikey.New(keys)},
+			Authenticators:  []auth.Authenticator{buildAPIKeyAuthenticator(cfg, keys, keyStore)},
 			DefaultDecision: auth.No,
-		}
+		}, nil
 
 	case "jwt":
 		jwtAuth := buildJWTAuthenticator(cfg)
-		slog.Info("auth enabled", "type", "jwt", "jwks_url", cfg.Auth.JWT.JWKSURL)
+		slog.Info("auth enabled", "type", "jwt", "jwks_url", cfg.Auth.JWT.JWKSURL, "issuers", len(cfg.Auth.JWT.Issuers))
 		return &auth.AuthChain{
 			Authenticators:  []auth.Authenticator{jwtAuth},
 			DefaultDecision: auth.No,
+		}, nil
+
+	case "kubernetes":
+		kubeAuth, err := buildKubernetesAuthenticator(cfg)
+		if err != nil {
+			return nil, err
 		}
+		slog.Info("auth enabled", "type", "kubernetes", "mode", cfg.Auth.Kubernetes.Mode)
+		return &auth.AuthChain{
+			Authenticators:  []auth.Authenticator{kubeAuth},
+			DefaultDecision: auth.No,
+		}, nil
 
 	case "chain":
-		// Chain combines API key and JWT authenticators. A request is
-		// authenticated if either method succeeds (first Yes wins).
+		// Chain combines API key, JWT, Kubernetes and introspection
+		// authenticators. A request is authenticated if any method
+		// succeeds (first Yes wins).
 		var authenticators []auth.Authenticator
 
 		keys := convertAPIKeys(cfg.Auth.APIKeys)
-		if len(keys) > 0 {
-			authenticators = append(authenticators, apikey.New(keys))
-			slog.Info("auth chain: apikey authenticator added", "keys", len(keys))
+		if len(keys) > 0 || keyStore != nil {
+			authenticators = append(authenticators, buildAPIKeyAuthenticator(cfg, keys, keyStore))
+			slog.Info("auth chain: apikey authenticator added", "keys", len(keys), "key_management", keyStore != nil)
 		}
 
-		jwtAuth := buildJWTAuthenticator(cfg)
-		authenticators = append(authenticators, jwtAuth)
-		slog.Info("auth chain: jwt authenticator added", "jwks_url", cfg.Auth.JWT.JWKSURL)
+		if jwt := cfg.Auth.JWT; jwt.JWKSURL != "" || jwt.Issuer != "" || len(jwt.Issuers) > 0 {
+			authenticators = append(authenticators, buildJWTAuthenticator(cfg))
+			slog.Info("auth chain: jwt authenticator added", "jwks_url", jwt.JWKSURL, "issuers", len(jwt.Issuers))
+		}
+
+		if cfg.Auth.Kubernetes.Enabled {
+			kubeAuth, err := buildKubernetesAuthenticator(cfg)
+			if err != nil {
+				return nil, err
+			}
+			authenticators = append(authenticators, kubeAuth)
+			slog.Info("auth chain: kubernetes authenticator added", "mode", cfg.Auth.Kubernetes.Mode)
+		}
+
+		if in := cfg.Auth.Introspection; in.URL != "" {
+			authenticators = append(authenticators, authjwt.NewIntrospector(authjwt.IntrospectionConfig{
+				URL:          in.URL,
+				ClientID:     in.ClientID,
+				ClientSecret: in.ClientSecret,
+				UserClaim:    in.UserClaim,
+				TenantClaim:  in.TenantClaim,
+				ScopesClaim:  in.ScopesClaim,
+				RolesClaim:   in.RolesClaim,
+				CacheTTL:     in.CacheTTL,
+			}))
+			slog.Info("auth chain: introspection authenticator added", "url", in.URL)
+		}
+
+		// Tokens one authenticator rejects may be of a kind a later one
+		// accepts, so only the last one rejects requests.
+		for i := 0; i < len(authenticators)-1; i++ {
+			authenticators[i] = auth.Fallthrough(authenticators[i])
+		}
 
 		slog.Info("auth enabled", "type", "chain", "authenticators", len(authenticators))
 		return &auth.AuthChain{
 			Authenticators:  authenticators,
 			DefaultDecision: auth.No,
-		}
+		}, nil
 
 	case "none", "":
 		// No auth (development mode).
-		return nil
+		return nil, nil
 
 	default:
 		slog.Warn("unknown auth type, auth disabled", "type", cfg.Auth.Type)
+		return nil, nil
+	}
+}
+
+// buildAPIKeyAuthenticator creates the API key authenticator for the static
+// keys and, if key management is enabled, the keys in keyStore.
+func buildAPIKeyAuthenticator(cfg *config.Config, keys []apikey.RawKeyEntry, keyStore apikey.Store) *apikey.Authenticator {
+	a := apikey.New(keys)
+	if keyStore != nil {
+		a.SetStore(keyStore, cfg.Auth.KeyManagement.CacheTTL)
+	}
+	return a
+}
+
+// createKeyStore creates the store of managed API keys. Returns nil if key
+// management is disabled. Keys are kept in PostgreSQL when responses are,
+// and in memory otherwise.
+func createKeyStore(cfg *config.Config, store transport.ResponseStore) apikey.Store {
+	if !cfg.Auth.KeyManagement.Enabled {
 		return nil
 	}
+	if pgStore, ok := store.(*postgres.Store); ok {
+		return postgres.NewAPIKeyStore(pgStore)
+	}
+	slog.Warn("managed API keys are kept in memory and lost on restart")
+	return memory.NewAPIKeyStore()
+}
+
+// createRateLimiter creates the per-tier rate limiter from config.
+// Returns nil if no tiers are configured. The postgres backend shares
+// the connection pool of the PostgreSQL response store.
+func createRateLimiter(cfg *config.Config, store transport.ResponseStore) auth.RateLimiter {
+	if len(cfg.RateLimits.Tiers) == 0 {
+		return nil
+	}
+	if cfg.Auth.Type == "none" || cfg.Auth.Type == "" {
+		slog.Warn("rate_limits configured but auth is disabled, rate limits are not enforced")
+		return nil
+	}
+
+	tiers := make(map[string]auth.TierConfig, len(cfg.RateLimits.Tiers))
+	for name, tc := range cfg.RateLimits.Tiers {
+		tiers[name] = auth.TierConfig{
+			RequestsPerMinute:     tc.RequestsPerMinute,
+			InputTokensPerMinute:  tc.InputTokensPerMinute,
+			OutputTokensPerMinute: tc.OutputTokensPerMinute,
+		}
+	}
+
+	if cfg.RateLimits.Backend == "postgres" {
+		if pgStore, ok := store.(*postgres.Store); ok {
+			slog.Info("rate limiting enabled", "tiers", len(tiers), "backend", "postgres", "failure_mode", cfg.RateLimits.FailureMode)
+			return postgres.NewRateLimiter(pgStore, postgres.RateLimiterConfig{
+				Tiers:        tiers,
+				SyncInterval: cfg.RateLimits.SyncInterval,
+				FailClosed:   cfg.RateLimits.FailureMode == "closed",
+			})
+		}
+		slog.Warn("rate_limits.backend is postgres but no PostgreSQL store is available, using in-process counters")
+	}
+
+	slog.Info("rate limiting enabled", "tiers", len(tiers), "backend", "memory")
+	return auth.NewInProcessLimiter(tiers)
 }
 
 // buildJWTAuthenticator creates a JWT authenticator from config.
-func buildJWTAuthenticator(cfg *config.Config) *authjwt.Authenticator {
-	return authjwt.New(authjwt.Config{
-		Issuer:      cfg.Auth.JWT.Issuer,
-		Audience:    cfg.Auth.JWT.Audience,
-		JWKSURL:     cfg.Auth.JWT.JWKSURL,
-		UserClaim:   cfg.Auth.JWT.UserClaim,
-		TenantClaim: cfg.Auth.JWT.TenantClaim,
-		ScopesClaim: cfg.Auth.JWT.ScopesClaim,
-		RolesClaim:  cfg.Auth.JWT.RolesClaim,
-	})
+func buildJWTAuthenticator(cfg *config.Config) auth.Authenticator {
+	if len(cfg.Auth.JWT.Issuers) == 0 {
+		return authjwt.New(convertJWTConfig(cfg.Auth.JWT))
+	}
+
+	// Several trusted issuers: the top-level settings, if any, are one
+	// of them.
+	var issuers []authjwt.Config
+	if cfg.Auth.JWT.Issuer != "" {
+		issuers = append(issuers, convertJWTConfig(cfg.Auth.JWT))
+	}
+	for _, iss := range cfg.Auth.JWT.Issuers {
+		issuers = append(issuers, convertJWTConfig(iss))
+	}
+	return authjwt.NewIssuers(issuers...)
+}
+
+// buildKubernetesAuthenticator creates the authenticator of service account
+// tokens of the cluster the gateway runs in (or its kubeconfig points to).
+func buildKubernetesAuthenticator(cfg *config.Config) (auth.Authenticator, error) {
+	k := cfg.Auth.Kubernetes
+	restConfig, err := k8sconfig.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes auth: get kubeconfig: %w", err)
+	}
+
+	if k.Mode == "oidc" {
+		jwtCfg := authjwt.Config{Issuer: k.Issuer, JWKSURL: k.JWKSURL}
+		if len(k.Audiences) > 0 {
+			jwtCfg.Audience = k.Audiences[0]
+		}
+		return kubeauth.NewOIDC(restConfig, jwtCfg)
+	}
+	return kubeauth.New(restConfig, kubeauth.Config{Audiences: k.Audiences, CacheTTL: k.CacheTTL})
+}
+
+// convertJWTConfig converts the config of a JWT issuer to the jwt package format.
+func convertJWTConfig(c config.JWTConfig) authjwt.Config {
+	return authjwt.Config{
+		Issuer:      c.Issuer,
+		Audience:    c.Audience,
+		JWKSURL:     c.JWKSURL,
+		UserClaim:   c.UserClaim,
+		TenantClaim: c.TenantClaim,
+		ScopesClaim: c.ScopesClaim,
+		RolesClaim:  c.RolesClaim,
+	}
 }
 
 // convertAPIKeys converts config API key entries to the apikey package format.
@@ -571,6 +898,9 @@ func convertAPIKeys(keys []config.APIKeyConfig) []apikey.RawKeyEntry {
 		if k.TenantID != "" {
 			metadata["tenant_id"] = k.TenantID
 		}
+		if len(k.Roles) > 0 {
+			metadata["roles"] = strings.Join(k.Roles, ",")
+		}
 		entries = append(entries, apikey.RawKeyEntry{
 			Key: k.Key,
 			Identity: auth.Identity{
@@ -5,7 +5,28 @@
 // via the "agent" field or the OpenAI "prompt" parameter.
 package agent
 
-import "github.com/rhuss/antwort/pkg/api"
+import (
+	"strings"
+
+	"github.com/rhuss/antwort/pkg/api"
+)
+
+// ModelPrefix prefixes the virtual model IDs under which agent profiles can
+// be listed by GET /v1/models ("agent/<name>"). A request using such a
+// model resolves the profile as if it had set the agent field.
+const ModelPrefix = "agent/"
+
+// ModelID returns the virtual model ID for a profile name.
+func ModelID(name string) string {
+	return ModelPrefix + name
+}
+
+// ProfileFromModel returns the profile name referenced by a virtual model
+// ID, or false if the model is not a virtual agent model.
+func ProfileFromModel(model string) (string, bool) {
+	name, ok := strings.CutPrefix(model, ModelPrefix)
+	return name, ok && name != ""
+}
 
 // AgentProfile is a named server-side configuration bundle.
 type AgentProfile struct {
@@ -29,6 +29,7 @@ const (
 	EventMCPCallInProgress         StreamEventType = "response.mcp_call.in_progress"
 	EventMCPCallCompleted          StreamEventType = "response.mcp_call.completed"
 	EventMCPCallFailed             StreamEventType = "response.mcp_call.failed"
+	EventMCPCallProgress           StreamEventType = "response.mcp_call.progress"
 	EventFileSearchCallInProgress  StreamEventType = "response.file_search_call.in_progress"
 	EventFileSearchCallSearching   StreamEventType = "response.file_search_call.searching"
 	EventFileSearchCallCompleted   StreamEventType = "response.file_search_call.completed"
@@ -39,6 +40,11 @@ const (
 	EventCodeInterpreterInterpreting   StreamEventType = "response.code_interpreter_call.interpreting"
 	EventCodeInterpreterCompleted      StreamEventType = "response.code_interpreter_call.completed"
 
+	// Code interpreter events: the code to run, and its output as it runs.
+	EventCodeInterpreterCodeDelta StreamEventType = "response.code_interpreter_call_code.delta"
+	EventCodeInterpreterCodeDone  StreamEventType = "response.code_interpreter_call_code.done"
+	EventCodeInterpreterLogsDelta StreamEventType = "response.code_interpreter_call_logs.delta"
+
 	// Annotation events emitted after output text is complete.
 	EventAnnotationAdded StreamEventType = "response.output_text.annotation.added"
 )
@@ -68,6 +74,16 @@ type StreamEvent struct {
 	ContentIndex    int                `json:"-"`
 	AnnotationIndex int                `json:"-"`
 	Annotation      *Annotation        `json:"-"`
+	Progress        *ToolProgress      `json:"-"`
+	OutputStream    string             `json:"-"` // stream of a logs delta: stdout or stderr
+}
+
+// ToolProgress is the progress reported by a running MCP tool call.
+// Total is omitted if the tool does not know the total amount of work.
+type ToolProgress struct {
+	Progress float64 `json:"progress"`
+	Total    float64 `json:"total,omitempty"`
+	Message  string  `json:"message,omitempty"`
 }
 
 // MarshalJSON serializes a StreamEvent with the correct fields for each event type.
@@ -209,6 +225,51 @@ func (e StreamEvent) MarshalJSON() ([]byte, error) {
 			OutputIndex    int             `json:"output_index"`
 		}{e.Type, e.SequenceNumber, e.ItemID, e.OutputIndex})
 
+	case EventMCPCallProgress:
+		// MCP progress event: type + seq + item_id + output_index + progress fields.
+		var progress ToolProgress
+		if e.Progress != nil {
+			progress = *e.Progress
+		}
+		return json.Marshal(struct {
+			Type           StreamEventType `json:"type"`
+			SequenceNumber int             `json:"sequence_number"`
+			ItemID         string          `json:"item_id"`
+			OutputIndex    int             `json:"output_index"`
+			ToolProgress
+		}{e.Type, e.SequenceNumber, e.ItemID, e.OutputIndex, progress})
+
+	case EventCodeInterpreterCodeDelta:
+		// Code delta: type + seq + item_id + output_index + delta.
+		return json.Marshal(struct {
+			Type           StreamEventType `json:"type"`
+			SequenceNumber int             `json:"sequence_number"`
+			ItemID         string          `json:"item_id"`
+			OutputIndex    int             `json:"output_index"`
+			Delta          string          `json:"delta"`
+		}{e.Type, e.SequenceNumber, e.ItemID, e.OutputIndex, e.Delta})
+
+	case EventCodeInterpreterCodeDone:
+		// Code done: type + seq + item_id + output_index + code.
+		return json.Marshal(struct {
+			Type           StreamEventType `json:"type"`
+			SequenceNumber int             `json:"sequence_number"`
+			ItemID         string          `json:"item_id"`
+			OutputIndex    int             `json:"output_index"`
+			Code           string          `json:"code"`
+		}{e.Type, e.SequenceNumber, e.ItemID, e.OutputIndex, e.Delta})
+
+	case EventCodeInterpreterLogsDelta:
+		// Logs delta: type + seq + item_id + output_index + stream + delta.
+		return json.Marshal(struct {
+			Type           StreamEventType `json:"type"`
+			SequenceNumber int             `json:"sequence_number"`
+			ItemID         string          `json:"item_id"`
+			OutputIndex    int             `json:"output_index"`
+			Stream         string          `json:"stream"`
+			Delta          string          `json:"delta"`
+		}{e.Type, e.SequenceNumber, e.ItemID, e.OutputIndex, e.OutputStream, e.Delta})
+
 	case EventAnnotationAdded:
 		// Annotation event: type + seq + item_id + output_index + content_index + annotation_index + annotation.
 		return json.Marshal(struct {
@@ -249,9 +310,12 @@ func (e *StreamEvent) UnmarshalJSON(data []byte) error {
 		Delta          string             `json:"delta"`
 		Text           string             `json:"text"`
 		Arguments      string             `json:"arguments"`
+		Code           string             `json:"code"`
+		Stream         string             `json:"stream"`
 		ItemID         string             `json:"item_id"`
 		OutputIndex    int                `json:"output_index"`
 		ContentIndex   int                `json:"content_index"`
+		ToolProgress
 	}
 	if err := json.Unmarshal(data, &raw); err != nil {
 		return err
@@ -264,14 +328,21 @@ func (e *StreamEvent) UnmarshalJSON(data []byte) error {
 	e.ItemID = raw.ItemID
 	e.OutputIndex = raw.OutputIndex
 	e.ContentIndex = raw.ContentIndex
+	e.OutputStream = raw.Stream
+	if raw.Type == EventMCPCallProgress {
+		progress := raw.ToolProgress
+		e.Progress = &progress
+	}
 
-	// Delta can come from delta, text, or arguments depending on event type.
+	// Delta can come from delta, text, arguments, or code depending on event type.
 	if raw.Delta != "" {
 		e.Delta = raw.Delta
 	} else if raw.Text != "" {
 		e.Delta = raw.Text
 	} else if raw.Arguments != "" {
 		e.Delta = raw.Arguments
+	} else if raw.Code != "" {
+		e.Delta = raw.Code
 	}
 
 	return nil
@@ -73,6 +73,37 @@ func TestStreamEventDeltaRoundTrip(t *testing.T) {
 				SequenceNumber: 50,
 			},
 		},
+		{
+			name: "mcp_call_progress",
+			event: StreamEvent{
+				Type:           EventMCPCallProgress,
+				ItemID:         "item_006",
+				OutputIndex:    1,
+				SequenceNumber: 60,
+				Progress:       &ToolProgress{Progress: 3, Total: 10, Message: "indexing"},
+			},
+		},
+		{
+			name: "code_interpreter_call_code_done",
+			event: StreamEvent{
+				Type:           EventCodeInterpreterCodeDone,
+				Delta:          "print(1)",
+				ItemID:         "item_007",
+				OutputIndex:    1,
+				SequenceNumber: 70,
+			},
+		},
+		{
+			name: "code_interpreter_call_logs_delta",
+			event: StreamEvent{
+				Type:           EventCodeInterpreterLogsDelta,
+				Delta:          "1\n",
+				ItemID:         "item_007",
+				OutputIndex:    1,
+				SequenceNumber: 71,
+				OutputStream:   "stdout",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -15,6 +15,8 @@ const (
 	fileIDPrefix         = "file_"
 	batchIDPrefix        = "batch_"
 	conversationIDPrefix = "conv_"
+	apiKeyIDPrefix       = "key_"
+	containerIDPrefix    = "cntr_"
 )
 
 var (
@@ -67,6 +69,18 @@ func NewConversationID() string {
 	return conversationIDPrefix + randomAlphanumeric(idLength)
 }
 
+// NewAPIKeyID generates a new API key ID with the "key_" prefix
+// followed by 24 cryptographically random alphanumeric characters.
+func NewAPIKeyID() string {
+	return apiKeyIDPrefix + randomAlphanumeric(idLength)
+}
+
+// NewContainerID generates a new code interpreter container ID with the
+// "cntr_" prefix followed by 24 cryptographically random alphanumeric characters.
+func NewContainerID() string {
+	return containerIDPrefix + randomAlphanumeric(idLength)
+}
+
 // ValidateConversationID checks whether the given string is a valid conversation ID.
 func ValidateConversationID(id string) bool {
 	return conversationIDPattern.MatchString(id)
@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -12,13 +13,16 @@ import (
 
 // ContentPart represents a part of user input content.
 // The Type field indicates the kind of content: input_text, input_image,
-// input_audio, or input_video.
+// input_audio, input_video, or input_file. An input_file part refers to a
+// file uploaded through the Files API by FileID.
 type ContentPart struct {
 	Type      string `json:"type"`
 	Text      string `json:"text,omitempty"`
 	URL       string `json:"url,omitempty"`
 	Data      string `json:"data,omitempty"`
 	MediaType string `json:"media_type,omitempty"`
+	FileID    string `json:"file_id,omitempty"`
+	Filename  string `json:"filename,omitempty"`
 }
 
 // OutputContentPart represents a part of model output content.
@@ -83,6 +87,9 @@ type Annotation struct {
 	Quote  string `json:"quote,omitempty"`    // file_citation: quoted passage from source
 	URL    string `json:"url,omitempty"`      // url_citation: source URL
 	Title  string `json:"title,omitempty"`    // url_citation: page title
+	// container_file_citation: file produced by the code interpreter.
+	ContainerID string `json:"container_id,omitempty"`
+	Filename    string `json:"filename,omitempty"`
 }
 
 // TokenLogprob holds log probability information for a single token.
@@ -120,6 +127,12 @@ const (
 	ItemTypeFunctionCallOutput ItemType = "function_call_output"
 	ItemTypeReasoning              ItemType = "reasoning"
 	ItemTypeCodeInterpreterCall    ItemType = "code_interpreter_call"
+	ItemTypeMCPListTools           ItemType = "mcp_list_tools"
+	ItemTypeMCPApprovalRequest     ItemType = "mcp_approval_request"
+	ItemTypeMCPApprovalResponse    ItemType = "mcp_approval_response"
+	ItemTypeMCPCall                ItemType = "mcp_call"
+	ItemTypeWebSearchCall          ItemType = "web_search_call"
+	ItemTypeFileSearchCall         ItemType = "file_search_call"
 )
 
 // ItemStatus represents the processing status of an item.
@@ -163,6 +176,9 @@ type ReasoningData struct {
 type CodeInterpreterCallData struct {
 	Code    string                    `json:"code"`
 	Outputs []CodeInterpreterOutput   `json:"outputs"`
+
+	// ContainerID is the container the code ran in.
+	ContainerID string `json:"container_id,omitempty"`
 }
 
 // CodeInterpreterOutput represents a single output from code execution.
@@ -178,6 +194,84 @@ type CodeInterpreterOutputImage struct {
 	URL    string `json:"url,omitempty"`
 }
 
+// MCPListToolsData holds the data specific to an mcp_list_tools item: the
+// tools an MCP server declared in the request offered to the model.
+type MCPListToolsData struct {
+	ServerLabel string        `json:"server_label"`
+	Tools       []MCPToolInfo `json:"tools"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// MCPToolInfo describes a single tool listed by an MCP server.
+type MCPToolInfo struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+}
+
+// MCPApprovalRequestData holds the data specific to an mcp_approval_request
+// item: a call to an MCP tool that waits for the user's approval.
+type MCPApprovalRequestData struct {
+	ServerLabel string `json:"server_label"`
+	Name        string `json:"name"`
+	Arguments   string `json:"arguments"`
+}
+
+// MCPApprovalResponseData holds the data specific to an mcp_approval_response
+// item: the user's decision on an mcp_approval_request.
+type MCPApprovalResponseData struct {
+	ApprovalRequestID string `json:"approval_request_id"`
+	Approve           bool   `json:"approve"`
+	Reason            string `json:"reason,omitempty"`
+}
+
+// MCPCallData holds the data specific to an mcp_call item: a call to an MCP
+// tool executed by the server. Error is set instead of Output when the call
+// failed.
+type MCPCallData struct {
+	ServerLabel string `json:"server_label"`
+	Name        string `json:"name"`
+	Arguments   string `json:"arguments"`
+	Output      string `json:"output,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// WebSearchCallData holds the data specific to a web_search_call item.
+type WebSearchCallData struct {
+	Action WebSearchAction `json:"action"`
+}
+
+// WebSearchAction describes what a web_search_call did. Sources is only
+// returned when the request includes "web_search_call.action.sources".
+type WebSearchAction struct {
+	Type    string            `json:"type"` // "search"
+	Query   string            `json:"query"`
+	Sources []WebSearchSource `json:"sources,omitempty"`
+}
+
+// WebSearchSource is a page found by a web search.
+type WebSearchSource struct {
+	Type  string `json:"type"` // "url"
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+}
+
+// FileSearchCallData holds the data specific to a file_search_call item.
+// Results is only returned when the request includes
+// "file_search_call.results".
+type FileSearchCallData struct {
+	Queries []string           `json:"queries"`
+	Results []FileSearchResult `json:"results"`
+}
+
+// FileSearchResult is a chunk found by a file search.
+type FileSearchResult struct {
+	FileID   string  `json:"file_id"`
+	Filename string  `json:"filename,omitempty"`
+	Score    float64 `json:"score"`
+	Text     string  `json:"text"`
+}
+
 // ---------------------------------------------------------------------------
 // Item struct (T009)
 // ---------------------------------------------------------------------------
@@ -195,6 +289,12 @@ type Item struct {
 	FunctionCallOutput   *FunctionCallOutputData   `json:"function_call_output,omitempty"`
 	Reasoning            *ReasoningData            `json:"reasoning,omitempty"`
 	CodeInterpreterCall  *CodeInterpreterCallData  `json:"code_interpreter,omitempty"`
+	MCPListTools         *MCPListToolsData         `json:"mcp_list_tools,omitempty"`
+	MCPApprovalRequest   *MCPApprovalRequestData   `json:"mcp_approval_request,omitempty"`
+	MCPApprovalResponse  *MCPApprovalResponseData  `json:"mcp_approval_response,omitempty"`
+	MCPCall              *MCPCallData              `json:"mcp_call,omitempty"`
+	WebSearchCall        *WebSearchCallData        `json:"web_search_call,omitempty"`
+	FileSearchCall       *FileSearchCallData       `json:"file_search_call,omitempty"`
 
 	Extension json.RawMessage `json:"extension,omitempty"`
 }
@@ -214,6 +314,18 @@ func (item Item) MarshalJSON() ([]byte, error) {
 		return item.marshalReasoning()
 	case ItemTypeCodeInterpreterCall:
 		return item.marshalCodeInterpreterCall()
+	case ItemTypeMCPListTools:
+		return item.marshalMCPListTools()
+	case ItemTypeMCPApprovalRequest:
+		return item.marshalMCPApprovalRequest()
+	case ItemTypeMCPApprovalResponse:
+		return item.marshalMCPApprovalResponse()
+	case ItemTypeMCPCall:
+		return item.marshalMCPCall()
+	case ItemTypeWebSearchCall:
+		return item.marshalWebSearchCall()
+	case ItemTypeFileSearchCall:
+		return item.marshalFileSearchCall()
 	default:
 		// Extension types or unknown: include extension data.
 		type wireExtension struct {
@@ -356,6 +468,120 @@ func (item Item) marshalCodeInterpreterCall() ([]byte, error) {
 	})
 }
 
+// marshalMCPListTools produces the flat mcp_list_tools wire format:
+// {type, id, status, server_label, tools: [...], error}
+func (item Item) marshalMCPListTools() ([]byte, error) {
+	type wireMCPListTools struct {
+		itemWireBase
+		MCPListToolsData
+	}
+
+	w := wireMCPListTools{
+		itemWireBase: itemWireBase{ID: item.ID, Type: item.Type, Status: item.Status},
+	}
+	if item.MCPListTools != nil {
+		w.MCPListToolsData = *item.MCPListTools
+	}
+	if w.Tools == nil {
+		w.Tools = []MCPToolInfo{}
+	}
+
+	return json.Marshal(w)
+}
+
+// marshalMCPApprovalRequest produces the flat mcp_approval_request wire format:
+// {type, id, status, server_label, name, arguments}
+func (item Item) marshalMCPApprovalRequest() ([]byte, error) {
+	type wireMCPApprovalRequest struct {
+		itemWireBase
+		MCPApprovalRequestData
+	}
+
+	w := wireMCPApprovalRequest{
+		itemWireBase: itemWireBase{ID: item.ID, Type: item.Type, Status: item.Status},
+	}
+	if item.MCPApprovalRequest != nil {
+		w.MCPApprovalRequestData = *item.MCPApprovalRequest
+	}
+
+	return json.Marshal(w)
+}
+
+// marshalMCPApprovalResponse produces the flat mcp_approval_response wire format:
+// {type, id, status, approval_request_id, approve, reason}
+func (item Item) marshalMCPApprovalResponse() ([]byte, error) {
+	type wireMCPApprovalResponse struct {
+		itemWireBase
+		MCPApprovalResponseData
+	}
+
+	w := wireMCPApprovalResponse{
+		itemWireBase: itemWireBase{ID: item.ID, Type: item.Type, Status: item.Status},
+	}
+	if item.MCPApprovalResponse != nil {
+		w.MCPApprovalResponseData = *item.MCPApprovalResponse
+	}
+
+	return json.Marshal(w)
+}
+
+// marshalMCPCall produces the flat mcp_call wire format:
+// {type, id, status, server_label, name, arguments, output, error}
+func (item Item) marshalMCPCall() ([]byte, error) {
+	type wireMCPCall struct {
+		itemWireBase
+		MCPCallData
+	}
+
+	w := wireMCPCall{
+		itemWireBase: itemWireBase{ID: item.ID, Type: item.Type, Status: item.Status},
+	}
+	if item.MCPCall != nil {
+		w.MCPCallData = *item.MCPCall
+	}
+
+	return json.Marshal(w)
+}
+
+// marshalWebSearchCall produces the flat web_search_call wire format:
+// {type, id, status, action: {type, query, sources}}
+func (item Item) marshalWebSearchCall() ([]byte, error) {
+	type wireWebSearchCall struct {
+		itemWireBase
+		WebSearchCallData
+	}
+
+	w := wireWebSearchCall{
+		itemWireBase: itemWireBase{ID: item.ID, Type: item.Type, Status: item.Status},
+	}
+	if item.WebSearchCall != nil {
+		w.WebSearchCallData = *item.WebSearchCall
+	}
+
+	return json.Marshal(w)
+}
+
+// marshalFileSearchCall produces the flat file_search_call wire format:
+// {type, id, status, queries: [...], results: [...]}
+func (item Item) marshalFileSearchCall() ([]byte, error) {
+	type wireFileSearchCall struct {
+		itemWireBase
+		FileSearchCallData
+	}
+
+	w := wireFileSearchCall{
+		itemWireBase: itemWireBase{ID: item.ID, Type: item.Type, Status: item.Status},
+	}
+	if item.FileSearchCall != nil {
+		w.FileSearchCallData = *item.FileSearchCall
+	}
+	if w.Queries == nil {
+		w.Queries = []string{}
+	}
+
+	return json.Marshal(w)
+}
+
 // UnmarshalJSON deserializes an Item from either the flat wire format
 // or the internal nested format, handling both for compatibility.
 func (item *Item) UnmarshalJSON(data []byte) error {
@@ -382,6 +608,12 @@ func (item *Item) UnmarshalJSON(data []byte) error {
 		FunctionCallOutput   *FunctionCallOutputData   `json:"function_call_output"`
 		Reasoning            *ReasoningData            `json:"reasoning"`
 		CodeInterpreterCall  *CodeInterpreterCallData  `json:"code_interpreter"`
+		MCPListTools         *MCPListToolsData         `json:"mcp_list_tools"`
+		MCPApprovalRequest   *MCPApprovalRequestData   `json:"mcp_approval_request"`
+		MCPApprovalResponse  *MCPApprovalResponseData  `json:"mcp_approval_response"`
+		MCPCall              *MCPCallData              `json:"mcp_call"`
+		WebSearchCall        *WebSearchCallData        `json:"web_search_call"`
+		FileSearchCall       *FileSearchCallData       `json:"file_search_call"`
 	}
 
 	if err := json.Unmarshal(data, &base); err != nil {
@@ -475,6 +707,66 @@ func (item *Item) UnmarshalJSON(data []byte) error {
 				item.CodeInterpreterCall = ci.CodeInterpreter
 			}
 		}
+
+	case ItemTypeMCPListTools:
+		if base.MCPListTools != nil {
+			item.MCPListTools = base.MCPListTools
+		} else {
+			var lt MCPListToolsData
+			if err := json.Unmarshal(data, &lt); err == nil {
+				item.MCPListTools = &lt
+			}
+		}
+
+	case ItemTypeMCPApprovalRequest:
+		if base.MCPApprovalRequest != nil {
+			item.MCPApprovalRequest = base.MCPApprovalRequest
+		} else {
+			var ar MCPApprovalRequestData
+			if err := json.Unmarshal(data, &ar); err == nil {
+				item.MCPApprovalRequest = &ar
+			}
+		}
+
+	case ItemTypeMCPApprovalResponse:
+		if base.MCPApprovalResponse != nil {
+			item.MCPApprovalResponse = base.MCPApprovalResponse
+		} else {
+			var ar MCPApprovalResponseData
+			if err := json.Unmarshal(data, &ar); err == nil {
+				item.MCPApprovalResponse = &ar
+			}
+		}
+
+	case ItemTypeMCPCall:
+		if base.MCPCall != nil {
+			item.MCPCall = base.MCPCall
+		} else {
+			var mc MCPCallData
+			if err := json.Unmarshal(data, &mc); err == nil {
+				item.MCPCall = &mc
+			}
+		}
+
+	case ItemTypeWebSearchCall:
+		if base.WebSearchCall != nil {
+			item.WebSearchCall = base.WebSearchCall
+		} else {
+			var ws WebSearchCallData
+			if err := json.Unmarshal(data, &ws); err == nil {
+				item.WebSearchCall = &ws
+			}
+		}
+
+	case ItemTypeFileSearchCall:
+		if base.FileSearchCall != nil {
+			item.FileSearchCall = base.FileSearchCall
+		} else {
+			var fs FileSearchCallData
+			if err := json.Unmarshal(data, &fs); err == nil {
+				item.FileSearchCall = &fs
+			}
+		}
 	}
 
 	// Preserve extension data.
@@ -565,6 +857,135 @@ type ToolDefinition struct {
 	Description string          `json:"description,omitempty"`
 	Parameters  json.RawMessage `json:"parameters,omitempty"`
 	Strict      bool            `json:"strict"`
+
+	// MCP tool fields (type "mcp"): a remote MCP server whose tools are
+	// offered to the model for this request only.
+	ServerLabel  string            `json:"server_label,omitempty"`
+	ServerURL    string            `json:"server_url,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	AllowedTools []string          `json:"allowed_tools,omitempty"`
+
+	// RequireApproval selects the server's tools that wait for the
+	// user's approval before they run. Nil means no approval is needed.
+	RequireApproval *MCPApprovalPolicy `json:"require_approval,omitempty"`
+
+	// Container selects the container of a code_interpreter tool. Nil
+	// means "auto".
+	Container *CodeInterpreterContainer `json:"container,omitempty"`
+}
+
+// MCPApprovalPolicy selects the MCP tools that need the user's approval.
+// On the wire it is either "always", "never", or an object naming tools:
+// {"always": {"tool_names": [...]}, "never": {"tool_names": [...]}}.
+// Tools named in neither list follow Mode.
+type MCPApprovalPolicy struct {
+	Mode   string   // "always" or "never"
+	Always []string // tools that always need approval
+	Never  []string // tools that never need approval
+}
+
+// Requires reports whether calls to the named tool need approval.
+func (p *MCPApprovalPolicy) Requires(toolName string) bool {
+	if p == nil || slices.Contains(p.Never, toolName) {
+		return false
+	}
+	return p.Mode == "always" || slices.Contains(p.Always, toolName)
+}
+
+// mcpToolNames is the wire format of a tool list in an approval policy.
+type mcpToolNames struct {
+	ToolNames []string `json:"tool_names"`
+}
+
+// MarshalJSON serializes the policy as a string when it names no tools.
+func (p MCPApprovalPolicy) MarshalJSON() ([]byte, error) {
+	if len(p.Always) == 0 && len(p.Never) == 0 {
+		return json.Marshal(p.Mode)
+	}
+	var w struct {
+		Always *mcpToolNames `json:"always,omitempty"`
+		Never  *mcpToolNames `json:"never,omitempty"`
+	}
+	if len(p.Always) > 0 {
+		w.Always = &mcpToolNames{ToolNames: p.Always}
+	}
+	if len(p.Never) > 0 {
+		w.Never = &mcpToolNames{ToolNames: p.Never}
+	}
+	return json.Marshal(w)
+}
+
+// UnmarshalJSON deserializes the policy from a string or an object.
+func (p *MCPApprovalPolicy) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		if mode != "always" && mode != "never" {
+			return fmt.Errorf("require_approval must be \"always\", \"never\", or an object, got %q", mode)
+		}
+		*p = MCPApprovalPolicy{Mode: mode}
+		return nil
+	}
+
+	var w struct {
+		Always mcpToolNames `json:"always"`
+		Never  mcpToolNames `json:"never"`
+	}
+	if err := json.Unmarshal(data, &w); err != nil {
+		return fmt.Errorf("require_approval must be a string or object: %w", err)
+	}
+	*p = MCPApprovalPolicy{Always: w.Always.ToolNames, Never: w.Never.ToolNames}
+	return nil
+}
+
+// CodeInterpreterContainer selects the container a code_interpreter tool
+// runs code in. On the wire it is either a container ID or the object
+// {"type": "auto", "file_ids": [...]}, which reuses the container of the
+// previous code_interpreter calls of the conversation or creates a new
+// one. The files named by file_ids are copied into the container.
+type CodeInterpreterContainer struct {
+	ID      string   // an existing container; empty for "auto"
+	FileIDs []string // uploaded files to copy into an "auto" container
+}
+
+// codeInterpreterAuto is the wire format of an "auto" container.
+type codeInterpreterAuto struct {
+	Type    string   `json:"type"`
+	FileIDs []string `json:"file_ids,omitempty"`
+}
+
+// MarshalJSON serializes the container as its ID, or as {"type": "auto"}.
+func (c CodeInterpreterContainer) MarshalJSON() ([]byte, error) {
+	if c.ID != "" {
+		return json.Marshal(c.ID)
+	}
+	return json.Marshal(codeInterpreterAuto{Type: "auto", FileIDs: c.FileIDs})
+}
+
+// UnmarshalJSON deserializes the container from an ID or an object.
+func (c *CodeInterpreterContainer) UnmarshalJSON(data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		if id == "" {
+			return fmt.Errorf("container must not be empty")
+		}
+		*c = CodeInterpreterContainer{ID: id}
+		return nil
+	}
+
+	var w codeInterpreterAuto
+	if err := json.Unmarshal(data, &w); err != nil {
+		return fmt.Errorf("container must be a container ID or object: %w", err)
+	}
+	if w.Type != "auto" {
+		return fmt.Errorf("container type must be \"auto\", got %q", w.Type)
+	}
+	for _, id := range w.FileIDs {
+		if id == "" {
+			return fmt.Errorf("container file_ids must not contain empty IDs")
+		}
+	}
+	*c = CodeInterpreterContainer{FileIDs: w.FileIDs}
+	return nil
 }
 
 // ---------------------------------------------------------------------------
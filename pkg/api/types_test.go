@@ -125,6 +125,73 @@ func TestItemRoundTrip(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "mcp_approval_request",
+			item: Item{
+				ID:     "item-006",
+				Type:   ItemTypeMCPApprovalRequest,
+				Status: ItemStatusCompleted,
+				MCPApprovalRequest: &MCPApprovalRequestData{
+					ServerLabel: "files",
+					Name:        "delete_file",
+					Arguments:   `{"path":"/tmp/x"}`,
+				},
+			},
+		},
+		{
+			name: "mcp_approval_response",
+			item: Item{
+				ID:     "item-007",
+				Type:   ItemTypeMCPApprovalResponse,
+				Status: ItemStatusCompleted,
+				MCPApprovalResponse: &MCPApprovalResponseData{
+					ApprovalRequestID: "item-006",
+					Approve:           false,
+					Reason:            "wrong file",
+				},
+			},
+		},
+		{
+			name: "mcp_call",
+			item: Item{
+				ID:     "item-008",
+				Type:   ItemTypeMCPCall,
+				Status: ItemStatusFailed,
+				MCPCall: &MCPCallData{
+					ServerLabel: "files",
+					Name:        "read_file",
+					Arguments:   `{"path":"/tmp/x"}`,
+					Error:       "file not found",
+				},
+			},
+		},
+		{
+			name: "web_search_call",
+			item: Item{
+				ID:     "item-009",
+				Type:   ItemTypeWebSearchCall,
+				Status: ItemStatusCompleted,
+				WebSearchCall: &WebSearchCallData{
+					Action: WebSearchAction{
+						Type:    "search",
+						Query:   "antwort gateway",
+						Sources: []WebSearchSource{{Type: "url", URL: "https://example.com", Title: "Example"}},
+					},
+				},
+			},
+		},
+		{
+			name: "file_search_call",
+			item: Item{
+				ID:     "item-010",
+				Type:   ItemTypeFileSearchCall,
+				Status: ItemStatusCompleted,
+				FileSearchCall: &FileSearchCallData{
+					Queries: []string{"refund policy"},
+					Results: []FileSearchResult{{FileID: "file-1", Filename: "policy.pdf", Score: 0.87, Text: "Refunds within 30 days."}},
+				},
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -232,6 +299,82 @@ func TestToolChoiceRoundTrip(t *testing.T) {
 	}
 }
 
+func TestMCPApprovalPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		wire     string
+		requires map[string]bool
+	}{
+		{"always", `"always"`, map[string]bool{"read": true, "write": true}},
+		{"never", `"never"`, map[string]bool{"read": false, "write": false}},
+		{
+			"by tool name",
+			`{"always":{"tool_names":["write"]},"never":{"tool_names":["read"]}}`,
+			map[string]bool{"read": false, "write": true, "list": false},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var p MCPApprovalPolicy
+			if err := json.Unmarshal([]byte(tc.wire), &p); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			for tool, want := range tc.requires {
+				if got := p.Requires(tool); got != want {
+					t.Errorf("Requires(%q) = %v, want %v", tool, got, want)
+				}
+			}
+			data, err := json.Marshal(p)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if string(data) != tc.wire {
+				t.Errorf("marshal = %s, want %s", data, tc.wire)
+			}
+		})
+	}
+
+	var p MCPApprovalPolicy
+	if err := json.Unmarshal([]byte(`"sometimes"`), &p); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestCodeInterpreterContainer(t *testing.T) {
+	tests := []struct {
+		wire string
+		want CodeInterpreterContainer
+	}{
+		{`"cntr_abc"`, CodeInterpreterContainer{ID: "cntr_abc"}},
+		{`{"type":"auto"}`, CodeInterpreterContainer{}},
+		{`{"type":"auto","file_ids":["file_a","file_b"]}`, CodeInterpreterContainer{FileIDs: []string{"file_a", "file_b"}}},
+	}
+	for _, tc := range tests {
+		var c CodeInterpreterContainer
+		if err := json.Unmarshal([]byte(tc.wire), &c); err != nil {
+			t.Fatalf("unmarshal %s: %v", tc.wire, err)
+		}
+		if !reflect.DeepEqual(c, tc.want) {
+			t.Errorf("unmarshal %s = %+v, want %+v", tc.wire, c, tc.want)
+		}
+		data, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		if string(data) != tc.wire {
+			t.Errorf("marshal = %s, want %s", data, tc.wire)
+		}
+	}
+
+	for _, wire := range []string{`""`, `{"type":"dedicated"}`, `{"type":"auto","file_ids":[""]}`, `42`} {
+		var c CodeInterpreterContainer
+		if err := json.Unmarshal([]byte(wire), &c); err == nil {
+			t.Errorf("expected error for %s", wire)
+		}
+	}
+}
+
 // ---------------------------------------------------------------------------
 // TestCreateResponseRequestRoundTrip
 // ---------------------------------------------------------------------------
@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 )
 
@@ -42,6 +43,14 @@ func ValidateRequest(req *CreateResponseRequest, cfg ValidationConfig) *APIError
 			fmt.Sprintf("tools exceeds maximum of %d", cfg.MaxTools))
 	}
 
+	for _, tool := range req.Tools {
+		if tool.Type == "mcp" {
+			if err := validateMCPTool(tool); err != nil {
+				return err
+			}
+		}
+	}
+
 	if req.MaxOutputTokens != nil && *req.MaxOutputTokens <= 0 {
 		return NewInvalidRequestError("max_output_tokens", "max_output_tokens must be positive")
 	}
@@ -81,6 +90,23 @@ func ValidateRequest(req *CreateResponseRequest, cfg ValidationConfig) *APIError
 	return nil
 }
 
+// validateMCPTool checks the fields of a tool with type "mcp".
+func validateMCPTool(tool ToolDefinition) *APIError {
+	if tool.ServerLabel == "" {
+		return NewInvalidRequestError("tools", "mcp tools require server_label")
+	}
+	if tool.ServerURL == "" {
+		return NewInvalidRequestError("tools",
+			fmt.Sprintf("mcp tool %q requires server_url", tool.ServerLabel))
+	}
+	u, err := url.Parse(tool.ServerURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return NewInvalidRequestError("tools",
+			fmt.Sprintf("mcp tool %q: server_url must be an absolute http or https URL", tool.ServerLabel))
+	}
+	return nil
+}
+
 // ValidateItem checks an Item for structural validity.
 func ValidateItem(item *Item) *APIError {
 	if item.ID != "" && !ValidateItemID(item.ID) {
@@ -119,6 +145,9 @@ func ValidateItem(item *Item) *APIError {
 	if item.Reasoning != nil {
 		count++
 	}
+	if item.MCPApprovalResponse != nil {
+		count++
+	}
 
 	if count != 1 {
 		return NewInvalidRequestError("type",
@@ -131,6 +160,11 @@ func ValidateItem(item *Item) *APIError {
 		if item.Message == nil {
 			return NewInvalidRequestError("message", "message field required for message type")
 		}
+		for _, part := range item.Message.Content {
+			if part.Type == "input_file" && part.FileID == "" {
+				return NewInvalidRequestError("file_id", "input_file content requires a file_id")
+			}
+		}
 	case ItemTypeFunctionCall:
 		if item.FunctionCall == nil {
 			return NewInvalidRequestError("function_call", "function_call field required for function_call type")
@@ -143,6 +177,10 @@ func ValidateItem(item *Item) *APIError {
 		if item.Reasoning == nil {
 			return NewInvalidRequestError("reasoning", "reasoning field required for reasoning type")
 		}
+	case ItemTypeMCPApprovalResponse:
+		if item.MCPApprovalResponse == nil || item.MCPApprovalResponse.ApprovalRequestID == "" {
+			return NewInvalidRequestError("approval_request_id", "approval_request_id required for mcp_approval_response type")
+		}
 	}
 
 	return nil
@@ -156,7 +194,8 @@ func IsStateless(req *CreateResponseRequest) bool {
 
 func isStandardItemType(t ItemType) bool {
 	switch t {
-	case ItemTypeMessage, ItemTypeFunctionCall, ItemTypeFunctionCallOutput, ItemTypeReasoning:
+	case ItemTypeMessage, ItemTypeFunctionCall, ItemTypeFunctionCallOutput, ItemTypeReasoning,
+		ItemTypeMCPApprovalResponse:
 		return true
 	}
 	return false
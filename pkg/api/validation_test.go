@@ -151,6 +151,29 @@ func TestValidateRequest(t *testing.T) {
 			wantErr:   true,
 			wantParam: "tools",
 		},
+		{
+			name: "mcp tool accepted",
+			modify: func(r *CreateResponseRequest) {
+				r.Tools = []ToolDefinition{{Type: "mcp", ServerLabel: "docs", ServerURL: "https://mcp.example.com/mcp"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "mcp tool without server_url rejected",
+			modify: func(r *CreateResponseRequest) {
+				r.Tools = []ToolDefinition{{Type: "mcp", ServerLabel: "docs"}}
+			},
+			wantErr:   true,
+			wantParam: "tools",
+		},
+		{
+			name: "mcp tool with non-http server_url rejected",
+			modify: func(r *CreateResponseRequest) {
+				r.Tools = []ToolDefinition{{Type: "mcp", ServerLabel: "docs", ServerURL: "file:///etc/passwd"}}
+			},
+			wantErr:   true,
+			wantParam: "tools",
+		},
 	}
 
 	for _, tt := range tests {
@@ -262,6 +285,15 @@ func TestValidateItem(t *testing.T) {
 			wantErr:   true,
 			wantParam: "message",
 		},
+		{
+			name: "input_file without file_id rejected",
+			item: Item{
+				Type:    ItemTypeMessage,
+				Message: &MessageData{Role: RoleUser, Content: []ContentPart{{Type: "input_file", Filename: "data.csv"}}},
+			},
+			wantErr:   true,
+			wantParam: "file_id",
+		},
 	}
 
 	for _, tt := range tests {
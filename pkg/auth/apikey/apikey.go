@@ -1,27 +1,59 @@
 // Package apikey provides an API key authenticator that validates
 // bearer tokens against a static key store using SHA-256 hashing
-// and constant-time comparison.
+// and constant-time comparison. Keys managed through the admin API
+// are looked up in a Store.
 package apikey
 
 import (
 	"context"
 	"crypto/sha256"
 	"crypto/subtle"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rhuss/antwort/pkg/auth"
+	"github.com/rhuss/antwort/pkg/storage"
 )
 
+// DefaultCacheTTL is how long keys looked up in the store are cached.
+const DefaultCacheTTL = 30 * time.Second
+
+// touchInterval is how often the last-used time of a key is written.
+const touchInterval = time.Minute
+
+// maxCachedKeys bounds the lookup cache. When it is full, expired entries
+// are pruned, then random ones.
+const maxCachedKeys = 10000
+
 // KeyEntry maps a key hash to an identity.
 type KeyEntry struct {
 	KeyHash  [32]byte
 	Identity auth.Identity
 }
 
-// Authenticator validates bearer tokens against a static key store.
+// Authenticator validates bearer tokens against a static key store
+// and, if set, a Store of managed keys.
 type Authenticator struct {
 	keys []KeyEntry
+
+	store    Store
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	// mu guards cache, the store lookups by key hash.
+	mu    sync.Mutex
+	cache map[string]*cachedKey
+}
+
+// cachedKey is the result of a store lookup of a known key.
+type cachedKey struct {
+	key       *Key
+	expires   time.Time
+	touchedAt time.Time
 }
 
 // New creates an API key authenticator from a list of raw keys and identities.
@@ -37,6 +69,20 @@ func New(entries []RawKeyEntry) *Authenticator {
 	return a
 }
 
+// SetStore makes the authenticator accept the keys in store, in addition to
+// the static keys. Found keys are cached for cacheTTL (DefaultCacheTTL if
+// zero), so revoked keys may be accepted that long. Misses are not cached,
+// so that unknown tokens cannot fill the cache.
+func (a *Authenticator) SetStore(store Store, cacheTTL time.Duration) {
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultCacheTTL
+	}
+	a.store = store
+	a.cacheTTL = cacheTTL
+	a.now = time.Now
+	a.cache = make(map[string]*cachedKey)
+}
+
 // RawKeyEntry is the configuration format for API keys.
 type RawKeyEntry struct {
 	Key      string
@@ -46,7 +92,7 @@ type RawKeyEntry struct {
 // Authenticate extracts the bearer token and validates it.
 // Returns Yes if valid, No if bearer token present but invalid,
 // Abstain if no Authorization header or not a Bearer token.
-func (a *Authenticator) Authenticate(_ context.Context, r *http.Request) auth.AuthResult {
+func (a *Authenticator) Authenticate(ctx context.Context, r *http.Request) auth.AuthResult {
 	header := r.Header.Get("Authorization")
 	if header == "" {
 		return auth.AuthResult{Decision: auth.Abstain}
@@ -73,6 +119,86 @@ func (a *Authenticator) Authenticate(_ context.Context, r *http.Request) auth.Au
 		}
 	}
 
+	if a.store != nil {
+		return a.authenticateStored(ctx, token)
+	}
+
 	// Bearer token present but not found.
 	return auth.AuthResult{Decision: auth.No, Err: auth.ErrUnauthenticated}
 }
+
+// authenticateStored validates a token against the managed keys and
+// records when the key was last used.
+func (a *Authenticator) authenticateStored(ctx context.Context, token string) auth.AuthResult {
+	hash := HashKey(token)
+	now := a.now()
+
+	a.mu.Lock()
+	entry, ok := a.cache[hash]
+	a.mu.Unlock()
+
+	if !ok || now.After(entry.expires) {
+		key, err := a.store.GetKeyByHash(ctx, hash)
+		if errors.Is(err, storage.ErrNotFound) {
+			if ok {
+				a.mu.Lock()
+				delete(a.cache, hash)
+				a.mu.Unlock()
+			}
+			return auth.AuthResult{Decision: auth.No, Err: auth.ErrUnauthenticated}
+		}
+		if err != nil {
+			slog.Error("failed to look up API key", "error", err)
+			return auth.AuthResult{Decision: auth.No, Err: auth.ErrUnauthenticated}
+		}
+		fresh := &cachedKey{key: key, expires: now.Add(a.cacheTTL)}
+		a.mu.Lock()
+		if ok {
+			fresh.touchedAt = entry.touchedAt
+		}
+		entry = fresh
+		a.prune(now)
+		a.cache[hash] = entry
+		a.mu.Unlock()
+	}
+
+	if entry.key.Expired(now.Unix()) {
+		return auth.AuthResult{Decision: auth.No, Err: auth.ErrUnauthenticated}
+	}
+
+	a.mu.Lock()
+	touch := now.Sub(entry.touchedAt) >= touchInterval
+	if touch {
+		entry.touchedAt = now
+	}
+	a.mu.Unlock()
+	if touch {
+		if err := a.store.TouchKey(ctx, entry.key.ID, now.Unix()); err != nil {
+			slog.Warn("failed to record API key use", "key_id", entry.key.ID, "error", err)
+		}
+	}
+
+	id := entry.key.Identity()
+	return auth.AuthResult{Decision: auth.Yes, Identity: &id}
+}
+
+// prune makes room for a new entry once the cache is full: it drops the
+// expired entries and, if the cache is still full, random ones. The
+// caller must hold a.mu.
+func (a *Authenticator) prune(now time.Time) {
+	if len(a.cache) < maxCachedKeys {
+		return
+	}
+	for hash, entry := range a.cache {
+		if now.After(entry.expires) {
+			delete(a.cache, hash)
+		}
+	}
+	// Map iteration order is random.
+	for hash := range a.cache {
+		if len(a.cache) < maxCachedKeys {
+			break
+		}
+		delete(a.cache, hash)
+	}
+}
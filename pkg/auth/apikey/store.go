@@ -0,0 +1,106 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/rhuss/antwort/pkg/auth"
+)
+
+// keyPrefix starts every generated API key.
+const keyPrefix = "sk-"
+
+// prefixLength is the number of leading key characters kept to let
+// administrators recognize a key.
+const prefixLength = 10
+
+// Key is an API key managed through the admin API. Only the SHA-256 hash
+// of the key is stored; the key itself is shown once, when it is created.
+type Key struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name,omitempty"`
+	Prefix      string   `json:"prefix"`
+	Hash        string   `json:"-"`
+	Subject     string   `json:"subject"`
+	TenantID    string   `json:"tenant_id,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	ServiceTier string   `json:"service_tier,omitempty"`
+	CreatedAt   int64    `json:"created_at"`
+	ExpiresAt   int64    `json:"expires_at,omitempty"`   // 0 if the key does not expire
+	LastUsedAt  int64    `json:"last_used_at,omitempty"` // 0 if the key was never used
+}
+
+// Expired reports whether the key has expired at the given Unix time.
+func (k *Key) Expired(now int64) bool {
+	return k.ExpiresAt > 0 && now >= k.ExpiresAt
+}
+
+// Identity returns the identity of callers authenticating with the key.
+func (k *Key) Identity() auth.Identity {
+	metadata := map[string]string{"api_key_id": k.ID}
+	if k.TenantID != "" {
+		metadata["tenant_id"] = k.TenantID
+	}
+	if len(k.Roles) > 0 {
+		metadata["roles"] = strings.Join(k.Roles, ",")
+	}
+	return auth.Identity{
+		Subject:     k.Subject,
+		ServiceTier: k.ServiceTier,
+		Scopes:      k.Scopes,
+		Metadata:    metadata,
+	}
+}
+
+// Store persists managed API keys. Implementations return
+// storage.ErrNotFound for unknown keys. GetKey, ListKeys and DeleteKey
+// only see keys of the tenant in ctx (storage.GetTenant), if set.
+type Store interface {
+	// CreateKey stores a new key.
+	CreateKey(ctx context.Context, key *Key) error
+
+	// GetKey returns the key with the given ID.
+	GetKey(ctx context.Context, id string) (*Key, error)
+
+	// GetKeyByHash returns the key with the given hash (see HashKey),
+	// regardless of tenant, to authenticate callers.
+	GetKeyByHash(ctx context.Context, hash string) (*Key, error)
+
+	// ListKeys returns all keys, oldest first.
+	ListKeys(ctx context.Context) ([]*Key, error)
+
+	// DeleteKey removes the key with the given ID.
+	DeleteKey(ctx context.Context, id string) error
+
+	// TouchKey records that the key was used at the given Unix time.
+	TouchKey(ctx context.Context, id string, usedAt int64) error
+}
+
+// GenerateKey returns a new random API key.
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashKey returns the hex-encoded SHA-256 hash under which a key is stored.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyPrefix returns the leading characters of key that are stored to let
+// administrators recognize it.
+func KeyPrefix(key string) string {
+	if len(key) <= prefixLength {
+		return key
+	}
+	return key[:prefixLength]
+}
@@ -0,0 +1,204 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/auth"
+	"github.com/rhuss/antwort/pkg/storage"
+)
+
+// fakeStore is a Store that counts lookups and touches.
+type fakeStore struct {
+	mu      sync.Mutex
+	keys    map[string]*Key // by hash
+	lookups int
+	touches int
+}
+
+func (s *fakeStore) CreateKey(_ context.Context, key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.Hash] = key
+	return nil
+}
+
+func (s *fakeStore) GetKey(context.Context, string) (*Key, error) { return nil, storage.ErrNotFound }
+
+func (s *fakeStore) GetKeyByHash(_ context.Context, hash string) (*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lookups++
+	key, ok := s.keys[hash]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	c := *key
+	return &c, nil
+}
+
+func (s *fakeStore) ListKeys(context.Context) ([]*Key, error) { return nil, nil }
+
+func (s *fakeStore) DeleteKey(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, key := range s.keys {
+		if key.ID == id {
+			delete(s.keys, hash)
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) TouchKey(context.Context, string, int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.touches++
+	return nil
+}
+
+func bearer(token string) *http.Request {
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func newStoreAuth(t *testing.T) (*Authenticator, *fakeStore, *time.Time, string) {
+	t.Helper()
+	store := &fakeStore{keys: map[string]*Key{}}
+	secret, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.CreateKey(context.Background(), &Key{
+		ID:          "key_1",
+		Hash:        HashKey(secret),
+		Subject:     "carol",
+		TenantID:    "org-2",
+		Roles:       []string{"admin", "ops"},
+		Scopes:      []string{"responses:create"},
+		ServiceTier: "premium",
+	})
+
+	now := time.Unix(1_700_000_000, 0)
+	a := newTestAuth()
+	a.SetStore(store, 30*time.Second)
+	a.now = func() time.Time { return now }
+	return a, store, &now, secret
+}
+
+func TestGenerateKey(t *testing.T) {
+	k1, _ := GenerateKey()
+	k2, _ := GenerateKey()
+	if !strings.HasPrefix(k1, "sk-") || k1 == k2 {
+		t.Errorf("GenerateKey() = %q, %q; want distinct sk- keys", k1, k2)
+	}
+	if KeyPrefix(k1) != k1[:10] {
+		t.Errorf("KeyPrefix = %q", KeyPrefix(k1))
+	}
+}
+
+func TestStoredKey(t *testing.T) {
+	a, store, _, secret := newStoreAuth(t)
+
+	result := a.Authenticate(context.Background(), bearer(secret))
+	if result.Decision != auth.Yes {
+		t.Fatalf("Decision = %d, want Yes", result.Decision)
+	}
+	id := result.Identity
+	if id.Subject != "carol" || id.ServiceTier != "premium" || id.TenantID() != "org-2" {
+		t.Errorf("Identity = %+v", id)
+	}
+	if !auth.IsAdmin(id, "admin") {
+		t.Error("identity should carry the admin role")
+	}
+	if id.Metadata["api_key_id"] != "key_1" {
+		t.Errorf("api_key_id = %q", id.Metadata["api_key_id"])
+	}
+	if store.touches != 1 {
+		t.Errorf("touches = %d, want 1", store.touches)
+	}
+
+	// Static keys still work and are not looked up in the store.
+	if result := a.Authenticate(context.Background(), bearer("sk-test-key-2")); result.Decision != auth.Yes {
+		t.Errorf("static key: Decision = %d, want Yes", result.Decision)
+	}
+	if store.lookups != 1 {
+		t.Errorf("lookups = %d, want 1", store.lookups)
+	}
+}
+
+func TestStoredKey_Unknown(t *testing.T) {
+	a, store, _, _ := newStoreAuth(t)
+
+	for range 2 {
+		result := a.Authenticate(context.Background(), bearer("sk-unknown"))
+		if result.Decision != auth.No {
+			t.Fatalf("Decision = %d, want No", result.Decision)
+		}
+	}
+	if store.lookups != 2 {
+		t.Errorf("lookups = %d, want 2 (misses are not cached)", store.lookups)
+	}
+	if len(a.cache) != 0 {
+		t.Errorf("cache holds %d entries after misses, want 0", len(a.cache))
+	}
+}
+
+func TestStoredKey_CacheBounded(t *testing.T) {
+	a, store, _, _ := newStoreAuth(t)
+	for i := range maxCachedKeys + 10 {
+		secret := fmt.Sprintf("sk-%d", i)
+		store.CreateKey(context.Background(), &Key{ID: secret, Hash: HashKey(secret), Subject: "s"})
+		if result := a.Authenticate(context.Background(), bearer(secret)); result.Decision != auth.Yes {
+			t.Fatalf("key %d: Decision = %d, want Yes", i, result.Decision)
+		}
+	}
+	// None of the entries has expired, so random ones were evicted.
+	if n := len(a.cache); n > maxCachedKeys {
+		t.Errorf("cache holds %d entries, want at most %d", n, maxCachedKeys)
+	}
+}
+
+func TestStoredKey_Expired(t *testing.T) {
+	a, store, now, secret := newStoreAuth(t)
+	store.keys[HashKey(secret)].ExpiresAt = now.Add(time.Hour).Unix()
+
+	if result := a.Authenticate(context.Background(), bearer(secret)); result.Decision != auth.Yes {
+		t.Fatalf("before expiry: Decision = %d, want Yes", result.Decision)
+	}
+	*now = now.Add(time.Hour)
+	if result := a.Authenticate(context.Background(), bearer(secret)); result.Decision != auth.No {
+		t.Errorf("after expiry: Decision = %d, want No", result.Decision)
+	}
+}
+
+func TestStoredKey_CacheAndTouch(t *testing.T) {
+	a, store, now, secret := newStoreAuth(t)
+	ctx := context.Background()
+
+	a.Authenticate(ctx, bearer(secret))
+	*now = now.Add(10 * time.Second)
+	a.Authenticate(ctx, bearer(secret))
+	if store.lookups != 1 || store.touches != 1 {
+		t.Errorf("lookups = %d, touches = %d; want 1, 1", store.lookups, store.touches)
+	}
+
+	// A revoked key is accepted until the cache entry expires.
+	store.DeleteKey(ctx, "key_1")
+	if result := a.Authenticate(ctx, bearer(secret)); result.Decision != auth.Yes {
+		t.Errorf("cached: Decision = %d, want Yes", result.Decision)
+	}
+	*now = now.Add(time.Minute)
+	if result := a.Authenticate(ctx, bearer(secret)); result.Decision != auth.No {
+		t.Errorf("revoked: Decision = %d, want No", result.Decision)
+	}
+	if store.lookups != 2 {
+		t.Errorf("lookups = %d, want 2", store.lookups)
+	}
+}
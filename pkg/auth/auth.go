@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 )
@@ -44,6 +45,11 @@ type Identity struct {
 	// Metadata carries auth-provider-specific data.
 	// The key "tenant_id" is used for storage multi-tenancy scoping.
 	Metadata map[string]string
+
+	// Token is the bearer token the caller authenticated with. It is set
+	// only by authenticators whose tokens may be passed on to downstream
+	// services (JWT), never for API keys.
+	Token string
 }
 
 // TenantID returns the tenant identifier from metadata, or empty string.
@@ -86,6 +92,10 @@ var (
 	ErrUnauthenticated = errors.New("authentication required")
 	ErrForbidden       = errors.New("access denied")
 	ErrTooManyRequests = errors.New("rate limit exceeded")
+
+	// ErrRateLimitUnavailable is returned by rate limiters that cannot
+	// reach their backing store and are configured to fail closed.
+	ErrRateLimitUnavailable = errors.New("rate limiter unavailable")
 )
 
 // AuthChain evaluates authenticators in order using three-outcome voting.
@@ -121,3 +131,25 @@ func (c *AuthChain) Authenticate(ctx context.Context, r *http.Request) AuthResul
 		Err:      ErrUnauthenticated,
 	}
 }
+
+// Fallthrough wraps an authenticator so that it abstains instead of
+// rejecting credentials it does not accept. In a chain, this lets later
+// authenticators validate tokens of another kind (for example, an opaque
+// token after the API key authenticator). If all authenticators abstain,
+// the chain's DefaultDecision applies.
+func Fallthrough(a Authenticator) Authenticator {
+	return fallthroughAuthenticator{a}
+}
+
+type fallthroughAuthenticator struct {
+	Authenticator
+}
+
+func (f fallthroughAuthenticator) Authenticate(ctx context.Context, r *http.Request) AuthResult {
+	result := f.Authenticator.Authenticate(ctx, r)
+	if result.Decision == No {
+		slog.Debug("authenticator rejected credentials, trying next", "error", result.Err)
+		return AuthResult{Decision: Abstain}
+	}
+	return result
+}
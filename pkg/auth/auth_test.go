@@ -52,6 +52,29 @@ func TestAuthChain_FirstNoStops(t *testing.T) {
 	}
 }
 
+func TestAuthChain_Fallthrough(t *testing.T) {
+	chain := &AuthChain{
+		Authenticators: []Authenticator{
+			Fallthrough(&mockAuthn{result: AuthResult{Decision: No, Err: ErrUnauthenticated}}),
+			&mockAuthn{result: AuthResult{Decision: Yes, Identity: &Identity{Subject: "bob"}}},
+		},
+		DefaultDecision: No,
+	}
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	result := chain.Authenticate(context.Background(), r)
+
+	if result.Decision != Yes || result.Identity.Subject != "bob" {
+		t.Errorf("Decision = %d, want Yes from the second authenticator", result.Decision)
+	}
+
+	// Accepted credentials still stop the chain.
+	yes := Fallthrough(&mockAuthn{result: AuthResult{Decision: Yes, Identity: &Identity{Subject: "alice"}}})
+	if result := yes.Authenticate(context.Background(), r); result.Decision != Yes {
+		t.Errorf("Decision = %d, want Yes", result.Decision)
+	}
+}
+
 func TestAuthChain_AllAbstain_DefaultReject(t *testing.T) {
 	chain := &AuthChain{
 		Authenticators: []Authenticator{
@@ -0,0 +1,213 @@
+package jwt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/rhuss/antwort/pkg/auth"
+)
+
+// maxIntrospectionCache bounds the introspection cache. When it is full,
+// expired entries are pruned, then random ones.
+const maxIntrospectionCache = 10000
+
+// IntrospectionConfig holds the configuration of an Introspector.
+type IntrospectionConfig struct {
+	// URL is the token introspection endpoint of the authorization server.
+	URL string
+
+	// ClientID and ClientSecret authenticate the gateway at the endpoint
+	// (HTTP Basic). If ClientID is empty, no credentials are sent.
+	ClientID     string
+	ClientSecret string
+
+	// UserClaim, TenantClaim, ScopesClaim and RolesClaim name the fields of
+	// the introspection response mapped to the identity, like the claims
+	// of a JWT (see Config). Defaults: "sub", "tenant_id", "scope",
+	// "realm_access.roles".
+	UserClaim   string
+	TenantClaim string
+	ScopesClaim string
+	RolesClaim  string
+
+	// CacheTTL controls how long introspection results are cached, at most
+	// until the token expires. Default: 1 minute.
+	CacheTTL time.Duration
+
+	// HTTPClient allows injecting a custom HTTP client (useful for testing).
+	// If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// claimConfig returns the claim mappings as a Config for claimsIdentity.
+func (c IntrospectionConfig) claimConfig() Config {
+	cfg := Config{
+		UserClaim:   c.UserClaim,
+		TenantClaim: c.TenantClaim,
+		ScopesClaim: c.ScopesClaim,
+		RolesClaim:  c.RolesClaim,
+	}
+	cfg.applyDefaults()
+	return cfg
+}
+
+// Introspector validates opaque bearer tokens at an OAuth 2.0 token
+// introspection endpoint (RFC 7662).
+type Introspector struct {
+	config IntrospectionConfig
+	claims Config
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]introspectionEntry // SHA-256 of the token -> result
+}
+
+// introspectionEntry is a cached introspection result. identity is nil
+// for inactive tokens.
+type introspectionEntry struct {
+	identity *auth.Identity
+	expires  time.Time
+}
+
+// NewIntrospector creates an introspection authenticator.
+func NewIntrospector(cfg IntrospectionConfig) *Introspector {
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	return &Introspector{
+		config: cfg,
+		claims: cfg.claimConfig(),
+		now:    time.Now,
+		cache:  make(map[string]introspectionEntry),
+	}
+}
+
+// Authenticate introspects the bearer token and returns the identity of
+// an active token.
+//
+// Decision outcomes:
+//   - Abstain: no Authorization header or not a Bearer scheme
+//   - No: the token is inactive or the endpoint cannot be reached
+//   - Yes: the token is active
+func (i *Introspector) Authenticate(ctx context.Context, r *http.Request) auth.AuthResult {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return auth.AuthResult{Decision: auth.Abstain}
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == "" {
+		return auth.AuthResult{Decision: auth.No, Err: fmt.Errorf("empty bearer token")}
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	now := i.now()
+
+	i.mu.Lock()
+	entry, ok := i.cache[key]
+	i.mu.Unlock()
+
+	if !ok || !now.Before(entry.expires) {
+		var err error
+		entry, err = i.introspect(ctx, token, now)
+		if err != nil {
+			slog.Warn("token introspection failed", "error", err)
+			return auth.AuthResult{Decision: auth.No, Err: err}
+		}
+		i.mu.Lock()
+		i.prune(now)
+		i.cache[key] = entry
+		i.mu.Unlock()
+	}
+
+	if entry.identity == nil {
+		return auth.AuthResult{Decision: auth.No, Err: fmt.Errorf("token is not active")}
+	}
+	id := *entry.identity
+	return auth.AuthResult{Decision: auth.Yes, Identity: &id}
+}
+
+// introspect asks the introspection endpoint about token.
+func (i *Introspector) introspect(ctx context.Context, token string, now time.Time) (introspectionEntry, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.config.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return introspectionEntry{}, fmt.Errorf("creating introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if i.config.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(i.config.ClientID), url.QueryEscape(i.config.ClientSecret))
+	}
+
+	resp, err := i.config.HTTPClient.Do(req)
+	if err != nil {
+		return introspectionEntry{}, fmt.Errorf("calling introspection endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return introspectionEntry{}, fmt.Errorf("introspection endpoint returned status %d", resp.StatusCode)
+	}
+
+	var claims jwtlib.MapClaims
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return introspectionEntry{}, fmt.Errorf("parsing introspection response: %w", err)
+	}
+
+	entry := introspectionEntry{expires: now.Add(i.config.CacheTTL)}
+	if active, _ := claims["active"].(bool); !active {
+		return entry, nil
+	}
+
+	// Do not cache beyond the expiry of the token.
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		if !now.Before(exp.Time) {
+			return entry, nil
+		}
+		if exp.Time.Before(entry.expires) {
+			entry.expires = exp.Time
+		}
+	}
+
+	identity, err := claimsIdentity(claims, token, i.claims)
+	if err != nil {
+		return introspectionEntry{}, err
+	}
+	entry.identity = identity
+	return entry, nil
+}
+
+// prune makes room for a new entry once the cache is full: it drops the
+// expired entries and, if the cache is still full, random ones. The
+// caller must hold i.mu.
+func (i *Introspector) prune(now time.Time) {
+	if len(i.cache) < maxIntrospectionCache {
+		return
+	}
+	for key, entry := range i.cache {
+		if !now.Before(entry.expires) {
+			delete(i.cache, key)
+		}
+	}
+	// Map iteration order is random.
+	for key := range i.cache {
+		if len(i.cache) < maxIntrospectionCache {
+			break
+		}
+		delete(i.cache, key)
+	}
+}
@@ -0,0 +1,121 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/auth"
+)
+
+// newIntrospectionServer serves an introspection endpoint that knows the
+// token "opaque-active" and counts its calls.
+func newIntrospectionServer(t *testing.T, calls *atomic.Int32, exp int64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if id, secret, _ := r.BasicAuth(); id != "gateway" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.PostFormValue("token") != "opaque-active" {
+			json.NewEncoder(w).Encode(map[string]any{"active": false})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"active":       true,
+			"sub":          "svc-1",
+			"tenant_id":    "org-1",
+			"scope":        "responses:create responses:read",
+			"realm_access": map[string]any{"roles": []string{"admin"}},
+			"exp":          exp,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestIntrospector(t *testing.T) {
+	var calls atomic.Int32
+	server := newIntrospectionServer(t, &calls, time.Now().Add(time.Hour).Unix())
+	in := NewIntrospector(IntrospectionConfig{URL: server.URL, ClientID: "gateway", ClientSecret: "s3cret"})
+
+	result := in.Authenticate(context.Background(), bearerRequest("opaque-active"))
+	if result.Decision != auth.Yes {
+		t.Fatalf("Decision = %d, want Yes; err=%v", result.Decision, result.Err)
+	}
+	id := result.Identity
+	if id.Subject != "svc-1" || id.TenantID() != "org-1" || len(id.Scopes) != 2 || id.Metadata["roles"] != "admin" {
+		t.Errorf("Identity = %+v", id)
+	}
+	if id.Token != "opaque-active" {
+		t.Errorf("Token = %q, want the bearer token", id.Token)
+	}
+
+	if result := in.Authenticate(context.Background(), bearerRequest("opaque-revoked")); result.Decision != auth.No {
+		t.Errorf("inactive token: Decision = %d, want No", result.Decision)
+	}
+
+	// Both results are cached.
+	in.Authenticate(context.Background(), bearerRequest("opaque-active"))
+	in.Authenticate(context.Background(), bearerRequest("opaque-revoked"))
+	if n := calls.Load(); n != 2 {
+		t.Errorf("introspection calls = %d, want 2", n)
+	}
+
+	if result := in.Authenticate(context.Background(), httptest.NewRequest("GET", "/", nil)); result.Decision != auth.Abstain {
+		t.Errorf("no token: Decision = %d, want Abstain", result.Decision)
+	}
+}
+
+func TestIntrospector_CacheExpiry(t *testing.T) {
+	var calls atomic.Int32
+	now := time.Now()
+	server := newIntrospectionServer(t, &calls, now.Add(10*time.Second).Unix())
+	in := NewIntrospector(IntrospectionConfig{URL: server.URL, ClientID: "gateway", ClientSecret: "s3cret"})
+	in.now = func() time.Time { return now }
+
+	in.Authenticate(context.Background(), bearerRequest("opaque-active"))
+
+	// The result is cached until the token expires, before the cache TTL.
+	now = now.Add(11 * time.Second)
+	in.Authenticate(context.Background(), bearerRequest("opaque-active"))
+	if n := calls.Load(); n != 2 {
+		t.Errorf("introspection calls = %d, want 2", n)
+	}
+}
+
+func TestIntrospector_CacheBounded(t *testing.T) {
+	var calls atomic.Int32
+	server := newIntrospectionServer(t, &calls, time.Now().Add(time.Hour).Unix())
+	in := NewIntrospector(IntrospectionConfig{URL: server.URL, ClientID: "gateway", ClientSecret: "s3cret"})
+
+	// Fill the cache with live entries.
+	for n := range maxIntrospectionCache {
+		in.cache[fmt.Sprintf("token-%d", n)] = introspectionEntry{expires: time.Now().Add(time.Hour)}
+	}
+	in.Authenticate(context.Background(), bearerRequest("opaque-active"))
+	if n := len(in.cache); n > maxIntrospectionCache {
+		t.Errorf("cache holds %d entries, want at most %d", n, maxIntrospectionCache)
+	}
+}
+
+func TestIntrospector_EndpointError(t *testing.T) {
+	var calls atomic.Int32
+	server := newIntrospectionServer(t, &calls, 0)
+	in := NewIntrospector(IntrospectionConfig{URL: server.URL, ClientID: "gateway", ClientSecret: "wrong"})
+
+	for range 2 {
+		if result := in.Authenticate(context.Background(), bearerRequest("opaque-active")); result.Decision != auth.No {
+			t.Errorf("Decision = %d, want No", result.Decision)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("introspection calls = %d, want 2 (errors are not cached)", n)
+	}
+}
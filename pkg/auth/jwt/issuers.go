@@ -0,0 +1,58 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/rhuss/antwort/pkg/auth"
+)
+
+// Issuers validates JWTs from several trusted issuers. Each token is
+// validated by the authenticator of the issuer named in its iss claim,
+// with that issuer's keys, audience and claim mappings. With more than
+// one issuer, subjects are qualified as "<issuer>#<subject>", so that
+// users of different issuers with the same subject stay apart in
+// ownership, rate limits and caches.
+type Issuers struct {
+	byIssuer map[string]*Authenticator
+}
+
+// NewIssuers creates an authenticator for the given issuers. Every config
+// must set Issuer; a later config for the same issuer replaces an earlier.
+func NewIssuers(cfgs ...Config) *Issuers {
+	m := &Issuers{byIssuer: make(map[string]*Authenticator, len(cfgs))}
+	for _, cfg := range cfgs {
+		m.byIssuer[cfg.Issuer] = New(cfg)
+	}
+	return m
+}
+
+// Authenticate validates the bearer token with the authenticator of its
+// issuer. Tokens from other issuers are rejected.
+func (m *Issuers) Authenticate(ctx context.Context, r *http.Request) auth.AuthResult {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return auth.AuthResult{Decision: auth.Abstain}
+	}
+
+	// The issuer is read before the signature is checked only to pick
+	// the keys to check it with.
+	var claims jwtlib.MapClaims
+	_, _, err := jwtlib.NewParser().ParseUnverified(strings.TrimPrefix(header, "Bearer "), &claims)
+	if err != nil {
+		return auth.AuthResult{Decision: auth.No, Err: fmt.Errorf("invalid JWT: %w", err)}
+	}
+	iss, _ := claims["iss"].(string)
+	a, ok := m.byIssuer[iss]
+	if !ok {
+		return auth.AuthResult{Decision: auth.No, Err: fmt.Errorf("untrusted JWT issuer %q", iss)}
+	}
+	result := a.Authenticate(ctx, r)
+	if result.Decision == auth.Yes && len(m.byIssuer) > 1 {
+		result.Identity.Subject = iss + "#" + result.Identity.Subject
+	}
+	return result
+}
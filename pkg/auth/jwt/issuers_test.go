@@ -0,0 +1,67 @@
+package jwt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/rhuss/antwort/pkg/auth"
+)
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestIssuers(t *testing.T) {
+	server := httptest.NewServer(jwksHandler(nil))
+	t.Cleanup(server.Close)
+
+	authn := NewIssuers(
+		Config{Issuer: "https://a.example.com", JWKSURL: server.URL},
+		Config{Issuer: "https://b.example.com", JWKSURL: server.URL, UserClaim: "email", TenantClaim: "org"},
+	)
+
+	tests := []struct {
+		name    string
+		claims  jwtlib.MapClaims
+		want    auth.AuthDecision
+		subject string
+		tenant  string
+	}{
+		{"issuer a", jwtlib.MapClaims{"iss": "https://a.example.com", "sub": "alice", "tenant_id": "t1"}, auth.Yes, "https://a.example.com#alice", "t1"},
+		{"issuer b mappings", jwtlib.MapClaims{"iss": "https://b.example.com", "sub": "x", "email": "bob@b", "org": "t2"}, auth.Yes, "https://b.example.com#bob@b", "t2"},
+		{"same subject at issuer b", jwtlib.MapClaims{"iss": "https://b.example.com", "sub": "x", "email": "alice", "org": "t1"}, auth.Yes, "https://b.example.com#alice", "t1"},
+		{"issuer b missing user claim", jwtlib.MapClaims{"iss": "https://b.example.com", "sub": "x"}, auth.No, "", ""},
+		{"untrusted issuer", jwtlib.MapClaims{"iss": "https://evil.example.com", "sub": "mallory"}, auth.No, "", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.claims["exp"] = time.Now().Add(time.Hour).Unix()
+			r := bearerRequest(createSignedToken(t, tc.claims))
+
+			result := authn.Authenticate(context.Background(), r)
+			if result.Decision != tc.want {
+				t.Fatalf("Decision = %d, want %d; err=%v", result.Decision, tc.want, result.Err)
+			}
+			if tc.want == auth.Yes && (result.Identity.Subject != tc.subject || result.Identity.TenantID() != tc.tenant) {
+				t.Errorf("Identity = %+v", result.Identity)
+			}
+		})
+	}
+
+	if result := authn.Authenticate(context.Background(), bearerRequest("not-a-jwt")); result.Decision != auth.No {
+		t.Errorf("garbage: Decision = %d, want No", result.Decision)
+	}
+
+	// A single issuer keeps plain subjects.
+	single := NewIssuers(Config{Issuer: "https://a.example.com", JWKSURL: server.URL})
+	token := createSignedToken(t, jwtlib.MapClaims{"iss": "https://a.example.com", "sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+	if result := single.Authenticate(context.Background(), bearerRequest(token)); result.Decision != auth.Yes || result.Identity.Subject != "alice" {
+		t.Errorf("single issuer: %+v", result)
+	}
+}
@@ -1,15 +1,23 @@
 // Package jwt provides a JWT/OIDC authenticator that validates
 // bearer tokens against a JWKS (JSON Web Key Set) endpoint.
 //
-// It supports RSA-signed JWTs with configurable issuer, audience,
-// and custom claim extraction for subject, tenant, and scopes.
+// It supports RSA, ECDSA and Ed25519 signed JWTs with configurable
+// issuer, audience, and custom claim extraction for subject, tenant,
+// and scopes. The JWKS endpoint can be discovered from the issuer's
+// OpenID configuration. Issuers accepts tokens from several issuers,
+// and Introspector validates opaque tokens (RFC 7662).
 package jwt
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -32,6 +40,8 @@ type Config struct {
 	Audience string
 
 	// JWKSURL is the URL to fetch the JSON Web Key Set for signature verification.
+	// If empty, it is discovered from the issuer's OpenID configuration
+	// ("{Issuer}/.well-known/openid-configuration").
 	JWKSURL string
 
 	// UserClaim is the JWT claim used as the identity subject. Default: "sub".
@@ -92,9 +102,10 @@ func New(cfg Config) *Authenticator {
 	return &Authenticator{
 		config: cfg,
 		jwksCache: &jwksCache{
-			keys:    make(map[string]*rsa.PublicKey),
+			keys:    make(map[string]crypto.PublicKey),
 			ttl:     cfg.CacheTTL,
 			jwksURL: cfg.JWKSURL,
+			issuer:  cfg.Issuer,
 			client:  cfg.HTTPClient,
 		},
 	}
@@ -130,8 +141,11 @@ func (a *Authenticator) Authenticate(ctx context.Context, r *http.Request) auth.
 	// We use ParseUnverified first to get the kid, then verify with the
 	// matching JWKS key.
 	token, err := jwtlib.Parse(tokenStr, func(token *jwtlib.Token) (interface{}, error) {
-		// Ensure the signing method is RSA.
-		if _, ok := token.Method.(*jwtlib.SigningMethodRSA); !ok {
+		// Ensure the signing method is asymmetric. The library checks
+		// that the key type matches the method.
+		switch token.Method.(type) {
+		case *jwtlib.SigningMethodRSA, *jwtlib.SigningMethodECDSA, *jwtlib.SigningMethodEd25519:
+		default:
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 
@@ -164,44 +178,52 @@ func (a *Authenticator) Authenticate(ctx context.Context, r *http.Request) auth.
 		}
 	}
 
+	identity, err := claimsIdentity(claims, tokenStr, a.config)
+	if err != nil {
+		return auth.AuthResult{Decision: auth.No, Err: err}
+	}
+	return auth.AuthResult{
+		Decision: auth.Yes,
+		Identity: identity,
+	}
+}
+
+// claimsIdentity builds the identity of a caller from the claims of its
+// token, using the claim names of cfg.
+func claimsIdentity(claims jwtlib.MapClaims, token string, cfg Config) (*auth.Identity, error) {
 	// Extract subject.
-	subject := claimString(claims, a.config.UserClaim)
+	subject := claimString(claims, cfg.UserClaim)
 	if subject == "" {
-		return auth.AuthResult{
-			Decision: auth.No,
-			Err:      fmt.Errorf("JWT missing %q claim", a.config.UserClaim),
-		}
+		return nil, fmt.Errorf("token missing %q claim", cfg.UserClaim)
 	}
 
 	// Build identity.
 	identity := &auth.Identity{
 		Subject:  subject,
 		Metadata: make(map[string]string),
+		Token:    token,
 	}
 
 	// Extract tenant.
-	if tenant := claimString(claims, a.config.TenantClaim); tenant != "" {
+	if tenant := claimString(claims, cfg.TenantClaim); tenant != "" {
 		identity.Metadata["tenant_id"] = tenant
 	}
 
 	// Extract scopes.
-	identity.Scopes = extractScopes(claims, a.config.ScopesClaim)
+	identity.Scopes = extractScopes(claims, cfg.ScopesClaim)
 
 	// Extract roles from nested claim path (e.g., "realm_access.roles").
-	if roles := extractRoles(claims, a.config.RolesClaim); roles != "" {
+	if roles := extractRoles(claims, cfg.RolesClaim); roles != "" {
 		identity.Metadata["roles"] = roles
 	}
 
-	return auth.AuthResult{
-		Decision: auth.Yes,
-		Identity: identity,
-	}
+	return identity, nil
 }
 
 // parserOptions builds JWT parser options based on the configuration.
 func (a *Authenticator) parserOptions() []jwtlib.ParserOption {
 	opts := []jwtlib.ParserOption{
-		jwtlib.WithValidMethods([]string{"RS256", "RS384", "RS512"}),
+		jwtlib.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
 	}
 
 	if a.config.Issuer != "" {
@@ -300,20 +322,21 @@ func extractRoles(claims jwtlib.MapClaims, path string) string {
 	return strings.Join(roles, ",")
 }
 
-// jwksCache caches RSA public keys fetched from a JWKS endpoint.
+// jwksCache caches public keys fetched from a JWKS endpoint.
 // It is thread-safe and supports TTL-based cache invalidation.
 type jwksCache struct {
 	mu        sync.RWMutex
-	keys      map[string]*rsa.PublicKey // kid -> public key
+	keys      map[string]crypto.PublicKey // kid -> public key
 	fetchedAt time.Time
 	ttl       time.Duration
-	jwksURL   string
+	jwksURL   string // discovered from issuer if empty
+	issuer    string
 	client    *http.Client
 }
 
-// getKey returns the RSA public key for the given kid.
+// getKey returns the public key for the given kid.
 // It fetches from the JWKS endpoint if the cache is expired or the kid is unknown.
-func (c *jwksCache) getKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+func (c *jwksCache) getKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
 	// Try cache first with read lock.
 	c.mu.RLock()
 	if key, ok := c.keys[kid]; ok && time.Since(c.fetchedAt) < c.ttl {
@@ -346,6 +369,14 @@ func (c *jwksCache) getKey(ctx context.Context, kid string) (*rsa.PublicKey, err
 // fetchJWKS fetches the JWKS from the configured URL and populates the key cache.
 // Must be called with the write lock held.
 func (c *jwksCache) fetchJWKS(ctx context.Context) error {
+	if c.jwksURL == "" {
+		jwksURL, err := discoverJWKSURL(ctx, c.client, c.issuer)
+		if err != nil {
+			return err
+		}
+		c.jwksURL = jwksURL
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.jwksURL, nil)
 	if err != nil {
 		return fmt.Errorf("creating JWKS request: %w", err)
@@ -371,16 +402,16 @@ func (c *jwksCache) fetchJWKS(ctx context.Context) error {
 		return fmt.Errorf("parsing JWKS: %w", err)
 	}
 
-	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
 	for _, jwk := range jwks.Keys {
-		if jwk.Kty != "RSA" {
-			continue
-		}
 		if jwk.Use != "" && jwk.Use != "sig" {
 			continue
 		}
 
-		pubKey, err := parseRSAPublicKey(jwk)
+		pubKey, err := parsePublicKey(jwk)
+		if errors.Is(err, errUnsupportedKey) {
+			continue
+		}
 		if err != nil {
 			slog.Warn("skipping JWKS key", "kid", jwk.Kid, "error", err)
 			continue
@@ -403,11 +434,32 @@ type jwksDocument struct {
 
 // jwkKey represents a single JSON Web Key.
 type jwkKey struct {
-	Kty string `json:"kty"` // Key type (e.g., "RSA")
+	Kty string `json:"kty"` // Key type ("RSA", "EC" or "OKP")
 	Kid string `json:"kid"` // Key ID
 	Use string `json:"use"` // Key use (e.g., "sig")
 	N   string `json:"n"`   // RSA modulus (base64url-encoded)
 	E   string `json:"e"`   // RSA public exponent (base64url-encoded)
+	Crv string `json:"crv"` // Curve of EC and OKP keys (e.g., "P-256", "Ed25519")
+	X   string `json:"x"`   // EC x coordinate or OKP public key (base64url-encoded)
+	Y   string `json:"y"`   // EC y coordinate (base64url-encoded)
+}
+
+// errUnsupportedKey is returned by parsePublicKey for key types and
+// curves that cannot verify JWT signatures.
+var errUnsupportedKey = errors.New("unsupported key type")
+
+// parsePublicKey constructs the public key of a JWK.
+func parsePublicKey(jwk jwkKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		return parseRSAPublicKey(jwk)
+	case "EC":
+		return parseECPublicKey(jwk)
+	case "OKP":
+		return parseEd25519PublicKey(jwk)
+	default:
+		return nil, errUnsupportedKey
+	}
 }
 
 // parseRSAPublicKey constructs an *rsa.PublicKey from a JWK.
@@ -436,3 +488,94 @@ func parseRSAPublicKey(jwk jwkKey) (*rsa.PublicKey, error) {
 		E: int(e.Int64()),
 	}, nil
 }
+
+// parseECPublicKey constructs an *ecdsa.PublicKey from a JWK.
+func parseECPublicKey(jwk jwkKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, errUnsupportedKey
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("decoding x coordinate: %w", err)
+	}
+	yBytes, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("decoding y coordinate: %w", err)
+	}
+
+	// Parse the uncompressed encoding of the point, which rejects points
+	// that are not on the curve.
+	size := (curve.Params().BitSize + 7) / 8
+	if len(xBytes) > size || len(yBytes) > size {
+		return nil, fmt.Errorf("EC coordinates too long for %s", jwk.Crv)
+	}
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+	copy(point[1+size-len(xBytes):1+size], xBytes)
+	copy(point[1+2*size-len(yBytes):], yBytes)
+	return ecdsa.ParseUncompressedPublicKey(curve, point)
+}
+
+// parseEd25519PublicKey constructs an ed25519.PublicKey from a JWK.
+func parseEd25519PublicKey(jwk jwkKey) (ed25519.PublicKey, error) {
+	if jwk.Crv != "Ed25519" {
+		return nil, errUnsupportedKey
+	}
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("decoding public key: %w", err)
+	}
+	if len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("Ed25519 public key has %d bytes, want %d", len(x), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(x), nil
+}
+
+// discoverJWKSURL reads the JWKS URL from the OpenID configuration of
+// the issuer.
+func discoverJWKSURL(ctx context.Context, client *http.Client, issuer string) (string, error) {
+	if issuer == "" {
+		return "", fmt.Errorf("no JWKS URL configured and no issuer to discover it from")
+	}
+	configURL := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, configURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating discovery request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetching OpenID configuration: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OpenID configuration endpoint returned status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("parsing OpenID configuration: %w", err)
+	}
+	if doc.Issuer != issuer {
+		return "", fmt.Errorf("OpenID configuration is for issuer %q, want %q", doc.Issuer, issuer)
+	}
+	if doc.JWKSURI == "" {
+		return "", fmt.Errorf("OpenID configuration of %q has no jwks_uri", issuer)
+	}
+
+	slog.Info("discovered JWKS URL", "issuer", issuer, "jwks_url", doc.JWKSURI)
+	return doc.JWKSURI, nil
+}
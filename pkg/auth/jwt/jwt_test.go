@@ -2,6 +2,9 @@ package jwt
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/base64"
@@ -122,6 +125,9 @@ func TestJWT_ValidToken(t *testing.T) {
 	if result.Identity.Subject != "user-123" {
 		t.Errorf("Subject = %q, want %q", result.Identity.Subject, "user-123")
 	}
+	if result.Identity.Token != token {
+		t.Errorf("Token = %q, want the bearer token", result.Identity.Token)
+	}
 }
 
 func TestJWT_ExpiredToken(t *testing.T) {
@@ -483,3 +489,92 @@ func TestJWT_NoAudienceValidation(t *testing.T) {
 		t.Fatalf("Decision = %d, want Yes (no audience validation); err=%v", result.Decision, result.Err)
 	}
 }
+
+func TestJWT_ECAndEdDSAKeys(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	point, _ := ecKey.PublicKey.Bytes() // 0x04 || x || y
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"}, // not usable, skipped
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256",
+				"x": base64.RawURLEncoding.EncodeToString(point[1:33]),
+				"y": base64.RawURLEncoding.EncodeToString(point[33:])},
+			{"kty": "OKP", "kid": "ed-1", "crv": "Ed25519", "x": base64.RawURLEncoding.EncodeToString(edPub)},
+		}})
+	}))
+	t.Cleanup(server.Close)
+	authn := New(Config{Issuer: "https://auth.example.com", JWKSURL: server.URL})
+
+	claims := jwtlib.MapClaims{
+		"sub": "user-123",
+		"iss": "https://auth.example.com",
+		"exp": time.Now().Add(1 * time.Hour).Unix(),
+	}
+	tests := []struct {
+		name   string
+		method jwtlib.SigningMethod
+		kid    string
+		key    any
+	}{
+		{"ES256", jwtlib.SigningMethodES256, "ec-1", ecKey},
+		{"EdDSA", jwtlib.SigningMethodEdDSA, "ed-1", edKey},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			token := jwtlib.NewWithClaims(tc.method, claims)
+			token.Header["kid"] = tc.kid
+			tokenStr, err := token.SignedString(tc.key)
+			if err != nil {
+				t.Fatalf("signing token: %v", err)
+			}
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Authorization", "Bearer "+tokenStr)
+			result := authn.Authenticate(context.Background(), r)
+			if result.Decision != auth.Yes {
+				t.Fatalf("Decision = %d, want Yes; err=%v", result.Decision, result.Err)
+			}
+
+			// A token signed for another key's kid is rejected.
+			token.Header["kid"] = map[string]string{"ec-1": "ed-1", "ed-1": "ec-1"}[tc.kid]
+			tokenStr, _ = token.SignedString(tc.key)
+			r.Header.Set("Authorization", "Bearer "+tokenStr)
+			if result := authn.Authenticate(context.Background(), r); result.Decision != auth.No {
+				t.Errorf("wrong kid: Decision = %d, want No", result.Decision)
+			}
+		})
+	}
+}
+
+func TestJWT_Discovery(t *testing.T) {
+	var issuer string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/realms/test/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/certs"})
+	})
+	mux.HandleFunc("/realms/test/certs", jwksHandler(nil))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	issuer = server.URL + "/realms/test"
+
+	token := createSignedToken(t, jwtlib.MapClaims{
+		"sub": "user-123",
+		"iss": issuer,
+		"exp": time.Now().Add(1 * time.Hour).Unix(),
+	})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	result := New(Config{Issuer: issuer}).Authenticate(context.Background(), r)
+	if result.Decision != auth.Yes {
+		t.Fatalf("Decision = %d, want Yes; err=%v", result.Decision, result.Err)
+	}
+
+	// The discovered configuration must be for the configured issuer.
+	result = New(Config{Issuer: issuer + "/"}).Authenticate(context.Background(), r)
+	if result.Decision != auth.No {
+		t.Errorf("issuer mismatch: Decision = %d, want No", result.Decision)
+	}
+}
@@ -0,0 +1,220 @@
+// Package kubernetes provides an authenticator for Kubernetes
+// ServiceAccount tokens, so that workloads in the cluster can call the
+// gateway with their projected tokens.
+//
+// Tokens are validated with the TokenReview API of the cluster, or locally
+// against the cluster's OIDC issuer (see NewOIDC). Service accounts map to
+// the subject "<namespace>/<name>" in the tenant "<namespace>", and the
+// user's groups map to roles, so that role scopes apply to workloads.
+package kubernetes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	authv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/rhuss/antwort/pkg/auth"
+)
+
+// DefaultCacheTTL is how long token reviews are cached by default.
+const DefaultCacheTTL = time.Minute
+
+// maxCachedReviews bounds the review cache.
+const maxCachedReviews = 10000
+
+// serviceAccountPrefix starts the user names of service accounts.
+const serviceAccountPrefix = "system:serviceaccount:"
+
+// Config holds the authenticator configuration.
+type Config struct {
+	// Audiences the token must be valid for. If empty, the API server
+	// checks the token against its own audience.
+	Audiences []string
+
+	// CacheTTL controls how long review results, including rejections,
+	// are cached. Default: DefaultCacheTTL.
+	CacheTTL time.Duration
+}
+
+// TokenReviewer creates TokenReviews. It is implemented by the TokenReview
+// client of client-go.
+type TokenReviewer interface {
+	Create(ctx context.Context, review *authv1.TokenReview, opts metav1.CreateOptions) (*authv1.TokenReview, error)
+}
+
+// Authenticator validates bearer tokens with the TokenReview API.
+type Authenticator struct {
+	reviews TokenReviewer
+	config  Config
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]reviewEntry // SHA-256 of the token -> result
+}
+
+// reviewEntry is a cached review result. identity is nil for rejected
+// tokens.
+type reviewEntry struct {
+	identity *auth.Identity
+	expires  time.Time
+}
+
+// New creates an authenticator that reviews tokens at the API server of
+// restConfig. The gateway's own credentials in restConfig must allow
+// creating tokenreviews (the system:auth-delegator cluster role).
+func New(restConfig *rest.Config, cfg Config) (*Authenticator, error) {
+	client, err := authv1client.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating TokenReview client: %w", err)
+	}
+	return NewWithReviewer(client.TokenReviews(), cfg), nil
+}
+
+// NewWithReviewer creates an authenticator that reviews tokens with the
+// given reviewer.
+func NewWithReviewer(reviews TokenReviewer, cfg Config) *Authenticator {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = DefaultCacheTTL
+	}
+	return &Authenticator{
+		reviews: reviews,
+		config:  cfg,
+		now:     time.Now,
+		cache:   make(map[string]reviewEntry),
+	}
+}
+
+// Authenticate reviews the bearer token and returns the identity of the
+// service account it belongs to.
+//
+// Decision outcomes:
+//   - Abstain: no Authorization header or not a Bearer scheme
+//   - No: the token is not authenticated, belongs to a user that is not a
+//     service account, or the API server cannot be reached
+//   - Yes: the token of a service account is authenticated
+func (a *Authenticator) Authenticate(ctx context.Context, r *http.Request) auth.AuthResult {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return auth.AuthResult{Decision: auth.Abstain}
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == "" {
+		return auth.AuthResult{Decision: auth.No, Err: fmt.Errorf("empty bearer token")}
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	now := a.now()
+
+	a.mu.Lock()
+	entry, ok := a.cache[key]
+	a.mu.Unlock()
+
+	if !ok || !now.Before(entry.expires) {
+		var err error
+		entry, err = a.review(ctx, token, now)
+		if err != nil {
+			slog.Warn("Kubernetes token review failed", "error", err)
+			return auth.AuthResult{Decision: auth.No, Err: err}
+		}
+		a.mu.Lock()
+		a.prune(now)
+		a.cache[key] = entry
+		a.mu.Unlock()
+	}
+
+	if entry.identity == nil {
+		return auth.AuthResult{Decision: auth.No, Err: fmt.Errorf("Kubernetes token not authenticated")}
+	}
+	id := *entry.identity
+	return auth.AuthResult{Decision: auth.Yes, Identity: &id}
+}
+
+// review asks the API server about token.
+func (a *Authenticator) review(ctx context.Context, token string, now time.Time) (reviewEntry, error) {
+	result, err := a.reviews.Create(ctx, &authv1.TokenReview{
+		Spec: authv1.TokenReviewSpec{Token: token, Audiences: a.config.Audiences},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return reviewEntry{}, fmt.Errorf("creating TokenReview: %w", err)
+	}
+
+	entry := reviewEntry{expires: now.Add(a.config.CacheTTL)}
+	status := result.Status
+	if !status.Authenticated || status.User.Username == "" {
+		if status.Error != "" {
+			slog.Debug("Kubernetes token rejected", "error", status.Error)
+		}
+		return entry, nil
+	}
+	// Other users of the cluster, such as nodes or users of its identity
+	// provider, have no tenant and are rejected like in OIDC mode.
+	namespace, name, ok := parseServiceAccount(status.User.Username)
+	if !ok {
+		slog.Debug("Kubernetes user is not a service account", "user", status.User.Username)
+		return entry, nil
+	}
+	entry.identity = userIdentity(namespace, name, status.User.Groups)
+	return entry, nil
+}
+
+// prune makes room for a new entry once the cache is full: it drops the
+// expired entries and, if the cache is still full, random ones. The
+// caller must hold a.mu.
+func (a *Authenticator) prune(now time.Time) {
+	if len(a.cache) < maxCachedReviews {
+		return
+	}
+	for key, entry := range a.cache {
+		if !now.Before(entry.expires) {
+			delete(a.cache, key)
+		}
+	}
+	// Map iteration order is random.
+	for key := range a.cache {
+		if len(a.cache) < maxCachedReviews {
+			break
+		}
+		delete(a.cache, key)
+	}
+}
+
+// userIdentity maps a service account to an identity with the subject
+// "<namespace>/<name>" in tenant <namespace>. Groups become roles. The
+// identity carries no token: service account tokens are not issued for
+// MCP servers and must not be passed on to them.
+func userIdentity(namespace, name string, groups []string) *auth.Identity {
+	identity := &auth.Identity{
+		Subject:  namespace + "/" + name,
+		Metadata: map[string]string{"tenant_id": namespace},
+	}
+	if len(groups) > 0 {
+		identity.Metadata["roles"] = strings.Join(groups, ",")
+	}
+	return identity
+}
+
+// parseServiceAccount splits the user name of a service account into its
+// namespace and name.
+func parseServiceAccount(username string) (namespace, name string, ok bool) {
+	rest, ok := strings.CutPrefix(username, serviceAccountPrefix)
+	if !ok {
+		return "", "", false
+	}
+	namespace, name, ok = strings.Cut(rest, ":")
+	if !ok || namespace == "" || name == "" {
+		return "", "", false
+	}
+	return namespace, name, true
+}
@@ -0,0 +1,258 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	authv1 "k8s.io/api/authentication/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/rhuss/antwort/pkg/auth"
+)
+
+// fakeAPIServer serves the TokenReview API. Tokens in users are
+// authenticated as the given user; all others are rejected. A nil users
+// map makes the server fail.
+type fakeAPIServer struct {
+	mu        sync.Mutex
+	users     map[string]authv1.UserInfo
+	audiences []string // audiences of the last review
+	calls     atomic.Int32
+}
+
+func (f *fakeAPIServer) setUsers(users map[string]authv1.UserInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users = users
+}
+
+func (f *fakeAPIServer) lastAudiences() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.audiences
+}
+
+func (f *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/apis/authentication.k8s.io/v1/tokenreviews" {
+		http.NotFound(w, r)
+		return
+	}
+	f.calls.Add(1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.users == nil {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var review authv1.TokenReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.audiences = review.Spec.Audiences
+
+	if user, ok := f.users[review.Spec.Token]; ok {
+		review.Status = authv1.TokenReviewStatus{Authenticated: true, User: user}
+	} else {
+		review.Status = authv1.TokenReviewStatus{Error: "invalid bearer token"}
+	}
+	review.APIVersion = "authentication.k8s.io/v1"
+	review.Kind = "TokenReview"
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
+
+func newTestAuthenticator(t *testing.T, fake *fakeAPIServer, cfg Config) *Authenticator {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	// The fake speaks JSON; client-go prefers protobuf by default.
+	a, err := New(&rest.Config{
+		Host:          server.URL,
+		ContentConfig: rest.ContentConfig{ContentType: "application/json"},
+	}, cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return a
+}
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/v1/responses", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestKubernetes_ServiceAccount(t *testing.T) {
+	fake := &fakeAPIServer{users: map[string]authv1.UserInfo{
+		"sa-token": {
+			Username: "system:serviceaccount:team-a:agent",
+			Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:team-a", "system:authenticated"},
+		},
+	}}
+	a := newTestAuthenticator(t, fake, Config{Audiences: []string{"antwort"}})
+
+	result := a.Authenticate(context.Background(), bearerRequest("sa-token"))
+	if result.Decision != auth.Yes {
+		t.Fatalf("Decision = %v, want Yes (err: %v)", result.Decision, result.Err)
+	}
+	id := result.Identity
+	if id.Subject != "team-a/agent" {
+		t.Errorf("Subject = %q, want %q", id.Subject, "team-a/agent")
+	}
+	if id.TenantID() != "team-a" {
+		t.Errorf("TenantID() = %q, want %q", id.TenantID(), "team-a")
+	}
+	if got, want := id.Metadata["roles"], "system:serviceaccounts,system:serviceaccounts:team-a,system:authenticated"; got != want {
+		t.Errorf("roles = %q, want %q", got, want)
+	}
+	if id.Token != "" {
+		t.Errorf("Token = %q, want none for a service account token", id.Token)
+	}
+	if got := fake.lastAudiences(); !slices.Equal(got, []string{"antwort"}) {
+		t.Errorf("review audiences = %v, want [antwort]", got)
+	}
+}
+
+func TestKubernetes_Rejected(t *testing.T) {
+	fake := &fakeAPIServer{users: map[string]authv1.UserInfo{}}
+	a := newTestAuthenticator(t, fake, Config{})
+
+	result := a.Authenticate(context.Background(), bearerRequest("bad-token"))
+	if result.Decision != auth.No {
+		t.Fatalf("Decision = %v, want No", result.Decision)
+	}
+	if result.Err == nil {
+		t.Error("expected an error for a rejected token")
+	}
+}
+
+func TestKubernetes_NoBearerToken(t *testing.T) {
+	fake := &fakeAPIServer{users: map[string]authv1.UserInfo{}}
+	a := newTestAuthenticator(t, fake, Config{})
+
+	result := a.Authenticate(context.Background(), bearerRequest(""))
+	if result.Decision != auth.Abstain {
+		t.Errorf("Decision = %v, want Abstain", result.Decision)
+	}
+	if fake.calls.Load() != 0 {
+		t.Errorf("API server called %d times, want 0", fake.calls.Load())
+	}
+}
+
+func TestKubernetes_Caching(t *testing.T) {
+	fake := &fakeAPIServer{users: map[string]authv1.UserInfo{
+		"sa-token": {Username: "system:serviceaccount:team-a:agent"},
+	}}
+	a := newTestAuthenticator(t, fake, Config{CacheTTL: time.Minute})
+	now := time.Now()
+	a.now = func() time.Time { return now }
+
+	for _, token := range []string{"sa-token", "sa-token", "bad-token", "bad-token"} {
+		a.Authenticate(context.Background(), bearerRequest(token))
+	}
+	if got := fake.calls.Load(); got != 2 {
+		t.Errorf("API server called %d times, want 2 (results are cached)", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if result := a.Authenticate(context.Background(), bearerRequest("sa-token")); result.Decision != auth.Yes {
+		t.Fatalf("Decision = %v, want Yes", result.Decision)
+	}
+	if got := fake.calls.Load(); got != 3 {
+		t.Errorf("API server called %d times, want 3 (expired entry reviewed again)", got)
+	}
+}
+
+func TestKubernetes_APIErrorNotCached(t *testing.T) {
+	fake := &fakeAPIServer{}
+	a := newTestAuthenticator(t, fake, Config{})
+
+	result := a.Authenticate(context.Background(), bearerRequest("sa-token"))
+	if result.Decision != auth.No || result.Err == nil {
+		t.Fatalf("Decision = %v, Err = %v, want No with an error", result.Decision, result.Err)
+	}
+
+	fake.setUsers(map[string]authv1.UserInfo{"sa-token": {Username: "system:serviceaccount:team-a:agent"}})
+	if result := a.Authenticate(context.Background(), bearerRequest("sa-token")); result.Decision != auth.Yes {
+		t.Errorf("Decision = %v, want Yes once the API server recovers", result.Decision)
+	}
+}
+
+func TestKubernetes_NotServiceAccount(t *testing.T) {
+	fake := &fakeAPIServer{users: map[string]authv1.UserInfo{
+		"user-token": {Username: "jane@example.com", Groups: []string{"system:authenticated"}},
+		"node-token": {Username: "system:node:worker-1", Groups: []string{"system:nodes"}},
+	}}
+	a := newTestAuthenticator(t, fake, Config{})
+
+	for _, token := range []string{"user-token", "node-token"} {
+		if result := a.Authenticate(context.Background(), bearerRequest(token)); result.Decision != auth.No {
+			t.Errorf("%s: Decision = %v, want No for a user that is not a service account", token, result.Decision)
+		}
+	}
+}
+
+func TestKubernetes_CacheBounded(t *testing.T) {
+	fake := &fakeAPIServer{users: map[string]authv1.UserInfo{
+		"sa-token": {Username: "system:serviceaccount:team-a:agent"},
+	}}
+	a := newTestAuthenticator(t, fake, Config{})
+
+	// Fill the cache with live entries.
+	for n := range maxCachedReviews {
+		a.cache[fmt.Sprintf("token-%d", n)] = reviewEntry{expires: time.Now().Add(time.Hour)}
+	}
+	a.Authenticate(context.Background(), bearerRequest("sa-token"))
+	if n := len(a.cache); n > maxCachedReviews {
+		t.Errorf("cache holds %d entries, want at most %d", n, maxCachedReviews)
+	}
+}
+
+func TestParseServiceAccount(t *testing.T) {
+	tests := []struct {
+		username      string
+		wantNamespace string
+		wantName      string
+		wantOK        bool
+	}{
+		{"system:serviceaccount:ns:name", "ns", "name", true},
+		{"jane@example.com", "", "", false},
+		{"system:serviceaccount:ns", "", "", false},
+		{"system:serviceaccount::name", "", "", false},
+		{"system:node:worker-1", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			namespace, name, ok := parseServiceAccount(tt.username)
+			if namespace != tt.wantNamespace || name != tt.wantName || ok != tt.wantOK {
+				t.Errorf("parseServiceAccount() = %q, %q, %v, want %q, %q, %v", namespace, name, ok, tt.wantNamespace, tt.wantName, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestUserIdentity(t *testing.T) {
+	id := userIdentity("ns", "name", nil)
+	if id.Subject != "ns/name" {
+		t.Errorf("Subject = %q, want %q", id.Subject, "ns/name")
+	}
+	if id.TenantID() != "ns" {
+		t.Errorf("TenantID() = %q, want %q", id.TenantID(), "ns")
+	}
+	if _, ok := id.Metadata["roles"]; ok {
+		t.Errorf("roles set without groups: %q", id.Metadata["roles"])
+	}
+}
@@ -0,0 +1,72 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/rhuss/antwort/pkg/auth"
+	"github.com/rhuss/antwort/pkg/auth/jwt"
+)
+
+// OIDCAuthenticator validates ServiceAccount tokens locally, as JWTs signed
+// by the cluster's service account issuer. Unlike the TokenReview API, it
+// makes no API server call per token, but tokens of deleted pods stay
+// valid until they expire.
+type OIDCAuthenticator struct {
+	jwt *jwt.Authenticator
+}
+
+// NewOIDC creates an authenticator for tokens of the issuer in cfg. Unless
+// cfg.JWKSURL is set, the keys are fetched from the API server of
+// restConfig (which may be nil), which serves them at /openid/v1/jwks.
+// Requests to the API server use the gateway's cluster credentials; other
+// hosts never receive them.
+func NewOIDC(restConfig *rest.Config, cfg jwt.Config) (*OIDCAuthenticator, error) {
+	if restConfig != nil {
+		if cfg.JWKSURL == "" {
+			cfg.JWKSURL = strings.TrimSuffix(restConfig.Host, "/") + "/openid/v1/jwks"
+		}
+		if cfg.HTTPClient == nil && sameHost(restConfig.Host, cfg.JWKSURL) {
+			client, err := rest.HTTPClientFor(restConfig)
+			if err != nil {
+				return nil, fmt.Errorf("creating API server client: %w", err)
+			}
+			cfg.HTTPClient = client
+		}
+	}
+	cfg.UserClaim = "sub"
+	return &OIDCAuthenticator{jwt: jwt.New(cfg)}, nil
+}
+
+// Authenticate validates the bearer token and maps the service account to
+// an identity like the TokenReview authenticator, with the groups the API
+// server assigns to service accounts as roles.
+func (a *OIDCAuthenticator) Authenticate(ctx context.Context, r *http.Request) auth.AuthResult {
+	result := a.jwt.Authenticate(ctx, r)
+	if result.Decision != auth.Yes {
+		return result
+	}
+
+	username := result.Identity.Subject
+	namespace, name, ok := parseServiceAccount(username)
+	if !ok {
+		return auth.AuthResult{Decision: auth.No, Err: fmt.Errorf("token subject %q is not a service account", username)}
+	}
+	groups := []string{"system:serviceaccounts", "system:serviceaccounts:" + namespace, "system:authenticated"}
+	return auth.AuthResult{Decision: auth.Yes, Identity: userIdentity(namespace, name, groups)}
+}
+
+// sameHost reports whether u points to the host of the API server.
+func sameHost(apiServer, u string) bool {
+	server, err := url.Parse(apiServer)
+	if err != nil || server.Host == "" {
+		return false
+	}
+	parsed, err := url.Parse(u)
+	return err == nil && parsed.Host == server.Host
+}
@@ -0,0 +1,133 @@
+package kubernetes
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"k8s.io/client-go/rest"
+
+	"github.com/rhuss/antwort/pkg/auth"
+	"github.com/rhuss/antwort/pkg/auth/jwt"
+)
+
+const testIssuer = "https://kubernetes.default.svc.cluster.local"
+
+// newOIDCServer serves the public key of key at /openid/v1/jwks, like
+// the API server does.
+func newOIDCServer(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openid/v1/jwks" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "sa-key",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func signServiceAccountToken(t *testing.T, key *rsa.PrivateKey, subject string) string {
+	t.Helper()
+	token := jwtlib.NewWithClaims(jwtlib.SigningMethodRS256, jwtlib.MapClaims{
+		"iss": testIssuer,
+		"aud": "antwort",
+		"sub": subject,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "sa-key"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return signed
+}
+
+func TestOIDC_ServiceAccount(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newOIDCServer(t, key)
+
+	a, err := NewOIDC(&rest.Config{Host: server.URL}, jwt.Config{Issuer: testIssuer, Audience: "antwort"})
+	if err != nil {
+		t.Fatalf("NewOIDC() error = %v", err)
+	}
+
+	result := a.Authenticate(context.Background(), bearerRequest(signServiceAccountToken(t, key, "system:serviceaccount:team-a:agent")))
+	if result.Decision != auth.Yes {
+		t.Fatalf("Decision = %v, want Yes (err: %v)", result.Decision, result.Err)
+	}
+	if result.Identity.Subject != "team-a/agent" {
+		t.Errorf("Subject = %q, want %q", result.Identity.Subject, "team-a/agent")
+	}
+	if result.Identity.TenantID() != "team-a" {
+		t.Errorf("TenantID() = %q, want %q", result.Identity.TenantID(), "team-a")
+	}
+	if got, want := result.Identity.Metadata["roles"], "system:serviceaccounts,system:serviceaccounts:team-a,system:authenticated"; got != want {
+		t.Errorf("roles = %q, want %q", got, want)
+	}
+
+	// Tokens of the issuer for other subjects are not service accounts.
+	result = a.Authenticate(context.Background(), bearerRequest(signServiceAccountToken(t, key, "jane")))
+	if result.Decision != auth.No {
+		t.Errorf("Decision = %v, want No for a non-service-account subject", result.Decision)
+	}
+}
+
+func TestOIDC_WrongKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newOIDCServer(t, key)
+
+	a, err := NewOIDC(&rest.Config{Host: server.URL}, jwt.Config{Issuer: testIssuer})
+	if err != nil {
+		t.Fatalf("NewOIDC() error = %v", err)
+	}
+	result := a.Authenticate(context.Background(), bearerRequest(signServiceAccountToken(t, other, "system:serviceaccount:team-a:agent")))
+	if result.Decision != auth.No {
+		t.Errorf("Decision = %v, want No for a token signed by another key", result.Decision)
+	}
+}
+
+func TestSameHost(t *testing.T) {
+	tests := []struct {
+		apiServer, url string
+		want           bool
+	}{
+		{"https://10.0.0.1:6443", "https://10.0.0.1:6443/openid/v1/jwks", true},
+		{"https://10.0.0.1:6443", "https://issuer.example.com/keys", false},
+		{"https://10.0.0.1:6443", "https://10.0.0.1/openid/v1/jwks", false},
+		{"", "https://10.0.0.1:6443/openid/v1/jwks", false},
+	}
+	for _, tt := range tests {
+		if got := sameHost(tt.apiServer, tt.url); got != tt.want {
+			t.Errorf("sameHost(%q, %q) = %v, want %v", tt.apiServer, tt.url, got, tt.want)
+		}
+	}
+}
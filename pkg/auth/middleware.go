@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 
@@ -25,7 +26,8 @@ func (noopAuditLogger) LogWarn(context.Context, string, ...any) {}
 
 // Middleware creates HTTP middleware from an AuthChain and optional RateLimiter.
 // It checks the bypass list, runs authentication, injects tenant and owner context,
-// and optionally enforces rate limits.
+// and optionally enforces rate limits, reporting them in x-ratelimit-*
+// response headers.
 // The adminRole parameter is used to check if the authenticated user has admin
 // privileges. Pass empty string to disable admin detection.
 func Middleware(chain *AuthChain, limiter RateLimiter, bypassEndpoints []string, al AuditLogger, adminRole ...string) func(http.Handler) http.Handler {
@@ -87,7 +89,19 @@ func Middleware(chain *AuthChain, limiter RateLimiter, bypassEndpoints []string,
 
 			// Rate limiting (if configured).
 			if limiter != nil {
-				if err := limiter.Allow(r.Context(), result.Identity); err != nil {
+				status, err := limiter.Allow(r.Context(), result.Identity)
+				if status != nil {
+					status.WriteHeaders(w.Header())
+				}
+				if err != nil && !errors.Is(err, ErrTooManyRequests) {
+					slog.Warn("rate limiter unavailable, rejecting request",
+						"subject", result.Identity.Subject,
+						"error", err,
+					)
+					http.Error(w, `{"error":{"type":"server_error","message":"rate limiter unavailable"}}`, http.StatusServiceUnavailable)
+					return
+				}
+				if err != nil {
 					slog.Warn("rate limit exceeded",
 						"subject", result.Identity.Subject,
 						"tier", result.Identity.ServiceTier,
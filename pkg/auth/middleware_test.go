@@ -94,7 +94,7 @@ func TestMiddleware_RateLimit_Exceeded(t *testing.T) {
 
 	limiter := NewInProcessLimiter(map[string]TierConfig{
 		"limited": {RequestsPerMinute: 2},
-	}, 100)
+	})
 
 	mw := Middleware(chain, limiter, DefaultBypassEndpoints, nil)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -118,6 +118,15 @@ func TestMiddleware_RateLimit_Exceeded(t *testing.T) {
 	if rec.Code != http.StatusTooManyRequests {
 		t.Errorf("rate limited request: status = %d, want 429", rec.Code)
 	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After header on 429")
+	}
+	if got := rec.Header().Get("x-ratelimit-limit-requests"); got != "2" {
+		t.Errorf("x-ratelimit-limit-requests = %q, want \"2\"", got)
+	}
+	if got := rec.Header().Get("x-ratelimit-remaining-requests"); got != "0" {
+		t.Errorf("x-ratelimit-remaining-requests = %q, want \"0\"", got)
+	}
 }
 
 func TestMiddleware_NoLimiter_AllAllowed(t *testing.T) {
@@ -298,7 +307,7 @@ func TestMiddleware_AuditRateLimited(t *testing.T) {
 
 	limiter := NewInProcessLimiter(map[string]TierConfig{
 		"basic": {RequestsPerMinute: 1},
-	}, 100)
+	})
 
 	mw := Middleware(chain, limiter, DefaultBypassEndpoints, al)
 	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -366,3 +375,31 @@ func TestMiddleware_NilAuditLogger_NoPanic(t *testing.T) {
 		t.Errorf("expected 401, got %d", rec.Code)
 	}
 }
+
+// failingLimiter is a RateLimiter whose backing store is unavailable.
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, *Identity) (*RateLimitStatus, error) {
+	return nil, ErrRateLimitUnavailable
+}
+
+func (failingLimiter) RecordUsage(context.Context, int, int) {}
+
+func TestMiddleware_RateLimiterUnavailable(t *testing.T) {
+	chain := &AuthChain{
+		Authenticators: []Authenticator{
+			&mockAuthn{result: AuthResult{Decision: Yes, Identity: &Identity{Subject: "alice"}}},
+		},
+	}
+
+	mw := Middleware(chain, failingLimiter{}, DefaultBypassEndpoints, nil)
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/responses", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
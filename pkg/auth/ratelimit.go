@@ -2,78 +2,206 @@ package auth
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
 	"sync"
 	"time"
 )
 
 // RateLimiter checks whether a request should be allowed based on
-// the identity's service tier.
+// the identity's service tier, and charges token usage to it.
 type RateLimiter interface {
-	Allow(ctx context.Context, identity *Identity) error
+	// Allow checks the request against the identity's limits. It returns
+	// the limit status for response headers (nil when the tier has no
+	// limits). A rejected request returns ErrTooManyRequests together with
+	// a status whose RetryAfter is set.
+	Allow(ctx context.Context, identity *Identity) (*RateLimitStatus, error)
+
+	// RecordUsage charges token usage to the identity carried in ctx. It
+	// is called after each response finishes; contexts without an
+	// identity are ignored.
+	RecordUsage(ctx context.Context, inputTokens, outputTokens int)
 }
 
-// TierConfig holds rate limit settings for a service tier.
+// TierConfig holds rate limit settings for a service tier. Zero values
+// disable the corresponding limit.
 type TierConfig struct {
-	RequestsPerMinute int
+	RequestsPerMinute     int
+	InputTokensPerMinute  int
+	OutputTokensPerMinute int
+}
+
+// Unlimited reports whether the tier has no limits at all.
+func (tc TierConfig) Unlimited() bool {
+	return tc.RequestsPerMinute <= 0 && tc.InputTokensPerMinute <= 0 && tc.OutputTokensPerMinute <= 0
+}
+
+// DefaultTier is the tier used for identities without a service tier and
+// the fallback for tiers that have no configuration of their own.
+const DefaultTier = "default"
+
+// RateLimitWindow describes one limit within the current window.
+type RateLimitWindow struct {
+	Limit     int           // 0 means the limit is not enforced
+	Remaining int           // remaining budget in the current window
+	Reset     time.Duration // time until the window resets
+}
+
+// RateLimitStatus describes the rate limits that apply to a request.
+type RateLimitStatus struct {
+	Requests     RateLimitWindow
+	InputTokens  RateLimitWindow
+	OutputTokens RateLimitWindow
+
+	// RetryAfter is set when the request was rejected.
+	RetryAfter time.Duration
+}
+
+// WriteHeaders sets x-ratelimit-* headers for every enforced limit, and
+// Retry-After when the request was rejected.
+func (s *RateLimitStatus) WriteHeaders(h http.Header) {
+	writeWindowHeaders(h, "requests", s.Requests)
+	writeWindowHeaders(h, "input-tokens", s.InputTokens)
+	writeWindowHeaders(h, "output-tokens", s.OutputTokens)
+	if s.RetryAfter > 0 {
+		h.Set("Retry-After", strconv.Itoa(int(math.Ceil(s.RetryAfter.Seconds()))))
+	}
+}
+
+func writeWindowHeaders(h http.Header, name string, w RateLimitWindow) {
+	if w.Limit <= 0 {
+		return
+	}
+	h.Set("x-ratelimit-limit-"+name, strconv.Itoa(w.Limit))
+	h.Set("x-ratelimit-remaining-"+name, strconv.Itoa(max(w.Remaining, 0)))
+	h.Set("x-ratelimit-reset-"+name, fmt.Sprintf("%ds", int(math.Ceil(w.Reset.Seconds()))))
 }
 
-// InProcessLimiter is a simple sliding-window rate limiter that tracks
-// request counts per subject in memory.
+// InProcessLimiter is a fixed-window rate limiter that tracks request and
+// token counts per subject and tier in memory. Token limits are checked
+// before a request (rejecting once the window's budget is spent) and
+// charged after it via RecordUsage.
 type InProcessLimiter struct {
-	tiers      map[string]TierConfig
-	defaultRPM int
-	mu         sync.Mutex
-	counters   map[string]*counter
+	tiers    map[string]TierConfig
+	mu       sync.Mutex
+	counters map[string]*counter
+	now      func() time.Time
 }
 
 type counter struct {
-	count    int
-	windowAt time.Time
+	requests     int
+	inputTokens  int
+	outputTokens int
+	windowAt     time.Time
 }
 
 // NewInProcessLimiter creates a rate limiter with per-tier configuration.
-func NewInProcessLimiter(tiers map[string]TierConfig, defaultRPM int) *InProcessLimiter {
+// Identities whose tier is not configured use the DefaultTier entry; if
+// there is none, they are not limited.
+func NewInProcessLimiter(tiers map[string]TierConfig) *InProcessLimiter {
 	return &InProcessLimiter{
-		tiers:      tiers,
-		defaultRPM: defaultRPM,
-		counters:   make(map[string]*counter),
+		tiers:    tiers,
+		counters: make(map[string]*counter),
+		now:      time.Now,
 	}
 }
 
 // Allow checks if the request is within the rate limit.
-// Fails open: any internal error allows the request.
-func (l *InProcessLimiter) Allow(_ context.Context, identity *Identity) error {
-	tier := identity.ServiceTier
-	if tier == "" {
-		tier = "default"
+func (l *InProcessLimiter) Allow(_ context.Context, identity *Identity) (*RateLimitStatus, error) {
+	key, tc := l.limitsFor(identity)
+	if tc.Unlimited() {
+		return nil, nil
 	}
 
-	rpm := l.defaultRPM
-	if tc, ok := l.tiers[tier]; ok {
-		rpm = tc.RequestsPerMinute
-	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-	if rpm <= 0 {
-		return nil // no limit
+	now := l.now()
+	c := l.window(key, now)
+	reset := c.windowAt.Add(time.Minute).Sub(now)
+
+	if exceeded(tc.RequestsPerMinute, c.requests) ||
+		exceeded(tc.InputTokensPerMinute, c.inputTokens) ||
+		exceeded(tc.OutputTokensPerMinute, c.outputTokens) {
+		status := tierStatus(tc, c, reset)
+		status.RetryAfter = reset
+		return status, ErrTooManyRequests
 	}
 
-	key := identity.Subject + ":" + tier
+	c.requests++
+	return tierStatus(tc, c, reset), nil
+}
+
+// RecordUsage charges token usage to the identity in ctx.
+func (l *InProcessLimiter) RecordUsage(ctx context.Context, inputTokens, outputTokens int) {
+	identity := IdentityFromContext(ctx)
+	if identity == nil {
+		return
+	}
+	key, tc := l.limitsFor(identity)
+	if tc.InputTokensPerMinute <= 0 && tc.OutputTokensPerMinute <= 0 {
+		return
+	}
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	now := time.Now()
-	c, ok := l.counters[key]
-	if !ok || now.Sub(c.windowAt) >= time.Minute {
-		// New window.
-		l.counters[key] = &counter{count: 1, windowAt: now}
-		return nil
+	c := l.window(key, l.now())
+	c.inputTokens += inputTokens
+	c.outputTokens += outputTokens
+}
+
+// limitsFor returns the counter key and limits for an identity.
+func (l *InProcessLimiter) limitsFor(identity *Identity) (string, TierConfig) {
+	return LimitKey(l.tiers, identity)
+}
+
+// LimitKey returns the key under which usage of identity is counted
+// ("<subject>:<tier>") and the limits of its tier. Identities without a
+// tier use DefaultTier, and tiers without configuration use the limits
+// of DefaultTier.
+func LimitKey(tiers map[string]TierConfig, identity *Identity) (string, TierConfig) {
+	tier := identity.ServiceTier
+	if tier == "" {
+		tier = DefaultTier
+	}
+	tc, ok := tiers[tier]
+	if !ok {
+		tc = tiers[DefaultTier]
 	}
+	return identity.Subject + ":" + tier, tc
+}
 
-	c.count++
-	if c.count > rpm {
-		return ErrTooManyRequests
+// window returns the counter for key, starting a new window if the
+// current one has expired. Expired counters of other keys are pruned so
+// the map does not grow without bound. Must be called with l.mu held.
+func (l *InProcessLimiter) window(key string, now time.Time) *counter {
+	c, ok := l.counters[key]
+	if ok && now.Sub(c.windowAt) < time.Minute {
+		return c
 	}
+	for k, other := range l.counters {
+		if now.Sub(other.windowAt) >= time.Minute {
+			delete(l.counters, k)
+		}
+	}
+	c = &counter{windowAt: now}
+	l.counters[key] = c
+	return c
+}
 
-	return nil
+// exceeded reports whether an enforced limit has been reached.
+func exceeded(limit, used int) bool {
+	return limit > 0 && used >= limit
+}
+
+// tierStatus builds the status for the counter's current window.
+func tierStatus(tc TierConfig, c *counter, reset time.Duration) *RateLimitStatus {
+	return &RateLimitStatus{
+		Requests:     RateLimitWindow{Limit: tc.RequestsPerMinute, Remaining: tc.RequestsPerMinute - c.requests, Reset: reset},
+		InputTokens:  RateLimitWindow{Limit: tc.InputTokensPerMinute, Remaining: tc.InputTokensPerMinute - c.inputTokens, Reset: reset},
+		OutputTokens: RateLimitWindow{Limit: tc.OutputTokensPerMinute, Remaining: tc.OutputTokensPerMinute - c.outputTokens, Reset: reset},
+	}
 }
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns a limiter with a controllable clock.
+func newTestLimiter(tiers map[string]TierConfig) (*InProcessLimiter, *time.Time) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewInProcessLimiter(tiers)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestInProcessLimiter_Requests(t *testing.T) {
+	l, now := newTestLimiter(map[string]TierConfig{"basic": {RequestsPerMinute: 2}})
+	alice := &Identity{Subject: "alice", ServiceTier: "basic"}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		status, err := l.Allow(ctx, alice)
+		if err != nil {
+			t.Fatalf("request %d: unexpected error %v", i+1, err)
+		}
+		if status.Requests.Remaining != 1-i {
+			t.Errorf("request %d: remaining = %d, want %d", i+1, status.Requests.Remaining, 1-i)
+		}
+	}
+
+	*now = now.Add(20 * time.Second)
+	status, err := l.Allow(ctx, alice)
+	if !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("expected ErrTooManyRequests, got %v", err)
+	}
+	if status.RetryAfter != 40*time.Second {
+		t.Errorf("RetryAfter = %v, want 40s", status.RetryAfter)
+	}
+
+	// Other subjects have their own counters.
+	if _, err := l.Allow(ctx, &Identity{Subject: "bob", ServiceTier: "basic"}); err != nil {
+		t.Errorf("bob: unexpected error %v", err)
+	}
+
+	// A new window resets the count.
+	*now = now.Add(time.Minute)
+	if _, err := l.Allow(ctx, alice); err != nil {
+		t.Errorf("after window reset: unexpected error %v", err)
+	}
+}
+
+func TestInProcessLimiter_Tokens(t *testing.T) {
+	l, _ := newTestLimiter(map[string]TierConfig{"basic": {OutputTokensPerMinute: 100}})
+	alice := &Identity{Subject: "alice", ServiceTier: "basic"}
+	ctx := SetIdentity(context.Background(), alice)
+
+	if _, err := l.Allow(ctx, alice); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	l.RecordUsage(ctx, 500, 60)
+
+	status, err := l.Allow(ctx, alice)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if status.OutputTokens.Remaining != 40 {
+		t.Errorf("output tokens remaining = %d, want 40", status.OutputTokens.Remaining)
+	}
+
+	l.RecordUsage(ctx, 0, 60)
+	if _, err := l.Allow(ctx, alice); !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("expected ErrTooManyRequests after token budget spent, got %v", err)
+	}
+
+	// Usage without an identity is ignored.
+	l.RecordUsage(context.Background(), 1000, 1000)
+}
+
+func TestInProcessLimiter_DefaultTier(t *testing.T) {
+	l, _ := newTestLimiter(map[string]TierConfig{
+		DefaultTier: {RequestsPerMinute: 1},
+		"premium":   {},
+	})
+	ctx := context.Background()
+
+	// Unknown tiers fall back to the default tier.
+	unknown := &Identity{Subject: "alice", ServiceTier: "gold"}
+	l.Allow(ctx, unknown)
+	if _, err := l.Allow(ctx, unknown); !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("unknown tier: expected ErrTooManyRequests, got %v", err)
+	}
+
+	// A configured tier without limits is unlimited.
+	premium := &Identity{Subject: "alice", ServiceTier: "premium"}
+	for i := 0; i < 5; i++ {
+		status, err := l.Allow(ctx, premium)
+		if err != nil || status != nil {
+			t.Fatalf("premium: status = %+v, err = %v", status, err)
+		}
+	}
+}
+
+func TestRateLimitStatus_WriteHeaders(t *testing.T) {
+	status := &RateLimitStatus{
+		Requests:    RateLimitWindow{Limit: 60, Remaining: 0, Reset: 1500 * time.Millisecond},
+		InputTokens: RateLimitWindow{Limit: 1000, Remaining: -20, Reset: 30 * time.Second},
+		RetryAfter:  1500 * time.Millisecond,
+	}
+	h := http.Header{}
+	status.WriteHeaders(h)
+
+	want := map[string]string{
+		"x-ratelimit-limit-requests":         "60",
+		"x-ratelimit-remaining-requests":     "0",
+		"x-ratelimit-reset-requests":         "2s",
+		"x-ratelimit-limit-input-tokens":     "1000",
+		"x-ratelimit-remaining-input-tokens": "0",
+		"x-ratelimit-reset-input-tokens":     "30s",
+		"Retry-After":                        "2",
+	}
+	for k, v := range want {
+		if got := h.Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if h.Get("x-ratelimit-limit-output-tokens") != "" {
+		t.Error("unenforced output token limit should not produce headers")
+	}
+}
@@ -23,7 +23,8 @@ func (noopAuditLogger) Log(context.Context, string, ...any)     {}
 func (noopAuditLogger) LogWarn(context.Context, string, ...any) {}
 
 // DefaultEndpointScopes maps HTTP method + path pattern to required scope.
-// Path parameters are represented as segments starting with "{".
+// Path parameters are represented as segments starting with "{". A final
+// segment of the form "{name...}" matches one or more remaining segments.
 var DefaultEndpointScopes = map[string]string{
 	"POST /v1/responses":                "responses:create",
 	"GET /v1/responses":                 "responses:read",
@@ -45,6 +46,12 @@ var DefaultEndpointScopes = map[string]string{
 	"GET /v1/files/{id}":                "files:read",
 	"DELETE /v1/files/{id}":             "files:delete",
 	"GET /v1/agents":                    "agents:read",
+	"GET /v1/models":                    "models:read",
+	"GET /v1/models/{id...}":            "models:read",
+	"POST /v1/admin/api_keys":           "api_keys:create",
+	"GET /v1/admin/api_keys":            "api_keys:read",
+	"GET /v1/admin/api_keys/{id}":       "api_keys:read",
+	"DELETE /v1/admin/api_keys/{id}":    "api_keys:delete",
 }
 
 // endpointPattern is a compiled pattern for matching request paths.
@@ -145,7 +152,7 @@ func matchEndpoint(method, path string, patterns []endpointPattern) string {
 		if p.method != method {
 			continue
 		}
-		if len(p.segments) != len(pathSegments) {
+		if !segmentCountMatches(p.segments, pathSegments) {
 			continue
 		}
 		match := true
@@ -166,6 +173,17 @@ func matchEndpoint(method, path string, patterns []endpointPattern) string {
 	return ""
 }
 
+// segmentCountMatches reports whether a path with the given segments can
+// match the pattern segments, allowing a trailing "{name...}" wildcard to
+// absorb any number (at least one) of remaining segments.
+func segmentCountMatches(patternSegments, pathSegments []string) bool {
+	last := patternSegments[len(patternSegments)-1]
+	if strings.HasPrefix(last, "{") && strings.HasSuffix(last, "...}") {
+		return len(pathSegments) >= len(patternSegments)
+	}
+	return len(patternSegments) == len(pathSegments)
+}
+
 // computeEffectiveScopes returns the union of identity scopes and
 // role-expanded scopes from the identity's metadata roles.
 func computeEffectiveScopes(identity *auth.Identity, expandedRoles map[string]map[string]bool) map[string]bool {
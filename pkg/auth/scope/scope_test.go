@@ -290,6 +290,35 @@ func TestMiddleware_PathParamMatching(t *testing.T) {
 	}
 }
 
+func TestMiddleware_TrailingWildcardMatching(t *testing.T) {
+	expandedRoles := map[string]map[string]bool{
+		"reader": {"responses:read": true},
+	}
+	handler := Middleware(expandedRoles, DefaultEndpointScopes)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	// Model IDs may contain slashes; the {id...} pattern must still
+	// require models:read.
+	for _, path := range []string{"/v1/models", "/v1/models/gpt-4o", "/v1/models/meta-llama/Llama-3.1-8B"} {
+		req := httptest.NewRequest("GET", path, nil)
+		ctx := auth.SetIdentity(req.Context(), &auth.Identity{
+			Subject:  "user1",
+			Metadata: map[string]string{"roles": "reader"},
+		})
+		req = req.WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403 without models:read, got %d", path, rr.Code)
+		}
+	}
+}
+
 // --- Audit event tests (T010) ---
 
 // testScopeAuditLogger implements auditLogger for testing.
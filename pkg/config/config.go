@@ -10,9 +10,11 @@
 package config
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/rhuss/antwort/pkg/audit"
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds all configuration for the antwort gateway.
@@ -28,6 +30,37 @@ type Config struct {
 	Observability ObservabilityConfig         `yaml:"observability"`
 	Logging       LoggingConfig               `yaml:"logging"`
 	Resilience    ResilienceConfig            `yaml:"resilience"`
+	Models        ModelsConfig                `yaml:"models"`
+	RateLimits    RateLimitsConfig            `yaml:"rate_limits"`
+}
+
+// RateLimitsConfig maps service tiers (from auth identities) to per-minute
+// limits. The "default" tier applies to identities without a tier and to
+// tiers that are not listed. Rate limiting is disabled when no tiers are
+// configured.
+type RateLimitsConfig struct {
+	Tiers map[string]RateLimitTierConfig `yaml:"tiers"`
+
+	// Backend selects where counters are kept: "memory" (per replica,
+	// default) or "postgres" (shared between replicas, requires
+	// storage.type "postgres").
+	Backend      string        `yaml:"backend"`
+	SyncInterval time.Duration `yaml:"sync_interval"` // postgres: how often usage is written and counters refreshed, default: 1s
+	FailureMode  string        `yaml:"failure_mode"`  // postgres: "open" (default) or "closed" when the database is unreachable
+}
+
+// RateLimitTierConfig holds the per-minute limits of a service tier.
+// Zero disables the corresponding limit.
+type RateLimitTierConfig struct {
+	RequestsPerMinute     int `yaml:"requests_per_minute"`
+	InputTokensPerMinute  int `yaml:"input_tokens_per_minute"`
+	OutputTokensPerMinute int `yaml:"output_tokens_per_minute"`
+}
+
+// ModelsConfig controls the GET /v1/models endpoint.
+type ModelsConfig struct {
+	CacheTTL      time.Duration `yaml:"cache_ttl"`      // how long backend model lists are cached, default: 60s
+	IncludeAgents bool          `yaml:"include_agents"` // list agent profiles as virtual "agent/<name>" models, default: false
 }
 
 // ResilienceConfig holds circuit breaker and retry settings for backend communication.
@@ -76,6 +109,18 @@ type ProviderConfig struct {
 // ObservabilityConfig holds monitoring and instrumentation settings.
 type ObservabilityConfig struct {
 	Metrics MetricsConfig `yaml:"metrics"`
+	Tracing TracingConfig `yaml:"tracing"`
+}
+
+// TracingConfig holds OpenTelemetry tracing settings. Spans are exported
+// via OTLP/HTTP; when disabled, incoming trace context is still propagated
+// to backends and MCP servers.
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`      // default: false
+	Endpoint    string  `yaml:"endpoint"`     // OTLP/HTTP host:port; empty uses OTEL_EXPORTER_OTLP_* env vars
+	Insecure    bool    `yaml:"insecure"`     // disable TLS to the collector, default: false
+	ServiceName string  `yaml:"service_name"` // default: "antwort"
+	SampleRate  float64 `yaml:"sample_rate"`  // fraction of new traces sampled, default: 1.0
 }
 
 // MetricsConfig holds Prometheus metrics endpoint settings.
@@ -86,21 +131,45 @@ type MetricsConfig struct {
 
 // ServerConfig holds HTTP server settings.
 type ServerConfig struct {
-	Port         int           `yaml:"port"`          // default: 8080
-	ReadTimeout  time.Duration `yaml:"read_timeout"`  // default: 30s
-	WriteTimeout time.Duration `yaml:"write_timeout"` // default: 120s
+	Port         int             `yaml:"port"`          // default: 8080
+	ReadTimeout  time.Duration   `yaml:"read_timeout"`  // default: 30s
+	WriteTimeout time.Duration   `yaml:"write_timeout"` // default: 120s
+	Streaming    StreamingConfig `yaml:"streaming"`
+}
+
+// StreamingConfig controls buffering of streaming events for stream
+// resumption (GET /v1/responses/{id}?stream=true).
+type StreamingConfig struct {
+	BufferSize int           `yaml:"buffer_size"` // events kept per response, 0 disables resumption, default: 0
+	Retention  time.Duration `yaml:"retention"`   // how long events are kept after a stream ends, default: 5m
 }
 
 // EngineConfig holds inference engine and provider settings.
 type EngineConfig struct {
-	Provider     string           `yaml:"provider"`      // "vllm", "litellm", or "vllm-responses", default: "vllm"
-	BackendURL   string           `yaml:"backend_url"`   // required
-	APIKey       string           `yaml:"api_key"`       // optional
-	APIKeyFile   string           `yaml:"api_key_file"`  // _file variant for api_key
-	DefaultModel string           `yaml:"default_model"` // optional
-	MaxTurns     int              `yaml:"max_turns"`     // default: 10
-	Mode         string           `yaml:"mode"`          // "gateway", "worker", "integrated", default: "integrated"
-	Background   BackgroundConfig `yaml:"background"`    // background processing settings
+	Provider         string           `yaml:"provider"`           // "vllm", "litellm", or "vllm-responses", default: "vllm"
+	BackendURL       string           `yaml:"backend_url"`        // required
+	APIKey           string           `yaml:"api_key"`            // optional
+	APIKeyFile       string           `yaml:"api_key_file"`       // _file variant for api_key
+	DefaultModel     string           `yaml:"default_model"`      // optional
+	MaxTurns         int              `yaml:"max_turns"`          // default: 10
+	MaxContextWindow int              `yaml:"max_context_window"` // backend context window in tokens, 0 = unknown
+	Mode             string           `yaml:"mode"`               // "gateway", "worker", "integrated", default: "integrated"
+	Background       BackgroundConfig `yaml:"background"`         // background processing settings
+	Backends         []BackendConfig  `yaml:"backends"`           // multi-backend routing, replaces provider/backend_url when set
+}
+
+// BackendConfig describes a named inference backend used for model routing.
+// Requests are routed to the first backend whose Models entry matches the
+// requested model, either exactly or as a glob pattern (e.g. "llama-*").
+type BackendConfig struct {
+	Name             string            `yaml:"name"`               // required, unique
+	Provider         string            `yaml:"provider"`           // "vllm", "litellm", or "vllm-responses", default: "vllm"
+	URL              string            `yaml:"url"`                // required
+	APIKey           string            `yaml:"api_key"`            // optional
+	APIKeyFile       string            `yaml:"api_key_file"`       // _file variant for api_key
+	Models           []string          `yaml:"models"`             // model names or glob patterns, required
+	MaxContextWindow int               `yaml:"max_context_window"` // backend context window in tokens, 0 = unknown
+	Resilience       *ResilienceConfig `yaml:"resilience"`         // per-backend override, unset fields inherit from resilience
 }
 
 // BackgroundConfig holds settings for background (async) request processing.
@@ -130,10 +199,47 @@ type PostgresConfig struct {
 
 // AuthConfig holds authentication settings.
 type AuthConfig struct {
-	Type          string              `yaml:"type"`          // "none", "apikey", "jwt", "chain", default: "none"
-	APIKeys       []APIKeyConfig      `yaml:"api_keys"`      // API key entries for type=apikey
-	JWT           JWTConfig           `yaml:"jwt"`           // JWT/OIDC settings for type=jwt or type=chain
-	Authorization AuthorizationConfig `yaml:"authorization"` // Authorization settings
+	Type          string               `yaml:"type"`           // "none", "apikey", "jwt", "kubernetes", "chain", default: "none"
+	APIKeys       []APIKeyConfig       `yaml:"api_keys"`       // API key entries for type=apikey
+	JWT           JWTConfig            `yaml:"jwt"`            // JWT/OIDC settings for type=jwt or type=chain
+	Authorization AuthorizationConfig  `yaml:"authorization"`  // Authorization settings
+	KeyManagement KeyManagementConfig  `yaml:"key_management"` // Admin API for managed API keys
+	Introspection IntrospectionConfig  `yaml:"introspection"`  // Opaque token validation for type=chain
+	Kubernetes    KubernetesAuthConfig `yaml:"kubernetes"`     // Service account tokens for type=kubernetes or type=chain
+}
+
+// KubernetesAuthConfig configures authentication of Kubernetes service
+// account tokens, either by the TokenReview API of the cluster or locally
+// against the keys of the cluster's service account issuer.
+type KubernetesAuthConfig struct {
+	Enabled   bool          `yaml:"enabled"`   // adds the authenticator to a chain
+	Mode      string        `yaml:"mode"`      // "tokenreview" or "oidc", default: "tokenreview"
+	Audiences []string      `yaml:"audiences"` // accepted token audiences, default: the API server's
+	CacheTTL  time.Duration `yaml:"cache_ttl"` // how long reviews are cached, default: 1m
+	Issuer    string        `yaml:"issuer"`    // service account issuer of the cluster (oidc mode)
+	JWKSURL   string        `yaml:"jwks_url"`  // default: /openid/v1/jwks of the API server (oidc mode)
+}
+
+// IntrospectionConfig configures validation of opaque bearer tokens at an
+// OAuth 2.0 token introspection endpoint (RFC 7662).
+type IntrospectionConfig struct {
+	URL              string        `yaml:"url"`                // Introspection endpoint; enables the authenticator
+	ClientID         string        `yaml:"client_id"`          // Credentials of the gateway at the endpoint
+	ClientSecret     string        `yaml:"client_secret"`      // Sent with client_id (HTTP Basic)
+	ClientSecretFile string        `yaml:"client_secret_file"` // _file variant for client_secret
+	CacheTTL         time.Duration `yaml:"cache_ttl"`          // default: 1m, at most until the token expires
+	UserClaim        string        `yaml:"user_claim"`         // default: "sub"
+	TenantClaim      string        `yaml:"tenant_claim"`       // default: "tenant_id"
+	ScopesClaim      string        `yaml:"scopes_claim"`       // default: "scope"
+	RolesClaim       string        `yaml:"roles_claim"`        // default: "realm_access.roles"
+}
+
+// KeyManagementConfig enables the /v1/admin/api_keys endpoints. Managed
+// keys are stored in the configured storage backend and accepted in
+// addition to the keys listed in auth.api_keys.
+type KeyManagementConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	CacheTTL time.Duration `yaml:"cache_ttl"` // how long key lookups are cached, default: 30s
 }
 
 // AuthorizationConfig holds authorization settings for resource ownership.
@@ -151,20 +257,36 @@ type JWTConfig struct {
 	TenantClaim string `yaml:"tenant_claim"` // Claim for tenant_id (default: "tenant_id")
 	ScopesClaim string `yaml:"scopes_claim"` // Claim for scopes (default: "scope")
 	RolesClaim  string `yaml:"roles_claim"`  // Dot-path for roles (default: "realm_access.roles")
+
+	// Issuers lists further trusted issuers, each with its own settings
+	// (the fields above; issuer is required). Tokens are validated with
+	// the settings of the issuer in their iss claim.
+	Issuers []JWTConfig `yaml:"issuers"`
 }
 
 // APIKeyConfig describes a single API key entry.
 type APIKeyConfig struct {
-	Key         string `yaml:"key"`
-	KeyFile     string `yaml:"key_file"` // _file variant for key
-	Subject     string `yaml:"subject"`
-	TenantID    string `yaml:"tenant_id"`
-	ServiceTier string `yaml:"service_tier"`
+	Key         string   `yaml:"key"`
+	KeyFile     string   `yaml:"key_file"` // _file variant for key
+	Subject     string   `yaml:"subject"`
+	TenantID    string   `yaml:"tenant_id"`
+	ServiceTier string   `yaml:"service_tier"`
+	Roles       []string `yaml:"roles"` // e.g. the admin role, to manage keys through the admin API
 }
 
 // MCPConfig holds MCP (Model Context Protocol) server settings.
 type MCPConfig struct {
 	Servers []MCPServerConfig `yaml:"servers"`
+
+	// AllowedServerURLs lists the server URLs that requests may declare
+	// with tools of type "mcp". Entries ending in "/*" match the paths
+	// below them on the same scheme and host.
+	// When empty, such tools are rejected.
+	AllowedServerURLs []string      `yaml:"allowed_server_urls"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"` // default: 5m
+
+	HealthCheckInterval time.Duration `yaml:"health_check_interval"` // default: 30s
+	ToolRefreshInterval time.Duration `yaml:"tool_refresh_interval"` // default: 5m
 }
 
 // MCPServerConfig describes a single MCP server connection.
@@ -174,17 +296,47 @@ type MCPServerConfig struct {
 	URL       string            `yaml:"url"`
 	Headers   map[string]string `yaml:"headers"`
 	Auth      MCPAuthConfig     `yaml:"auth"`
+
+	RequireApproval MCPApprovalConfig `yaml:"require_approval" json:"require_approval"`
+}
+
+// MCPApprovalConfig selects the tools of an MCP server whose calls wait for
+// the user's approval. It is written either as "always" or "never", or as
+// a mapping with "always" and "never" lists of tool names.
+type MCPApprovalConfig struct {
+	Mode   string   `yaml:"-" json:"-"` // "always" or "never"
+	Always []string `yaml:"always" json:"always"`
+	Never  []string `yaml:"never" json:"never"`
+}
+
+// UnmarshalYAML accepts a mode string or a mapping of tool name lists.
+func (a *MCPApprovalConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&a.Mode)
+	}
+	type plain MCPApprovalConfig
+	return node.Decode((*plain)(a))
+}
+
+// UnmarshalJSON accepts a mode string or an object of tool name lists.
+func (a *MCPApprovalConfig) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Mode); err == nil {
+		return nil
+	}
+	type plain MCPApprovalConfig
+	return json.Unmarshal(data, (*plain)(a))
 }
 
 // MCPAuthConfig describes the authentication configuration for an MCP server.
 type MCPAuthConfig struct {
-	Type             string   `yaml:"type" json:"type"`                                           // "static", "oauth_client_credentials"
+	Type             string   `yaml:"type" json:"type"`                                           // "static", "oauth_client_credentials", "oauth_token_exchange", "forward_bearer"
 	TokenURL         string   `yaml:"token_url" json:"token_url,omitempty"`                       // OAuth token endpoint
 	ClientID         string   `yaml:"client_id" json:"client_id,omitempty"`                       // OAuth client ID
 	ClientIDFile     string   `yaml:"client_id_file" json:"client_id_file,omitempty"`             // File path for client ID
 	ClientSecret     string   `yaml:"client_secret" json:"client_secret,omitempty"`               // OAuth client secret
 	ClientSecretFile string   `yaml:"client_secret_file" json:"client_secret_file,omitempty"`     // File path for client secret
 	Scopes           []string `yaml:"scopes" json:"scopes,omitempty"`                             // OAuth scopes
+	Audience         string   `yaml:"audience" json:"audience,omitempty"`                         // Audience of exchanged tokens
 }
 
 // Defaults returns a Config with all default values filled in.
@@ -194,6 +346,9 @@ func Defaults() Config {
 			Port:         8080,
 			ReadTimeout:  30 * time.Second,
 			WriteTimeout: 120 * time.Second,
+			Streaming: StreamingConfig{
+				Retention: 5 * time.Minute,
+			},
 		},
 		Engine: EngineConfig{
 			Provider: "vllm",
@@ -217,6 +372,21 @@ func Defaults() Config {
 		},
 		Auth: AuthConfig{
 			Type: "none",
+			KeyManagement: KeyManagementConfig{
+				CacheTTL: 30 * time.Second,
+			},
+			Introspection: IntrospectionConfig{
+				CacheTTL: time.Minute,
+			},
+			Kubernetes: KubernetesAuthConfig{
+				Mode:     "tokenreview",
+				CacheTTL: time.Minute,
+			},
+		},
+		MCP: MCPConfig{
+			IdleTimeout:         5 * time.Minute,
+			HealthCheckInterval: 30 * time.Second,
+			ToolRefreshInterval: 5 * time.Minute,
 		},
 		Providers: map[string]ProviderConfig{},
 		Observability: ObservabilityConfig{
@@ -224,6 +394,18 @@ func Defaults() Config {
 				Enabled: true,
 				Path:    "/metrics",
 			},
+			Tracing: TracingConfig{
+				ServiceName: "antwort",
+				SampleRate:  1.0,
+			},
+		},
+		Models: ModelsConfig{
+			CacheTTL: 60 * time.Second,
+		},
+		RateLimits: RateLimitsConfig{
+			Backend:      "memory",
+			SyncInterval: time.Second,
+			FailureMode:  "open",
 		},
 		Resilience: ResilienceConfig{
 			Enabled:          false,
@@ -19,6 +19,9 @@ func TestDefaults(t *testing.T) {
 	if cfg.Server.WriteTimeout != 120*time.Second {
 		t.Errorf("default server.write_timeout = %v, want 120s", cfg.Server.WriteTimeout)
 	}
+	if cfg.Server.Streaming.BufferSize != 0 {
+		t.Errorf("default server.streaming.buffer_size = %d, want 0", cfg.Server.Streaming.BufferSize)
+	}
 	if cfg.Engine.Provider != "vllm" {
 		t.Errorf("default engine.provider = %q, want \"vllm\"", cfg.Engine.Provider)
 	}
@@ -74,6 +77,8 @@ mcp:
       url: http://localhost:3000/mcp
       headers:
         Authorization: "Bearer tok-123"
+      require_approval:
+        always: [delete_repo]
 `
 
 	tmpFile := writeTemp(t, "config-*.yaml", yamlContent)
@@ -164,6 +169,9 @@ mcp:
 	if cfg.MCP.Servers[0].Headers["Authorization"] != "Bearer tok-123" {
 		t.Errorf("mcp.servers[0].headers[Authorization] = %q, want \"Bearer tok-123\"", cfg.MCP.Servers[0].Headers["Authorization"])
 	}
+	if got := cfg.MCP.Servers[0].RequireApproval.Always; len(got) != 1 || got[0] != "delete_repo" {
+		t.Errorf("mcp.servers[0].require_approval.always = %v, want [delete_repo]", got)
+	}
 }
 
 func TestEnvOverride(t *testing.T) {
@@ -438,6 +446,171 @@ func TestValidation(t *testing.T) {
 			},
 			wantErr: "engine.provider must be",
 		},
+		{
+			name: "negative stream buffer size",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Server.Streaming.BufferSize = -1
+			},
+			wantErr: "server.streaming.buffer_size must be >= 0",
+		},
+		{
+			name: "mcp allowed server URL without scheme",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.MCP.AllowedServerURLs = []string{"mcp.example.com/*"}
+			},
+			wantErr: "mcp.allowed_server_urls",
+		},
+		{
+			name: "mcp allowed server URL with host wildcard",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.MCP.AllowedServerURLs = []string{"https://mcp.example.com*"}
+			},
+			wantErr: "mcp.allowed_server_urls",
+		},
+		{
+			name: "mcp allowed server URL with wildcard inside",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.MCP.AllowedServerURLs = []string{"https://*.example.com/"}
+			},
+			wantErr: "mcp.allowed_server_urls",
+		},
+		{
+			name: "mcp allowed server URL with path wildcard not after slash",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.MCP.AllowedServerURLs = []string{"https://mcp.example.com/team*"}
+			},
+			wantErr: "mcp.allowed_server_urls",
+		},
+		{
+			name: "mcp invalid require_approval mode",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.MCP.Servers = []MCPServerConfig{{Name: "s", URL: "http://mcp:3000", RequireApproval: MCPApprovalConfig{Mode: "sometimes"}}}
+			},
+			wantErr: "mcp.servers[0].require_approval",
+		},
+		{
+			name: "mcp token exchange without token_url",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.Type = "jwt"
+				c.Auth.JWT.JWKSURL = "https://auth.example.com/jwks"
+				c.MCP.Servers = []MCPServerConfig{{Name: "s", URL: "http://mcp:3000", Auth: MCPAuthConfig{Type: "oauth_token_exchange"}}}
+			},
+			wantErr: "mcp.servers[0].auth.token_url is required",
+		},
+		{
+			name: "mcp forward_bearer without jwt auth",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.Type = "apikey"
+				c.MCP.Servers = []MCPServerConfig{{Name: "s", URL: "http://mcp:3000", Auth: MCPAuthConfig{Type: "forward_bearer"}}}
+			},
+			wantErr: "requires auth.type \"jwt\" or \"chain\"",
+		},
+		{
+			name: "jwt without jwks_url or issuer",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.Type = "jwt"
+			},
+			wantErr: "auth.jwt.jwks_url or auth.jwt.issuer is required",
+		},
+		{
+			name: "jwt issuers without issuer",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.Type = "jwt"
+				c.Auth.JWT.Issuers = []JWTConfig{{Issuer: "https://a.example.com"}, {JWKSURL: "https://b.example.com/certs"}}
+			},
+			wantErr: "auth.jwt.issuers[1].issuer is required",
+		},
+		{
+			name: "introspection outside chain",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.Type = "jwt"
+				c.Auth.JWT.Issuer = "https://a.example.com"
+				c.Auth.Introspection.URL = "https://a.example.com/introspect"
+			},
+			wantErr: "auth.introspection requires auth.type \"chain\"",
+		},
+		{
+			name: "key management without apikey auth",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.KeyManagement.Enabled = true
+				c.Auth.Authorization.AdminRole = "admin"
+			},
+			wantErr: "auth.key_management requires auth.type \"apikey\" or \"chain\"",
+		},
+		{
+			name: "key management without admin role",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.Type = "apikey"
+				c.Auth.KeyManagement.Enabled = true
+			},
+			wantErr: "auth.key_management requires auth.authorization.admin_role",
+		},
+		{
+			name: "kubernetes oidc without issuer",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.Type = "kubernetes"
+				c.Auth.Kubernetes.Mode = "oidc"
+			},
+			wantErr: "auth.kubernetes.issuer is required",
+		},
+		{
+			name: "kubernetes invalid mode",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.Type = "kubernetes"
+				c.Auth.Kubernetes.Mode = "webhook"
+			},
+			wantErr: "auth.kubernetes.mode must be",
+		},
+		{
+			name: "kubernetes enabled outside chain",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.Type = "apikey"
+				c.Auth.Kubernetes.Enabled = true
+			},
+			wantErr: "auth.kubernetes.enabled requires auth.type \"chain\"",
+		},
+		{
+			name: "valid chain with kubernetes only",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.Type = "chain"
+				c.Auth.Kubernetes.Enabled = true
+			},
+			wantErr: "",
+		},
+		{
+			name: "mcp zero health check interval",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.MCP.HealthCheckInterval = 0
+			},
+			wantErr: "mcp.health_check_interval must be > 0",
+		},
+		{
+			name: "valid chain with introspection only",
+			modify: func(c *Config) {
+				c.Engine.BackendURL = "http://localhost:8000"
+				c.Auth.Type = "chain"
+				c.Auth.Introspection.URL = "https://a.example.com/introspect"
+			},
+			wantErr: "",
+		},
 		{
 			name: "valid config",
 			modify: func(c *Config) {
@@ -746,3 +919,192 @@ func searchSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestBackendsFromYAML(t *testing.T) {
+	keyFile := writeTemp(t, "backend-key-*.txt", "  sk-proxy  \n")
+
+	yamlContent := `
+engine:
+  backends:
+    - name: llama
+      url: http://vllm-llama:8000
+      models: ["llama-*"]
+      max_context_window: 8192
+    - name: proxy
+      provider: litellm
+      url: http://litellm:4000
+      api_key_file: ` + keyFile + `
+      models: ["gpt-4o"]
+      resilience:
+        enabled: true
+        max_attempts: 5
+resilience:
+  failure_threshold: 7
+`
+	tmpFile := writeTemp(t, "config-*.yaml", yamlContent)
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if len(cfg.Engine.Backends) != 2 {
+		t.Fatalf("engine.backends length = %d, want 2", len(cfg.Engine.Backends))
+	}
+	llama, proxy := cfg.Engine.Backends[0], cfg.Engine.Backends[1]
+	if llama.Provider != "vllm" {
+		t.Errorf("backends[0].provider = %q, want default \"vllm\"", llama.Provider)
+	}
+	if llama.MaxContextWindow != 8192 {
+		t.Errorf("backends[0].max_context_window = %d, want 8192", llama.MaxContextWindow)
+	}
+	if llama.Resilience != nil {
+		t.Errorf("backends[0].resilience = %+v, want nil (inherit)", llama.Resilience)
+	}
+	if proxy.APIKey != "sk-proxy" {
+		t.Errorf("backends[1].api_key = %q, want value from file", proxy.APIKey)
+	}
+	if proxy.Resilience == nil || proxy.Resilience.MaxAttempts != 5 {
+		t.Fatalf("backends[1].resilience = %+v, want max_attempts 5", proxy.Resilience)
+	}
+	if proxy.Resilience.FailureThreshold != 7 || proxy.Resilience.BackoffBase != 100*time.Millisecond {
+		t.Errorf("backends[1].resilience did not inherit unset fields: %+v", proxy.Resilience)
+	}
+}
+
+func TestBackendsValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		backends []BackendConfig
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			backends: []BackendConfig{{Name: "a", Provider: "vllm", URL: "http://a", Models: []string{"*"}}},
+		},
+		{
+			name:     "missing name",
+			backends: []BackendConfig{{URL: "http://a", Models: []string{"*"}}},
+			wantErr:  "engine.backends[0].name is required",
+		},
+		{
+			name: "duplicate name",
+			backends: []BackendConfig{
+				{Name: "a", URL: "http://a", Models: []string{"x"}},
+				{Name: "a", URL: "http://b", Models: []string{"y"}},
+			},
+			wantErr: "engine.backends[1].name \"a\" is duplicated",
+		},
+		{
+			name:     "missing url",
+			backends: []BackendConfig{{Name: "a", Models: []string{"*"}}},
+			wantErr:  "engine.backends[0].url is required",
+		},
+		{
+			name:     "unknown provider",
+			backends: []BackendConfig{{Name: "a", Provider: "ollama", URL: "http://a", Models: []string{"*"}}},
+			wantErr:  "engine.backends[0].provider must be",
+		},
+		{
+			name:     "no models",
+			backends: []BackendConfig{{Name: "a", URL: "http://a"}},
+			wantErr:  "engine.backends[0].models must list",
+		},
+		{
+			name:     "invalid pattern",
+			backends: []BackendConfig{{Name: "a", URL: "http://a", Models: []string{"["}}},
+			wantErr:  "invalid pattern",
+		},
+		{
+			name: "invalid resilience override",
+			backends: []BackendConfig{{Name: "a", URL: "http://a", Models: []string{"*"},
+				Resilience: &ResilienceConfig{Enabled: true, MaxAttempts: 0}}},
+			wantErr: "engine.backends[0].resilience.max_attempts must be >= 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Defaults()
+			cfg.Engine.Backends = tt.backends
+			err := cfg.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() expected error containing %q, got nil", tt.wantErr)
+			}
+			if !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %q, want it to contain %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRateLimitsFromYAML(t *testing.T) {
+	yamlContent := `
+engine:
+  backend_url: http://localhost:8000
+rate_limits:
+  tiers:
+    default:
+      requests_per_minute: 60
+    premium:
+      requests_per_minute: 600
+      output_tokens_per_minute: 50000
+`
+	tmpFile := writeTemp(t, "config-*.yaml", yamlContent)
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if got := cfg.RateLimits.Tiers["default"].RequestsPerMinute; got != 60 {
+		t.Errorf("rate_limits.tiers.default.requests_per_minute = %d, want 60", got)
+	}
+	premium := cfg.RateLimits.Tiers["premium"]
+	if premium.RequestsPerMinute != 600 || premium.OutputTokensPerMinute != 50000 || premium.InputTokensPerMinute != 0 {
+		t.Errorf("rate_limits.tiers.premium = %+v", premium)
+	}
+
+	cfg.RateLimits.Tiers["premium"] = RateLimitTierConfig{InputTokensPerMinute: -1}
+	err = cfg.Validate()
+	if err == nil || !contains(err.Error(), "rate_limits.tiers.premium: limits must be >= 0") {
+		t.Errorf("Validate() error = %v, want negative limit error", err)
+	}
+}
+
+func TestTracingConfig(t *testing.T) {
+	yamlContent := `
+engine:
+  backend_url: http://localhost:8000
+observability:
+  tracing:
+    sample_rate: 0.25
+`
+	tmpFile := writeTemp(t, "config-*.yaml", yamlContent)
+	t.Setenv("ANTWORT_TRACING_ENABLED", "true")
+	t.Setenv("ANTWORT_TRACING_ENDPOINT", "otel-collector:4318")
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	tr := cfg.Observability.Tracing
+	if !tr.Enabled || tr.Endpoint != "otel-collector:4318" {
+		t.Errorf("env overrides not applied: %+v", tr)
+	}
+	if tr.SampleRate != 0.25 || tr.ServiceName != "antwort" {
+		t.Errorf("sample_rate = %v, service_name = %q", tr.SampleRate, tr.ServiceName)
+	}
+
+	cfg.Observability.Tracing.SampleRate = 1.5
+	if err := cfg.Validate(); err == nil || !contains(err.Error(), "observability.tracing.sample_rate") {
+		t.Errorf("expected sample_rate validation error, got %v", err)
+	}
+}
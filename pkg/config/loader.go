@@ -16,8 +16,9 @@ import (
 //  1. Built-in defaults
 //  2. YAML config file (explicit path, ANTWORT_CONFIG env, ./config.yaml, /etc/antwort/config.yaml)
 //  3. Backward-compatible environment variable mapping
-//  4. File reference resolution (_file suffix)
-//  5. Validation
+//  4. Per-backend defaults (provider, resilience)
+//  5. File reference resolution (_file suffix)
+//  6. Validation
 func Load(configPath string) (*Config, error) {
 	// Start with defaults.
 	cfg := Defaults()
@@ -33,6 +34,9 @@ func Load(configPath string) (*Config, error) {
 	// Apply backward-compatible environment variable overrides.
 	applyEnvOverrides(&cfg)
 
+	// Fill per-backend defaults from the global settings.
+	applyBackendDefaults(&cfg)
+
 	// Resolve _file references.
 	if err := resolveFileReferences(&cfg); err != nil {
 		return nil, fmt.Errorf("resolving file references: %w", err)
@@ -160,6 +164,16 @@ func applyEnvOverrides(cfg *Config) {
 		cfg.Engine.Mode = v
 	}
 
+	// Tracing overrides.
+	if v, ok := os.LookupEnv("ANTWORT_TRACING_ENABLED"); ok {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			cfg.Observability.Tracing.Enabled = enabled
+		}
+	}
+	if v := os.Getenv("ANTWORT_TRACING_ENDPOINT"); v != "" {
+		cfg.Observability.Tracing.Endpoint = v
+	}
+
 	// Resilience overrides.
 	if v, ok := os.LookupEnv("ANTWORT_RESILIENCE_ENABLED"); ok {
 		if enabled, err := strconv.ParseBool(v); err == nil {
@@ -196,6 +210,40 @@ func parseMCPServersJSON(jsonStr string) ([]MCPServerConfig, error) {
 	return servers, nil
 }
 
+// applyBackendDefaults fills unset per-backend settings. The provider type
+// defaults to "vllm", and a backend resilience override inherits every
+// field it leaves unset from the global resilience config.
+func applyBackendDefaults(cfg *Config) {
+	for i := range cfg.Engine.Backends {
+		b := &cfg.Engine.Backends[i]
+		if b.Provider == "" {
+			b.Provider = "vllm"
+		}
+		if b.Resilience == nil {
+			continue
+		}
+		r, g := b.Resilience, cfg.Resilience
+		if r.FailureThreshold == 0 {
+			r.FailureThreshold = g.FailureThreshold
+		}
+		if r.ResetTimeout == 0 {
+			r.ResetTimeout = g.ResetTimeout
+		}
+		if r.MaxAttempts == 0 {
+			r.MaxAttempts = g.MaxAttempts
+		}
+		if r.BackoffBase == 0 {
+			r.BackoffBase = g.BackoffBase
+		}
+		if r.BackoffMax == 0 {
+			r.BackoffMax = g.BackoffMax
+		}
+		if r.RetryAfterMax == 0 {
+			r.RetryAfterMax = g.RetryAfterMax
+		}
+	}
+}
+
 // resolveFileReferences reads _file fields and populates the corresponding value fields.
 // For each field ending in _file, if the value field is empty and the file field is set,
 // the file is read, whitespace is trimmed, and the value field is populated.
@@ -209,6 +257,17 @@ func resolveFileReferences(cfg *Config) error {
 		cfg.Engine.APIKey = val
 	}
 
+	// engine.backends[*].api_key_file -> engine.backends[*].api_key
+	for i := range cfg.Engine.Backends {
+		if cfg.Engine.Backends[i].APIKeyFile != "" && cfg.Engine.Backends[i].APIKey == "" {
+			val, err := readSecretFile(cfg.Engine.Backends[i].APIKeyFile)
+			if err != nil {
+				return fmt.Errorf("engine.backends[%d].api_key_file: %w", i, err)
+			}
+			cfg.Engine.Backends[i].APIKey = val
+		}
+	}
+
 	// storage.postgres.dsn_file -> storage.postgres.dsn
 	if cfg.Storage.Postgres.DSNFile != "" && cfg.Storage.Postgres.DSN == "" {
 		val, err := readSecretFile(cfg.Storage.Postgres.DSNFile)
@@ -229,6 +288,15 @@ func resolveFileReferences(cfg *Config) error {
 		}
 	}
 
+	// auth.introspection.client_secret_file -> auth.introspection.client_secret
+	if cfg.Auth.Introspection.ClientSecretFile != "" && cfg.Auth.Introspection.ClientSecret == "" {
+		val, err := readSecretFile(cfg.Auth.Introspection.ClientSecretFile)
+		if err != nil {
+			return fmt.Errorf("auth.introspection.client_secret_file: %w", err)
+		}
+		cfg.Auth.Introspection.ClientSecret = val
+	}
+
 	// mcp.servers[*].auth.client_id_file -> mcp.servers[*].auth.client_id
 	// mcp.servers[*].auth.client_secret_file -> mcp.servers[*].auth.client_secret
 	for i := range cfg.MCP.Servers {
@@ -3,6 +3,9 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"path"
+	"strings"
 )
 
 // Validate checks the configuration for required fields and valid values.
@@ -10,8 +13,8 @@ import (
 func (c *Config) Validate() error {
 	var errs []error
 
-	// engine.backend_url is required.
-	if c.Engine.BackendURL == "" {
+	// engine.backend_url is required unless backends are configured.
+	if c.Engine.BackendURL == "" && len(c.Engine.Backends) == 0 {
 		errs = append(errs, fmt.Errorf("engine.backend_url is required"))
 	}
 
@@ -20,6 +23,14 @@ func (c *Config) Validate() error {
 		errs = append(errs, fmt.Errorf("server.port must be > 0, got %d", c.Server.Port))
 	}
 
+	// server.streaming settings must not be negative.
+	if c.Server.Streaming.BufferSize < 0 {
+		errs = append(errs, fmt.Errorf("server.streaming.buffer_size must be >= 0, got %d", c.Server.Streaming.BufferSize))
+	}
+	if c.Server.Streaming.Retention < 0 {
+		errs = append(errs, fmt.Errorf("server.streaming.retention must be >= 0, got %s", c.Server.Streaming.Retention))
+	}
+
 	// storage.type must be a known value.
 	switch c.Storage.Type {
 	case "memory", "postgres":
@@ -37,44 +48,142 @@ func (c *Config) Validate() error {
 
 	// auth.type must be a known value.
 	switch c.Auth.Type {
-	case "none", "apikey", "jwt", "chain":
+	case "none", "apikey", "jwt", "kubernetes", "chain":
 		// valid
 	default:
-		errs = append(errs, fmt.Errorf("auth.type must be \"none\", \"apikey\", \"jwt\", or \"chain\", got %q", c.Auth.Type))
+		errs = append(errs, fmt.Errorf("auth.type must be \"none\", \"apikey\", \"jwt\", \"kubernetes\", or \"chain\", got %q", c.Auth.Type))
 	}
 
-	// If auth.type requires JWT, jwks_url must be set.
-	if c.Auth.Type == "jwt" || c.Auth.Type == "chain" {
-		if c.Auth.JWT.JWKSURL == "" {
-			errs = append(errs, fmt.Errorf("auth.jwt.jwks_url is required when auth.type is %q", c.Auth.Type))
+	// Managed API keys are checked by the API key authenticator, and only
+	// admins may manage them.
+	if c.Auth.KeyManagement.Enabled {
+		if c.Auth.Type != "apikey" && c.Auth.Type != "chain" {
+			errs = append(errs, fmt.Errorf("auth.key_management requires auth.type \"apikey\" or \"chain\", got %q", c.Auth.Type))
+		}
+		if c.Auth.Authorization.AdminRole == "" {
+			errs = append(errs, fmt.Errorf("auth.key_management requires auth.authorization.admin_role"))
+		}
+		if c.Auth.KeyManagement.CacheTTL <= 0 {
+			errs = append(errs, fmt.Errorf("auth.key_management.cache_ttl must be > 0, got %s", c.Auth.KeyManagement.CacheTTL))
 		}
 	}
 
-	// Validate resilience config when enabled.
-	if c.Resilience.Enabled {
-		if c.Resilience.FailureThreshold <= 0 {
-			errs = append(errs, fmt.Errorf("resilience.failure_threshold must be > 0, got %d", c.Resilience.FailureThreshold))
+	// If auth.type requires JWT, the keys must be configured or
+	// discoverable from the issuer. A chain may validate opaque tokens by
+	// introspection instead.
+	jwt := c.Auth.JWT
+	jwtConfigured := jwt.JWKSURL != "" || jwt.Issuer != "" || len(jwt.Issuers) > 0
+	switch {
+	case c.Auth.Type == "jwt" && !jwtConfigured:
+		errs = append(errs, fmt.Errorf("auth.jwt.jwks_url or auth.jwt.issuer is required when auth.type is \"jwt\""))
+	case c.Auth.Type == "chain" && !jwtConfigured && c.Auth.Introspection.URL == "" && !c.Auth.Kubernetes.Enabled:
+		errs = append(errs, fmt.Errorf("auth.jwt.jwks_url, auth.jwt.issuer, auth.introspection.url or auth.kubernetes.enabled is required when auth.type is \"chain\""))
+	}
+	if len(jwt.Issuers) > 0 && jwt.JWKSURL != "" && jwt.Issuer == "" {
+		errs = append(errs, fmt.Errorf("auth.jwt.issuer is required when auth.jwt.issuers is set"))
+	}
+	seenIssuers := map[string]bool{jwt.Issuer: jwt.Issuer != ""}
+	for i, iss := range jwt.Issuers {
+		if iss.Issuer == "" {
+			errs = append(errs, fmt.Errorf("auth.jwt.issuers[%d].issuer is required", i))
+		} else if seenIssuers[iss.Issuer] {
+			errs = append(errs, fmt.Errorf("auth.jwt.issuers[%d].issuer %q is duplicated", i, iss.Issuer))
+		}
+		seenIssuers[iss.Issuer] = true
+		if len(iss.Issuers) > 0 {
+			errs = append(errs, fmt.Errorf("auth.jwt.issuers[%d].issuers cannot be nested", i))
+		}
+	}
+
+	// Token introspection is one of the authenticators of a chain.
+	if in := c.Auth.Introspection; in.URL != "" {
+		if c.Auth.Type != "chain" {
+			errs = append(errs, fmt.Errorf("auth.introspection requires auth.type \"chain\", got %q", c.Auth.Type))
+		}
+		if !strings.HasPrefix(in.URL, "http://") && !strings.HasPrefix(in.URL, "https://") {
+			errs = append(errs, fmt.Errorf("auth.introspection.url must start with http:// or https://, got %q", in.URL))
+		}
+		if in.CacheTTL <= 0 {
+			errs = append(errs, fmt.Errorf("auth.introspection.cache_ttl must be > 0, got %s", in.CacheTTL))
+		}
+	}
+
+	// Service account tokens are validated by the TokenReview API or
+	// against the keys of the cluster's issuer.
+	if k := c.Auth.Kubernetes; c.Auth.Type == "kubernetes" || k.Enabled {
+		if k.Enabled && c.Auth.Type != "chain" {
+			errs = append(errs, fmt.Errorf("auth.kubernetes.enabled requires auth.type \"chain\", got %q", c.Auth.Type))
 		}
-		if c.Resilience.MaxAttempts < 1 {
-			errs = append(errs, fmt.Errorf("resilience.max_attempts must be >= 1, got %d", c.Resilience.MaxAttempts))
+		switch k.Mode {
+		case "tokenreview":
+		case "oidc":
+			if k.Issuer == "" {
+				errs = append(errs, fmt.Errorf("auth.kubernetes.issuer is required when auth.kubernetes.mode is \"oidc\""))
+			}
+			if len(k.Audiences) > 1 {
+				errs = append(errs, fmt.Errorf("auth.kubernetes.audiences must have at most one entry when auth.kubernetes.mode is \"oidc\""))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("auth.kubernetes.mode must be \"tokenreview\" or \"oidc\", got %q", k.Mode))
 		}
-		if c.Resilience.ResetTimeout <= 0 {
-			errs = append(errs, fmt.Errorf("resilience.reset_timeout must be > 0"))
+		if k.CacheTTL <= 0 {
+			errs = append(errs, fmt.Errorf("auth.kubernetes.cache_ttl must be > 0, got %s", k.CacheTTL))
 		}
-		if c.Resilience.BackoffBase <= 0 {
-			errs = append(errs, fmt.Errorf("resilience.backoff_base must be > 0"))
+	}
+
+	for i, srv := range c.MCP.Servers {
+		switch srv.RequireApproval.Mode {
+		case "", "always", "never":
+		default:
+			errs = append(errs, fmt.Errorf("mcp.servers[%d].require_approval must be \"always\", \"never\", or lists of tool names, got %q", i, srv.RequireApproval.Mode))
 		}
-		if c.Resilience.BackoffMax <= 0 {
-			errs = append(errs, fmt.Errorf("resilience.backoff_max must be > 0"))
+
+		switch srv.Auth.Type {
+		case "", "static", "forward_bearer":
+		case "oauth_client_credentials", "oauth_token_exchange":
+			if srv.Auth.TokenURL == "" {
+				errs = append(errs, fmt.Errorf("mcp.servers[%d].auth.token_url is required when auth.type is %q", i, srv.Auth.Type))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("mcp.servers[%d].auth.type must be \"static\", \"oauth_client_credentials\", \"oauth_token_exchange\", or \"forward_bearer\", got %q", i, srv.Auth.Type))
 		}
-		if c.Resilience.RetryAfterMax <= 0 {
-			errs = append(errs, fmt.Errorf("resilience.retry_after_max must be > 0"))
+
+		// Only JWT authentication leaves a caller token to pass on.
+		if (srv.Auth.Type == "oauth_token_exchange" || srv.Auth.Type == "forward_bearer") && c.Auth.Type != "jwt" && c.Auth.Type != "chain" {
+			errs = append(errs, fmt.Errorf("mcp.servers[%d].auth.type %q requires auth.type \"jwt\" or \"chain\"", i, srv.Auth.Type))
 		}
-		if c.Resilience.BackoffMax > 0 && c.Resilience.BackoffBase > 0 && c.Resilience.BackoffMax < c.Resilience.BackoffBase {
-			errs = append(errs, fmt.Errorf("resilience.backoff_max must be >= resilience.backoff_base (got backoff_max=%v, backoff_base=%v)", c.Resilience.BackoffMax, c.Resilience.BackoffBase))
+	}
+
+	// Validate the allowlist of per-request MCP servers.
+	for _, u := range c.MCP.AllowedServerURLs {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			errs = append(errs, fmt.Errorf("mcp.allowed_server_urls: %q must start with http:// or https://", u))
+			continue
 		}
+		// A wildcard may only extend the path, so that it cannot match
+		// other hosts.
+		prefix, wildcard := strings.CutSuffix(u, "*")
+		parsed, err := url.Parse(prefix)
+		switch {
+		case err != nil || parsed.Host == "":
+			errs = append(errs, fmt.Errorf("mcp.allowed_server_urls: %q is not a valid URL", u))
+		case strings.Contains(prefix, "*") || wildcard && !strings.HasSuffix(parsed.Path, "/"):
+			errs = append(errs, fmt.Errorf("mcp.allowed_server_urls: %q may only end in a wildcard that follows a \"/\" after the host", u))
+		}
+	}
+	if c.MCP.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("mcp.idle_timeout must be >= 0, got %s", c.MCP.IdleTimeout))
+	}
+	if c.MCP.HealthCheckInterval <= 0 {
+		errs = append(errs, fmt.Errorf("mcp.health_check_interval must be > 0, got %s", c.MCP.HealthCheckInterval))
+	}
+	if c.MCP.ToolRefreshInterval <= 0 {
+		errs = append(errs, fmt.Errorf("mcp.tool_refresh_interval must be > 0, got %s", c.MCP.ToolRefreshInterval))
 	}
 
+	// Validate resilience config when enabled.
+	errs = append(errs, validateResilience("resilience", c.Resilience)...)
+
 	// engine.provider must be a known value if set.
 	switch c.Engine.Provider {
 	case "vllm", "litellm", "vllm-responses", "":
@@ -83,5 +192,96 @@ func (c *Config) Validate() error {
 		errs = append(errs, fmt.Errorf("engine.provider must be \"vllm\", \"litellm\", or \"vllm-responses\", got %q", c.Engine.Provider))
 	}
 
+	// observability.tracing.sample_rate must be a fraction.
+	if r := c.Observability.Tracing.SampleRate; r < 0 || r > 1 {
+		errs = append(errs, fmt.Errorf("observability.tracing.sample_rate must be between 0 and 1, got %g", r))
+	}
+
+	// Rate limits must not be negative.
+	for tier, tc := range c.RateLimits.Tiers {
+		if tc.RequestsPerMinute < 0 || tc.InputTokensPerMinute < 0 || tc.OutputTokensPerMinute < 0 {
+			errs = append(errs, fmt.Errorf("rate_limits.tiers.%s: limits must be >= 0", tier))
+		}
+	}
+	switch c.RateLimits.Backend {
+	case "memory", "":
+		// valid
+	case "postgres":
+		if c.Storage.Type != "postgres" {
+			errs = append(errs, fmt.Errorf("rate_limits.backend \"postgres\" requires storage.type \"postgres\""))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("rate_limits.backend must be \"memory\" or \"postgres\", got %q", c.RateLimits.Backend))
+	}
+	switch c.RateLimits.FailureMode {
+	case "open", "closed", "":
+		// valid
+	default:
+		errs = append(errs, fmt.Errorf("rate_limits.failure_mode must be \"open\" or \"closed\", got %q", c.RateLimits.FailureMode))
+	}
+
+	// Validate engine.backends entries.
+	names := make(map[string]bool)
+	for i, b := range c.Engine.Backends {
+		field := fmt.Sprintf("engine.backends[%d]", i)
+		if b.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name is required", field))
+		} else if names[b.Name] {
+			errs = append(errs, fmt.Errorf("%s.name %q is duplicated", field, b.Name))
+		}
+		names[b.Name] = true
+		if b.URL == "" {
+			errs = append(errs, fmt.Errorf("%s.url is required", field))
+		}
+		switch b.Provider {
+		case "vllm", "litellm", "vllm-responses", "":
+			// valid
+		default:
+			errs = append(errs, fmt.Errorf("%s.provider must be \"vllm\", \"litellm\", or \"vllm-responses\", got %q", field, b.Provider))
+		}
+		if len(b.Models) == 0 {
+			errs = append(errs, fmt.Errorf("%s.models must list at least one model name or pattern", field))
+		}
+		for _, m := range b.Models {
+			if _, err := path.Match(m, ""); err != nil {
+				errs = append(errs, fmt.Errorf("%s.models: invalid pattern %q", field, m))
+			}
+		}
+		if b.Resilience != nil {
+			errs = append(errs, validateResilience(field+".resilience", *b.Resilience)...)
+		}
+	}
+
 	return errors.Join(errs...)
 }
+
+// validateResilience checks a resilience config when it is enabled. The
+// field prefix is used in error messages (e.g. "resilience").
+func validateResilience(field string, r ResilienceConfig) []error {
+	if !r.Enabled {
+		return nil
+	}
+	var errs []error
+	if r.FailureThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("%s.failure_threshold must be > 0, got %d", field, r.FailureThreshold))
+	}
+	if r.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("%s.max_attempts must be >= 1, got %d", field, r.MaxAttempts))
+	}
+	if r.ResetTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("%s.reset_timeout must be > 0", field))
+	}
+	if r.BackoffBase <= 0 {
+		errs = append(errs, fmt.Errorf("%s.backoff_base must be > 0", field))
+	}
+	if r.BackoffMax <= 0 {
+		errs = append(errs, fmt.Errorf("%s.backoff_max must be > 0", field))
+	}
+	if r.RetryAfterMax <= 0 {
+		errs = append(errs, fmt.Errorf("%s.retry_after_max must be > 0", field))
+	}
+	if r.BackoffMax > 0 && r.BackoffBase > 0 && r.BackoffMax < r.BackoffBase {
+		errs = append(errs, fmt.Errorf("%s.backoff_max must be >= %s.backoff_base (got backoff_max=%v, backoff_base=%v)", field, field, r.BackoffMax, r.BackoffBase))
+	}
+	return errs
+}
@@ -9,9 +9,9 @@ import (
 
 // SourceContext holds metadata about a tool result source for annotation generation.
 type SourceContext struct {
-	ToolName string // "file_search" or "web_search"
+	ToolName string // "file_search", "web_search" or "mcp_resource"
 	FileID   string // Source file ID (file_search)
-	URL      string // Source URL (web_search)
+	URL      string // Source URL or resource URI (web_search, mcp_resource)
 	Title    string // Page or file title
 	Content  string // Source content (chunk text or search snippet)
 }
@@ -97,7 +97,7 @@ func (m *SubstringMatcher) buildAnnotation(src SourceContext, quote string, star
 			StartIndex: start,
 			EndIndex:   end,
 		}
-	case "web_search":
+	case "web_search", "mcp_resource":
 		return &api.Annotation{
 			Type:       "url_citation",
 			URL:        src.URL,
@@ -131,6 +131,40 @@ func ExtractSourceContexts(results []tools.ToolResult) []SourceContext {
 	return sources
 }
 
+// fileCitations cites the files tools produced (ToolResult.Files) in the
+// output text, as container_file_citation annotations. A citation covers
+// the first mention of the file name, or is placed at the end of the text
+// if the name is not mentioned.
+func fileCitations(outputText string, results []tools.ToolResult) []api.Annotation {
+	if outputText == "" {
+		return nil
+	}
+	var annotations []api.Annotation
+	seen := map[string]bool{}
+	for _, r := range results {
+		for _, f := range r.Files {
+			if f.FileID == "" || seen[f.FileID] {
+				continue
+			}
+			seen[f.FileID] = true
+
+			start, end := len(outputText), len(outputText)
+			if idx := strings.Index(outputText, f.Filename); idx >= 0 && f.Filename != "" {
+				start, end = idx, idx+len(f.Filename)
+			}
+			annotations = append(annotations, api.Annotation{
+				Type:        "container_file_citation",
+				ContainerID: f.ContainerID,
+				FileID:      f.FileID,
+				Filename:    f.Filename,
+				StartIndex:  start,
+				EndIndex:    end,
+			})
+		}
+	}
+	return annotations
+}
+
 // longestCommonSubstring finds the longest common substring between a and b.
 // Returns the start index in a and the length of the match.
 func longestCommonSubstring(a, b string) (int, int) {
@@ -54,6 +54,22 @@ func TestSubstringMatcher_URLCitation(t *testing.T) {
 	}
 }
 
+func TestSubstringMatcher_MCPResourceCitation(t *testing.T) {
+	m := NewSubstringMatcher(10)
+	sources := []SourceContext{
+		{ToolName: "mcp_resource", URL: "docs://handbook", Title: "Handbook", Content: "Vacation requests need two weeks notice."},
+	}
+	output := "Per the handbook, vacation requests need two weeks notice."
+
+	anns := m.Generate(output, sources)
+	if len(anns) != 1 {
+		t.Fatalf("expected 1 annotation, got %d", len(anns))
+	}
+	if anns[0].Type != "url_citation" || anns[0].URL != "docs://handbook" {
+		t.Errorf("annotation = %+v, want url_citation of docs://handbook", anns[0])
+	}
+}
+
 func TestSubstringMatcher_NoMatch(t *testing.T) {
 	m := NewSubstringMatcher(20)
 	sources := []SourceContext{
@@ -246,3 +262,31 @@ func TestLongestCommonSubstring(t *testing.T) {
 		})
 	}
 }
+
+func TestFileCitations(t *testing.T) {
+	results := []tools.ToolResult{
+		{Files: []tools.OutputFile{{FileID: "file-1", Filename: "chart.png"}, {FileID: "file-2", Filename: "data.csv"}}},
+		{Files: []tools.OutputFile{{FileID: "file-1", Filename: "chart.png"}}}, // duplicate
+		{Output: "no files"},
+	}
+	output := "I saved the plot as chart.png."
+
+	anns := fileCitations(output, results)
+	if len(anns) != 2 {
+		t.Fatalf("expected 2 annotations, got %d", len(anns))
+	}
+	if anns[0].Type != "container_file_citation" || anns[0].FileID != "file-1" || anns[0].Filename != "chart.png" {
+		t.Errorf("unexpected first annotation: %+v", anns[0])
+	}
+	if got := output[anns[0].StartIndex:anns[0].EndIndex]; got != "chart.png" {
+		t.Errorf("first annotation covers %q, want chart.png", got)
+	}
+	// Files not mentioned are cited at the end of the text.
+	if anns[1].StartIndex != len(output) || anns[1].EndIndex != len(output) {
+		t.Errorf("second annotation range: start=%d end=%d, want %d", anns[1].StartIndex, anns[1].EndIndex, len(output))
+	}
+
+	if anns := fileCitations("", results); anns != nil {
+		t.Errorf("expected no annotations for empty text, got %v", anns)
+	}
+}
@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// approvalRequirer is implemented by executors whose tools can require the
+// user's approval before they run (MCPExecutor, mcp.RequestServers).
+type approvalRequirer interface {
+	RequiresApproval(toolName string) (serverLabel string, required bool)
+}
+
+// pendingApproval is a tool call that waits for the user's approval.
+type pendingApproval struct {
+	callID string
+	item   api.Item // the mcp_approval_request item
+}
+
+// leadingItemsKey is the context key for output items produced before the
+// agentic loop starts.
+type leadingItemsKey struct{}
+
+// withLeadingItems adds items to the start of the response output.
+func withLeadingItems(ctx context.Context, items []api.Item) context.Context {
+	if len(items) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, leadingItemsKey{}, append(leadingItems(ctx), items...))
+}
+
+// leadingItems returns the items that start the response output, such as
+// MCP tool listings and tool calls approved by the user.
+func leadingItems(ctx context.Context) []api.Item {
+	items, _ := ctx.Value(leadingItemsKey{}).([]api.Item)
+	return slices.Clone(items)
+}
+
+// requestApprovals separates the tool calls that need the user's approval
+// from those that can run right away. For each held call it returns an
+// mcp_approval_request item.
+func (e *Engine) requestApprovals(ctx context.Context, calls []tools.ToolCall) ([]tools.ToolCall, []pendingApproval) {
+	var run []tools.ToolCall
+	var pending []pendingApproval
+	for _, call := range calls {
+		ar, ok := e.findExecutor(ctx, call.Name).(approvalRequirer)
+		if !ok {
+			run = append(run, call)
+			continue
+		}
+		label, required := ar.RequiresApproval(call.Name)
+		if !required {
+			run = append(run, call)
+			continue
+		}
+		pending = append(pending, pendingApproval{
+			callID: call.ID,
+			item: api.Item{
+				ID:     api.NewItemID(),
+				Type:   api.ItemTypeMCPApprovalRequest,
+				Status: api.ItemStatusCompleted,
+				MCPApprovalRequest: &api.MCPApprovalRequestData{
+					ServerLabel: label,
+					Name:        call.Name,
+					Arguments:   call.Arguments,
+				},
+			},
+		})
+	}
+	return run, pending
+}
+
+// replaceWithApprovalRequests replaces the function_call items of held
+// calls with their mcp_approval_request items, so that the held calls do
+// not show up in the history of a follow-up request.
+func replaceWithApprovalRequests(items []api.Item, pending []pendingApproval) []api.Item {
+	for i, item := range items {
+		if item.Type != api.ItemTypeFunctionCall || item.FunctionCall == nil {
+			continue
+		}
+		for _, p := range pending {
+			if p.callID == item.FunctionCall.CallID {
+				items[i] = p.item
+				break
+			}
+		}
+	}
+	return items
+}
+
+// resolveApprovals handles mcp_approval_response items in the request
+// input. Each answers an mcp_approval_request of the previous response:
+// approved calls are executed, rejected calls get an error result telling
+// the model so. The calls and their results start the response output and
+// are appended to the conversation sent to the model.
+func (e *Engine) resolveApprovals(ctx context.Context, req *api.CreateResponseRequest, provReq *provider.ProviderRequest) (context.Context, error) {
+	var answers []*api.MCPApprovalResponseData
+	for _, item := range req.Input {
+		if item.Type == api.ItemTypeMCPApprovalResponse && item.MCPApprovalResponse != nil {
+			answers = append(answers, item.MCPApprovalResponse)
+		}
+	}
+	if len(answers) == 0 {
+		return ctx, nil
+	}
+	if req.PreviousResponseID == "" || e.store == nil {
+		return ctx, api.NewInvalidRequestError("input", "mcp_approval_response requires previous_response_id")
+	}
+
+	prev, err := e.store.GetResponse(ctx, req.PreviousResponseID)
+	if err != nil {
+		return ctx, err
+	}
+	requests := make(map[string]*api.MCPApprovalRequestData)
+	for _, item := range prev.Output {
+		if item.Type == api.ItemTypeMCPApprovalRequest && item.MCPApprovalRequest != nil {
+			requests[item.ID] = item.MCPApprovalRequest
+		}
+	}
+
+	// The approval request ID doubles as the call ID; the model's
+	// original call never reached the output.
+	var approved []tools.ToolCall
+	var calls []tools.ToolCall
+	results := make(map[string]tools.ToolResult)
+	for _, answer := range answers {
+		request, ok := requests[answer.ApprovalRequestID]
+		if !ok {
+			return ctx, api.NewInvalidRequestError("input",
+				fmt.Sprintf("approval_request_id %q does not match a pending approval request of the previous response", answer.ApprovalRequestID))
+		}
+		call := tools.ToolCall{ID: answer.ApprovalRequestID, Name: request.Name, Arguments: request.Arguments}
+		calls = append(calls, call)
+		if answer.Approve {
+			approved = append(approved, call)
+			continue
+		}
+		output := "The user did not approve this tool call."
+		if answer.Reason != "" {
+			output += " Reason: " + answer.Reason
+		}
+		results[call.ID] = tools.ToolResult{CallID: call.ID, Output: output, IsError: true}
+	}
+
+	for _, r := range e.executeTools(ctx, approved, getParallelToolCalls(req)) {
+		results[r.CallID] = r
+	}
+
+	var items []api.Item
+	var ordered []tools.ToolResult
+	provReq.Messages = append(provReq.Messages, buildAssistantToolCallMessage(calls))
+	for _, call := range calls {
+		r := results[call.ID]
+		ordered = append(ordered, r)
+		provReq.Messages = append(provReq.Messages, provider.ProviderMessage{
+			Role:       "tool",
+			Content:    r.Output,
+			ToolCallID: call.ID,
+		})
+		if item, ok := toolCallItem(api.NewItemID(), call, e.findExecutor(ctx, call.Name), &r); ok {
+			items = append(items, item)
+			continue
+		}
+		items = append(items,
+			api.Item{
+				ID:     api.NewItemID(),
+				Type:   api.ItemTypeFunctionCall,
+				Status: api.ItemStatusCompleted,
+				FunctionCall: &api.FunctionCallData{
+					Name:      call.Name,
+					CallID:    call.ID,
+					Arguments: call.Arguments,
+				},
+			},
+			api.Item{
+				ID:     api.NewItemID(),
+				Type:   api.ItemTypeFunctionCallOutput,
+				Status: api.ItemStatusCompleted,
+				FunctionCallOutput: &api.FunctionCallOutputData{
+					CallID: call.ID,
+					Output: r.Output,
+				},
+			},
+		)
+	}
+	provReq.Messages = e.appendToolImages(provReq.Messages, req.Model, ordered)
+	return withLeadingItems(ctx, items), nil
+}
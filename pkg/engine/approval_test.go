@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/storage/memory"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// approvalExecutor is an MCP executor whose tools all require approval.
+type approvalExecutor struct {
+	alwaysExecutor
+	calls []string
+}
+
+func (e *approvalExecutor) Kind() tools.ToolKind                   { return tools.ToolKindMCP }
+func (e *approvalExecutor) ServerLabel(string) string              { return "repo" }
+func (e *approvalExecutor) RequiresApproval(string) (string, bool) { return "repo", true }
+
+func (e *approvalExecutor) Execute(ctx context.Context, call tools.ToolCall) (*tools.ToolResult, error) {
+	e.calls = append(e.calls, call.Name)
+	return e.alwaysExecutor.Execute(ctx, call)
+}
+
+func TestAgenticLoop_MCPApproval(t *testing.T) {
+	tests := []struct {
+		name       string
+		approve    bool
+		wantCalls  int
+		wantOutput string
+	}{
+		{"approved", true, 1, "deleted"},
+		{"rejected", false, 0, "The user did not approve this tool call. Reason: too risky"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prov := &turnAwareProvider{
+				caps: provider.ProviderCapabilities{ToolCalling: true},
+				responses: []*provider.ProviderResponse{
+					{
+						Status: api.ResponseStatusCompleted,
+						Items: []api.Item{
+							{Type: api.ItemTypeFunctionCall, Status: api.ItemStatusCompleted,
+								FunctionCall: &api.FunctionCallData{Name: "delete_repo", CallID: "c1", Arguments: `{"repo":"x"}`}},
+						},
+					},
+					{
+						Status: api.ResponseStatusCompleted,
+						Items: []api.Item{
+							{Type: api.ItemTypeMessage, Status: api.ItemStatusCompleted,
+								Message: &api.MessageData{Role: api.RoleAssistant, Output: []api.OutputContentPart{{Type: "output_text", Text: "Done."}}}},
+						},
+					},
+				},
+			}
+			exec := &approvalExecutor{alwaysExecutor: alwaysExecutor{results: map[string]string{"delete_repo": "deleted"}}}
+			eng, err := New(prov, memory.New(0), Config{Executors: []tools.ToolExecutor{exec}})
+			toolDefs := []api.ToolDefinition{{Type: "function", Name: "delete_repo"}}
+			if err != nil {
+				t.Fatalf("failed to create engine: %v", err)
+			}
+
+			w := &mockResponseWriter{}
+			err = eng.CreateResponse(context.Background(), &api.CreateResponseRequest{
+				Model: "m",
+				Input: []api.Item{{Type: api.ItemTypeMessage, Message: &api.MessageData{Role: api.RoleUser, Content: []api.ContentPart{{Type: "input_text", Text: "Delete x"}}}}},
+				Tools: toolDefs,
+			}, w)
+			if err != nil {
+				t.Fatalf("CreateResponse failed: %v", err)
+			}
+
+			first := w.response
+			if first.Status != api.ResponseStatusRequiresAction {
+				t.Fatalf("status = %s, want requires_action", first.Status)
+			}
+			if len(first.Output) != 1 || first.Output[0].Type != api.ItemTypeMCPApprovalRequest {
+				t.Fatalf("output = %+v, want a single mcp_approval_request", first.Output)
+			}
+			request := first.Output[0]
+			if request.MCPApprovalRequest.ServerLabel != "repo" || request.MCPApprovalRequest.Name != "delete_repo" {
+				t.Errorf("approval request = %+v", request.MCPApprovalRequest)
+			}
+			if len(exec.calls) != 0 {
+				t.Fatalf("tool ran before approval: %v", exec.calls)
+			}
+
+			w = &mockResponseWriter{}
+			err = eng.CreateResponse(context.Background(), &api.CreateResponseRequest{
+				Model:              "m",
+				PreviousResponseID: first.ID,
+				Input: []api.Item{{Type: api.ItemTypeMCPApprovalResponse, MCPApprovalResponse: &api.MCPApprovalResponseData{
+					ApprovalRequestID: request.ID, Approve: tt.approve, Reason: "too risky",
+				}}},
+				Tools: toolDefs,
+			}, w)
+			if err != nil {
+				t.Fatalf("continuation failed: %v", err)
+			}
+
+			if len(exec.calls) != tt.wantCalls {
+				t.Errorf("tool calls = %v, want %d", exec.calls, tt.wantCalls)
+			}
+			// mcp_call + message.
+			out := w.response.Output
+			if w.response.Status != api.ResponseStatusCompleted || len(out) != 2 {
+				t.Fatalf("continuation = %s with %d items, want completed with 2", w.response.Status, len(out))
+			}
+			call := out[0].MCPCall
+			if out[0].Type != api.ItemTypeMCPCall || call.Name != "delete_repo" || call.ServerLabel != "repo" {
+				t.Fatalf("output[0] = %+v, want the answered mcp_call", out[0])
+			}
+			if got := call.Output + call.Error; got != tt.wantOutput {
+				t.Errorf("mcp_call output = %q, want %q", got, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestCreateResponse_MCPApprovalUnknownRequest(t *testing.T) {
+	prov := &turnAwareProvider{caps: provider.ProviderCapabilities{ToolCalling: true}}
+	store := memory.New(0)
+	eng, err := New(prov, store, Config{})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	w := &mockResponseWriter{}
+	err = eng.CreateResponse(context.Background(), &api.CreateResponseRequest{
+		Model: "m",
+		Input: []api.Item{{Type: api.ItemTypeMessage, Message: &api.MessageData{Role: api.RoleUser, Content: []api.ContentPart{{Type: "input_text", Text: "Hi"}}}}},
+	}, w)
+	if err != nil {
+		t.Fatalf("CreateResponse failed: %v", err)
+	}
+
+	err = eng.CreateResponse(context.Background(), &api.CreateResponseRequest{
+		Model:              "m",
+		PreviousResponseID: w.response.ID,
+		Input: []api.Item{{Type: api.ItemTypeMCPApprovalResponse, MCPApprovalResponse: &api.MCPApprovalResponseData{
+			ApprovalRequestID: "item_unknown", Approve: true,
+		}}},
+	}, &mockResponseWriter{})
+	apiErr, ok := err.(*api.APIError)
+	if !ok || apiErr.Type != api.ErrorTypeInvalidRequest {
+		t.Errorf("err = %v, want invalid_request error", err)
+	}
+}
+
+func TestAgenticLoopStreaming_MCPApproval(t *testing.T) {
+	prov := &turnAwareProvider{
+		caps: provider.ProviderCapabilities{Streaming: true, ToolCalling: true},
+		streamFns: []func(context.Context, *provider.ProviderRequest) (<-chan provider.ProviderEvent, error){
+			func(_ context.Context, _ *provider.ProviderRequest) (<-chan provider.ProviderEvent, error) {
+				ch := make(chan provider.ProviderEvent, 4)
+				ch <- provider.ProviderEvent{Type: provider.ProviderEventToolCallDelta, ToolCallIndex: 0,
+					ToolCallID: "c1", FunctionName: "delete_repo", Delta: `{"repo":"x"}`}
+				ch <- provider.ProviderEvent{Type: provider.ProviderEventToolCallDone, ToolCallIndex: 0,
+					ToolCallID: "c1", FunctionName: "delete_repo", Delta: `{"repo":"x"}`,
+					Item: &api.Item{Type: api.ItemTypeFunctionCall, Status: api.ItemStatusCompleted,
+						FunctionCall: &api.FunctionCallData{Name: "delete_repo", CallID: "c1", Arguments: `{"repo":"x"}`}}}
+				ch <- provider.ProviderEvent{Type: provider.ProviderEventDone}
+				close(ch)
+				return ch, nil
+			},
+		},
+	}
+	exec := &approvalExecutor{alwaysExecutor: alwaysExecutor{results: map[string]string{"delete_repo": "deleted"}}}
+	eng, err := New(prov, memory.New(0), Config{Executors: []tools.ToolExecutor{exec}})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	w := &mockResponseWriter{}
+	err = eng.CreateResponse(context.Background(), &api.CreateResponseRequest{
+		Model:  "m",
+		Stream: true,
+		Input:  []api.Item{{Type: api.ItemTypeMessage, Message: &api.MessageData{Role: api.RoleUser, Content: []api.ContentPart{{Type: "input_text", Text: "Delete x"}}}}},
+		Tools:  []api.ToolDefinition{{Type: "function", Name: "delete_repo"}},
+	}, w)
+	if err != nil {
+		t.Fatalf("CreateResponse failed: %v", err)
+	}
+
+	callIndex := -1
+	var requests []api.StreamEvent
+	var resp *api.Response
+	for _, ev := range w.events {
+		if ev.Type == api.EventResponseRequiresAction {
+			resp = ev.Response
+		}
+		if ev.Item == nil {
+			continue
+		}
+		switch ev.Item.Type {
+		case api.ItemTypeFunctionCall:
+			callIndex = ev.OutputIndex
+		case api.ItemTypeMCPApprovalRequest:
+			requests = append(requests, ev)
+		}
+	}
+	if callIndex < 0 {
+		t.Fatal("function_call item was not streamed")
+	}
+	if len(requests) != 2 || requests[0].Type != api.EventOutputItemAdded || requests[1].Type != api.EventOutputItemDone {
+		t.Fatalf("mcp_approval_request events = %+v, want added and done", requests)
+	}
+	for _, ev := range requests {
+		if ev.OutputIndex != callIndex {
+			t.Errorf("%s output_index = %d, want %d (the function_call's)", ev.Type, ev.OutputIndex, callIndex)
+		}
+	}
+	if resp == nil || resp.Status != api.ResponseStatusRequiresAction {
+		t.Fatalf("response = %+v, want requires_action", resp)
+	}
+	if len(resp.Output) != 1 || resp.Output[0].ID != requests[0].Item.ID {
+		t.Errorf("output = %+v, want the streamed approval request", resp.Output)
+	}
+	if len(exec.calls) != 0 {
+		t.Errorf("tool ran before approval: %v", exec.calls)
+	}
+}
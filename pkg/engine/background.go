@@ -5,14 +5,17 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/auth"
 	"github.com/rhuss/antwort/pkg/config"
 	"github.com/rhuss/antwort/pkg/observability"
+	"github.com/rhuss/antwort/pkg/storage"
 	"github.com/rhuss/antwort/pkg/transport"
 )
 
@@ -29,6 +32,11 @@ type Worker struct {
 	// Used for in-process cancellation in integrated mode.
 	mu             sync.RWMutex
 	cancelRegistry map[string]context.CancelFunc
+
+	// streams buffers the events of requests being processed, so clients
+	// can follow them with GET /v1/responses/{id}?stream=true. Nil when
+	// stream resumption is disabled.
+	streams *transport.StreamRegistry
 }
 
 // NewWorker creates a background worker that processes queued requests.
@@ -41,6 +49,12 @@ func NewWorker(engine *Engine, cfg config.BackgroundConfig) *Worker {
 	}
 }
 
+// SetStreamRegistry makes the worker stream requests through the engine
+// and buffer their events in r for stream resumption.
+func (w *Worker) SetStreamRegistry(r *transport.StreamRegistry) {
+	w.streams = r
+}
+
 // Start begins the worker poll loop. It blocks until the context is cancelled.
 func (w *Worker) Start(ctx context.Context) {
 	ctx, w.cancel = context.WithCancel(ctx)
@@ -164,9 +178,9 @@ func (w *Worker) processRequest(parentCtx context.Context, resp *api.Response, r
 	go w.heartbeat(ctx, responseID, heartbeatDone)
 	defer close(heartbeatDone)
 
-	// Deserialize the original request.
-	var req api.CreateResponseRequest
-	if err := json.Unmarshal(reqData, &req); err != nil {
+	// Deserialize the original request and run it as its caller.
+	queued, err := decodeQueuedRequest(reqData)
+	if err != nil {
 		slog.Error("failed to unmarshal background request",
 			"response_id", responseID,
 			"error", err,
@@ -174,26 +188,42 @@ func (w *Worker) processRequest(parentCtx context.Context, resp *api.Response, r
 		w.markFailed(context.Background(), responseID, fmt.Errorf("invalid request data: %w", err))
 		return
 	}
+	req := *queued.Request
+	ctx = queued.Caller.context(ctx)
 
-	// Force non-background, non-streaming for worker processing.
+	// Force non-background, non-streaming for worker processing. With
+	// stream resumption, the request is streamed and its events buffered.
 	req.Background = false
 	req.Stream = false
 
 	// Process through the engine using a capture writer.
-	cw := &captureWriter{}
-	err := w.engine.CreateResponse(ctx, &req, cw)
+	cw := &captureWriter{responseID: responseID}
+	if w.streams != nil {
+		req.Stream = true
+		cw.buf = w.streams.Open(responseID, "")
+		defer cw.buf.Close()
+	}
+	err = w.engine.CreateResponse(ctx, &req, cw)
 
 	// Check for cancellation.
 	if ctx.Err() != nil {
+		cw.finish(api.EventResponseCancelled, api.ResponseStatusCancelled, nil)
 		w.markCancelled(context.Background(), responseID)
 		return
 	}
 
+	if err == nil && cw.resp != nil && cw.resp.Status == api.ResponseStatusFailed {
+		err = errors.New("response failed")
+		if cw.resp.Error != nil {
+			err = errors.New(cw.resp.Error.Message)
+		}
+	}
 	if err != nil {
 		slog.Error("background request processing failed",
 			"response_id", responseID,
 			"error", err,
 		)
+		cw.finish(api.EventResponseFailed, api.ResponseStatusFailed, api.NewServerError(err.Error()))
 		w.markFailed(context.Background(), responseID, err)
 		return
 	}
@@ -202,6 +232,85 @@ func (w *Worker) processRequest(parentCtx context.Context, resp *api.Response, r
 	w.markCompleted(context.Background(), responseID, cw)
 }
 
+// queuedRequest is the stored form of a background request: the request
+// and the caller it was made by. The worker runs the request as that
+// caller, so that ownership, tenancy and token quotas apply to it as to
+// any other request.
+type queuedRequest struct {
+	Request *api.CreateResponseRequest `json:"request"`
+	Caller  *queuedCaller              `json:"caller,omitempty"`
+}
+
+// queuedCaller is the authenticated caller of a background request. The
+// caller's bearer token is not stored, so background requests cannot
+// pass it on to MCP servers.
+type queuedCaller struct {
+	Subject     string            `json:"subject"`
+	ServiceTier string            `json:"service_tier,omitempty"`
+	Scopes      []string          `json:"scopes,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Tenant      string            `json:"tenant,omitempty"`
+	Admin       bool              `json:"admin,omitempty"`
+}
+
+// queuedCallerFrom returns the caller in ctx, or nil without
+// authentication.
+func queuedCallerFrom(ctx context.Context) *queuedCaller {
+	id := auth.IdentityFromContext(ctx)
+	owner := storage.GetOwner(ctx)
+	if id == nil && owner == "" {
+		return nil
+	}
+	c := &queuedCaller{
+		Owner:  owner,
+		Tenant: storage.GetTenant(ctx),
+		Admin:  storage.GetAdmin(ctx),
+	}
+	if id != nil {
+		c.Subject, c.ServiceTier, c.Scopes, c.Metadata = id.Subject, id.ServiceTier, id.Scopes, id.Metadata
+	}
+	return c
+}
+
+// context returns ctx with the caller's identity, owner and tenant, as
+// the auth middleware sets them.
+func (c *queuedCaller) context(ctx context.Context) context.Context {
+	if c == nil {
+		return ctx
+	}
+	if c.Subject != "" {
+		ctx = auth.SetIdentity(ctx, &auth.Identity{
+			Subject:     c.Subject,
+			ServiceTier: c.ServiceTier,
+			Scopes:      c.Scopes,
+			Metadata:    c.Metadata,
+		})
+	}
+	if c.Tenant != "" {
+		ctx = storage.SetTenant(ctx, c.Tenant)
+	}
+	ctx = storage.SetOwner(ctx, c.Owner)
+	return storage.SetAdmin(ctx, c.Admin)
+}
+
+// decodeQueuedRequest decodes a stored background request. Requests
+// queued before callers were stored hold the request alone and run
+// without a caller.
+func decodeQueuedRequest(data json.RawMessage) (*queuedRequest, error) {
+	var queued queuedRequest
+	if err := json.Unmarshal(data, &queued); err != nil {
+		return nil, err
+	}
+	if queued.Request == nil {
+		queued.Request = &api.CreateResponseRequest{}
+		if err := json.Unmarshal(data, queued.Request); err != nil {
+			return nil, err
+		}
+	}
+	return &queued, nil
+}
+
 // heartbeat periodically updates the worker heartbeat on the response record.
 func (w *Worker) heartbeat(ctx context.Context, responseID string, done <-chan struct{}) {
 	ticker := time.NewTicker(w.cfg.HeartbeatInterval)
@@ -352,9 +461,14 @@ func (w *Worker) markInFlightAsFailed() {
 }
 
 // captureWriter captures the response from the engine for background processing.
-// It implements transport.ResponseWriter.
+// It implements transport.ResponseWriter. When buf is set, streamed events are
+// buffered for stream resumption and the final response is taken from the
+// terminal event.
 type captureWriter struct {
-	resp *api.Response
+	resp       *api.Response
+	responseID string
+	buf        *transport.StreamBuffer
+	terminal   bool
 }
 
 func (cw *captureWriter) WriteResponse(_ context.Context, resp *api.Response) error {
@@ -362,8 +476,52 @@ func (cw *captureWriter) WriteResponse(_ context.Context, resp *api.Response) er
 	return nil
 }
 
-func (cw *captureWriter) WriteEvent(_ context.Context, _ api.StreamEvent) error {
-	return fmt.Errorf("streaming not supported for background requests")
+func (cw *captureWriter) WriteEvent(_ context.Context, event api.StreamEvent) error {
+	if cw.buf == nil {
+		return fmt.Errorf("streaming not supported for background requests")
+	}
+	if event.Response != nil {
+		// The engine assigns its own ID; clients know the queued response.
+		resp := *event.Response
+		resp.ID = cw.responseID
+		resp.Background = true
+		event.Response = &resp
+	}
+	if isTerminalEvent(event.Type) {
+		cw.resp = event.Response
+		cw.terminal = true
+	}
+	cw.buf.Append(event)
+	return nil
+}
+
+// finish ends the buffered stream with a terminal event if the engine did
+// not send one, so that clients following the stream see the outcome.
+func (cw *captureWriter) finish(eventType api.StreamEventType, status api.ResponseStatus, apiErr *api.APIError) {
+	if cw.buf == nil || cw.terminal {
+		return
+	}
+	cw.buf.Append(api.StreamEvent{
+		Type: eventType,
+		Response: &api.Response{
+			ID:         cw.responseID,
+			Object:     "response",
+			Status:     status,
+			Background: true,
+			Error:      apiErr,
+		},
+	})
+	cw.terminal = true
+}
+
+// isTerminalEvent reports whether the event type ends a response stream.
+func isTerminalEvent(t api.StreamEventType) bool {
+	switch t {
+	case api.EventResponseCompleted, api.EventResponseFailed, api.EventResponseCancelled,
+		api.EventResponseIncomplete, api.EventResponseRequiresAction:
+		return true
+	}
+	return false
 }
 
 func (cw *captureWriter) Flush() error {
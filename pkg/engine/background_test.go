@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/auth"
+	"github.com/rhuss/antwort/pkg/config"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/storage"
+	"github.com/rhuss/antwort/pkg/storage/memory"
+	"github.com/rhuss/antwort/pkg/transport"
+)
+
+func TestWorker_StreamsBackgroundResponse(t *testing.T) {
+	mp := &mockProvider{
+		name: "test",
+		caps: provider.ProviderCapabilities{Streaming: true},
+		streamFn: func(_ context.Context, _ *provider.ProviderRequest) (<-chan provider.ProviderEvent, error) {
+			ch := make(chan provider.ProviderEvent, 4)
+			ch <- provider.ProviderEvent{Type: provider.ProviderEventTextDelta, Delta: "Hello"}
+			ch <- provider.ProviderEvent{Type: provider.ProviderEventTextDone}
+			ch <- provider.ProviderEvent{Type: provider.ProviderEventDone, Usage: &api.Usage{InputTokens: 3, OutputTokens: 1, TotalTokens: 4}}
+			close(ch)
+			return ch, nil
+		},
+	}
+	store := memory.New(0)
+	eng, err := New(mp, store, Config{})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	// Queue a background request.
+	w := &mockResponseWriter{}
+	req := &api.CreateResponseRequest{
+		Model:      "m",
+		Background: true,
+		Input:      []api.Item{{Type: api.ItemTypeMessage, Message: &api.MessageData{Role: api.RoleUser, Content: []api.ContentPart{{Type: "input_text", Text: "Hi"}}}}},
+	}
+	if err := eng.CreateResponse(context.Background(), req, w); err != nil {
+		t.Fatalf("CreateResponse failed: %v", err)
+	}
+	id := w.response.ID
+
+	// Process it with stream resumption enabled.
+	streams := transport.NewStreamRegistry(100, time.Minute)
+	worker := NewWorker(eng, config.BackgroundConfig{HeartbeatInterval: time.Hour})
+	worker.SetStreamRegistry(streams)
+
+	resp, reqData, err := store.ClaimQueuedResponse(context.Background(), "test-worker")
+	if err != nil || resp == nil {
+		t.Fatalf("ClaimQueuedResponse: %v, %v", resp, err)
+	}
+	worker.wg.Add(1)
+	worker.processRequest(context.Background(), resp, reqData)
+
+	buf := streams.Get(id)
+	if buf == nil {
+		t.Fatal("no stream buffer for background response")
+	}
+	events, done, _, err := buf.Since(-1)
+	if err != nil || !done || len(events) == 0 {
+		t.Fatalf("Since(-1) = %d events, done=%v, err=%v", len(events), done, err)
+	}
+	first, last := events[0], events[len(events)-1]
+	if first.Type != api.EventResponseCreated || first.Response.ID != id || !first.Response.Background {
+		t.Errorf("first event = %s for %s, want response.created for %s", first.Type, first.Response.ID, id)
+	}
+	if last.Type != api.EventResponseCompleted || last.Response.ID != id {
+		t.Errorf("last event = %s, want response.completed", last.Type)
+	}
+
+	stored, err := store.GetResponse(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetResponse: %v", err)
+	}
+	if stored.Status != api.ResponseStatusCompleted || len(stored.Output) == 0 {
+		t.Errorf("stored response = %s with %d items, want completed with output", stored.Status, len(stored.Output))
+	}
+}
+
+// usageRecorderFunc adapts a function to the UsageRecorder interface.
+type usageRecorderFunc func(ctx context.Context, inputTokens, outputTokens int)
+
+func (f usageRecorderFunc) RecordUsage(ctx context.Context, inputTokens, outputTokens int) {
+	f(ctx, inputTokens, outputTokens)
+}
+
+func TestWorker_RunsRequestAsCaller(t *testing.T) {
+	mp := &mockProvider{
+		name: "test",
+		response: &provider.ProviderResponse{
+			Items: []api.Item{{Type: api.ItemTypeMessage, Status: api.ItemStatusCompleted, Message: &api.MessageData{Role: api.RoleAssistant, Output: []api.OutputContentPart{{Type: "output_text", Text: "Hello"}}}}},
+			Usage: api.Usage{InputTokens: 3, OutputTokens: 1, TotalTokens: 4},
+		},
+	}
+	var charged, owner string
+	recorder := usageRecorderFunc(func(ctx context.Context, in, out int) {
+		if id := auth.IdentityFromContext(ctx); id != nil {
+			charged = id.Subject
+		}
+		owner = storage.GetOwner(ctx)
+	})
+	store := memory.New(0)
+	eng, err := New(mp, store, Config{UsageRecorder: recorder})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	// Queue a background request as an authenticated caller.
+	ctx := auth.SetIdentity(context.Background(), &auth.Identity{Subject: "alice", Token: "secret"})
+	ctx = storage.SetOwner(ctx, "alice")
+	w := &mockResponseWriter{}
+	req := &api.CreateResponseRequest{
+		Model:      "m",
+		Background: true,
+		Input:      []api.Item{{Type: api.ItemTypeMessage, Message: &api.MessageData{Role: api.RoleUser, Content: []api.ContentPart{{Type: "input_text", Text: "Hi"}}}}},
+	}
+	if err := eng.CreateResponse(ctx, req, w); err != nil {
+		t.Fatalf("CreateResponse failed: %v", err)
+	}
+
+	resp, reqData, err := store.ClaimQueuedResponse(context.Background(), "test-worker")
+	if err != nil || resp == nil {
+		t.Fatalf("ClaimQueuedResponse: %v, %v", resp, err)
+	}
+	if strings.Contains(string(reqData), "secret") {
+		t.Error("queued request stores the caller's bearer token")
+	}
+	worker := NewWorker(eng, config.BackgroundConfig{HeartbeatInterval: time.Hour})
+	worker.wg.Add(1)
+	worker.processRequest(context.Background(), resp, reqData)
+
+	if charged != "alice" || owner != "alice" {
+		t.Errorf("usage charged to %q with owner %q, want alice", charged, owner)
+	}
+}
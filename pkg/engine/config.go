@@ -5,6 +5,8 @@ import (
 
 	"github.com/rhuss/antwort/pkg/agent"
 	"github.com/rhuss/antwort/pkg/tools"
+	mcptools "github.com/rhuss/antwort/pkg/tools/mcp"
+	"github.com/rhuss/antwort/pkg/transport"
 )
 
 // Config holds configuration for the core engine.
@@ -23,6 +25,10 @@ type Config struct {
 	// behavior (tool calls returned as function_call items).
 	Executors []tools.ToolExecutor
 
+	// MCPPool connects to MCP servers that requests declare with tools of
+	// type "mcp". When nil, such requests are rejected.
+	MCPPool *mcptools.Pool
+
 	// Annotator generates citations on output text from tool result sources.
 	// When nil, no annotations are generated (feature disabled).
 	Annotator AnnotationGenerator
@@ -31,9 +37,29 @@ type Config struct {
 	// When nil, the agent and prompt fields on requests are rejected.
 	ProfileResolver agent.ProfileResolver
 
+	// Conversations stores conversation items for requests that set
+	// conversation_id. When nil, such requests are rejected.
+	Conversations transport.ConversationStore
+
+	// TokenCounter estimates token counts for truncation "auto".
+	// When nil, a character-based heuristic is used.
+	TokenCounter TokenCounter
+
 	// AuditLogger emits structured audit events for tool execution.
 	// When nil, no audit events are emitted.
 	AuditLogger AuditLogger
+
+	// UsageRecorder is charged with the token usage of each finished
+	// response (e.g., for token-based rate limits). When nil, usage is
+	// only recorded in metrics.
+	UsageRecorder UsageRecorder
+}
+
+// UsageRecorder receives the token usage of finished responses. The
+// context carries the caller's identity. This avoids an import cycle
+// between engine and auth; auth.RateLimiter satisfies this interface.
+type UsageRecorder interface {
+	RecordUsage(ctx context.Context, inputTokens, outputTokens int)
 }
 
 // AuditLogger defines the interface for emitting audit events.
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// bindContainer pins the code interpreter container for the tool calls
+// of the response: the container named by the request's code_interpreter
+// tool or, for "auto", the one the conversation history last used. If
+// neither exists, the code interpreter creates a container on first use.
+// The binding also carries the files to copy into the container. Requests
+// without a code_interpreter tool get no binding.
+func bindContainer(ctx context.Context, req *api.CreateResponseRequest, messages []provider.ProviderMessage) context.Context {
+	for _, t := range req.Tools {
+		if t.Type != "code_interpreter" {
+			continue
+		}
+		binding := &tools.ContainerBinding{}
+		if t.Container != nil && t.Container.ID != "" {
+			binding.ID = t.Container.ID
+			binding.Explicit = true
+		} else {
+			binding.ID = historyContainerID(messages)
+		}
+		binding.FileIDs = containerFileIDs(t.Container, req.Input)
+		return tools.SetContainerBinding(ctx, binding)
+	}
+	return ctx
+}
+
+// containerFileIDs returns the files named by the container and the
+// input_file parts of the input, in order and without duplicates.
+func containerFileIDs(container *api.CodeInterpreterContainer, input []api.Item) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if container != nil {
+		for _, id := range container.FileIDs {
+			add(id)
+		}
+	}
+	for _, item := range input {
+		if item.Message == nil {
+			continue
+		}
+		for _, part := range item.Message.Content {
+			if part.Type == "input_file" {
+				add(part.FileID)
+			}
+		}
+	}
+	return ids
+}
+
+// historyContainerID returns the container of the last code_interpreter
+// call in messages, or "" if there is none.
+func historyContainerID(messages []provider.ProviderMessage) string {
+	codeCalls := make(map[string]bool)
+	containerID := ""
+	for _, msg := range messages {
+		for _, tc := range msg.ToolCalls {
+			if tc.Function.Name == "code_interpreter" {
+				codeCalls[tc.ID] = true
+			}
+		}
+		if msg.Role != "tool" || !codeCalls[msg.ToolCallID] {
+			continue
+		}
+		output, ok := msg.Content.(string)
+		if !ok {
+			continue
+		}
+		var data api.CodeInterpreterCallData
+		if json.Unmarshal([]byte(output), &data) == nil && data.ContainerID != "" {
+			containerID = data.ContainerID
+		}
+	}
+	return containerID
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// codeCallMessages replays a code_interpreter call that ran in containerID.
+func codeCallMessages(callID, name, containerID string) []provider.ProviderMessage {
+	output := `{"code":"x = 1","outputs":[],"container_id":"` + containerID + `"}`
+	return []provider.ProviderMessage{
+		{Role: "assistant", ToolCalls: []provider.ProviderToolCall{{
+			ID: callID, Type: "function", Function: provider.ProviderFunctionCall{Name: name},
+		}}},
+		{Role: "tool", ToolCallID: callID, Content: output},
+	}
+}
+
+func TestBindContainer(t *testing.T) {
+	var history []provider.ProviderMessage
+	history = append(history, codeCallMessages("call_1", "code_interpreter", "cntr_old")...)
+	history = append(history, codeCallMessages("call_2", "code_interpreter", "cntr_last")...)
+	history = append(history, codeCallMessages("call_3", "lookalike", "cntr_other")...)
+
+	tests := []struct {
+		name         string
+		tools        []api.ToolDefinition
+		history      []provider.ProviderMessage
+		wantBinding  bool
+		wantID       string
+		wantExplicit bool
+	}{
+		{"no code interpreter", []api.ToolDefinition{{Type: "function", Name: "get_weather"}}, history, false, "", false},
+		{"auto without history", []api.ToolDefinition{{Type: "code_interpreter"}}, nil, true, "", false},
+		{"auto reuses history", []api.ToolDefinition{{Type: "code_interpreter", Container: &api.CodeInterpreterContainer{}}}, history, true, "cntr_last", false},
+		{"explicit container", []api.ToolDefinition{{Type: "code_interpreter", Container: &api.CodeInterpreterContainer{ID: "cntr_mine"}}}, history, true, "cntr_mine", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &api.CreateResponseRequest{Tools: tt.tools}
+			b := tools.GetContainerBinding(bindContainer(context.Background(), req, tt.history))
+			if (b != nil) != tt.wantBinding {
+				t.Fatalf("binding = %+v, want binding: %v", b, tt.wantBinding)
+			}
+			if b == nil {
+				return
+			}
+			if b.ID != tt.wantID || b.Explicit != tt.wantExplicit {
+				t.Errorf("binding = {ID: %q, Explicit: %v}, want {ID: %q, Explicit: %v}", b.ID, b.Explicit, tt.wantID, tt.wantExplicit)
+			}
+		})
+	}
+}
+
+func TestBindContainer_FileIDs(t *testing.T) {
+	req := &api.CreateResponseRequest{
+		Tools: []api.ToolDefinition{{
+			Type:      "code_interpreter",
+			Container: &api.CodeInterpreterContainer{FileIDs: []string{"file_a", "file_b"}},
+		}},
+		Input: []api.Item{{
+			Type: api.ItemTypeMessage,
+			Message: &api.MessageData{Role: api.RoleUser, Content: []api.ContentPart{
+				{Type: "input_text", Text: "Compare these."},
+				{Type: "input_file", FileID: "file_b"},
+				{Type: "input_file", FileID: "file_c"},
+			}},
+		}},
+	}
+
+	b := tools.GetContainerBinding(bindContainer(context.Background(), req, nil))
+	if b == nil {
+		t.Fatal("expected a binding")
+	}
+	want := []string{"file_a", "file_b", "file_c"}
+	if !slices.Equal(b.FileIDs, want) {
+		t.Errorf("FileIDs = %v, want %v", b.FileIDs, want)
+	}
+}
+
+// outputExecutor is a code interpreter whose code prints to stdout and
+// stderr.
+type outputExecutor struct{}
+
+func (outputExecutor) Kind() tools.ToolKind        { return tools.ToolKindBuiltin }
+func (outputExecutor) CanExecute(name string) bool { return name == "code_interpreter" }
+func (outputExecutor) Execute(ctx context.Context, call tools.ToolCall) (*tools.ToolResult, error) {
+	if report := tools.GetOutputReporter(ctx); report != nil {
+		report(tools.Output{Stream: "stdout", Data: "1\n"})
+		report(tools.Output{Stream: "stderr", Data: "warning\n"})
+	}
+	return &tools.ToolResult{CallID: call.ID, Output: "{}"}, nil
+}
+
+func TestExecuteToolsWithEvents_CodeInterpreterOutput(t *testing.T) {
+	eng, err := New(&turnAwareProvider{}, nil, Config{Executors: []tools.ToolExecutor{outputExecutor{}}})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	w := &mockResponseWriter{}
+	call := tools.ToolCall{ID: "call_1", Name: "code_interpreter", Arguments: `{"code":"print(1)"}`}
+	eng.executeToolsWithEvents(context.Background(), []tools.ToolCall{call}, false, w, &streamState{outputIndex: 1}, nil)
+
+	var types []api.StreamEventType
+	for _, ev := range w.events {
+		types = append(types, ev.Type)
+		if ev.ItemID != "call_1" || ev.OutputIndex != 2 {
+			t.Errorf("%s refers to %s at %d, want call_1 at 2", ev.Type, ev.ItemID, ev.OutputIndex)
+		}
+	}
+	want := []api.StreamEventType{
+		api.EventCodeInterpreterInProgress,
+		api.EventCodeInterpreterCodeDelta,
+		api.EventCodeInterpreterCodeDone,
+		api.EventCodeInterpreterInterpreting,
+		api.EventCodeInterpreterLogsDelta,
+		api.EventCodeInterpreterLogsDelta,
+		api.EventCodeInterpreterCompleted,
+	}
+	if !slices.Equal(types, want) {
+		t.Fatalf("events = %v, want %v", types, want)
+	}
+	if w.events[2].Delta != "print(1)" {
+		t.Errorf("code = %q, want print(1)", w.events[2].Delta)
+	}
+	if ev := w.events[5]; ev.OutputStream != "stderr" || ev.Delta != "warning\n" {
+		t.Errorf("logs delta = %+v, want warning on stderr", ev)
+	}
+}
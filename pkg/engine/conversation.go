@@ -0,0 +1,167 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/storage"
+	"github.com/rhuss/antwort/pkg/transport"
+)
+
+// conversationPageSize is the page size used when reading conversation
+// items through the paginated ListItems API.
+const conversationPageSize = 100
+
+// conversationItemsLoader is implemented by conversation stores that can
+// return all items of a conversation in one call (e.g. the memory store).
+type conversationItemsLoader interface {
+	AllItems(ctx context.Context, conversationID string) ([]api.ConversationItem, error)
+}
+
+// validateConversationRequest checks conversation_id constraints: the ID
+// must be well-formed, cannot be combined with previous_response_id, and
+// requires a conversation store.
+func (e *Engine) validateConversationRequest(req *api.CreateResponseRequest) error {
+	if req.ConversationID == "" {
+		return nil
+	}
+	if req.PreviousResponseID != "" {
+		return api.NewInvalidRequestError("conversation_id", "conversation_id cannot be used with previous_response_id")
+	}
+	if !api.ValidateConversationID(req.ConversationID) {
+		return api.NewInvalidRequestError("conversation_id", "invalid conversation ID format")
+	}
+	if e.cfg.Conversations == nil {
+		return api.NewInvalidRequestError("conversation_id", "conversations require a conversation store")
+	}
+	return nil
+}
+
+// loadConversationMessages returns the items of a conversation as provider
+// messages in position order (oldest first).
+func (e *Engine) loadConversationMessages(ctx context.Context, conversationID string) ([]provider.ProviderMessage, error) {
+	items, err := e.loadConversationItems(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []provider.ProviderMessage
+	for _, item := range items {
+		messages = append(messages, itemToMessages(item)...)
+	}
+	return messages, nil
+}
+
+// loadConversationItems reads all items of a conversation. The conversation
+// is looked up first so that missing, deleted or foreign conversations map
+// to a not-found error.
+func (e *Engine) loadConversationItems(ctx context.Context, conversationID string) ([]api.Item, error) {
+	store := e.cfg.Conversations
+	if _, err := store.GetConversation(ctx, conversationID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, api.NewNotFoundError("conversation " + conversationID + " not found")
+		}
+		return nil, err
+	}
+
+	if loader, ok := store.(conversationItemsLoader); ok {
+		convItems, err := loader.AllItems(ctx, conversationID)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]api.Item, len(convItems))
+		for i, ci := range convItems {
+			items[i] = ci.Item
+		}
+		return items, nil
+	}
+
+	var items []api.Item
+	opts := transport.ListOptions{Order: "asc", Limit: conversationPageSize}
+	for {
+		page, err := store.ListItems(ctx, conversationID, opts)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Data...)
+		if !page.HasMore || page.LastID == "" {
+			return items, nil
+		}
+		opts.After = page.LastID
+	}
+}
+
+// conversationWriter wraps a ResponseWriter and appends the request input
+// and the response output to a conversation once the response reaches a
+// terminal state that produced usable output (completed, incomplete or
+// requires_action). Input and output are appended in a single AddItems
+// call, so the turn is recorded atomically. Append failures are logged but
+// do not affect the client response, matching response persistence.
+type conversationWriter struct {
+	transport.ResponseWriter
+	ctx      context.Context
+	store    transport.ConversationStore
+	req      *api.CreateResponseRequest
+	appended bool
+}
+
+// WriteEvent forwards the event and appends the turn on terminal events.
+func (cw *conversationWriter) WriteEvent(ctx context.Context, event api.StreamEvent) error {
+	if err := cw.ResponseWriter.WriteEvent(ctx, event); err != nil {
+		return err
+	}
+	switch event.Type {
+	case api.EventResponseCompleted, api.EventResponseIncomplete, api.EventResponseRequiresAction:
+		cw.appendTurn(event.Response)
+	}
+	return nil
+}
+
+// WriteResponse forwards the response and appends the turn.
+func (cw *conversationWriter) WriteResponse(ctx context.Context, resp *api.Response) error {
+	if err := cw.ResponseWriter.WriteResponse(ctx, resp); err != nil {
+		return err
+	}
+	cw.appendTurn(resp)
+	return nil
+}
+
+// appendTurn records the request input and response output in the
+// conversation. Queued, failed and cancelled responses are skipped.
+func (cw *conversationWriter) appendTurn(resp *api.Response) {
+	if cw.appended || resp == nil {
+		return
+	}
+	switch resp.Status {
+	case api.ResponseStatusCompleted, api.ResponseStatusIncomplete, api.ResponseStatusRequiresAction:
+	default:
+		return
+	}
+	cw.appended = true
+
+	now := time.Now().Unix()
+	items := make([]api.ConversationItem, 0, len(cw.req.Input)+len(resp.Output))
+	for _, item := range cw.req.Input {
+		if item.ID == "" {
+			item.ID = api.NewItemID()
+		}
+		items = append(items, api.ConversationItem{Item: item, CreatedAt: now})
+	}
+	for _, item := range resp.Output {
+		items = append(items, api.ConversationItem{Item: item, CreatedAt: now})
+	}
+
+	// Use the request context without its cancellation so a client that
+	// disconnects right after the terminal event does not lose the turn.
+	if err := cw.store.AddItems(context.WithoutCancel(cw.ctx), cw.req.ConversationID, items); err != nil {
+		slog.Warn("failed to append items to conversation",
+			"conversation_id", cw.req.ConversationID,
+			"response_id", resp.ID,
+			"error", err.Error(),
+		)
+	}
+}
@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/storage/memory"
+)
+
+// capturingProvider records the last request passed to Complete or Stream.
+type capturingProvider struct {
+	mockProvider
+	lastReq *provider.ProviderRequest
+}
+
+func (p *capturingProvider) Complete(ctx context.Context, req *provider.ProviderRequest) (*provider.ProviderResponse, error) {
+	p.lastReq = req
+	return p.mockProvider.Complete(ctx, req)
+}
+
+func (p *capturingProvider) Stream(ctx context.Context, req *provider.ProviderRequest) (<-chan provider.ProviderEvent, error) {
+	p.lastReq = req
+	return p.mockProvider.Stream(ctx, req)
+}
+
+func newConversationTestProvider(reply string) *capturingProvider {
+	return &capturingProvider{mockProvider: mockProvider{
+		name: "test",
+		caps: provider.ProviderCapabilities{Streaming: true},
+		response: &provider.ProviderResponse{
+			Model:  "m",
+			Status: api.ResponseStatusCompleted,
+			Items: []api.Item{{
+				Type:   api.ItemTypeMessage,
+				Status: api.ItemStatusCompleted,
+				Message: &api.MessageData{
+					Role:   api.RoleAssistant,
+					Output: []api.OutputContentPart{{Type: "output_text", Text: reply}},
+				},
+			}},
+		},
+		streamFn: func(_ context.Context, _ *provider.ProviderRequest) (<-chan provider.ProviderEvent, error) {
+			ch := make(chan provider.ProviderEvent, 4)
+			ch <- provider.ProviderEvent{Type: provider.ProviderEventTextDelta, Delta: reply}
+			ch <- provider.ProviderEvent{Type: provider.ProviderEventTextDone}
+			ch <- provider.ProviderEvent{Type: provider.ProviderEventDone, Item: &api.Item{Status: api.ItemStatusCompleted}}
+			close(ch)
+			return ch, nil
+		},
+	}}
+}
+
+func userInput(text string) []api.Item {
+	return []api.Item{{
+		Type: api.ItemTypeMessage,
+		Message: &api.MessageData{
+			Role:    api.RoleUser,
+			Content: []api.ContentPart{{Type: "input_text", Text: text}},
+		},
+	}}
+}
+
+func newTestConversation(t *testing.T, store *memory.ConversationStore) string {
+	t.Helper()
+	now := time.Now().Unix()
+	conv := &api.Conversation{ID: api.NewConversationID(), Object: "conversation", CreatedAt: now, UpdatedAt: now}
+	if err := store.SaveConversation(context.Background(), conv); err != nil {
+		t.Fatalf("SaveConversation failed: %v", err)
+	}
+	return conv.ID
+}
+
+func TestConversation_AppendsTurnsAndLoadsHistory(t *testing.T) {
+	convStore := memory.NewConversationStore()
+	convID := newTestConversation(t, convStore)
+	mp := newConversationTestProvider("Hi Alice")
+
+	eng, err := New(mp, nil, Config{Conversations: convStore})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	// First turn.
+	w := &mockResponseWriter{}
+	req := &api.CreateResponseRequest{Model: "m", ConversationID: convID, Input: userInput("I am Alice")}
+	if err := eng.CreateResponse(context.Background(), req, w); err != nil {
+		t.Fatalf("CreateResponse failed: %v", err)
+	}
+	if w.response.ConversationID == nil || *w.response.ConversationID != convID {
+		t.Errorf("response conversation_id = %v, want %q", w.response.ConversationID, convID)
+	}
+	if n := convStore.ItemCount(convID); n != 2 {
+		t.Fatalf("after first turn: %d items, want 2 (input + output)", n)
+	}
+
+	// Second turn (streaming) sees the first turn as history.
+	w = &mockResponseWriter{}
+	req = &api.CreateResponseRequest{Model: "m", Stream: true, ConversationID: convID, Input: userInput("Who am I?")}
+	if err := eng.CreateResponse(context.Background(), req, w); err != nil {
+		t.Fatalf("CreateResponse (streaming) failed: %v", err)
+	}
+
+	msgs := mp.lastReq.Messages
+	if len(msgs) != 3 {
+		t.Fatalf("provider got %d messages, want 3 (2 history + 1 new)", len(msgs))
+	}
+	if msgs[0].Content != "I am Alice" || msgs[1].Content != "Hi Alice" || msgs[2].Content != "Who am I?" {
+		t.Errorf("unexpected message order: %+v", msgs)
+	}
+	if n := convStore.ItemCount(convID); n != 4 {
+		t.Errorf("after second turn: %d items, want 4", n)
+	}
+
+	items, _ := convStore.AllItems(context.Background(), convID)
+	for i, ci := range items {
+		if ci.Item.ID == "" {
+			t.Errorf("item %d has no ID", i)
+		}
+		if ci.Position != i {
+			t.Errorf("item %d position = %d", i, ci.Position)
+		}
+	}
+}
+
+func TestConversation_RejectsPreviousResponseID(t *testing.T) {
+	convStore := memory.NewConversationStore()
+	eng, _ := New(newConversationTestProvider("x"), &mockStore{}, Config{Conversations: convStore})
+
+	req := &api.CreateResponseRequest{
+		Model:              "m",
+		ConversationID:     newTestConversation(t, convStore),
+		PreviousResponseID: "resp_abc",
+		Input:              userInput("hi"),
+	}
+	err := eng.CreateResponse(context.Background(), req, &mockResponseWriter{})
+	apiErr, ok := err.(*api.APIError)
+	if !ok || apiErr.Param != "conversation_id" {
+		t.Fatalf("expected invalid_request on conversation_id, got %v", err)
+	}
+}
+
+func TestConversation_NotFound(t *testing.T) {
+	eng, _ := New(newConversationTestProvider("x"), nil, Config{Conversations: memory.NewConversationStore()})
+
+	req := &api.CreateResponseRequest{Model: "m", ConversationID: api.NewConversationID(), Input: userInput("hi")}
+	err := eng.CreateResponse(context.Background(), req, &mockResponseWriter{})
+	apiErr, ok := err.(*api.APIError)
+	if !ok || apiErr.Type != api.ErrorTypeNotFound {
+		t.Fatalf("expected not_found error, got %v", err)
+	}
+}
+
+func TestConversation_NoStore(t *testing.T) {
+	eng, _ := New(newConversationTestProvider("x"), nil, Config{})
+
+	req := &api.CreateResponseRequest{Model: "m", ConversationID: api.NewConversationID(), Input: userInput("hi")}
+	err := eng.CreateResponse(context.Background(), req, &mockResponseWriter{})
+	if err == nil || !strings.Contains(err.Error(), "conversation store") {
+		t.Fatalf("expected missing conversation store error, got %v", err)
+	}
+}
+
+func TestConversation_FailedResponseNotAppended(t *testing.T) {
+	convStore := memory.NewConversationStore()
+	convID := newTestConversation(t, convStore)
+	mp := newConversationTestProvider("x")
+	mp.streamFn = func(_ context.Context, _ *provider.ProviderRequest) (<-chan provider.ProviderEvent, error) {
+		ch := make(chan provider.ProviderEvent, 1)
+		ch <- provider.ProviderEvent{Type: provider.ProviderEventError, Err: api.NewServerError("boom")}
+		close(ch)
+		return ch, nil
+	}
+	eng, _ := New(mp, nil, Config{Conversations: convStore})
+
+	req := &api.CreateResponseRequest{Model: "m", Stream: true, ConversationID: convID, Input: userInput("hi")}
+	eng.CreateResponse(context.Background(), req, &mockResponseWriter{})
+
+	if n := convStore.ItemCount(convID); n != 0 {
+		t.Errorf("failed response appended %d items, want 0", n)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/rhuss/antwort/pkg/agent"
@@ -62,13 +63,23 @@ func responseMode(req *api.CreateResponseRequest) string {
 
 // CreateResponse handles a non-streaming or streaming response creation request.
 func (e *Engine) CreateResponse(ctx context.Context, req *api.CreateResponseRequest, w transport.ResponseWriter) error {
+	ctx, span := startResponseSpan(ctx, req)
+	defer span.End()
+
+	err := e.createResponse(ctx, req, w)
+	observability.RecordSpanError(span, err)
+	return err
+}
+
+// createResponse implements CreateResponse within the response span.
+func (e *Engine) createResponse(ctx context.Context, req *api.CreateResponseRequest, w transport.ResponseWriter) error {
 	// Record response metrics (spec 046).
 	mode := responseMode(req)
 	observability.ResponsesActive.WithLabelValues(mode).Inc()
 	defer observability.ResponsesActive.WithLabelValues(mode).Dec()
 
 	// Resolve agent profile or prompt parameter (Spec 038).
-	profileVectorStoreIDs, err := e.resolveProfile(req)
+	profileVectorStoreIDs, err := e.resolveProfile(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -91,6 +102,11 @@ func (e *Engine) CreateResponse(ctx context.Context, req *api.CreateResponseRequ
 		}
 	}
 
+	// Validate conversation constraints (conversation_id).
+	if err := e.validateConversationRequest(req); err != nil {
+		return err
+	}
+
 	// Apply default model if the request omits it.
 	if req.Model == "" {
 		if e.cfg.DefaultModel != "" {
@@ -99,9 +115,10 @@ func (e *Engine) CreateResponse(ctx context.Context, req *api.CreateResponseRequ
 			return api.NewInvalidRequestError("model", "model is required")
 		}
 	}
+	setSpanModel(ctx, req.Model)
 
 	// Validate capabilities.
-	if apiErr := provider.ValidateCapabilities(e.provider.Capabilities(), req); apiErr != nil {
+	if apiErr := provider.ValidateCapabilities(provider.CapabilitiesFor(e.provider, req.Model), req); apiErr != nil {
 		return apiErr
 	}
 
@@ -114,6 +131,21 @@ func (e *Engine) CreateResponse(ctx context.Context, req *api.CreateResponseRequ
 	// Collect built-in tool definitions for the provider to expand stubs.
 	provReq.BuiltinToolDefs = e.collectBuiltinToolDefs()
 
+	// Connect to MCP servers declared in the request. Background requests
+	// connect when the worker runs them.
+	if req.Background {
+		if err := e.checkMCPServers(req); err != nil {
+			return err
+		}
+	} else {
+		var closeServers func()
+		ctx, closeServers, err = e.openMCPServers(ctx, req, provReq)
+		if err != nil {
+			return err
+		}
+		defer closeServers()
+	}
+
 	// Record chained response metric (spec 046).
 	if req.PreviousResponseID != "" {
 		observability.ResponsesChainedTotal.WithLabelValues(req.Model).Inc()
@@ -129,11 +161,40 @@ func (e *Engine) CreateResponse(ctx context.Context, req *api.CreateResponseRequ
 		provReq.Messages = append(historyMsgs, provReq.Messages...)
 	}
 
+	// If conversation_id is set, load the conversation's items as history
+	// and record this turn in the conversation once the response completes.
+	if req.ConversationID != "" {
+		historyMsgs, err := e.loadConversationMessages(ctx, req.ConversationID)
+		if err != nil {
+			return err
+		}
+		provReq.Messages = append(historyMsgs, provReq.Messages...)
+
+		w = &conversationWriter{
+			ResponseWriter: w,
+			ctx:            ctx,
+			store:          e.cfg.Conversations,
+			req:            req,
+		}
+	}
+
+	// Execute or reject the tool calls the user answered with
+	// mcp_approval_response items.
+	if !req.Background {
+		ctx, err = e.resolveApprovals(ctx, req, provReq)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Keep code interpreter calls of the response chain in one container.
+	ctx = bindContainer(ctx, req, provReq.Messages)
+
 	// Determine if the agentic loop should be used:
 	// - Executors are registered
 	// - Tools are present in the request
 	// - tool_choice is not "none"
-	useLoop := e.hasExecutors() && len(req.Tools) > 0 &&
+	useLoop := e.hasExecutors(ctx) && len(req.Tools) > 0 &&
 		!(req.ToolChoice != nil && req.ToolChoice.String == "none")
 
 	// Log request handling mode.
@@ -162,6 +223,11 @@ func (e *Engine) CreateResponse(ctx context.Context, req *api.CreateResponseRequ
 		return e.handleBackground(ctx, req, w)
 	}
 
+	// Trim the oldest turns to fit the context window (truncation "auto").
+	if !e.truncateMessages(req, provReq) {
+		return e.writeContextLengthExceeded(ctx, req, w)
+	}
+
 	if req.Stream {
 		if useLoop {
 			return e.runAgenticLoopStreaming(ctx, req, provReq, w)
@@ -189,8 +255,9 @@ func (e *Engine) handleBackground(ctx context.Context, req *api.CreateResponseRe
 		return fmt.Errorf("saving background response: %w", err)
 	}
 
-	// Serialize and save the original request for worker reconstruction.
-	reqData, err := json.Marshal(req)
+	// Serialize and save the original request and its caller for worker
+	// reconstruction.
+	reqData, err := json.Marshal(queuedRequest{Request: req, Caller: queuedCallerFrom(ctx)})
 	if err != nil {
 		return fmt.Errorf("serializing background request: %w", err)
 	}
@@ -264,11 +331,8 @@ func (e *Engine) handleNonStreaming(ctx context.Context, req *api.CreateResponse
 		resp.IncompleteDetails = &api.IncompleteDetails{Reason: "max_output_tokens"}
 	}
 
-	// Apply include filtering before writing to client.
-	applyIncludeFilter(resp, req.Include)
-
-	// Write the response to the client first.
-	if err := w.WriteResponse(ctx, resp); err != nil {
+	// Write the response to the client first, with include filtering.
+	if err := w.WriteResponse(ctx, applyIncludeFilter(resp, req.Include)); err != nil {
 		return err
 	}
 
@@ -276,8 +340,7 @@ func (e *Engine) handleNonStreaming(ctx context.Context, req *api.CreateResponse
 	observability.ResponsesTotal.WithLabelValues(req.Model, string(resp.Status), "sync").Inc()
 	observability.ResponsesDuration.WithLabelValues(req.Model, "sync").Observe(time.Since(responseStart).Seconds())
 	if resp.Usage != nil {
-		observability.ResponsesTokensTotal.WithLabelValues(req.Model, "input").Add(float64(resp.Usage.InputTokens))
-		observability.ResponsesTokensTotal.WithLabelValues(req.Model, "output").Add(float64(resp.Usage.OutputTokens))
+		e.recordTokenUsage(ctx, req.Model, resp.Usage.InputTokens, resp.Usage.OutputTokens)
 	}
 
 	// Save the response to the store (after client write).
@@ -405,8 +468,7 @@ func (e *Engine) handleStreaming(ctx context.Context, req *api.CreateResponseReq
 			observability.ResponsesTotal.WithLabelValues(req.Model, string(finalStatus), "streaming").Inc()
 			observability.ResponsesDuration.WithLabelValues(req.Model, "streaming").Observe(time.Since(responseStart).Seconds())
 			if ev.Usage != nil {
-				observability.ResponsesTokensTotal.WithLabelValues(req.Model, "input").Add(float64(ev.Usage.InputTokens))
-				observability.ResponsesTokensTotal.WithLabelValues(req.Model, "output").Add(float64(ev.Usage.OutputTokens))
+				e.recordTokenUsage(ctx, req.Model, ev.Usage.InputTokens, ev.Usage.OutputTokens)
 			}
 
 			// Strip usage from the response event if the client didn't opt in.
@@ -660,8 +722,9 @@ func (e *Engine) mergeMCPTools(ctx context.Context, req *api.CreateResponseReque
 
 // resolveProfile handles agent profile and prompt parameter resolution.
 // If agent or prompt is set, the profile is resolved and merged into the request.
+// Profile instructions referencing an MCP prompt are rendered by the server.
 // Returns the profile's vector store IDs for context injection (nil if no profile).
-func (e *Engine) resolveProfile(req *api.CreateResponseRequest) ([]string, error) {
+func (e *Engine) resolveProfile(ctx context.Context, req *api.CreateResponseRequest) ([]string, error) {
 	// Determine profile name and variables from agent or prompt field.
 	var profileName string
 	var variables map[string]string
@@ -676,6 +739,11 @@ func (e *Engine) resolveProfile(req *api.CreateResponseRequest) ([]string, error
 		if variables == nil {
 			variables = req.Variables
 		}
+	} else if name, ok := agent.ProfileFromModel(req.Model); ok && e.cfg.ProfileResolver != nil {
+		// Virtual agent model ("agent/<name>"): the profile supplies the model.
+		profileName = name
+		variables = req.Variables
+		req.Model = ""
 	}
 
 	if profileName == "" {
@@ -691,18 +759,43 @@ func (e *Engine) resolveProfile(req *api.CreateResponseRequest) ([]string, error
 		return nil, api.NewNotFoundError(fmt.Sprintf("agent profile %q not found", profileName))
 	}
 
+	if req.Instructions == "" && strings.HasPrefix(profile.Instructions, mcptools.PromptScheme) {
+		instructions, err := e.renderPrompt(ctx, profile.Instructions, variables)
+		if err != nil {
+			return nil, err
+		}
+		rendered := *profile
+		rendered.Instructions = instructions
+		profile = &rendered
+	}
+
 	vectorStoreIDs := agent.MergeProfileIntoRequest(profile, req, variables)
 	return vectorStoreIDs, nil
 }
 
-// hasExecutors returns true if any tool executors are registered.
-func (e *Engine) hasExecutors() bool {
-	return len(e.executors) > 0
+// recordTokenUsage records the token usage of a finished response in
+// metrics and charges it to the usage recorder, if configured.
+func (e *Engine) recordTokenUsage(ctx context.Context, model string, inputTokens, outputTokens int) {
+	observability.ResponsesTokensTotal.WithLabelValues(model, "input").Add(float64(inputTokens))
+	observability.ResponsesTokensTotal.WithLabelValues(model, "output").Add(float64(outputTokens))
+	if e.cfg.UsageRecorder != nil {
+		e.cfg.UsageRecorder.RecordUsage(ctx, inputTokens, outputTokens)
+	}
+}
+
+// hasExecutors returns true if any tool executors are registered or the
+// request declared MCP servers.
+func (e *Engine) hasExecutors(ctx context.Context) bool {
+	return len(e.executors) > 0 || requestServersFromContext(ctx) != nil
 }
 
 // findExecutor returns the first executor that can handle the given tool name.
-// Returns nil if no executor matches.
-func (e *Engine) findExecutor(toolName string) tools.ToolExecutor {
+// MCP servers declared by the request take precedence over registered
+// executors. Returns nil if no executor matches.
+func (e *Engine) findExecutor(ctx context.Context, toolName string) tools.ToolExecutor {
+	if rs := requestServersFromContext(ctx); rs != nil && rs.CanExecute(toolName) {
+		return rs
+	}
 	for _, exec := range e.executors {
 		if exec.CanExecute(toolName) {
 			return exec
@@ -799,6 +892,7 @@ func buildResponseFromRequest(req *api.CreateResponseRequest, status api.Respons
 		Output:             []api.Item{},
 		Model:              req.Model,
 		PreviousResponseID: stringPtr(req.PreviousResponseID),
+		ConversationID:     stringPtr(req.ConversationID),
 		CreatedAt:          time.Now().Unix(),
 		Tools:              ensureTools(req.Tools),
 		ToolChoice:         toolChoiceValue(req.ToolChoice),
@@ -846,12 +940,14 @@ func getMetadata(req *api.CreateResponseRequest) map[string]any {
 	return make(map[string]any)
 }
 
-// applyIncludeFilter removes optional response sections not listed in the
-// include whitelist. When include is nil or empty, all sections are returned
-// (backward compatible). When include is set, only the listed sections are kept.
-func applyIncludeFilter(resp *api.Response, include []string) {
+// applyIncludeFilter returns a copy of resp without the optional sections
+// not listed in the include whitelist. When include is nil or empty, resp is
+// returned unchanged (backward compatible). The stored response is not
+// filtered, so that it keeps the search results needed to replay the
+// conversation.
+func applyIncludeFilter(resp *api.Response, include []string) *api.Response {
 	if len(include) == 0 {
-		return // No filtering, return everything.
+		return resp // No filtering, return everything.
 	}
 
 	// Build a set of included sections.
@@ -861,12 +957,29 @@ func applyIncludeFilter(resp *api.Response, include []string) {
 	}
 
 	// Nil-out sections not in the include list.
+	filtered := *resp
 	if !included["usage"] {
-		resp.Usage = nil
+		filtered.Usage = nil
 	}
 	if !included["reasoning"] {
-		resp.Reasoning = nil
+		filtered.Reasoning = nil
+	}
+
+	filtered.Output = make([]api.Item, len(resp.Output))
+	for i, item := range resp.Output {
+		if item.FileSearchCall != nil && !included["file_search_call.results"] {
+			fs := *item.FileSearchCall
+			fs.Results = nil
+			item.FileSearchCall = &fs
+		}
+		if item.WebSearchCall != nil && !included["web_search_call.action.sources"] {
+			ws := *item.WebSearchCall
+			ws.Action.Sources = nil
+			item.WebSearchCall = &ws
+		}
+		filtered.Output[i] = item
 	}
+	return &filtered
 }
 
 // shouldIncludeStreamUsage returns true if the request opts in to usage
@@ -884,10 +997,22 @@ func derefInt(p *int) int {
 }
 
 // ensureTools returns the tools slice, defaulting to an empty slice (not nil)
-// so it serializes as [] instead of null.
+// so it serializes as [] instead of null. Headers of MCP tools usually carry
+// credentials and are not echoed.
 func ensureTools(tools []api.ToolDefinition) []api.ToolDefinition {
 	if tools == nil {
 		return []api.ToolDefinition{}
 	}
+	for i, t := range tools {
+		if t.Headers == nil {
+			continue
+		}
+		redacted := make([]api.ToolDefinition, len(tools))
+		copy(redacted, tools)
+		for j := i; j < len(redacted); j++ {
+			redacted[j].Headers = nil
+		}
+		return redacted
+	}
 	return tools
 }
@@ -1,6 +1,8 @@
 package engine
 
 import (
+	"sync"
+
 	"github.com/rhuss/antwort/pkg/api"
 	"github.com/rhuss/antwort/pkg/provider"
 )
@@ -9,6 +11,9 @@ import (
 // It holds the running sequence number and output item tracking needed
 // to generate the correct OpenResponses event sequence.
 type streamState struct {
+	// seqMu guards seq; tools report progress concurrently.
+	seqMu sync.Mutex
+
 	seq         int    // Next sequence number (monotonically increasing from 0).
 	itemID      string // Current output item ID (for text message).
 	outputIndex int    // Current output index (position in response output array).
@@ -32,8 +37,22 @@ type toolCallItemState struct {
 	started     bool // Whether the first delta has been emitted.
 }
 
+// toolCallIndex returns the output index of the streamed function_call item
+// with the given ID, or the next free index if there is none.
+func (s *streamState) toolCallIndex(itemID string) int {
+	for _, tc := range s.toolCallItems {
+		if tc.itemID == itemID {
+			return tc.outputIndex
+		}
+	}
+	s.outputIndex++
+	return s.outputIndex
+}
+
 // nextSeq returns the current sequence number and increments it.
 func (s *streamState) nextSeq() int {
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
 	n := s.seq
 	s.seq++
 	return n
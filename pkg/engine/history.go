@@ -57,22 +57,31 @@ func loadConversationHistory(ctx context.Context, store transport.ResponseStore,
 	for _, resp := range chain {
 		// Convert input items to messages.
 		for _, item := range resp.Input {
-			if msg := itemToMessage(item); msg != nil {
-				messages = append(messages, *msg)
-			}
+			messages = append(messages, itemToMessages(item)...)
 		}
 
 		// Convert output items to messages.
 		for _, item := range resp.Output {
-			if msg := itemToMessage(item); msg != nil {
-				messages = append(messages, *msg)
-			}
+			messages = append(messages, itemToMessages(item)...)
 		}
 	}
 
 	return messages, nil
 }
 
+// itemToMessages converts an Item to the ProviderMessages it stands for in
+// the conversation history. Calls to server-side tools are reported as one
+// item that becomes the assistant's tool call and the tool's result.
+func itemToMessages(item api.Item) []provider.ProviderMessage {
+	if msgs := toolCallItemMessages(item); msgs != nil {
+		return msgs
+	}
+	if msg := itemToMessage(item); msg != nil {
+		return []provider.ProviderMessage{*msg}
+	}
+	return nil
+}
+
 // itemToMessage converts an Item to a ProviderMessage for conversation
 // history reconstruction. Returns nil for items that should be skipped
 // (e.g., reasoning items).
@@ -129,6 +138,16 @@ func itemToMessage(item api.Item) *provider.ProviderMessage {
 		// Reasoning items are not sent to the backend.
 		return nil
 
+	case api.ItemTypeMCPCall, api.ItemTypeWebSearchCall, api.ItemTypeFileSearchCall:
+		// Use itemToMessages, which replays these as a call and its result.
+		return nil
+
+	case api.ItemTypeMCPListTools, api.ItemTypeMCPApprovalRequest, api.ItemTypeMCPApprovalResponse:
+		// MCP bookkeeping items are not sent to the backend. A call held
+		// for approval reaches the history as the function_call item of
+		// the response that resolved it.
+		return nil
+
 	default:
 		return nil
 	}
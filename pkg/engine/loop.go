@@ -27,7 +27,7 @@ func (e *Engine) runAgenticLoop(ctx context.Context, req *api.CreateResponseRequ
 	}
 	parallel := getParallelToolCalls(req)
 
-	var allOutputItems []api.Item
+	allOutputItems := leadingItems(ctx)
 	var cumulativeUsage api.Usage
 	var allToolResults []tools.ToolResult // Track results for annotation generation.
 
@@ -36,7 +36,11 @@ func (e *Engine) runAgenticLoop(ctx context.Context, req *api.CreateResponseRequ
 	// Record conversation depth (spec 046): count items in rehydrated history.
 	observability.EngineConversationDepth.WithLabelValues(req.Model).Observe(float64(len(provReq.Messages)))
 
+	turns := &turnSpans{parent: ctx, model: req.Model}
+	defer turns.end()
+
 	for turn := 0; turn < maxTurns; turn++ {
+		ctx := turns.next(turn)
 		turnStart := time.Now()
 		observability.EngineIterationsTotal.WithLabelValues(req.Model).Inc()
 		debug.Log("engine", "agentic loop turn", "turn", turn+1, "max_turns", maxTurns)
@@ -47,6 +51,13 @@ func (e *Engine) runAgenticLoop(ctx context.Context, req *api.CreateResponseRequ
 			return e.buildAndWriteResponse(ctx, req, allOutputItems, &cumulativeUsage, api.ResponseStatusCancelled, nil, w)
 		}
 
+		// Tool outputs from earlier turns grow the request; re-apply
+		// truncation "auto". If it still does not fit, the backend reports
+		// the context-length error.
+		if turn > 0 {
+			e.truncateMessages(req, provReq)
+		}
+
 		// Call the provider.
 		startTime := time.Now()
 		provResp, err := e.provider.Complete(ctx, provReq)
@@ -95,8 +106,7 @@ func (e *Engine) runAgenticLoop(ctx context.Context, req *api.CreateResponseRequ
 			mode := responseMode(req)
 			observability.ResponsesTotal.WithLabelValues(req.Model, string(provResp.Status), mode).Inc()
 			observability.ResponsesDuration.WithLabelValues(req.Model, mode).Observe(time.Since(responseStart).Seconds())
-			observability.ResponsesTokensTotal.WithLabelValues(req.Model, "input").Add(float64(cumulativeUsage.InputTokens))
-			observability.ResponsesTokensTotal.WithLabelValues(req.Model, "output").Add(float64(cumulativeUsage.OutputTokens))
+			e.recordTokenUsage(ctx, req.Model, cumulativeUsage.InputTokens, cumulativeUsage.OutputTokens)
 			return e.buildAndWriteResponse(ctx, req, allOutputItems, &cumulativeUsage, provResp.Status, nil, w, allToolResults)
 		}
 
@@ -115,10 +125,14 @@ func (e *Engine) runAgenticLoop(ctx context.Context, req *api.CreateResponseRequ
 		}
 
 		// Check if any tool calls require client action (no matching executor).
-		if e.hasUnhandledToolCalls(toolCalls) {
+		if e.hasUnhandledToolCalls(ctx, toolCalls) {
 			return e.buildAndWriteResponse(ctx, req, allOutputItems, &cumulativeUsage, api.ResponseStatusRequiresAction, nil, w)
 		}
 
+		// Hold the calls that need the user's approval; the others run now.
+		toolCalls, approvals := e.requestApprovals(ctx, toolCalls)
+		allOutputItems = replaceWithApprovalRequests(allOutputItems, approvals)
+
 		// Filter by allowed_tools.
 		filterResult := tools.FilterAllowedTools(toolCalls, req.AllowedTools)
 
@@ -149,9 +163,15 @@ func (e *Engine) runAgenticLoop(ctx context.Context, req *api.CreateResponseRequ
 		// Track tool results for annotation generation.
 		allToolResults = append(allToolResults, allResults...)
 
-		// Convert results to function_call_output items and add to output.
+		// Report calls to server-side tools as typed items in place of their
+		// function_call items; the other results become function_call_output
+		// items.
 		var resultItems []api.Item
 		for _, r := range allResults {
+			if pos, item, ok := e.typedCallItem(ctx, allOutputItems, r.CallID, &r); ok {
+				allOutputItems[pos] = item
+				continue
+			}
 			item := api.Item{
 				ID:     api.NewItemID(),
 				Type:   api.ItemTypeFunctionCallOutput,
@@ -168,6 +188,12 @@ func (e *Engine) runAgenticLoop(ctx context.Context, req *api.CreateResponseRequ
 		// Record iteration duration (spec 046).
 		observability.EngineIterationDuration.WithLabelValues(req.Model).Observe(time.Since(turnStart).Seconds())
 
+		// Held calls end the response until the user answers them.
+		if len(approvals) > 0 {
+			debug.Log("engine", "tool calls await approval", "turn", turn+1, "count", len(approvals))
+			return e.buildAndWriteResponse(ctx, req, allOutputItems, &cumulativeUsage, api.ResponseStatusRequiresAction, nil, w, allToolResults)
+		}
+
 		// Build messages for next turn: first the assistant's tool call message,
 		// then the tool results. The assistant message with tool_calls must
 		// precede the tool role messages per Chat Completions convention.
@@ -179,6 +205,7 @@ func (e *Engine) runAgenticLoop(ctx context.Context, req *api.CreateResponseRequ
 				ToolCallID: r.CallID,
 			})
 		}
+		provReq.Messages = e.appendToolImages(provReq.Messages, req.Model, allResults)
 	}
 
 	// Max turns reached: record max-iterations-hit (spec 046).
@@ -187,8 +214,7 @@ func (e *Engine) runAgenticLoop(ctx context.Context, req *api.CreateResponseRequ
 	mode := responseMode(req)
 	observability.ResponsesTotal.WithLabelValues(req.Model, string(api.ResponseStatusIncomplete), mode).Inc()
 	observability.ResponsesDuration.WithLabelValues(req.Model, mode).Observe(time.Since(responseStart).Seconds())
-	observability.ResponsesTokensTotal.WithLabelValues(req.Model, "input").Add(float64(cumulativeUsage.InputTokens))
-	observability.ResponsesTokensTotal.WithLabelValues(req.Model, "output").Add(float64(cumulativeUsage.OutputTokens))
+	e.recordTokenUsage(ctx, req.Model, cumulativeUsage.InputTokens, cumulativeUsage.OutputTokens)
 	return e.buildAndWriteResponse(ctx, req, allOutputItems, &cumulativeUsage, api.ResponseStatusIncomplete, nil, w, allToolResults)
 }
 
@@ -228,7 +254,30 @@ func (e *Engine) runAgenticLoopStreaming(ctx context.Context, req *api.CreateRes
 		return err
 	}
 
+	// Emit the items produced before the first turn (MCP tool listings,
+	// approved tool calls).
+	for i, item := range leadingItems(ctx) {
+		allOutputItems = append(allOutputItems, item)
+		state.outputIndex = i
+		if err := w.WriteEvent(ctx, api.StreamEvent{
+			Type: api.EventOutputItemAdded, SequenceNumber: state.nextSeq(),
+			Item: &item, OutputIndex: i,
+		}); err != nil {
+			return err
+		}
+		if err := w.WriteEvent(ctx, api.StreamEvent{
+			Type: api.EventOutputItemDone, SequenceNumber: state.nextSeq(),
+			Item: &item, OutputIndex: i,
+		}); err != nil {
+			return err
+		}
+	}
+
+	turns := &turnSpans{parent: ctx, model: req.Model}
+	defer turns.end()
+
 	for turn := 0; turn < maxTurns; turn++ {
+		ctx := turns.next(turn)
 		observability.EngineIterationsTotal.WithLabelValues(req.Model).Inc()
 		turnStart := time.Now()
 
@@ -236,6 +285,11 @@ func (e *Engine) runAgenticLoopStreaming(ctx context.Context, req *api.CreateRes
 			return e.emitCancelled(ctx, resp, state, w)
 		}
 
+		// Re-apply truncation "auto" as tool outputs accumulate.
+		if turn > 0 {
+			e.truncateMessages(req, provReq)
+		}
+
 		// Start provider stream for this turn.
 		turnStreamStart := time.Now()
 		eventCh, err := e.provider.Stream(ctx, provReq)
@@ -295,16 +349,19 @@ func (e *Engine) runAgenticLoopStreaming(ctx context.Context, req *api.CreateRes
 			// Record response metrics (spec 046).
 			observability.ResponsesTotal.WithLabelValues(req.Model, string(api.ResponseStatusCompleted), "streaming").Inc()
 			observability.ResponsesDuration.WithLabelValues(req.Model, "streaming").Observe(time.Since(responseStart).Seconds())
-			observability.ResponsesTokensTotal.WithLabelValues(req.Model, "input").Add(float64(cumulativeUsage.InputTokens))
-			observability.ResponsesTokensTotal.WithLabelValues(req.Model, "output").Add(float64(cumulativeUsage.OutputTokens))
+			e.recordTokenUsage(ctx, req.Model, cumulativeUsage.InputTokens, cumulativeUsage.OutputTokens)
 
 			resp.Output = allOutputItems
 			resp.Usage = streamUsage(&cumulativeUsage, req)
 			resp.Status = api.ResponseStatusCompleted
-			return w.WriteEvent(ctx, api.StreamEvent{
+			if err := w.WriteEvent(ctx, api.StreamEvent{
 				Type: api.EventResponseCompleted, SequenceNumber: state.nextSeq(),
 				Response: resp,
-			})
+			}); err != nil {
+				return err
+			}
+			e.saveIfStateful(ctx, req, resp)
+			return nil
 		}
 
 		// Check tool_choice "none".
@@ -319,7 +376,7 @@ func (e *Engine) runAgenticLoopStreaming(ctx context.Context, req *api.CreateRes
 		}
 
 		// Check for unhandled tool calls (requires_action).
-		if e.hasUnhandledToolCalls(toolCalls) {
+		if e.hasUnhandledToolCalls(ctx, toolCalls) {
 			resp.Output = allOutputItems
 			resp.Usage = streamUsage(&cumulativeUsage, req)
 			resp.Status = api.ResponseStatusRequiresAction
@@ -329,9 +386,41 @@ func (e *Engine) runAgenticLoopStreaming(ctx context.Context, req *api.CreateRes
 			})
 		}
 
-		// Filter and execute tools (with lifecycle events in streaming mode).
+		// Hold the calls that need the user's approval; the others run now.
+		// The approval requests take the output index of the held calls'
+		// streamed function_call items.
+		toolCalls, approvals := e.requestApprovals(ctx, toolCalls)
+		approvalIndex := make([]int, len(approvals))
+		for i, p := range approvals {
+			var callItemID string
+			if pos := callItemIndex(allOutputItems, p.callID); pos >= 0 {
+				callItemID = allOutputItems[pos].ID
+			}
+			approvalIndex[i] = state.toolCallIndex(callItemID)
+		}
+		allOutputItems = replaceWithApprovalRequests(allOutputItems, approvals)
+
+		// Filter tools. Calls to server-side tools are reported as typed
+		// items that take the place of their function_call items.
 		filterResult := tools.FilterAllowedTools(toolCalls, req.AllowedTools)
-		results := e.executeToolsWithEvents(ctx, filterResult.Allowed, parallel, w, state)
+		typed := make(map[string]itemRef)
+		for _, tc := range filterResult.Allowed {
+			_, item, ok := e.typedCallItem(ctx, allOutputItems, tc.ID, nil)
+			if !ok {
+				continue
+			}
+			ref := itemRef{id: item.ID, index: state.toolCallIndex(item.ID)}
+			typed[tc.ID] = ref
+			if err := w.WriteEvent(ctx, api.StreamEvent{
+				Type: api.EventOutputItemAdded, SequenceNumber: state.nextSeq(),
+				Item: &item, OutputIndex: ref.index,
+			}); err != nil {
+				return err
+			}
+		}
+
+		// Execute tools with lifecycle events.
+		results := e.executeToolsWithEvents(ctx, filterResult.Allowed, parallel, w, state, typed)
 		allResults := append(results, filterResult.Rejected...)
 
 		// Track tool results for annotation generation.
@@ -340,8 +429,36 @@ func (e *Engine) runAgenticLoopStreaming(ctx context.Context, req *api.CreateRes
 		// Append the assistant's tool call message before results.
 		provReq.Messages = append(provReq.Messages, buildAssistantToolCallMessage(toolCalls))
 
+		provReq.Messages = e.appendToolImages(provReq.Messages, req.Model, allResults)
+
 		// Emit tool result items as events.
 		for _, r := range allResults {
+			// Append tool result to conversation for next turn.
+			provReq.Messages = append(provReq.Messages, provider.ProviderMessage{
+				Role: "tool", Content: r.Output, ToolCallID: r.CallID,
+			})
+
+			if pos, item, ok := e.typedCallItem(ctx, allOutputItems, r.CallID, &r); ok {
+				allOutputItems[pos] = item
+				ref, started := typed[r.CallID]
+				if !started {
+					ref = itemRef{id: item.ID, index: state.toolCallIndex(item.ID)}
+					if err := w.WriteEvent(ctx, api.StreamEvent{
+						Type: api.EventOutputItemAdded, SequenceNumber: state.nextSeq(),
+						Item: &item, OutputIndex: ref.index,
+					}); err != nil {
+						return err
+					}
+				}
+				if err := w.WriteEvent(ctx, api.StreamEvent{
+					Type: api.EventOutputItemDone, SequenceNumber: state.nextSeq(),
+					Item: &item, OutputIndex: ref.index,
+				}); err != nil {
+					return err
+				}
+				continue
+			}
+
 			item := api.Item{
 				ID:     api.NewItemID(),
 				Type:   api.ItemTypeFunctionCallOutput,
@@ -366,16 +483,40 @@ func (e *Engine) runAgenticLoopStreaming(ctx context.Context, req *api.CreateRes
 			}); err != nil {
 				return err
 			}
-
-			// Append tool result to conversation for next turn.
-			provReq.Messages = append(provReq.Messages, provider.ProviderMessage{
-				Role: "tool", Content: r.Output, ToolCallID: r.CallID,
-			})
 		}
 
 		// Record iteration duration (spec 046).
 		observability.EngineIterationDuration.WithLabelValues(req.Model).Observe(time.Since(turnStart).Seconds())
 
+		// Held calls end the response until the user answers them.
+		if len(approvals) > 0 {
+			for i, p := range approvals {
+				if err := w.WriteEvent(ctx, api.StreamEvent{
+					Type: api.EventOutputItemAdded, SequenceNumber: state.nextSeq(),
+					Item: &p.item, OutputIndex: approvalIndex[i],
+				}); err != nil {
+					return err
+				}
+				if err := w.WriteEvent(ctx, api.StreamEvent{
+					Type: api.EventOutputItemDone, SequenceNumber: state.nextSeq(),
+					Item: &p.item, OutputIndex: approvalIndex[i],
+				}); err != nil {
+					return err
+				}
+			}
+			resp.Output = allOutputItems
+			resp.Usage = streamUsage(&cumulativeUsage, req)
+			resp.Status = api.ResponseStatusRequiresAction
+			if err := w.WriteEvent(ctx, api.StreamEvent{
+				Type: api.EventResponseRequiresAction, SequenceNumber: state.nextSeq(),
+				Response: resp,
+			}); err != nil {
+				return err
+			}
+			e.saveIfStateful(ctx, req, resp)
+			return nil
+		}
+
 		// Reset stream state for next turn (keep sequence numbers).
 		state.textStarted = false
 		state.toolCallItems = nil
@@ -387,8 +528,7 @@ func (e *Engine) runAgenticLoopStreaming(ctx context.Context, req *api.CreateRes
 	// Record response metrics (spec 046).
 	observability.ResponsesTotal.WithLabelValues(req.Model, string(api.ResponseStatusIncomplete), "streaming").Inc()
 	observability.ResponsesDuration.WithLabelValues(req.Model, "streaming").Observe(time.Since(responseStart).Seconds())
-	observability.ResponsesTokensTotal.WithLabelValues(req.Model, "input").Add(float64(cumulativeUsage.InputTokens))
-	observability.ResponsesTokensTotal.WithLabelValues(req.Model, "output").Add(float64(cumulativeUsage.OutputTokens))
+	e.recordTokenUsage(ctx, req.Model, cumulativeUsage.InputTokens, cumulativeUsage.OutputTokens)
 
 	resp.Output = allOutputItems
 	resp.Usage = streamUsage(&cumulativeUsage, req)
@@ -469,18 +609,19 @@ func (e *Engine) consumeStreamTurn(ctx context.Context, eventCh <-chan provider.
 				if e.cfg.Annotator != nil && len(toolResults) > 0 {
 					sources := ExtractSourceContexts(toolResults)
 					annotations = e.cfg.Annotator.Generate(accumulatedText, sources)
-					for i, ann := range annotations {
-						if err := w.WriteEvent(ctx, api.StreamEvent{
-							Type:            api.EventAnnotationAdded,
-							SequenceNumber:  state.nextSeq(),
-							ItemID:          outputItem.ID,
-							OutputIndex:     state.outputIndex,
-							ContentIndex:    0,
-							AnnotationIndex: i,
-							Annotation:      &ann,
-						}); err != nil {
-							return nil, nil, err
-						}
+				}
+				annotations = append(annotations, fileCitations(accumulatedText, toolResults)...)
+				for i, ann := range annotations {
+					if err := w.WriteEvent(ctx, api.StreamEvent{
+						Type:            api.EventAnnotationAdded,
+						SequenceNumber:  state.nextSeq(),
+						ItemID:          outputItem.ID,
+						OutputIndex:     state.outputIndex,
+						ContentIndex:    0,
+						AnnotationIndex: i,
+						Annotation:      &ann,
+					}); err != nil {
+						return nil, nil, err
 					}
 				}
 
@@ -570,9 +711,9 @@ func extractToolCalls(items []api.Item) []tools.ToolCall {
 
 // hasUnhandledToolCalls returns true if any tool call cannot be handled
 // by a registered executor.
-func (e *Engine) hasUnhandledToolCalls(calls []tools.ToolCall) bool {
+func (e *Engine) hasUnhandledToolCalls(ctx context.Context, calls []tools.ToolCall) bool {
 	for _, call := range calls {
-		if e.findExecutor(call.Name) == nil {
+		if e.findExecutor(ctx, call.Name) == nil {
 			return true
 		}
 	}
@@ -594,7 +735,7 @@ func (e *Engine) executeToolsConcurrently(ctx context.Context, calls []tools.Too
 		go func(idx int, tc tools.ToolCall) {
 			defer wg.Done()
 
-			exec := e.findExecutor(tc.Name)
+			exec := e.findExecutor(ctx, tc.Name)
 			if exec == nil {
 				results[idx] = tools.ToolResult{
 					CallID:  tc.ID,
@@ -607,7 +748,7 @@ func (e *Engine) executeToolsConcurrently(ctx context.Context, calls []tools.Too
 			}
 
 			toolStart := time.Now()
-			result, err := exec.Execute(ctx, tc)
+			result, err := e.executeTool(ctx, exec, tc)
 			observability.EngineToolDuration.WithLabelValues(tc.Name).Observe(time.Since(toolStart).Seconds())
 
 			if err != nil {
@@ -664,7 +805,7 @@ func (e *Engine) executeToolsSequentially(ctx context.Context, calls []tools.Too
 			continue
 		}
 
-		exec := e.findExecutor(tc.Name)
+		exec := e.findExecutor(ctx, tc.Name)
 		if exec == nil {
 			results[i] = tools.ToolResult{
 				CallID:  tc.ID,
@@ -677,7 +818,7 @@ func (e *Engine) executeToolsSequentially(ctx context.Context, calls []tools.Too
 		}
 
 		toolStart := time.Now()
-		result, err := exec.Execute(ctx, tc)
+		result, err := e.executeTool(ctx, exec, tc)
 		observability.EngineToolDuration.WithLabelValues(tc.Name).Observe(time.Since(toolStart).Seconds())
 
 		if err != nil {
@@ -769,7 +910,7 @@ func toolLifecycleEvents(toolType string) (inProgress, searching, completed, fai
 // executeToolsWithEvents wraps tool execution with lifecycle SSE events.
 // Used only in streaming mode. Emits in_progress before execution,
 // searching for search tools, and completed/failed after execution.
-func (e *Engine) executeToolsWithEvents(ctx context.Context, calls []tools.ToolCall, parallel bool, w transport.ResponseWriter, state *streamState) []tools.ToolResult {
+func (e *Engine) executeToolsWithEvents(ctx context.Context, calls []tools.ToolCall, parallel bool, w transport.ResponseWriter, state *streamState, typed map[string]itemRef) []tools.ToolResult {
 	if len(calls) == 0 {
 		return nil
 	}
@@ -777,30 +918,83 @@ func (e *Engine) executeToolsWithEvents(ctx context.Context, calls []tools.ToolC
 	results := make([]tools.ToolResult, len(calls))
 
 	execOne := func(idx int, tc tools.ToolCall) {
-		exec := e.findExecutor(tc.Name)
+		exec := e.findExecutor(ctx, tc.Name)
 		toolType := classifyToolType(tc.Name, exec)
 		inProgress, searching, completed, failed := toolLifecycleEvents(toolType)
 
+		// Lifecycle events refer to the call's typed item if it has one.
+		ref, ok := typed[tc.ID]
+		if !ok {
+			ref = itemRef{id: tc.ID, index: state.outputIndex + idx + 1}
+		}
+
 		// Emit in_progress event.
 		if inProgress != "" {
 			_ = w.WriteEvent(ctx, api.StreamEvent{
 				Type:           inProgress,
 				SequenceNumber: state.nextSeq(),
-				ItemID:         tc.ID,
-				OutputIndex:    state.outputIndex + idx + 1,
+				ItemID:         ref.id,
+				OutputIndex:    ref.index,
 			})
 
+			// Announce the code the code interpreter is about to run.
+			if toolType == "code_interpreter" {
+				if code := interpreterCode(tc.Arguments); code != "" {
+					_ = w.WriteEvent(ctx, api.StreamEvent{
+						Type:           api.EventCodeInterpreterCodeDelta,
+						SequenceNumber: state.nextSeq(),
+						ItemID:         ref.id,
+						OutputIndex:    ref.index,
+						Delta:          code,
+					})
+					_ = w.WriteEvent(ctx, api.StreamEvent{
+						Type:           api.EventCodeInterpreterCodeDone,
+						SequenceNumber: state.nextSeq(),
+						ItemID:         ref.id,
+						OutputIndex:    ref.index,
+						Delta:          code,
+					})
+				}
+			}
+
 			// Emit searching for search tools.
 			if searching != "" {
 				_ = w.WriteEvent(ctx, api.StreamEvent{
 					Type:           searching,
 					SequenceNumber: state.nextSeq(),
-					ItemID:         tc.ID,
-					OutputIndex:    state.outputIndex + idx + 1,
+					ItemID:         ref.id,
+					OutputIndex:    ref.index,
 				})
 			}
 		}
 
+		// Forward progress notifications of MCP tools and the output of
+		// code interpreter code.
+		callCtx := ctx
+		switch toolType {
+		case "code_interpreter":
+			callCtx = tools.SetOutputReporter(ctx, func(o tools.Output) {
+				_ = w.WriteEvent(ctx, api.StreamEvent{
+					Type:           api.EventCodeInterpreterLogsDelta,
+					SequenceNumber: state.nextSeq(),
+					ItemID:         ref.id,
+					OutputIndex:    ref.index,
+					Delta:          o.Data,
+					OutputStream:   o.Stream,
+				})
+			})
+		case "mcp":
+			callCtx = tools.SetProgressReporter(ctx, func(p tools.Progress) {
+				_ = w.WriteEvent(ctx, api.StreamEvent{
+					Type:           api.EventMCPCallProgress,
+					SequenceNumber: state.nextSeq(),
+					ItemID:         ref.id,
+					OutputIndex:    ref.index,
+					Progress:       &api.ToolProgress{Progress: p.Progress, Total: p.Total, Message: p.Message},
+				})
+			})
+		}
+
 		// Execute the tool.
 		if exec == nil {
 			results[idx] = tools.ToolResult{
@@ -814,14 +1008,14 @@ func (e *Engine) executeToolsWithEvents(ctx context.Context, calls []tools.ToolC
 				_ = w.WriteEvent(ctx, api.StreamEvent{
 					Type:           failed,
 					SequenceNumber: state.nextSeq(),
-					ItemID:         tc.ID,
-					OutputIndex:    state.outputIndex + idx + 1,
+					ItemID:         ref.id,
+					OutputIndex:    ref.index,
 				})
 			}
 			return
 		}
 
-		result, err := exec.Execute(ctx, tc)
+		result, err := e.executeTool(callCtx, exec, tc)
 		if err != nil {
 			slog.Warn("tool execution error",
 				"tool", tc.Name,
@@ -839,8 +1033,8 @@ func (e *Engine) executeToolsWithEvents(ctx context.Context, calls []tools.ToolC
 				_ = w.WriteEvent(ctx, api.StreamEvent{
 					Type:           failed,
 					SequenceNumber: state.nextSeq(),
-					ItemID:         tc.ID,
-					OutputIndex:    state.outputIndex + idx + 1,
+					ItemID:         ref.id,
+					OutputIndex:    ref.index,
 				})
 			}
 			return
@@ -864,8 +1058,8 @@ func (e *Engine) executeToolsWithEvents(ctx context.Context, calls []tools.ToolC
 			_ = w.WriteEvent(ctx, api.StreamEvent{
 				Type:           completed,
 				SequenceNumber: state.nextSeq(),
-				ItemID:         tc.ID,
-				OutputIndex:    state.outputIndex + idx + 1,
+				ItemID:         ref.id,
+				OutputIndex:    ref.index,
 			})
 		}
 	}
@@ -889,6 +1083,24 @@ func (e *Engine) executeToolsWithEvents(ctx context.Context, calls []tools.ToolC
 	return results
 }
 
+// appendToolImages appends a user message showing the images returned by
+// tools to vision-capable models. Chat Completions accepts images only in
+// user messages, so they follow the tool result messages.
+func (e *Engine) appendToolImages(messages []provider.ProviderMessage, model string, results []tools.ToolResult) []provider.ProviderMessage {
+	var parts []api.ContentPart
+	for _, r := range results {
+		if len(r.Images) == 0 {
+			continue
+		}
+		parts = append(parts, api.ContentPart{Type: "input_text", Text: "Images returned by tool call " + r.CallID + ":"})
+		parts = append(parts, r.Images...)
+	}
+	if len(parts) == 0 || !provider.CapabilitiesFor(e.provider, model).Vision {
+		return messages
+	}
+	return append(messages, provider.ProviderMessage{Role: "user", Content: extractUserContent(parts)})
+}
+
 // buildAssistantToolCallMessage creates an assistant message with tool_calls
 // for the conversation history. Per Chat Completions convention, the assistant
 // message containing tool_calls must precede the tool role result messages.
@@ -912,18 +1124,25 @@ func buildAssistantToolCallMessage(calls []tools.ToolCall) provider.ProviderMess
 
 // buildAndWriteResponse creates the final response and writes it.
 func (e *Engine) buildAndWriteResponse(ctx context.Context, req *api.CreateResponseRequest, items []api.Item, usage *api.Usage, status api.ResponseStatus, respErr *api.APIError, w transport.ResponseWriter, toolResults ...[]tools.ToolResult) error {
-	// Generate annotations from tool results if annotator is configured.
-	if e.cfg.Annotator != nil && len(toolResults) > 0 {
-		sources := ExtractSourceContexts(toolResults[0])
-		if len(sources) > 0 {
-			for i := range items {
-				if items[i].Type == api.ItemTypeMessage && items[i].Message != nil {
-					for j := range items[i].Message.Output {
-						if items[i].Message.Output[j].Type == "output_text" && items[i].Message.Output[j].Text != "" {
-							anns := e.cfg.Annotator.Generate(items[i].Message.Output[j].Text, sources)
-							if len(anns) > 0 {
-								items[i].Message.Output[j].Annotations = anns
-							}
+	// Generate annotations from tool results: citations of sources if an
+	// annotator is configured, and of the files tools produced.
+	if len(toolResults) > 0 {
+		var sources []SourceContext
+		if e.cfg.Annotator != nil {
+			sources = ExtractSourceContexts(toolResults[0])
+		}
+		for i := range items {
+			if items[i].Type == api.ItemTypeMessage && items[i].Message != nil {
+				for j := range items[i].Message.Output {
+					if items[i].Message.Output[j].Type == "output_text" && items[i].Message.Output[j].Text != "" {
+						text := items[i].Message.Output[j].Text
+						var anns []api.Annotation
+						if len(sources) > 0 {
+							anns = e.cfg.Annotator.Generate(text, sources)
+						}
+						anns = append(anns, fileCitations(text, toolResults[0])...)
+						if len(anns) > 0 {
+							items[i].Message.Output[j].Annotations = anns
 						}
 					}
 				}
@@ -938,5 +1157,12 @@ func (e *Engine) buildAndWriteResponse(ctx context.Context, req *api.CreateRespo
 	if status == api.ResponseStatusIncomplete {
 		resp.IncompleteDetails = &api.IncompleteDetails{Reason: "max_output_tokens"}
 	}
-	return w.WriteResponse(ctx, resp)
+	if err := w.WriteResponse(ctx, applyIncludeFilter(resp, req.Include)); err != nil {
+		return err
+	}
+
+	// Save the response to the store (after client write). It keeps the
+	// server-side tool calls and their results for conversation replay.
+	e.saveIfStateful(ctx, req, resp)
+	return nil
 }
@@ -111,20 +111,20 @@ func TestAgenticLoop_TwoTurns(t *testing.T) {
 		t.Fatal("expected response")
 	}
 
-	// Should have: function_call + function_call_output + message = 3 output items.
-	if len(w.response.Output) != 3 {
-		t.Fatalf("expected 3 output items, got %d", len(w.response.Output))
+	// Should have: mcp_call + message = 2 output items.
+	if len(w.response.Output) != 2 {
+		t.Fatalf("expected 2 output items, got %d", len(w.response.Output))
 	}
 
-	// Verify order: function_call, function_call_output, message.
-	if w.response.Output[0].Type != api.ItemTypeFunctionCall {
-		t.Errorf("output[0] type = %q, want function_call", w.response.Output[0].Type)
+	// Verify order: mcp_call (carrying the tool output), message.
+	call := w.response.Output[0]
+	if call.Type != api.ItemTypeMCPCall {
+		t.Errorf("output[0] type = %q, want mcp_call", call.Type)
+	} else if call.MCPCall.Name != "get_weather" || call.MCPCall.Output != `{"temp":22}` {
+		t.Errorf("output[0] = %+v, want get_weather call with its output", call.MCPCall)
 	}
-	if w.response.Output[1].Type != api.ItemTypeFunctionCallOutput {
-		t.Errorf("output[1] type = %q, want function_call_output", w.response.Output[1].Type)
-	}
-	if w.response.Output[2].Type != api.ItemTypeMessage {
-		t.Errorf("output[2] type = %q, want message", w.response.Output[2].Type)
+	if w.response.Output[1].Type != api.ItemTypeMessage {
+		t.Errorf("output[1] type = %q, want message", w.response.Output[1].Type)
 	}
 
 	// Verify cumulative usage.
@@ -190,9 +190,14 @@ func TestAgenticLoop_ConcurrentToolCalls(t *testing.T) {
 		t.Fatalf("CreateResponse failed: %v", err)
 	}
 
-	// Should have: 2 function_calls + 2 function_call_outputs + 1 message = 5 items.
-	if len(w.response.Output) != 5 {
-		t.Fatalf("expected 5 output items, got %d", len(w.response.Output))
+	// Should have: 2 mcp_calls + 1 message = 3 items.
+	if len(w.response.Output) != 3 {
+		t.Fatalf("expected 3 output items, got %d", len(w.response.Output))
+	}
+	for _, item := range w.response.Output[:2] {
+		if item.Type != api.ItemTypeMCPCall || item.MCPCall.Output == "" {
+			t.Errorf("item type = %q, want mcp_call with output", item.Type)
+		}
 	}
 }
 
@@ -280,17 +285,17 @@ func TestAgenticLoop_ToolError(t *testing.T) {
 		t.Errorf("status = %q, want completed", w.response.Status)
 	}
 
-	// The function_call_output should have is_error (we check output contains error text).
+	// The mcp_call should have failed with the error message.
 	var foundErrorOutput bool
 	for _, item := range w.response.Output {
-		if item.Type == api.ItemTypeFunctionCallOutput && item.FunctionCallOutput != nil {
-			if item.FunctionCallOutput.Output != "" {
+		if item.Type == api.ItemTypeMCPCall && item.MCPCall != nil {
+			if item.Status == api.ItemStatusFailed && item.MCPCall.Error != "" {
 				foundErrorOutput = true
 			}
 		}
 	}
 	if !foundErrorOutput {
-		t.Error("expected function_call_output with error message")
+		t.Error("expected failed mcp_call with error message")
 	}
 }
 
@@ -380,8 +385,8 @@ func TestAgenticLoop_AllowedToolsRejection(t *testing.T) {
 	// Verify the rejection error was fed back.
 	var foundRejection bool
 	for _, item := range w.response.Output {
-		if item.Type == api.ItemTypeFunctionCallOutput && item.FunctionCallOutput != nil {
-			if item.FunctionCallOutput.Output != "" {
+		if item.Type == api.ItemTypeMCPCall && item.MCPCall != nil {
+			if item.MCPCall.Error != "" {
 				foundRejection = true
 			}
 		}
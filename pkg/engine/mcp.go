@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	mcptools "github.com/rhuss/antwort/pkg/tools/mcp"
+)
+
+// requestServersKey is the context key for the MCP servers declared by the
+// current request.
+type requestServersKey struct{}
+
+// requestServersFromContext returns the MCP servers declared by the current
+// request, or nil if it declared none.
+func requestServersFromContext(ctx context.Context) *mcptools.RequestServers {
+	rs, _ := ctx.Value(requestServersKey{}).(*mcptools.RequestServers)
+	return rs
+}
+
+// hasMCPServers reports whether the request declares MCP servers with
+// tools of type "mcp".
+func hasMCPServers(req *api.CreateResponseRequest) bool {
+	for _, t := range req.Tools {
+		if t.Type == "mcp" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMCPServers verifies that the MCP servers declared by the request may
+// be used, without connecting to them.
+func (e *Engine) checkMCPServers(req *api.CreateResponseRequest) error {
+	if !hasMCPServers(req) {
+		return nil
+	}
+	if e.cfg.MCPPool == nil {
+		return api.NewInvalidRequestError("tools", "mcp tools are not enabled on this server")
+	}
+	for _, t := range req.Tools {
+		if t.Type == "mcp" && !e.cfg.MCPPool.Allowed(t.ServerURL) {
+			return api.NewInvalidRequestError("tools",
+				fmt.Sprintf("mcp server %q: server_url %s is not allowed", t.ServerLabel, t.ServerURL))
+		}
+	}
+	return nil
+}
+
+// openMCPServers connects to the MCP servers declared by the request and
+// offers their tools to the model. The servers are stored in the returned
+// context, where the agentic loop finds them. The returned function
+// releases the connections and must be called when the request is done.
+func (e *Engine) openMCPServers(ctx context.Context, req *api.CreateResponseRequest, provReq *provider.ProviderRequest) (context.Context, func(), error) {
+	if err := e.checkMCPServers(req); err != nil {
+		return ctx, nil, err
+	}
+	if !hasMCPServers(req) {
+		return ctx, func() {}, nil
+	}
+
+	rs, err := e.cfg.MCPPool.OpenRequestServers(ctx, req.Tools)
+	if err != nil {
+		if errors.Is(err, mcptools.ErrServerNotAllowed) {
+			return ctx, nil, api.NewInvalidRequestError("tools", err.Error())
+		}
+		return ctx, nil, err
+	}
+
+	existing := make(map[string]bool, len(provReq.Tools))
+	for _, t := range provReq.Tools {
+		existing[t.Function.Name] = true
+	}
+	for _, td := range rs.DiscoveredTools() {
+		if existing[td.Name] {
+			continue
+		}
+		provReq.Tools = append(provReq.Tools, provider.ProviderTool{
+			Type: "function",
+			Function: provider.ProviderFunctionDef{
+				Name:        td.Name,
+				Description: td.Description,
+				Parameters:  td.Parameters,
+			},
+		})
+	}
+
+	ctx = context.WithValue(ctx, requestServersKey{}, rs)
+	return withLeadingItems(ctx, rs.ListToolsItems()), rs.Close, nil
+}
+
+// promptRenderer is implemented by executors that render MCP prompts
+// (MCPExecutor).
+type promptRenderer interface {
+	RenderPrompt(ctx context.Context, ref string, variables map[string]string) (string, error)
+}
+
+// renderPrompt renders the MCP prompt referenced by an agent profile's
+// instructions ("mcp://<server>/<prompt>") with the request variables.
+func (e *Engine) renderPrompt(ctx context.Context, ref string, variables map[string]string) (string, error) {
+	for _, exec := range e.executors {
+		r, ok := exec.(promptRenderer)
+		if !ok {
+			continue
+		}
+		text, err := r.RenderPrompt(ctx, ref, variables)
+		if errors.Is(err, mcptools.ErrInvalidPrompt) {
+			return "", api.NewInvalidRequestError("agent", err.Error())
+		}
+		if err != nil {
+			return "", api.NewServerError(fmt.Sprintf("rendering MCP prompt %s: %v", ref, err))
+		}
+		return text, nil
+	}
+	return "", api.NewInvalidRequestError("agent", fmt.Sprintf("MCP prompt %s: no MCP servers are configured", ref))
+}
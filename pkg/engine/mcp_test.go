@@ -0,0 +1,274 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rhuss/antwort/pkg/agent"
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/tools"
+	mcptools "github.com/rhuss/antwort/pkg/tools/mcp"
+)
+
+// toolRecordingProvider records the tools offered on each turn.
+type toolRecordingProvider struct {
+	turnAwareProvider
+	tools [][]provider.ProviderTool
+}
+
+func (p *toolRecordingProvider) Complete(ctx context.Context, req *provider.ProviderRequest) (*provider.ProviderResponse, error) {
+	p.tools = append(p.tools, req.Tools)
+	return p.turnAwareProvider.Complete(ctx, req)
+}
+
+// startMCPServer serves an MCP server with a "lookup" tool over streamable
+// HTTP and returns its endpoint URL.
+func startMCPServer(t *testing.T) string {
+	t.Helper()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	server.AddTool(
+		&mcp.Tool{Name: "lookup", Description: "Look up a term", InputSchema: map[string]any{"type": "object"}},
+		func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "found it"}}}, nil
+		},
+	)
+	ts := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	t.Cleanup(ts.Close)
+	return ts.URL + "/mcp"
+}
+
+func TestAgenticLoop_RequestMCPServer(t *testing.T) {
+	url := startMCPServer(t)
+	pool := mcptools.NewPool(mcptools.PoolConfig{AllowedURLs: []string{url}})
+	defer pool.Close()
+
+	prov := &toolRecordingProvider{turnAwareProvider: turnAwareProvider{
+		caps: provider.ProviderCapabilities{ToolCalling: true},
+		responses: []*provider.ProviderResponse{
+			{
+				Status: api.ResponseStatusCompleted,
+				Items: []api.Item{
+					{Type: api.ItemTypeFunctionCall, Status: api.ItemStatusCompleted,
+						FunctionCall: &api.FunctionCallData{Name: "lookup", CallID: "c1", Arguments: `{}`}},
+				},
+			},
+			{
+				Status: api.ResponseStatusCompleted,
+				Items: []api.Item{
+					{Type: api.ItemTypeMessage, Status: api.ItemStatusCompleted,
+						Message: &api.MessageData{Role: api.RoleAssistant, Output: []api.OutputContentPart{{Type: "output_text", Text: "Done."}}}},
+				},
+			},
+		},
+	}}
+
+	eng, err := New(prov, nil, Config{MCPPool: pool})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	req := &api.CreateResponseRequest{
+		Model: "m",
+		Input: []api.Item{{Type: api.ItemTypeMessage, Message: &api.MessageData{Role: api.RoleUser, Content: []api.ContentPart{{Type: "input_text", Text: "Look it up"}}}}},
+		Tools: []api.ToolDefinition{{
+			Type: "mcp", ServerLabel: "docs", ServerURL: url,
+			Headers: map[string]string{"Authorization": "Bearer secret"},
+		}},
+	}
+
+	w := &mockResponseWriter{}
+	if err := eng.CreateResponse(context.Background(), req, w); err != nil {
+		t.Fatalf("CreateResponse failed: %v", err)
+	}
+
+	if len(prov.tools) == 0 || len(prov.tools[0]) != 1 || prov.tools[0][0].Function.Name != "lookup" {
+		t.Fatalf("provider tools = %+v, want only lookup", prov.tools)
+	}
+
+	// mcp_list_tools + mcp_call + message.
+	out := w.response.Output
+	if len(out) != 3 {
+		t.Fatalf("expected 3 output items, got %d", len(out))
+	}
+	if out[0].Type != api.ItemTypeMCPListTools || out[0].MCPListTools.ServerLabel != "docs" {
+		t.Errorf("output[0] = %s, want mcp_list_tools for docs", out[0].Type)
+	}
+	if out[1].Type != api.ItemTypeMCPCall || out[1].MCPCall.ServerLabel != "docs" || out[1].MCPCall.Output != "found it" {
+		t.Errorf("output[1] = %+v, want mcp_call with the MCP tool output", out[1])
+	}
+
+	if len(w.response.Tools) != 1 || w.response.Tools[0].Headers != nil {
+		t.Errorf("response tools = %+v, want the mcp tool without headers", w.response.Tools)
+	}
+}
+
+func TestCreateResponse_RequestMCPServerRejected(t *testing.T) {
+	prov := &turnAwareProvider{caps: provider.ProviderCapabilities{ToolCalling: true}}
+	req := func() *api.CreateResponseRequest {
+		return &api.CreateResponseRequest{
+			Model: "m",
+			Input: []api.Item{{Type: api.ItemTypeMessage, Message: &api.MessageData{Role: api.RoleUser, Content: []api.ContentPart{{Type: "input_text", Text: "Hi"}}}}},
+			Tools: []api.ToolDefinition{{Type: "mcp", ServerLabel: "meta", ServerURL: "http://169.254.169.254/latest"}},
+		}
+	}
+
+	tests := []struct {
+		name string
+		pool *mcptools.Pool
+	}{
+		{"disabled", nil},
+		{"not allowed", mcptools.NewPool(mcptools.PoolConfig{AllowedURLs: []string{"https://mcp.example.com/*"}})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng, err := New(prov, nil, Config{MCPPool: tt.pool})
+			if err != nil {
+				t.Fatalf("failed to create engine: %v", err)
+			}
+			err = eng.CreateResponse(context.Background(), req(), &mockResponseWriter{})
+			var apiErr *api.APIError
+			if !errors.As(err, &apiErr) || apiErr.Type != api.ErrorTypeInvalidRequest {
+				t.Errorf("err = %v, want invalid_request error", err)
+			}
+		})
+	}
+}
+
+// staticProfiles resolves profiles from a map.
+type staticProfiles map[string]*agent.AgentProfile
+
+func (p staticProfiles) Resolve(name string) (*agent.AgentProfile, error) {
+	profile, ok := p[name]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return profile, nil
+}
+
+func TestResolveProfile_MCPPrompt(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	server.AddPrompt(
+		&mcp.Prompt{Name: "support", Arguments: []*mcp.PromptArgument{{Name: "product", Required: true}}},
+		func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return &mcp.GetPromptResult{Messages: []*mcp.PromptMessage{
+				{Role: "user", Content: &mcp.TextContent{Text: "You support " + req.Params.Arguments["product"] + "."}},
+			}}, nil
+		},
+	)
+	ts := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+	t.Cleanup(ts.Close)
+
+	client := mcptools.NewMCPClient(mcptools.ServerConfig{Name: "docs", URL: ts.URL + "/mcp"})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	executor := mcptools.NewMCPExecutor(map[string]*mcptools.MCPClient{"docs": client})
+	t.Cleanup(func() { _ = executor.Close() })
+
+	profile := &agent.AgentProfile{Name: "support", Instructions: "mcp://docs/support"}
+	eng, err := New(&turnAwareProvider{}, nil, Config{
+		Executors:       []tools.ToolExecutor{executor},
+		ProfileResolver: staticProfiles{"support": profile},
+	})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	req := &api.CreateResponseRequest{Agent: "support", Variables: map[string]string{"product": "antwort"}}
+	if _, err := eng.resolveProfile(context.Background(), req); err != nil {
+		t.Fatalf("resolveProfile failed: %v", err)
+	}
+	if req.Instructions != "You support antwort." {
+		t.Errorf("instructions = %q, want the rendered prompt", req.Instructions)
+	}
+	if profile.Instructions != "mcp://docs/support" {
+		t.Errorf("profile instructions changed to %q", profile.Instructions)
+	}
+
+	req = &api.CreateResponseRequest{Agent: "support"}
+	_, err = eng.resolveProfile(context.Background(), req)
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) || apiErr.Type != api.ErrorTypeInvalidRequest {
+		t.Errorf("err = %v, want invalid_request error for a missing variable", err)
+	}
+}
+
+func TestAppendToolImages(t *testing.T) {
+	results := []tools.ToolResult{
+		{CallID: "call_1", Output: "text only"},
+		{CallID: "call_2", Output: "[image 1 (image/png)]", Images: []api.ContentPart{
+			{Type: "input_image", Data: "aW1n", MediaType: "image/png"},
+		}},
+	}
+
+	eng, err := New(&turnAwareProvider{}, nil, Config{})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	if msgs := eng.appendToolImages(nil, "m", results); len(msgs) != 0 {
+		t.Errorf("messages = %+v, want none without vision", msgs)
+	}
+
+	eng, err = New(&turnAwareProvider{caps: provider.ProviderCapabilities{Vision: true}}, nil, Config{})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	msgs := eng.appendToolImages(nil, "m", results)
+	if len(msgs) != 1 || msgs[0].Role != "user" {
+		t.Fatalf("messages = %+v, want one user message", msgs)
+	}
+	content, ok := msgs[0].Content.([]map[string]any)
+	if !ok || len(content) != 2 {
+		t.Fatalf("content = %#v", msgs[0].Content)
+	}
+	if content[0]["text"] != "Images returned by tool call call_2:" {
+		t.Errorf("label = %v", content[0]["text"])
+	}
+	if url := content[1]["image_url"].(map[string]any)["url"]; url != "data:image/png;base64,aW1n" {
+		t.Errorf("image url = %v", url)
+	}
+}
+
+// progressExecutor is an MCP executor whose tool reports progress.
+type progressExecutor struct{}
+
+func (progressExecutor) Kind() tools.ToolKind        { return tools.ToolKindMCP }
+func (progressExecutor) CanExecute(name string) bool { return name == "index" }
+func (progressExecutor) Execute(ctx context.Context, call tools.ToolCall) (*tools.ToolResult, error) {
+	if report := tools.GetProgressReporter(ctx); report != nil {
+		report(tools.Progress{Progress: 1, Total: 2, Message: "indexing"})
+	}
+	return &tools.ToolResult{CallID: call.ID, Output: "done"}, nil
+}
+
+func TestExecuteToolsWithEvents_MCPProgress(t *testing.T) {
+	eng, err := New(&turnAwareProvider{}, nil, Config{Executors: []tools.ToolExecutor{progressExecutor{}}})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	w := &mockResponseWriter{}
+	typed := map[string]itemRef{"call_1": {id: "mcp_1", index: 2}}
+	eng.executeToolsWithEvents(context.Background(), []tools.ToolCall{{ID: "call_1", Name: "index", Arguments: "{}"}},
+		false, w, &streamState{}, typed)
+
+	var types []api.StreamEventType
+	for _, ev := range w.events {
+		types = append(types, ev.Type)
+	}
+	want := []api.StreamEventType{api.EventMCPCallInProgress, api.EventMCPCallProgress, api.EventMCPCallCompleted}
+	if !slices.Equal(types, want) {
+		t.Fatalf("events = %v, want %v", types, want)
+	}
+	ev := w.events[1]
+	if ev.ItemID != "mcp_1" || ev.OutputIndex != 2 || ev.SequenceNumber != 1 ||
+		ev.Progress == nil || *ev.Progress != (api.ToolProgress{Progress: 1, Total: 2, Message: "indexing"}) {
+		t.Errorf("progress event = %+v", ev)
+	}
+}
@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// serverLabeler is implemented by executors of MCP tools (MCPExecutor,
+// mcp.RequestServers).
+type serverLabeler interface {
+	ServerLabel(toolName string) string
+}
+
+// itemRef locates a streamed output item.
+type itemRef struct {
+	id    string
+	index int
+}
+
+// toolCallItem builds the typed output item reporting a call to a tool the
+// server executes: mcp_call, web_search_call or file_search_call. result is
+// nil while the call runs. It returns false for tools whose calls are
+// reported as function_call items.
+func toolCallItem(id string, call tools.ToolCall, exec tools.ToolExecutor, result *tools.ToolResult) (api.Item, bool) {
+	item := api.Item{ID: id, Status: api.ItemStatusInProgress}
+	if result != nil {
+		item.Status = api.ItemStatusCompleted
+		if result.IsError {
+			item.Status = api.ItemStatusFailed
+		}
+	}
+
+	switch classifyToolType(call.Name, exec) {
+	case "mcp":
+		item.Type = api.ItemTypeMCPCall
+		item.MCPCall = &api.MCPCallData{Name: call.Name, Arguments: call.Arguments}
+		if sl, ok := exec.(serverLabeler); ok {
+			item.MCPCall.ServerLabel = sl.ServerLabel(call.Name)
+		}
+		if result != nil && result.IsError {
+			item.MCPCall.Error = result.Output
+		} else if result != nil {
+			item.MCPCall.Output = result.Output
+		}
+
+	case "web_search":
+		item.Type = api.ItemTypeWebSearchCall
+		item.WebSearchCall = &api.WebSearchCallData{
+			Action: api.WebSearchAction{Type: "search", Query: searchQuery(call.Arguments)},
+		}
+		if result != nil {
+			for _, s := range result.Sources {
+				item.WebSearchCall.Action.Sources = append(item.WebSearchCall.Action.Sources,
+					api.WebSearchSource{Type: "url", URL: s.URL, Title: s.Title})
+			}
+		}
+
+	case "file_search":
+		item.Type = api.ItemTypeFileSearchCall
+		item.FileSearchCall = &api.FileSearchCallData{Queries: []string{searchQuery(call.Arguments)}}
+		if result != nil {
+			item.FileSearchCall.Results = []api.FileSearchResult{}
+			for _, s := range result.Sources {
+				item.FileSearchCall.Results = append(item.FileSearchCall.Results, api.FileSearchResult{
+					FileID:   s.FileID,
+					Filename: s.Filename,
+					Score:    s.Score,
+					Text:     s.Text,
+				})
+			}
+		}
+
+	default:
+		return api.Item{}, false
+	}
+	return item, true
+}
+
+// searchQuery extracts the query from the arguments of a search tool call.
+func searchQuery(arguments string) string {
+	var args struct {
+		Query string `json:"query"`
+	}
+	_ = json.Unmarshal([]byte(arguments), &args)
+	return args.Query
+}
+
+// interpreterCode extracts the code from the arguments of a code
+// interpreter call.
+func interpreterCode(arguments string) string {
+	var args struct {
+		Code string `json:"code"`
+	}
+	_ = json.Unmarshal([]byte(arguments), &args)
+	return args.Code
+}
+
+// callItemIndex returns the position in items of the function_call item
+// of the call with callID, or -1.
+func callItemIndex(items []api.Item, callID string) int {
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i].Type == api.ItemTypeFunctionCall && items[i].FunctionCall != nil && items[i].FunctionCall.CallID == callID {
+			return i
+		}
+	}
+	return -1
+}
+
+// typedCallItem builds the typed output item for the call with callID,
+// which takes the place of the call's function_call item at position pos
+// in items. ok is false if the call is reported as a function_call item.
+func (e *Engine) typedCallItem(ctx context.Context, items []api.Item, callID string, result *tools.ToolResult) (pos int, item api.Item, ok bool) {
+	pos = callItemIndex(items, callID)
+	if pos < 0 {
+		return -1, api.Item{}, false
+	}
+	fc := items[pos].FunctionCall
+	call := tools.ToolCall{ID: fc.CallID, Name: fc.Name, Arguments: fc.Arguments}
+	item, ok = toolCallItem(items[pos].ID, call, e.findExecutor(ctx, call.Name), result)
+	return pos, item, ok
+}
+
+// toolCallItemMessages replays a typed tool call item as the assistant's
+// tool call and the tool's result. Search calls are replayed with the
+// results recorded in the item.
+func toolCallItemMessages(item api.Item) []provider.ProviderMessage {
+	var name, arguments, output string
+	switch {
+	case item.MCPCall != nil:
+		name, arguments = item.MCPCall.Name, item.MCPCall.Arguments
+		output = item.MCPCall.Output
+		if item.MCPCall.Error != "" {
+			output = item.MCPCall.Error
+		}
+
+	case item.WebSearchCall != nil:
+		action := item.WebSearchCall.Action
+		name, arguments = "web_search", queryArguments(action.Query)
+		var b strings.Builder
+		fmt.Fprintf(&b, "Search results for %q:\n", action.Query)
+		for i, s := range action.Sources {
+			fmt.Fprintf(&b, "\n%d. %s\n   URL: %s\n", i+1, s.Title, s.URL)
+		}
+		output = b.String()
+
+	case item.FileSearchCall != nil:
+		var query string
+		if len(item.FileSearchCall.Queries) > 0 {
+			query = item.FileSearchCall.Queries[0]
+		}
+		name, arguments = "file_search", queryArguments(query)
+		var b strings.Builder
+		fmt.Fprintf(&b, "Search results for %q:\n", query)
+		for i, r := range item.FileSearchCall.Results {
+			fmt.Fprintf(&b, "\n%d. [Score: %.4f] (file: %s)\n   %s\n", i+1, r.Score, r.FileID, r.Text)
+		}
+		output = b.String()
+
+	default:
+		return nil
+	}
+
+	return []provider.ProviderMessage{
+		{
+			Role: "assistant",
+			ToolCalls: []provider.ProviderToolCall{{
+				ID:       item.ID,
+				Type:     "function",
+				Function: provider.ProviderFunctionCall{Name: name, Arguments: arguments},
+			}},
+		},
+		{Role: "tool", Content: output, ToolCallID: item.ID},
+	}
+}
+
+// queryArguments encodes the arguments of a search tool call.
+func queryArguments(query string) string {
+	data, _ := json.Marshal(map[string]string{"query": query})
+	return string(data)
+}
@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// searchExecutor is a builtin file_search tool returning one match.
+type searchExecutor struct{}
+
+func (searchExecutor) Kind() tools.ToolKind        { return tools.ToolKindBuiltin }
+func (searchExecutor) CanExecute(name string) bool { return name == "file_search" }
+func (searchExecutor) Execute(_ context.Context, call tools.ToolCall) (*tools.ToolResult, error) {
+	return &tools.ToolResult{
+		CallID:  call.ID,
+		Output:  "Search results for \"refunds\": ...",
+		Sources: []tools.Source{{FileID: "file-1", Filename: "policy.pdf", Score: 0.9, Text: "Refunds within 30 days."}},
+	}, nil
+}
+
+func TestAgenticLoop_FileSearchCallItem(t *testing.T) {
+	tests := []struct {
+		name        string
+		include     []string
+		wantResults bool
+	}{
+		{"everything by default", nil, true},
+		{"results included", []string{"file_search_call.results"}, true},
+		{"results not included", []string{"usage"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prov := &turnAwareProvider{
+				caps: provider.ProviderCapabilities{ToolCalling: true},
+				responses: []*provider.ProviderResponse{
+					{Status: api.ResponseStatusCompleted, Items: []api.Item{
+						{Type: api.ItemTypeFunctionCall, Status: api.ItemStatusCompleted,
+							FunctionCall: &api.FunctionCallData{Name: "file_search", CallID: "c1", Arguments: `{"query":"refunds"}`}},
+					}},
+					{Status: api.ResponseStatusCompleted, Items: []api.Item{
+						{Type: api.ItemTypeMessage, Status: api.ItemStatusCompleted,
+							Message: &api.MessageData{Role: api.RoleAssistant, Output: []api.OutputContentPart{{Type: "output_text", Text: "30 days."}}}},
+					}},
+				},
+			}
+			store := &mockStore{}
+			eng, err := New(prov, store, Config{Executors: []tools.ToolExecutor{searchExecutor{}}})
+			if err != nil {
+				t.Fatalf("failed to create engine: %v", err)
+			}
+
+			w := &mockResponseWriter{}
+			err = eng.CreateResponse(context.Background(), &api.CreateResponseRequest{
+				Model:   "m",
+				Input:   []api.Item{{Type: api.ItemTypeMessage, Message: &api.MessageData{Role: api.RoleUser, Content: []api.ContentPart{{Type: "input_text", Text: "Refunds?"}}}}},
+				Tools:   []api.ToolDefinition{{Type: "file_search"}},
+				Include: tt.include,
+			}, w)
+			if err != nil {
+				t.Fatalf("CreateResponse failed: %v", err)
+			}
+
+			// file_search_call + message.
+			out := w.response.Output
+			if len(out) != 2 || out[0].Type != api.ItemTypeFileSearchCall {
+				t.Fatalf("output = %+v, want file_search_call and message", out)
+			}
+			fs := out[0].FileSearchCall
+			if len(fs.Queries) != 1 || fs.Queries[0] != "refunds" {
+				t.Errorf("queries = %v, want [refunds]", fs.Queries)
+			}
+			if got := len(fs.Results) == 1; got != tt.wantResults {
+				t.Errorf("results = %+v, want results: %v", fs.Results, tt.wantResults)
+			}
+
+			stored := store.responses[w.response.ID]
+			if stored == nil || len(stored.Output[0].FileSearchCall.Results) != 1 {
+				t.Error("stored response should keep the search results")
+			}
+		})
+	}
+}
+
+func TestItemToMessages_ToolCallItems(t *testing.T) {
+	tests := []struct {
+		name       string
+		item       api.Item
+		wantTool   string
+		wantOutput string
+	}{
+		{
+			name: "mcp_call",
+			item: api.Item{ID: "item_1", Type: api.ItemTypeMCPCall, MCPCall: &api.MCPCallData{
+				ServerLabel: "docs", Name: "lookup", Arguments: `{}`, Output: "found it",
+			}},
+			wantTool:   "lookup",
+			wantOutput: "found it",
+		},
+		{
+			name: "failed mcp_call",
+			item: api.Item{ID: "item_2", Type: api.ItemTypeMCPCall, MCPCall: &api.MCPCallData{
+				ServerLabel: "docs", Name: "lookup", Arguments: `{}`, Error: "timeout",
+			}},
+			wantTool:   "lookup",
+			wantOutput: "timeout",
+		},
+		{
+			name: "file_search_call",
+			item: api.Item{ID: "item_3", Type: api.ItemTypeFileSearchCall, FileSearchCall: &api.FileSearchCallData{
+				Queries: []string{"refunds"},
+				Results: []api.FileSearchResult{{FileID: "file-1", Score: 0.9, Text: "Refunds within 30 days."}},
+			}},
+			wantTool:   "file_search",
+			wantOutput: "Search results for \"refunds\":\n\n1. [Score: 0.9000] (file: file-1)\n   Refunds within 30 days.\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs := itemToMessages(tt.item)
+			if len(msgs) != 2 {
+				t.Fatalf("got %d messages, want tool call and result", len(msgs))
+			}
+			call := msgs[0].ToolCalls
+			if msgs[0].Role != "assistant" || len(call) != 1 || call[0].Function.Name != tt.wantTool || call[0].ID != tt.item.ID {
+				t.Errorf("call message = %+v", msgs[0])
+			}
+			if msgs[1].Role != "tool" || msgs[1].ToolCallID != tt.item.ID || msgs[1].Content != tt.wantOutput {
+				t.Errorf("result message = %+v, want output %q", msgs[1], tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestAgenticLoopStreaming_FileSearchCallItem(t *testing.T) {
+	prov := &turnAwareProvider{
+		caps: provider.ProviderCapabilities{Streaming: true, ToolCalling: true},
+		streamFns: []func(context.Context, *provider.ProviderRequest) (<-chan provider.ProviderEvent, error){
+			func(_ context.Context, _ *provider.ProviderRequest) (<-chan provider.ProviderEvent, error) {
+				ch := make(chan provider.ProviderEvent, 4)
+				ch <- provider.ProviderEvent{Type: provider.ProviderEventToolCallDelta, ToolCallIndex: 0,
+					ToolCallID: "c1", FunctionName: "file_search", Delta: `{"query":"refunds"}`}
+				ch <- provider.ProviderEvent{Type: provider.ProviderEventToolCallDone, ToolCallIndex: 0,
+					ToolCallID: "c1", FunctionName: "file_search", Delta: `{"query":"refunds"}`,
+					Item: &api.Item{Type: api.ItemTypeFunctionCall, Status: api.ItemStatusCompleted,
+						FunctionCall: &api.FunctionCallData{Name: "file_search", CallID: "c1", Arguments: `{"query":"refunds"}`}}}
+				ch <- provider.ProviderEvent{Type: provider.ProviderEventDone}
+				close(ch)
+				return ch, nil
+			},
+		},
+	}
+	eng, err := New(prov, nil, Config{Executors: []tools.ToolExecutor{searchExecutor{}}})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	w := &mockResponseWriter{}
+	err = eng.CreateResponse(context.Background(), &api.CreateResponseRequest{
+		Model:  "m",
+		Stream: true,
+		Input:  []api.Item{{Type: api.ItemTypeMessage, Message: &api.MessageData{Role: api.RoleUser, Content: []api.ContentPart{{Type: "input_text", Text: "Refunds?"}}}}},
+		Tools:  []api.ToolDefinition{{Type: "file_search"}},
+	}, w)
+	if err != nil {
+		t.Fatalf("CreateResponse failed: %v", err)
+	}
+
+	var callIndex int
+	var typed []api.StreamEvent
+	for _, ev := range w.events {
+		if ev.Item == nil {
+			continue
+		}
+		switch ev.Item.Type {
+		case api.ItemTypeFunctionCall:
+			callIndex = ev.OutputIndex
+		case api.ItemTypeFileSearchCall:
+			typed = append(typed, ev)
+		}
+	}
+	if len(typed) != 2 || typed[0].Type != api.EventOutputItemAdded || typed[1].Type != api.EventOutputItemDone {
+		t.Fatalf("file_search_call events = %+v, want added and done", typed)
+	}
+	for _, ev := range typed {
+		if ev.OutputIndex != callIndex {
+			t.Errorf("%s output_index = %d, want %d (the function_call's)", ev.Type, ev.OutputIndex, callIndex)
+		}
+	}
+	if typed[0].Item.Status != api.ItemStatusInProgress || len(typed[1].Item.FileSearchCall.Results) != 1 {
+		t.Errorf("file_search_call items = %+v, %+v", typed[0].Item, typed[1].Item)
+	}
+
+	for _, ev := range w.events {
+		if ev.Type == api.EventFileSearchCallCompleted && ev.ItemID != typed[0].Item.ID {
+			t.Errorf("%s item_id = %q, want %q", ev.Type, ev.ItemID, typed[0].Item.ID)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/observability"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// startResponseSpan starts the root span of a CreateResponse call.
+func startResponseSpan(ctx context.Context, req *api.CreateResponseRequest) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("antwort.response.mode", responseMode(req)),
+	}
+	if req.Model != "" {
+		attrs = append(attrs, semconv.GenAIRequestModel(req.Model))
+	}
+	if req.ConversationID != "" {
+		attrs = append(attrs, semconv.GenAIConversationID(req.ConversationID))
+	}
+	if req.PreviousResponseID != "" {
+		attrs = append(attrs, attribute.String("antwort.previous_response_id", req.PreviousResponseID))
+	}
+	return observability.Tracer().Start(ctx, "create_response",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// setSpanModel records the resolved model on the span in ctx.
+func setSpanModel(ctx context.Context, model string) {
+	trace.SpanFromContext(ctx).SetAttributes(semconv.GenAIRequestModel(model))
+}
+
+// turnSpans starts one span per agentic loop turn as children of the
+// response span. Starting the next turn, or calling end, ends the
+// current turn's span.
+type turnSpans struct {
+	parent context.Context
+	model  string
+	span   trace.Span
+}
+
+// next ends the previous turn's span and starts the span of turn
+// (zero-based). The returned context carries the new span.
+func (t *turnSpans) next(turn int) context.Context {
+	t.end()
+	ctx, span := observability.Tracer().Start(t.parent, "agentic_turn",
+		trace.WithAttributes(
+			attribute.Int("antwort.turn", turn+1),
+			semconv.GenAIRequestModel(t.model),
+		),
+	)
+	t.span = span
+	return ctx
+}
+
+// end ends the current turn's span, if any.
+func (t *turnSpans) end() {
+	if t.span != nil {
+		t.span.End()
+		t.span = nil
+	}
+}
+
+// executeTool runs a tool call in an "execute_tool {name}" span.
+func (e *Engine) executeTool(ctx context.Context, exec tools.ToolExecutor, tc tools.ToolCall) (*tools.ToolResult, error) {
+	ctx, span := observability.Tracer().Start(ctx, "execute_tool "+tc.Name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			semconv.GenAIOperationNameExecuteTool,
+			semconv.GenAIToolName(tc.Name),
+			semconv.GenAIToolCallID(tc.ID),
+			semconv.GenAIToolType(classifyToolType(tc.Name, exec)),
+		),
+	)
+	defer span.End()
+
+	result, err := exec.Execute(ctx, tc)
+	if err != nil {
+		observability.RecordSpanError(span, err)
+	} else if result.IsError {
+		span.SetAttributes(attribute.Bool("antwort.tool.error", true))
+	}
+	return result, err
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// recordSpans installs a tracer provider that records ended spans for the
+// duration of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return sr
+}
+
+func TestTracing_AgenticLoopSpans(t *testing.T) {
+	sr := recordSpans(t)
+
+	prov := &turnAwareProvider{
+		caps: provider.ProviderCapabilities{ToolCalling: true},
+		responses: []*provider.ProviderResponse{
+			{
+				Status: api.ResponseStatusCompleted,
+				Items: []api.Item{
+					{Type: api.ItemTypeFunctionCall, Status: api.ItemStatusCompleted,
+						FunctionCall: &api.FunctionCallData{Name: "get_weather", CallID: "c1", Arguments: `{}`}},
+				},
+			},
+			{Status: api.ResponseStatusCompleted},
+		},
+	}
+	eng, err := New(prov, nil, Config{Executors: []tools.ToolExecutor{&alwaysExecutor{}}})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+
+	req := &api.CreateResponseRequest{
+		Model: "m",
+		Input: []api.Item{{Type: api.ItemTypeMessage, Message: &api.MessageData{Role: api.RoleUser, Content: []api.ContentPart{{Type: "input_text", Text: "Weather?"}}}}},
+		Tools: []api.ToolDefinition{{Type: "function", Name: "get_weather"}},
+	}
+	if err := eng.CreateResponse(context.Background(), req, &mockResponseWriter{}); err != nil {
+		t.Fatalf("CreateResponse failed: %v", err)
+	}
+
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, s := range sr.Ended() {
+		spans[s.Name()] = append(spans[s.Name()], s)
+	}
+	if len(spans["create_response"]) != 1 || len(spans["agentic_turn"]) != 2 || len(spans["execute_tool get_weather"]) != 1 {
+		t.Fatalf("unexpected spans: %v", spans)
+	}
+
+	root := spans["create_response"][0].SpanContext().SpanID()
+	for _, turn := range spans["agentic_turn"] {
+		if turn.Parent().SpanID() != root {
+			t.Errorf("turn span parent = %s, want response span %s", turn.Parent().SpanID(), root)
+		}
+	}
+	tool := spans["execute_tool get_weather"][0]
+	if tool.Parent().SpanID() != spans["agentic_turn"][0].SpanContext().SpanID() {
+		t.Error("tool span is not a child of the first turn span")
+	}
+}
@@ -46,8 +46,12 @@ func translateRequest(req *api.CreateResponseRequest) *provider.ProviderRequest
 		pr.Messages = append(pr.Messages, msgs...)
 	}
 
-	// Map tools from api.ToolDefinition to provider.ProviderTool.
+	// Map tools from api.ToolDefinition to provider.ProviderTool. MCP
+	// server declarations are replaced by the tools the servers offer.
 	for _, t := range req.Tools {
+		if t.Type == "mcp" {
+			continue
+		}
 		pr.Tools = append(pr.Tools, provider.ProviderTool{
 			Type: t.Type,
 			Function: provider.ProviderFunctionDef{
@@ -123,10 +127,10 @@ func extractUserContent(parts []api.ContentPart) any {
 		return ""
 	}
 
-	// Check if any non-text parts exist.
+	// Check if any non-text parts exist. Files are passed as text notes.
 	hasMultimodal := false
 	for _, p := range parts {
-		if p.Type != "input_text" {
+		if p.Type != "input_text" && p.Type != "input_file" {
 			hasMultimodal = true
 			break
 		}
@@ -136,8 +140,11 @@ func extractUserContent(parts []api.ContentPart) any {
 	if !hasMultimodal {
 		var result string
 		for _, p := range parts {
-			if p.Type == "input_text" {
+			switch p.Type {
+			case "input_text":
 				result += p.Text
+			case "input_file":
+				result += fileNote(p)
 			}
 		}
 		return result
@@ -152,6 +159,11 @@ func extractUserContent(parts []api.ContentPart) any {
 				"type": "text",
 				"text": p.Text,
 			})
+		case "input_file":
+			contentArray = append(contentArray, map[string]any{
+				"type": "text",
+				"text": fileNote(p),
+			})
 		case "input_image":
 			imageURL := p.URL
 			if imageURL == "" && p.Data != "" {
@@ -175,6 +187,16 @@ func extractUserContent(parts []api.ContentPart) any {
 	return contentArray
 }
 
+// fileNote tells the model about an attached file. Its content is not
+// sent to the model; tools such as code_interpreter can read it.
+func fileNote(p api.ContentPart) string {
+	name := p.Filename
+	if name == "" {
+		name = p.FileID
+	}
+	return fmt.Sprintf("\n[Attached file: %s (%s)]\n", name, p.FileID)
+}
+
 // extractAssistantContent builds a string from OutputContentParts.
 func extractAssistantContent(parts []api.OutputContentPart) string {
 	if len(parts) == 0 {
@@ -534,6 +534,36 @@ func TestTranslateRequest_TextOnlyStaysString(t *testing.T) {
 	}
 }
 
+func TestTranslateRequest_InputFileNote(t *testing.T) {
+	// Attached files are noted in the text; their content is not sent.
+	req := &api.CreateResponseRequest{
+		Model: "text-model",
+		Input: []api.Item{
+			{
+				Type: api.ItemTypeMessage,
+				Message: &api.MessageData{
+					Role: api.RoleUser,
+					Content: []api.ContentPart{
+						{Type: "input_text", Text: "Summarize this."},
+						{Type: "input_file", FileID: "file_abc", Filename: "sales.csv"},
+					},
+				},
+			},
+		},
+	}
+
+	pr := translateRequest(req)
+
+	content, ok := pr.Messages[0].Content.(string)
+	if !ok {
+		t.Fatalf("expected string content for text and file input, got %T", pr.Messages[0].Content)
+	}
+	want := "Summarize this.\n[Attached file: sales.csv (file_abc)]\n"
+	if content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
+
 func TestTranslateRequest_MultimodalJSON(t *testing.T) {
 	// Verify that multimodal content serializes correctly to JSON
 	// (as the Chat Completions API expects).
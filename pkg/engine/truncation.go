@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/debug"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/transport"
+)
+
+// messageTokenOverhead approximates the per-message framing tokens added by
+// chat templates (role markers, separators).
+const messageTokenOverhead = 4
+
+// incompleteReasonContextLength is the incomplete_details reason used when
+// truncation cannot make the request fit the backend's context window.
+const incompleteReasonContextLength = "context_length_exceeded"
+
+// TokenCounter estimates the number of tokens in a piece of text. It is
+// used by truncation "auto" to fit requests into the backend's context
+// window. Implementations may wrap a model-specific tokenizer.
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
+// HeuristicTokenCounter estimates tokens as one token per four characters,
+// a common approximation for English text with BPE tokenizers.
+type HeuristicTokenCounter struct{}
+
+// CountTokens returns the estimated token count for text.
+func (HeuristicTokenCounter) CountTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// tokenCounter returns the configured token counter or the heuristic default.
+func (c Config) tokenCounter() TokenCounter {
+	if c.TokenCounter == nil {
+		return HeuristicTokenCounter{}
+	}
+	return c.TokenCounter
+}
+
+// truncateMessages applies truncation "auto" to the provider request. The
+// oldest turns are dropped until the estimated input fits the provider's
+// context window minus max_output_tokens. A turn starts at a user message
+// and includes the assistant replies, tool calls and tool outputs that
+// follow it, so tool-call/output pairs are always kept or dropped together.
+// System and developer messages are never dropped, and neither is the most
+// recent turn. It returns false when even the minimal context does not fit.
+// Requests without truncation "auto", or providers that do not report a
+// context window, are left unchanged.
+func (e *Engine) truncateMessages(req *api.CreateResponseRequest, provReq *provider.ProviderRequest) bool {
+	if getTruncation(req) != "auto" {
+		return true
+	}
+	window := provider.CapabilitiesFor(e.provider, req.Model).MaxContextWindow
+	if window <= 0 {
+		return true
+	}
+
+	counter := e.cfg.tokenCounter()
+	budget := window - toolTokens(counter, provReq.Tools)
+	if req.MaxOutputTokens != nil {
+		budget -= *req.MaxOutputTokens
+	}
+
+	// Group droppable messages into turns and total up the estimate.
+	var turns [][]int
+	tokens := make([]int, len(provReq.Messages))
+	total := 0
+	for i, msg := range provReq.Messages {
+		tokens[i] = messageTokens(counter, msg)
+		total += tokens[i]
+
+		switch {
+		case msg.Role == "system" || msg.Role == "developer":
+			continue
+		case msg.Role == "user" || len(turns) == 0:
+			turns = append(turns, []int{i})
+		default:
+			turns[len(turns)-1] = append(turns[len(turns)-1], i)
+		}
+	}
+
+	if total <= budget {
+		return true
+	}
+
+	// Drop the oldest turns, always keeping the most recent one.
+	dropped := make(map[int]bool)
+	droppedTurns := 0
+	for _, turn := range turns[:max(len(turns)-1, 0)] {
+		if total <= budget {
+			break
+		}
+		for _, i := range turn {
+			dropped[i] = true
+			total -= tokens[i]
+		}
+		droppedTurns++
+	}
+
+	if len(dropped) > 0 {
+		kept := make([]provider.ProviderMessage, 0, len(provReq.Messages)-len(dropped))
+		for i, msg := range provReq.Messages {
+			if !dropped[i] {
+				kept = append(kept, msg)
+			}
+		}
+		provReq.Messages = kept
+	}
+
+	debug.Log("engine", "truncation",
+		"context_window", window,
+		"budget", budget,
+		"estimated_tokens", total,
+		"dropped_turns", droppedTurns,
+		"dropped_messages", len(dropped),
+	)
+
+	return total <= budget
+}
+
+// messageTokens estimates the tokens of a provider message, including tool
+// calls and the per-message overhead.
+func messageTokens(counter TokenCounter, msg provider.ProviderMessage) int {
+	n := messageTokenOverhead + counter.CountTokens(msg.Role)
+	switch content := msg.Content.(type) {
+	case nil:
+	case string:
+		n += counter.CountTokens(content)
+	default:
+		// Multimodal content parts: size the serialized form.
+		if data, err := json.Marshal(content); err == nil {
+			n += counter.CountTokens(string(data))
+		}
+	}
+	for _, tc := range msg.ToolCalls {
+		n += counter.CountTokens(tc.Function.Name) + counter.CountTokens(tc.Function.Arguments)
+	}
+	return n
+}
+
+// toolTokens estimates the tokens taken by tool definitions, which count
+// against the context window alongside the messages.
+func toolTokens(counter TokenCounter, tools []provider.ProviderTool) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return 0
+	}
+	return counter.CountTokens(string(data))
+}
+
+// writeContextLengthExceeded returns an incomplete response without calling
+// the provider, for requests that cannot fit the context window even after
+// truncation.
+func (e *Engine) writeContextLengthExceeded(ctx context.Context, req *api.CreateResponseRequest, w transport.ResponseWriter) error {
+	resp := buildResponseFromRequest(req, api.ResponseStatusIncomplete)
+	resp.IncompleteDetails = &api.IncompleteDetails{Reason: incompleteReasonContextLength}
+
+	if !req.Stream {
+		if err := w.WriteResponse(ctx, resp); err != nil {
+			return err
+		}
+		e.saveIfStateful(ctx, req, resp)
+		return nil
+	}
+
+	state := &streamState{}
+	created := snapshotResponse(resp)
+	created.Status = api.ResponseStatusInProgress
+	created.IncompleteDetails = nil
+	if err := w.WriteEvent(ctx, api.StreamEvent{
+		Type:           api.EventResponseCreated,
+		SequenceNumber: state.nextSeq(),
+		Response:       created,
+	}); err != nil {
+		return err
+	}
+	if err := w.WriteEvent(ctx, api.StreamEvent{
+		Type:           api.EventResponseIncomplete,
+		SequenceNumber: state.nextSeq(),
+		Response:       snapshotResponse(resp),
+	}); err != nil {
+		return err
+	}
+	e.saveIfStateful(ctx, req, resp)
+	return nil
+}
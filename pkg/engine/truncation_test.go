@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/storage/memory"
+	"github.com/rhuss/antwort/pkg/transport"
+)
+
+// wordCounter counts one token per whitespace-separated word.
+type wordCounter struct{}
+
+func (wordCounter) CountTokens(text string) int { return len(strings.Fields(text)) }
+
+func newTruncationEngine(t *testing.T, window int, store transport.ResponseStore) (*Engine, *capturingProvider) {
+	t.Helper()
+	mp := newConversationTestProvider("ok")
+	mp.caps.MaxContextWindow = window
+	eng, err := New(mp, store, Config{TokenCounter: wordCounter{}})
+	if err != nil {
+		t.Fatalf("failed to create engine: %v", err)
+	}
+	return eng, mp
+}
+
+// words returns a string of n words.
+func words(n int) string {
+	return strings.TrimSpace(strings.Repeat("w ", n))
+}
+
+func truncationHistory() []provider.ProviderMessage {
+	return []provider.ProviderMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: words(20)},
+		{Role: "assistant", ToolCalls: []provider.ProviderToolCall{{ID: "call_1", Type: "function", Function: provider.ProviderFunctionCall{Name: "lookup", Arguments: "{}"}}}},
+		{Role: "tool", ToolCallID: "call_1", Content: words(20)},
+		{Role: "assistant", Content: words(10)},
+		{Role: "user", Content: words(5)},
+		{Role: "assistant", Content: words(5)},
+		{Role: "user", Content: "latest question"},
+	}
+}
+
+func TestTruncateMessages_DropsOldestTurns(t *testing.T) {
+	eng, _ := newTruncationEngine(t, 60, nil)
+	maxOut := 10
+	req := &api.CreateResponseRequest{Model: "m", Truncation: "auto", MaxOutputTokens: &maxOut}
+	provReq := &provider.ProviderRequest{Messages: truncationHistory()}
+
+	if !eng.truncateMessages(req, provReq) {
+		t.Fatal("expected request to fit after truncation")
+	}
+
+	var roles []string
+	for _, msg := range provReq.Messages {
+		roles = append(roles, msg.Role)
+	}
+	// The first turn (user, tool call, tool output, reply) is dropped as a
+	// unit; the system message and the later turns remain.
+	want := "system,user,assistant,user"
+	if got := strings.Join(roles, ","); got != want {
+		t.Errorf("roles = %s, want %s", got, want)
+	}
+	if provReq.Messages[len(provReq.Messages)-1].Content != "latest question" {
+		t.Error("latest turn was dropped")
+	}
+}
+
+func TestTruncateMessages_Disabled(t *testing.T) {
+	eng, _ := newTruncationEngine(t, 10, nil)
+	req := &api.CreateResponseRequest{Model: "m"}
+	provReq := &provider.ProviderRequest{Messages: truncationHistory()}
+
+	if !eng.truncateMessages(req, provReq) {
+		t.Fatal("truncation disabled should never report overflow")
+	}
+	if len(provReq.Messages) != len(truncationHistory()) {
+		t.Errorf("messages trimmed with truncation disabled: %d", len(provReq.Messages))
+	}
+}
+
+func TestTruncateMessages_UnknownContextWindow(t *testing.T) {
+	eng, _ := newTruncationEngine(t, 0, nil)
+	req := &api.CreateResponseRequest{Model: "m", Truncation: "auto"}
+	provReq := &provider.ProviderRequest{Messages: truncationHistory()}
+
+	if !eng.truncateMessages(req, provReq) || len(provReq.Messages) != len(truncationHistory()) {
+		t.Error("expected no truncation without a known context window")
+	}
+}
+
+func TestCreateResponse_TruncationContextLengthExceeded(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		store := memory.New(0)
+		eng, mp := newTruncationEngine(t, 8, store)
+		w := &mockResponseWriter{}
+		req := &api.CreateResponseRequest{Model: "m", Stream: stream, Truncation: "auto", Input: userInput(words(50))}
+
+		if err := eng.CreateResponse(context.Background(), req, w); err != nil {
+			t.Fatalf("stream=%v: CreateResponse failed: %v", stream, err)
+		}
+		if mp.lastReq != nil {
+			t.Errorf("stream=%v: provider called for oversized request", stream)
+		}
+
+		resp := w.response
+		if stream {
+			if len(w.events) == 0 {
+				t.Fatalf("stream=%v: no events written", stream)
+			}
+			last := w.events[len(w.events)-1]
+			if last.Type != api.EventResponseIncomplete {
+				t.Fatalf("stream=%v: last event = %s, want %s", stream, last.Type, api.EventResponseIncomplete)
+			}
+			resp = last.Response
+		}
+		if resp == nil || resp.Status != api.ResponseStatusIncomplete {
+			t.Fatalf("stream=%v: expected incomplete response, got %+v", stream, resp)
+		}
+		if resp.IncompleteDetails == nil || resp.IncompleteDetails.Reason != incompleteReasonContextLength {
+			t.Errorf("stream=%v: incomplete_details = %+v", stream, resp.IncompleteDetails)
+		}
+		if saved, err := store.GetResponse(context.Background(), resp.ID); err != nil || saved.Status != api.ResponseStatusIncomplete {
+			t.Errorf("stream=%v: saved response = %+v, err = %v", stream, saved, err)
+		}
+	}
+}
+
+func TestHeuristicTokenCounter(t *testing.T) {
+	var c HeuristicTokenCounter
+	if n := c.CountTokens(""); n != 0 {
+		t.Errorf("empty = %d, want 0", n)
+	}
+	if n := c.CountTokens("abcdefgh"); n != 2 {
+		t.Errorf("8 chars = %d, want 2", n)
+	}
+	if n := c.CountTokens("abcde"); n != 2 {
+		t.Errorf("5 chars = %d, want 2", n)
+	}
+}
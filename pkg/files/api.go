@@ -1,6 +1,7 @@
 package files
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -107,40 +108,50 @@ func (a *FilesAPI) handleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := userFromCtx(r.Context())
-	tenantID := storage.GetTenant(r.Context())
-	fileID := api.NewFileID()
-	file := NewFile(fileID, filename, fileMIMEType, purpose, userID, int64(len(fileData)))
-	file.TenantID = tenantID
-
 	// Parse permissions if provided.
+	var permissions string
 	if permissionsRaw != "" {
-		file.Permissions = parseFilePermissions(permissionsRaw)
+		permissions = parseFilePermissions(permissionsRaw)
 	}
 
-	// Store file content.
-	if err := a.fileStore.Store(r.Context(), fileID, newBytesReader(fileData)); err != nil {
-		a.logger.Error("failed to store file", "error", err)
+	file, err := a.saveFile(r.Context(), filename, fileMIMEType, purpose, permissions, fileData)
+	if err != nil {
+		a.logger.Error("failed to save file", "error", err)
 		writeAPIError(w, api.NewServerError("failed to store file"))
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(file)
+}
+
+// saveFile stores the content and metadata of a new file owned by the
+// caller in ctx. Empty permissions mean the default permissions.
+func (a *FilesAPI) saveFile(ctx context.Context, filename, mimeType, purpose, permissions string, content []byte) (*File, error) {
+	fileID := api.NewFileID()
+	file := NewFile(fileID, filename, mimeType, purpose, userFromCtx(ctx), int64(len(content)))
+	file.TenantID = storage.GetTenant(ctx)
+	if permissions != "" {
+		file.Permissions = permissions
+	}
+
+	// Store file content.
+	if err := a.fileStore.Store(ctx, fileID, newBytesReader(content)); err != nil {
+		return nil, fmt.Errorf("storing content: %w", err)
+	}
+
 	// Save metadata.
-	if err := a.metadata.Save(r.Context(), file); err != nil {
-		a.logger.Error("failed to save file metadata", "error", err)
-		writeAPIError(w, api.NewServerError("failed to save file metadata"))
-		return
+	if err := a.metadata.Save(ctx, file); err != nil {
+		return nil, fmt.Errorf("saving metadata: %w", err)
 	}
 
 	// Record upload metrics.
-	filesUploadedTotal.WithLabelValues(fileMIMEType).Inc()
-	observability.FilesUploadedTotal.WithLabelValues(fileMIMEType).Inc()
-
-	a.auditLogger.Log(r.Context(), "resource.created", "resource_type", "file", "resource_id", fileID)
+	filesUploadedTotal.WithLabelValues(mimeType).Inc()
+	observability.FilesUploadedTotal.WithLabelValues(mimeType).Inc()
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(file)
+	a.auditLogger.Log(ctx, "resource.created", "resource_type", "file", "resource_id", fileID)
+	return file, nil
 }
 
 func (a *FilesAPI) handleListFiles(w http.ResponseWriter, r *http.Request) {
@@ -465,3 +465,97 @@ func TestFilesAPI_DetectMIME(t *testing.T) {
 		})
 	}
 }
+
+func TestFilesProvider_SaveOutputFile(t *testing.T) {
+	fileStore := NewMemoryFileStore()
+	metadata := NewMemoryMetadataStore()
+	p := &FilesProvider{filesAPI: &FilesAPI{
+		fileStore:     fileStore,
+		metadata:      metadata,
+		maxUploadSize: 16,
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}}
+	ctx := auth.SetIdentity(context.Background(), &auth.Identity{Subject: "alice"})
+
+	fileID, err := p.SaveOutputFile(ctx, "chart.png", []byte("png data"))
+	if err != nil {
+		t.Fatalf("SaveOutputFile: %v", err)
+	}
+	if !strings.HasPrefix(fileID, "file_") {
+		t.Errorf("file ID %q should start with file_", fileID)
+	}
+
+	file, err := metadata.Get(ctx, fileID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if file.Purpose != string(FilePurposeAssistantsOutput) {
+		t.Errorf("purpose: got %q, want assistants_output", file.Purpose)
+	}
+	if file.UserID != "alice" || file.MIMEType != "image/png" || file.Filename != "chart.png" {
+		t.Errorf("unexpected file: %+v", file)
+	}
+
+	// Other users cannot see the file.
+	other := auth.SetIdentity(context.Background(), &auth.Identity{Subject: "bob"})
+	if _, err := metadata.Get(other, fileID); err == nil {
+		t.Error("expected other user to be denied access")
+	}
+
+	rc, err := fileStore.Retrieve(ctx, fileID)
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	defer rc.Close()
+	if data, _ := io.ReadAll(rc); string(data) != "png data" {
+		t.Errorf("content: got %q", data)
+	}
+
+	if _, err := p.SaveOutputFile(ctx, "big.csv", make([]byte, 17)); err == nil {
+		t.Error("expected error for file over the size limit")
+	}
+}
+
+func TestFilesProvider_ReadFile(t *testing.T) {
+	p := &FilesProvider{filesAPI: &FilesAPI{
+		fileStore:     NewMemoryFileStore(),
+		metadata:      NewMemoryMetadataStore(),
+		maxUploadSize: 1024,
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}}
+	ctx := auth.SetIdentity(context.Background(), &auth.Identity{Subject: "alice"})
+
+	fileID, err := p.SaveOutputFile(ctx, "sales.csv", []byte("a,b\n1,2\n"))
+	if err != nil {
+		t.Fatalf("SaveOutputFile: %v", err)
+	}
+
+	name, content, err := p.ReadFile(ctx, fileID, 100)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if name != "sales.csv" || string(content) != "a,b\n1,2\n" {
+		t.Errorf("ReadFile = %q, %q", name, content)
+	}
+
+	if _, _, err := p.ReadFile(ctx, fileID, 4); err == nil || !strings.Contains(err.Error(), "exceeding the limit of 4 bytes") {
+		t.Errorf("expected size limit error, got %v", err)
+	}
+
+	other := auth.SetIdentity(context.Background(), &auth.Identity{Subject: "bob"})
+	if _, _, err := p.ReadFile(other, fileID, 100); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected other user to be denied access, got %v", err)
+	}
+	if _, _, err := p.ReadFile(context.Background(), fileID, 100); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected access without a caller to be denied, got %v", err)
+	}
+
+	// Without authentication, files belong to no user and stay readable.
+	anonID, err := p.SaveOutputFile(context.Background(), "notes.txt", []byte("hi"))
+	if err != nil {
+		t.Fatalf("SaveOutputFile: %v", err)
+	}
+	if _, _, err := p.ReadFile(context.Background(), anonID, 100); err != nil {
+		t.Errorf("ReadFile without authentication: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package files
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"time"
 
@@ -218,6 +219,50 @@ func (p *FilesProvider) Collectors() []prometheus.Collector {
 	}
 }
 
+// SaveOutputFile stores a file produced by a tool, owned by the caller in
+// ctx, with purpose assistants_output. It returns the ID under which the
+// file can be downloaded.
+func (p *FilesProvider) SaveOutputFile(ctx context.Context, filename string, content []byte) (string, error) {
+	if int64(len(content)) > p.filesAPI.maxUploadSize {
+		return "", fmt.Errorf("file %q exceeds maximum size of %d bytes", filename, p.filesAPI.maxUploadSize)
+	}
+	file, err := p.filesAPI.saveFile(ctx, filename, detectMIME(filename, ""), string(FilePurposeAssistantsOutput), "", content)
+	if err != nil {
+		return "", err
+	}
+	return file.ID, nil
+}
+
+// ReadFile returns the name and content of a file the caller in ctx may
+// read, so that tools can work with uploaded files. Files of a user are
+// not readable without a caller, since metadata lookups then skip the
+// ownership check. Files larger than maxBytes are rejected without reading
+// them.
+func (p *FilesProvider) ReadFile(ctx context.Context, fileID string, maxBytes int64) (string, []byte, error) {
+	file, err := p.filesAPI.metadata.Get(ctx, fileID)
+	if err != nil || (file.UserID != "" && userFromCtx(ctx) == "") {
+		return "", nil, fmt.Errorf("file %s not found", fileID)
+	}
+	if file.Bytes > maxBytes {
+		return "", nil, fmt.Errorf("file %s (%s) is %d bytes, exceeding the limit of %d bytes", fileID, file.Filename, file.Bytes, maxBytes)
+	}
+
+	reader, err := p.filesAPI.fileStore.Retrieve(ctx, fileID)
+	if err != nil {
+		return "", nil, fmt.Errorf("retrieving file %s: %w", fileID, err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(io.LimitReader(reader, maxBytes+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("reading file %s: %w", fileID, err)
+	}
+	if int64(len(content)) > maxBytes {
+		return "", nil, fmt.Errorf("file %s (%s) exceeds the limit of %d bytes", fileID, file.Filename, maxBytes)
+	}
+	return file.Filename, content, nil
+}
+
 // SetAuditLogger sets the audit logger for resource mutation events.
 func (p *FilesProvider) SetAuditLogger(l *audit.Logger) {
 	p.filesAPI.auditLogger = l
@@ -20,9 +20,14 @@ const (
 	FilePurposeBatch      FilePurpose = "batch"
 	FilePurposeFineTune   FilePurpose = "fine-tune"
 	FilePurposeVision     FilePurpose = "vision"
+
+	// FilePurposeAssistantsOutput marks files produced by tools, such as
+	// the charts the code interpreter draws. Clients cannot upload them.
+	FilePurposeAssistantsOutput FilePurpose = "assistants_output"
 )
 
-// ValidPurpose checks whether the given string is a recognized file purpose.
+// ValidPurpose checks whether the given string is a recognized purpose
+// for uploaded files.
 func ValidPurpose(s string) bool {
 	switch FilePurpose(s) {
 	case FilePurposeAssistants, FilePurposeBatch, FilePurposeFineTune, FilePurposeVision:
@@ -366,6 +366,33 @@ var (
 		},
 		[]string{"provider"},
 	)
+
+	// MCPServerUp reports whether an MCP server passed its last health check.
+	MCPServerUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "antwort_mcp_server_up",
+			Help: "Whether the MCP server is connected and healthy (1) or not (0)",
+		},
+		[]string{"server"},
+	)
+
+	// MCPServerTools tracks the number of tools offered by each MCP server.
+	MCPServerTools = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "antwort_mcp_server_tools",
+			Help: "Tools offered by the MCP server",
+		},
+		[]string{"server"},
+	)
+
+	// MCPReconnectsTotal counts re-established MCP sessions.
+	MCPReconnectsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "antwort_mcp_reconnects_total",
+			Help: "MCP sessions re-established after a failure",
+		},
+		[]string{"server"},
+	)
 )
 
 // OTel GenAI semantic convention metrics.
@@ -469,6 +496,11 @@ func init() {
 		ResilienceConsecutiveFailures,
 		ResilienceRetryAttemptsTotal,
 		ResilienceRetryExhaustedTotal,
+
+		// MCP servers.
+		MCPServerUp,
+		MCPServerTools,
+		MCPReconnectsTotal,
 	)
 }
 
@@ -0,0 +1,128 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of all antwort spans.
+const tracerName = "github.com/rhuss/antwort"
+
+// TracingConfig holds OpenTelemetry tracing settings.
+type TracingConfig struct {
+	// Enabled installs an OTLP exporter. When false, the global no-op
+	// tracer provider stays in place and spans cost next to nothing.
+	Enabled bool
+
+	// Endpoint is the OTLP/HTTP collector endpoint as host:port (e.g.
+	// "otel-collector:4318"). Empty uses the OTEL_EXPORTER_OTLP_* env vars.
+	Endpoint string
+
+	// Insecure disables TLS for the exporter connection.
+	Insecure bool
+
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string
+
+	// SampleRate is the fraction of new traces that are sampled (0..1).
+	// Traces started by a sampled upstream caller are always sampled.
+	SampleRate float64
+}
+
+// InitTracing installs the global tracer provider and the W3C trace
+// context propagator. The returned function flushes and stops the
+// exporter; it is a no-op when tracing is disabled.
+func InitTracing(ctx context.Context, cfg TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("creating trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRate))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the tracer for antwort spans.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// RecordSpanError marks the span as failed if err is non-nil.
+func RecordSpanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// TracingMiddleware extracts W3C trace context (traceparent, tracestate)
+// from incoming requests, so that spans started while handling the
+// request join the caller's trace.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// NewTracingTransport returns an http.RoundTripper that injects the trace
+// context of each request's context into its headers before delegating to
+// base (http.DefaultTransport if nil).
+func NewTracingTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tracingTransport{base: base}
+}
+
+// tracingTransport propagates trace context to outgoing HTTP requests.
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request.
+	req = req.Clone(req.Context())
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,45 @@
+package observability
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingPropagation(t *testing.T) {
+	if _, err := InitTracing(context.Background(), TracingConfig{}); err != nil {
+		t.Fatalf("InitTracing: %v", err)
+	}
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	var outgoing string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outgoing = r.Header.Get("traceparent")
+	}))
+	defer backend.Close()
+
+	client := &http.Client{Transport: NewTracingTransport(nil)}
+	handler := TracingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := trace.SpanContextFromContext(r.Context()).TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("extracted trace id = %q", got)
+		}
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, backend.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("outgoing request: %v", err)
+		}
+		resp.Body.Close()
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/responses", nil)
+	req.Header.Set("traceparent", traceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if outgoing != traceparent {
+		t.Errorf("outgoing traceparent = %q, want %q", outgoing, traceparent)
+	}
+}
@@ -17,6 +17,10 @@ type Config struct {
 	// For example: {"gpt-4": "openai/gpt-4", "claude": "anthropic/claude-3-opus"}.
 	// If a model is not in the map, it is passed through unchanged.
 	ModelMapping map[string]string
+
+	// MaxContextWindow is the backend's context window in tokens,
+	// reported via Capabilities (0 = unknown).
+	MaxContextWindow int
 }
 
 // DefaultConfig returns a Config with sensible defaults.
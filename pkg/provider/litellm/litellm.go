@@ -50,9 +50,10 @@ func New(cfg Config) (*LiteLLMProvider, error) {
 		cfg:    cfg,
 		client: client,
 		caps: provider.ProviderCapabilities{
-			Streaming:   true,
-			ToolCalling: true,
-			Vision:      true,
+			Streaming:        true,
+			ToolCalling:      true,
+			Vision:           true,
+			MaxContextWindow: cfg.MaxContextWindow,
 		},
 	}, nil
 }
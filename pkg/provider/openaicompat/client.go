@@ -12,6 +12,7 @@ import (
 
 	"github.com/rhuss/antwort/pkg/api"
 	"github.com/rhuss/antwort/pkg/debug"
+	"github.com/rhuss/antwort/pkg/observability"
 	"github.com/rhuss/antwort/pkg/provider"
 )
 
@@ -41,7 +42,8 @@ func NewClient(baseURL, apiKey string, timeout time.Duration) *Client {
 
 	return &Client{
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Transport: observability.NewTracingTransport(nil),
+			Timeout:   timeout,
 		},
 		baseURL: baseURL,
 		apiKey:  apiKey,
@@ -270,6 +272,7 @@ func (c *Client) ListModels(ctx context.Context) ([]provider.ModelInfo, error) {
 		models = append(models, provider.ModelInfo{
 			ID:      m.ID,
 			Object:  m.Object,
+			Created: m.Created,
 			OwnedBy: m.OwnedBy,
 		})
 	}
@@ -146,5 +146,6 @@ type ChatModelsResponse struct {
 type ChatModel struct {
 	ID      string `json:"id"`
 	Object  string `json:"object"`
+	Created int64  `json:"created,omitempty"`
 	OwnedBy string `json:"owned_by"`
 }
@@ -43,3 +43,20 @@ type Translator interface {
 	// TranslateResponse converts a provider response to OpenResponses items.
 	TranslateResponse(ctx context.Context, resp *ProviderResponse) ([]api.Item, *api.Usage, error)
 }
+
+// ModelCapabilities is an optional interface for providers whose
+// capabilities depend on the requested model (e.g., a router spanning
+// several backends). The engine prefers it over Capabilities when present.
+type ModelCapabilities interface {
+	// CapabilitiesFor returns what the provider supports for the given model.
+	CapabilitiesFor(model string) ProviderCapabilities
+}
+
+// CapabilitiesFor returns the capabilities of p for the given model, using
+// ModelCapabilities when p implements it and Capabilities otherwise.
+func CapabilitiesFor(p Provider, model string) ProviderCapabilities {
+	if mc, ok := p.(ModelCapabilities); ok {
+		return mc.CapabilitiesFor(model)
+	}
+	return p.Capabilities()
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/rhuss/antwort/pkg/observability"
 	"github.com/rhuss/antwort/pkg/provider"
 )
 
@@ -31,6 +32,10 @@ type Config struct {
 	BaseURL string
 	APIKey  string
 	Timeout time.Duration
+
+	// MaxContextWindow is the backend's context window in tokens,
+	// reported via Capabilities (0 = unknown).
+	MaxContextWindow int
 }
 
 // New creates a new ResponsesProvider. It validates that the backend supports
@@ -47,12 +52,14 @@ func New(cfg Config) (*ResponsesProvider, error) {
 		baseURL: cfg.BaseURL,
 		apiKey:  cfg.APIKey,
 		httpClient: &http.Client{
-			Timeout: cfg.Timeout,
+			Transport: observability.NewTracingTransport(nil),
+			Timeout:   cfg.Timeout,
 		},
 		caps: provider.ProviderCapabilities{
-			Streaming:   true,
-			ToolCalling: true,
-			Vision:      true,
+			Streaming:        true,
+			ToolCalling:      true,
+			Vision:           true,
+			MaxContextWindow: cfg.MaxContextWindow,
 		},
 	}
 
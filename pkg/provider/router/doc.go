@@ -0,0 +1,7 @@
+// Package router implements a Provider that routes requests across
+// multiple named backends by model. Each backend is a complete provider
+// (vLLM, LiteLLM, Responses API) with its own URL, credentials and
+// resilience settings; models are matched by exact name or glob pattern.
+// ListModels merges the models of all backends, and requests for models
+// no backend serves fail with a not-found API error.
+package router
@@ -0,0 +1,194 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sync"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/debug"
+	"github.com/rhuss/antwort/pkg/provider"
+)
+
+// Backend is a named provider serving the models matched by Models.
+// Entries in Models are exact model names or glob patterns as understood
+// by path.Match (e.g. "llama-*").
+type Backend struct {
+	Name     string
+	Models   []string
+	Provider provider.Provider
+}
+
+// Router is a provider.Provider that dispatches each request to the
+// backend serving the requested model. Exact model names take precedence
+// over glob patterns; among patterns, the first matching backend in
+// configuration order wins.
+type Router struct {
+	backends []Backend
+}
+
+// Compile-time check that Router implements the optional capability interface.
+var _ provider.ModelCapabilities = (*Router)(nil)
+
+// New creates a Router over the given backends. At least one backend is
+// required, and backend names must be unique.
+func New(backends []Backend) (*Router, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("router: at least one backend is required")
+	}
+	seen := make(map[string]bool, len(backends))
+	for _, b := range backends {
+		if b.Name == "" {
+			return nil, errors.New("router: backend name is required")
+		}
+		if seen[b.Name] {
+			return nil, fmt.Errorf("router: duplicate backend %q", b.Name)
+		}
+		seen[b.Name] = true
+		if b.Provider == nil {
+			return nil, fmt.Errorf("router: backend %q has no provider", b.Name)
+		}
+		for _, m := range b.Models {
+			if _, err := path.Match(m, ""); err != nil {
+				return nil, fmt.Errorf("router: backend %q: invalid model pattern %q", b.Name, m)
+			}
+		}
+	}
+	return &Router{backends: backends}, nil
+}
+
+// Name returns the provider identifier.
+func (r *Router) Name() string {
+	return "router"
+}
+
+// Capabilities returns the union of all backend capabilities. Use
+// CapabilitiesFor to get the capabilities of the backend serving a model.
+func (r *Router) Capabilities() provider.ProviderCapabilities {
+	var caps provider.ProviderCapabilities
+	for _, b := range r.backends {
+		bc := b.Provider.Capabilities()
+		caps.Streaming = caps.Streaming || bc.Streaming
+		caps.ToolCalling = caps.ToolCalling || bc.ToolCalling
+		caps.Vision = caps.Vision || bc.Vision
+		caps.Audio = caps.Audio || bc.Audio
+		caps.Reasoning = caps.Reasoning || bc.Reasoning
+		caps.SupportedModels = append(caps.SupportedModels, bc.SupportedModels...)
+		caps.Extensions = append(caps.Extensions, bc.Extensions...)
+	}
+	return caps
+}
+
+// CapabilitiesFor returns the capabilities of the backend serving model.
+// Unknown models get the union of all capabilities so that request
+// validation passes and the request fails with a not-found error instead.
+func (r *Router) CapabilitiesFor(model string) provider.ProviderCapabilities {
+	if b, ok := r.route(model); ok {
+		return provider.CapabilitiesFor(b.Provider, model)
+	}
+	return r.Capabilities()
+}
+
+// Complete routes a non-streaming request to the backend serving its model.
+func (r *Router) Complete(ctx context.Context, req *provider.ProviderRequest) (*provider.ProviderResponse, error) {
+	b, err := r.resolve(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	return b.Provider.Complete(ctx, req)
+}
+
+// Stream routes a streaming request to the backend serving its model.
+func (r *Router) Stream(ctx context.Context, req *provider.ProviderRequest) (<-chan provider.ProviderEvent, error) {
+	b, err := r.resolve(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	return b.Provider.Stream(ctx, req)
+}
+
+// ListModels merges the models of all backends, queried concurrently. A
+// model listed by several backends appears once. Backends that fail are
+// logged and skipped; an error is returned only if every backend fails.
+func (r *Router) ListModels(ctx context.Context) ([]provider.ModelInfo, error) {
+	results := make([][]provider.ModelInfo, len(r.backends))
+	errs := make([]error, len(r.backends))
+
+	var wg sync.WaitGroup
+	for i, b := range r.backends {
+		wg.Add(1)
+		go func(i int, b Backend) {
+			defer wg.Done()
+			results[i], errs[i] = b.Provider.ListModels(ctx)
+		}(i, b)
+	}
+	wg.Wait()
+
+	var models []provider.ModelInfo
+	seen := make(map[string]bool)
+	failed := 0
+	for i, b := range r.backends {
+		if errs[i] != nil {
+			failed++
+			slog.Warn("failed to list backend models", "backend", b.Name, "error", errs[i].Error())
+			continue
+		}
+		for _, m := range results[i] {
+			if seen[m.ID] {
+				continue
+			}
+			seen[m.ID] = true
+			models = append(models, m)
+		}
+	}
+
+	if failed == len(r.backends) {
+		return nil, fmt.Errorf("listing models: %w", errors.Join(errs...))
+	}
+	return models, nil
+}
+
+// Close closes all backend providers.
+func (r *Router) Close() error {
+	var errs []error
+	for _, b := range r.backends {
+		if err := b.Provider.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing backend %q: %w", b.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolve returns the backend serving model, or a not-found API error.
+func (r *Router) resolve(model string) (Backend, error) {
+	b, ok := r.route(model)
+	if !ok {
+		debug.Log("providers", "no backend for model", "model", model)
+		return Backend{}, api.NewNotFoundError(fmt.Sprintf("model %q not found", model))
+	}
+	debug.Log("providers", "routing request", "model", model, "backend", b.Name)
+	return b, nil
+}
+
+// route finds the backend for model: an exact name match in any backend
+// first, then the first backend with a matching glob pattern.
+func (r *Router) route(model string) (Backend, bool) {
+	for _, b := range r.backends {
+		for _, m := range b.Models {
+			if m == model {
+				return b, true
+			}
+		}
+	}
+	for _, b := range r.backends {
+		for _, m := range b.Models {
+			if ok, _ := path.Match(m, model); ok {
+				return b, true
+			}
+		}
+	}
+	return Backend{}, false
+}
@@ -0,0 +1,164 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+)
+
+// mockProvider is a test double for provider.Provider that reports its
+// name as the response model.
+type mockProvider struct {
+	name    string
+	caps    provider.ProviderCapabilities
+	models  []provider.ModelInfo
+	listErr error
+	closed  bool
+}
+
+func (m *mockProvider) Name() string                                { return m.name }
+func (m *mockProvider) Capabilities() provider.ProviderCapabilities { return m.caps }
+func (m *mockProvider) Close() error                                { m.closed = true; return nil }
+
+func (m *mockProvider) ListModels(ctx context.Context) ([]provider.ModelInfo, error) {
+	return m.models, m.listErr
+}
+
+func (m *mockProvider) Complete(ctx context.Context, req *provider.ProviderRequest) (*provider.ProviderResponse, error) {
+	return &provider.ProviderResponse{Model: m.name}, nil
+}
+
+func (m *mockProvider) Stream(ctx context.Context, req *provider.ProviderRequest) (<-chan provider.ProviderEvent, error) {
+	ch := make(chan provider.ProviderEvent)
+	close(ch)
+	return ch, nil
+}
+
+func newTestRouter(t *testing.T) (*Router, *mockProvider, *mockProvider) {
+	t.Helper()
+	llama := &mockProvider{
+		name:   "llama",
+		caps:   provider.ProviderCapabilities{Streaming: true, MaxContextWindow: 8192},
+		models: []provider.ModelInfo{{ID: "llama-3-8b"}, {ID: "shared"}},
+	}
+	proxy := &mockProvider{
+		name:   "proxy",
+		caps:   provider.ProviderCapabilities{ToolCalling: true},
+		models: []provider.ModelInfo{{ID: "gpt-4o"}, {ID: "shared"}},
+	}
+	r, err := New([]Backend{
+		{Name: "llama", Models: []string{"llama-*"}, Provider: llama},
+		{Name: "proxy", Models: []string{"gpt-*", "llama-special"}, Provider: proxy},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return r, llama, proxy
+}
+
+func TestRouter_Complete(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+
+	tests := []struct {
+		model   string
+		backend string
+	}{
+		{"llama-3-8b", "llama"},
+		{"gpt-4o", "proxy"},
+		// Exact names take precedence over earlier glob patterns.
+		{"llama-special", "proxy"},
+	}
+	for _, tt := range tests {
+		resp, err := r.Complete(context.Background(), &provider.ProviderRequest{Model: tt.model})
+		if err != nil {
+			t.Fatalf("%s: Complete failed: %v", tt.model, err)
+		}
+		if resp.Model != tt.backend {
+			t.Errorf("%s: routed to %q, want %q", tt.model, resp.Model, tt.backend)
+		}
+	}
+}
+
+func TestRouter_UnknownModel(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+
+	_, err := r.Complete(context.Background(), &provider.ProviderRequest{Model: "mistral"})
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) || apiErr.Type != api.ErrorTypeNotFound {
+		t.Fatalf("Complete: expected not_found error, got %v", err)
+	}
+
+	_, err = r.Stream(context.Background(), &provider.ProviderRequest{Model: "mistral"})
+	if !errors.As(err, &apiErr) || apiErr.Type != api.ErrorTypeNotFound {
+		t.Fatalf("Stream: expected not_found error, got %v", err)
+	}
+}
+
+func TestRouter_CapabilitiesFor(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+
+	caps := r.CapabilitiesFor("llama-3-8b")
+	if !caps.Streaming || caps.ToolCalling || caps.MaxContextWindow != 8192 {
+		t.Errorf("llama caps = %+v", caps)
+	}
+
+	// Unknown models get the union so the request fails with not found.
+	caps = r.CapabilitiesFor("mistral")
+	if !caps.Streaming || !caps.ToolCalling {
+		t.Errorf("union caps = %+v", caps)
+	}
+}
+
+func TestRouter_ListModels(t *testing.T) {
+	r, _, proxy := newTestRouter(t)
+
+	models, err := r.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 3 {
+		t.Errorf("got %d models, want 3 (deduplicated): %+v", len(models), models)
+	}
+
+	// A failing backend is skipped.
+	proxy.listErr = errors.New("unreachable")
+	models, err = r.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels with one failing backend: %v", err)
+	}
+	if len(models) != 2 {
+		t.Errorf("got %d models, want 2", len(models))
+	}
+}
+
+func TestRouter_Close(t *testing.T) {
+	r, llama, proxy := newTestRouter(t)
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !llama.closed || !proxy.closed {
+		t.Error("not all backends were closed")
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	p := &mockProvider{name: "p"}
+	tests := []struct {
+		name     string
+		backends []Backend
+	}{
+		{"empty", nil},
+		{"missing name", []Backend{{Models: []string{"*"}, Provider: p}}},
+		{"duplicate name", []Backend{{Name: "a", Provider: p}, {Name: "a", Provider: p}}},
+		{"missing provider", []Backend{{Name: "a"}}},
+		{"bad pattern", []Backend{{Name: "a", Models: []string{"["}, Provider: p}}},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.backends); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Package tracing wraps a provider.Provider with OpenTelemetry spans that
+// follow the GenAI semantic conventions (gen_ai.* attributes).
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/observability"
+	"github.com/rhuss/antwort/pkg/provider"
+)
+
+// TracedProvider wraps a provider.Provider and records a client span per
+// Complete or Stream call. Streaming spans end when the event channel is
+// closed, so they cover the whole generation.
+type TracedProvider struct {
+	inner provider.Provider
+}
+
+// Wrap creates a TracedProvider wrapping the given provider.
+func Wrap(inner provider.Provider) provider.Provider {
+	return &TracedProvider{inner: inner}
+}
+
+// Name delegates to the wrapped provider.
+func (t *TracedProvider) Name() string {
+	return t.inner.Name()
+}
+
+// Capabilities delegates to the wrapped provider.
+func (t *TracedProvider) Capabilities() provider.ProviderCapabilities {
+	return t.inner.Capabilities()
+}
+
+// CapabilitiesFor delegates to the wrapped provider.
+func (t *TracedProvider) CapabilitiesFor(model string) provider.ProviderCapabilities {
+	return provider.CapabilitiesFor(t.inner, model)
+}
+
+// ListModels delegates to the wrapped provider without tracing.
+func (t *TracedProvider) ListModels(ctx context.Context) ([]provider.ModelInfo, error) {
+	return t.inner.ListModels(ctx)
+}
+
+// Close delegates to the wrapped provider.
+func (t *TracedProvider) Close() error {
+	return t.inner.Close()
+}
+
+// Complete records a span around a non-streaming inference call.
+func (t *TracedProvider) Complete(ctx context.Context, req *provider.ProviderRequest) (*provider.ProviderResponse, error) {
+	ctx, span := t.start(ctx, req)
+	defer span.End()
+
+	resp, err := t.inner.Complete(ctx, req)
+	if err != nil {
+		observability.RecordSpanError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(
+		semconv.GenAIResponseModel(resp.Model),
+		semconv.GenAIUsageInputTokens(resp.Usage.InputTokens),
+		semconv.GenAIUsageOutputTokens(resp.Usage.OutputTokens),
+		semconv.GenAIResponseFinishReasons(finishReason(resp.Status, hasToolCalls(resp.Items))),
+	)
+	return resp, nil
+}
+
+// Stream records a span around a streaming inference call. Events are
+// forwarded unchanged; usage and errors are taken from the stream.
+func (t *TracedProvider) Stream(ctx context.Context, req *provider.ProviderRequest) (<-chan provider.ProviderEvent, error) {
+	ctx, span := t.start(ctx, req)
+
+	ch, err := t.inner.Stream(ctx, req)
+	if err != nil {
+		observability.RecordSpanError(span, err)
+		span.End()
+		return nil, err
+	}
+
+	out := make(chan provider.ProviderEvent)
+	go func() {
+		defer close(out)
+		defer span.End()
+
+		toolCalls := false
+		for ev := range ch {
+			switch ev.Type {
+			case provider.ProviderEventToolCallDone:
+				toolCalls = true
+			case provider.ProviderEventError:
+				observability.RecordSpanError(span, ev.Err)
+			case provider.ProviderEventDone:
+				if ev.Usage != nil {
+					span.SetAttributes(
+						semconv.GenAIUsageInputTokens(ev.Usage.InputTokens),
+						semconv.GenAIUsageOutputTokens(ev.Usage.OutputTokens),
+					)
+				}
+				span.SetAttributes(semconv.GenAIResponseFinishReasons(finishReason(api.ResponseStatusCompleted, toolCalls)))
+			}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				// The consumer is gone; drain so the inner provider can finish.
+				for range ch {
+				}
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// start begins a "chat {model}" client span with the request attributes.
+func (t *TracedProvider) start(ctx context.Context, req *provider.ProviderRequest) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		semconv.GenAIOperationNameChat,
+		semconv.GenAIProviderNameKey.String(t.inner.Name()),
+		semconv.GenAIRequestModel(req.Model),
+		attribute.Bool("gen_ai.request.stream", req.Stream),
+	}
+	if req.MaxTokens != nil {
+		attrs = append(attrs, semconv.GenAIRequestMaxTokens(*req.MaxTokens))
+	}
+	if req.Temperature != nil {
+		attrs = append(attrs, semconv.GenAIRequestTemperature(*req.Temperature))
+	}
+	if req.TopP != nil {
+		attrs = append(attrs, semconv.GenAIRequestTopP(*req.TopP))
+	}
+	return observability.Tracer().Start(ctx, "chat "+req.Model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// hasToolCalls reports whether the items contain a function call.
+func hasToolCalls(items []api.Item) bool {
+	for _, item := range items {
+		if item.Type == api.ItemTypeFunctionCall {
+			return true
+		}
+	}
+	return false
+}
+
+// finishReason maps a response outcome to an OpenAI-style finish reason.
+func finishReason(status api.ResponseStatus, toolCalls bool) string {
+	switch {
+	case toolCalls:
+		return "tool_calls"
+	case status == api.ResponseStatusIncomplete:
+		return "length"
+	default:
+		return "stop"
+	}
+}
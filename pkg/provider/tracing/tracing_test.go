@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+)
+
+// mockProvider is a test double for provider.Provider.
+type mockProvider struct {
+	resp   *provider.ProviderResponse
+	err    error
+	events []provider.ProviderEvent
+}
+
+func (m *mockProvider) Name() string { return "mock" }
+func (m *mockProvider) Capabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{}
+}
+func (m *mockProvider) ListModels(context.Context) ([]provider.ModelInfo, error) {
+	return nil, nil
+}
+func (m *mockProvider) Close() error { return nil }
+
+func (m *mockProvider) Complete(context.Context, *provider.ProviderRequest) (*provider.ProviderResponse, error) {
+	return m.resp, m.err
+}
+
+func (m *mockProvider) Stream(context.Context, *provider.ProviderRequest) (<-chan provider.ProviderEvent, error) {
+	ch := make(chan provider.ProviderEvent, len(m.events))
+	for _, ev := range m.events {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
+}
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return sr
+}
+
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestComplete_RecordsGenAIAttributes(t *testing.T) {
+	sr := recordSpans(t)
+	maxTokens := 256
+	p := Wrap(&mockProvider{resp: &provider.ProviderResponse{
+		Model:  "llama-3-8b",
+		Status: api.ResponseStatusCompleted,
+		Usage:  api.Usage{InputTokens: 12, OutputTokens: 34},
+	}})
+
+	if _, err := p.Complete(context.Background(), &provider.ProviderRequest{Model: "llama", MaxTokens: &maxTokens}); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 1 || spans[0].Name() != "chat llama" {
+		t.Fatalf("expected one 'chat llama' span, got %v", spans)
+	}
+	got := attrs(spans[0])
+	want := map[attribute.Key]any{
+		"gen_ai.operation.name":      "chat",
+		"gen_ai.provider.name":       "mock",
+		"gen_ai.request.model":       "llama",
+		"gen_ai.request.max_tokens":  int64(256),
+		"gen_ai.response.model":      "llama-3-8b",
+		"gen_ai.usage.input_tokens":  int64(12),
+		"gen_ai.usage.output_tokens": int64(34),
+	}
+	for k, v := range want {
+		if got[k].AsInterface() != v {
+			t.Errorf("%s = %v, want %v", k, got[k].AsInterface(), v)
+		}
+	}
+	if reasons := got["gen_ai.response.finish_reasons"].AsStringSlice(); len(reasons) != 1 || reasons[0] != "stop" {
+		t.Errorf("finish_reasons = %v, want [stop]", reasons)
+	}
+}
+
+func TestComplete_RecordsError(t *testing.T) {
+	sr := recordSpans(t)
+	p := Wrap(&mockProvider{err: errors.New("backend down")})
+
+	if _, err := p.Complete(context.Background(), &provider.ProviderRequest{Model: "m"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if spans := sr.Ended(); len(spans) != 1 || spans[0].Status().Code != codes.Error {
+		t.Fatalf("expected one failed span, got %v", spans)
+	}
+}
+
+func TestStream_SpanCoversStream(t *testing.T) {
+	sr := recordSpans(t)
+	p := Wrap(&mockProvider{events: []provider.ProviderEvent{
+		{Type: provider.ProviderEventTextDelta, Delta: "hi"},
+		{Type: provider.ProviderEventToolCallDone},
+		{Type: provider.ProviderEventDone, Usage: &api.Usage{InputTokens: 5, OutputTokens: 7}},
+	}})
+
+	ch, err := p.Stream(context.Background(), &provider.ProviderRequest{Model: "m", Stream: true})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != 3 {
+		t.Errorf("forwarded %d events, want 3", n)
+	}
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected one ended span after the stream closed, got %d", len(spans))
+	}
+	got := attrs(spans[0])
+	if got["gen_ai.usage.output_tokens"].AsInt64() != 7 || !got["gen_ai.request.stream"].AsBool() {
+		t.Errorf("unexpected attributes: %v", spans[0].Attributes())
+	}
+	if reasons := got["gen_ai.response.finish_reasons"].AsStringSlice(); len(reasons) != 1 || reasons[0] != "tool_calls" {
+		t.Errorf("finish_reasons = %v, want [tool_calls]", reasons)
+	}
+}
@@ -154,5 +154,6 @@ type ProviderEvent struct {
 type ModelInfo struct {
 	ID      string `json:"id"`
 	Object  string `json:"object,omitempty"`
+	Created int64  `json:"created,omitempty"`
 	OwnedBy string `json:"owned_by,omitempty"`
 }
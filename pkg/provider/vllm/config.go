@@ -15,6 +15,10 @@ type Config struct {
 
 	// MaxRetries for transient failures. Defaults to 0 (no retries).
 	MaxRetries int
+
+	// MaxContextWindow is the backend's context window in tokens,
+	// reported via Capabilities (0 = unknown).
+	MaxContextWindow int
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/rhuss/antwort/pkg/auth/apikey"
+	"github.com/rhuss/antwort/pkg/storage"
+)
+
+// Compile-time check.
+var _ apikey.Store = (*APIKeyStore)(nil)
+
+// APIKeyStore is an in-memory implementation of apikey.Store. Keys are
+// lost when the process exits.
+type APIKeyStore struct {
+	mu     sync.RWMutex
+	keys   map[string]*apikey.Key
+	byHash map[string]string // hash -> ID
+}
+
+// NewAPIKeyStore creates an empty in-memory API key store.
+func NewAPIKeyStore() *APIKeyStore {
+	return &APIKeyStore{
+		keys:   make(map[string]*apikey.Key),
+		byHash: make(map[string]string),
+	}
+}
+
+func (s *APIKeyStore) CreateKey(_ context.Context, key *apikey.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[key.ID]; ok {
+		return fmt.Errorf("API key %q already exists", key.ID)
+	}
+	if _, ok := s.byHash[key.Hash]; ok {
+		return fmt.Errorf("API key with the same hash already exists")
+	}
+	stored := cloneKey(key)
+	s.keys[key.ID] = stored
+	s.byHash[key.Hash] = key.ID
+	return nil
+}
+
+func (s *APIKeyStore) GetKey(ctx context.Context, id string) (*apikey.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[id]
+	if !ok || !keyVisible(ctx, key) {
+		return nil, storage.ErrNotFound
+	}
+	return cloneKey(key), nil
+}
+
+func (s *APIKeyStore) GetKeyByHash(_ context.Context, hash string) (*apikey.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.byHash[hash]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return cloneKey(s.keys[id]), nil
+}
+
+func (s *APIKeyStore) ListKeys(ctx context.Context) ([]*apikey.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*apikey.Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		if keyVisible(ctx, key) {
+			keys = append(keys, cloneKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt != keys[j].CreatedAt {
+			return keys[i].CreatedAt < keys[j].CreatedAt
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (s *APIKeyStore) DeleteKey(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok || !keyVisible(ctx, key) {
+		return storage.ErrNotFound
+	}
+	delete(s.byHash, key.Hash)
+	delete(s.keys, id)
+	return nil
+}
+
+func (s *APIKeyStore) TouchKey(_ context.Context, id string, usedAt int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	key.LastUsedAt = usedAt
+	return nil
+}
+
+// keyVisible reports whether key belongs to the tenant in ctx, if any.
+func keyVisible(ctx context.Context, key *apikey.Key) bool {
+	tenantID := storage.GetTenant(ctx)
+	return tenantID == "" || key.TenantID == tenantID
+}
+
+// cloneKey returns a copy of key that shares no slices with it.
+func cloneKey(key *apikey.Key) *apikey.Key {
+	c := *key
+	c.Roles = append([]string(nil), key.Roles...)
+	c.Scopes = append([]string(nil), key.Scopes...)
+	return &c
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rhuss/antwort/pkg/auth/apikey"
+	"github.com/rhuss/antwort/pkg/storage"
+)
+
+func TestAPIKeyStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewAPIKeyStore()
+
+	for i, id := range []string{"key_b", "key_a"} {
+		err := s.CreateKey(ctx, &apikey.Key{ID: id, Hash: "hash-" + id, Subject: "alice", Roles: []string{"admin"}, CreatedAt: int64(i)})
+		if err != nil {
+			t.Fatalf("CreateKey(%s): %v", id, err)
+		}
+	}
+	if err := s.CreateKey(ctx, &apikey.Key{ID: "key_c", Hash: "hash-key_a"}); err == nil {
+		t.Error("CreateKey with a duplicate hash should fail")
+	}
+
+	key, err := s.GetKeyByHash(ctx, "hash-key_a")
+	if err != nil || key.ID != "key_a" {
+		t.Fatalf("GetKeyByHash = %v, %v", key, err)
+	}
+	key.Roles[0] = "changed"
+	if key, _ := s.GetKey(ctx, "key_a"); key.Roles[0] != "admin" {
+		t.Error("returned keys must not share state with the store")
+	}
+
+	if err := s.TouchKey(ctx, "key_a", 42); err != nil {
+		t.Fatal(err)
+	}
+	keys, _ := s.ListKeys(ctx)
+	if len(keys) != 2 || keys[0].ID != "key_b" || keys[1].LastUsedAt != 42 {
+		t.Errorf("ListKeys = %+v, %+v", keys[0], keys[1])
+	}
+
+	if err := s.DeleteKey(ctx, "key_a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetKeyByHash(ctx, "hash-key_a"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetKeyByHash after delete: err = %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteKey(ctx, "key_a"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("DeleteKey twice: err = %v, want ErrNotFound", err)
+	}
+}
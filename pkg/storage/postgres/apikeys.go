@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rhuss/antwort/pkg/auth/apikey"
+	"github.com/rhuss/antwort/pkg/storage"
+)
+
+// Compile-time check.
+var _ apikey.Store = (*APIKeyStore)(nil)
+
+// APIKeyStore is a PostgreSQL-backed apikey.Store using the api_keys table
+// created by the Store's migrations.
+type APIKeyStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewAPIKeyStore creates an API key store that shares the connection pool
+// of the given response store.
+func NewAPIKeyStore(s *Store) *APIKeyStore {
+	return &APIKeyStore{pool: s.pool}
+}
+
+// apiKeyColumns is the column list read by scanAPIKey.
+const apiKeyColumns = "id, name, prefix, key_hash, subject, tenant_id, roles, scopes, service_tier, created_at, expires_at, last_used_at"
+
+// scanAPIKey reads a single row selected with apiKeyColumns.
+func scanAPIKey(row pgx.Row) (*apikey.Key, error) {
+	var k apikey.Key
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, &k.Subject, &k.TenantID,
+		&k.Roles, &k.Scopes, &k.ServiceTier, &k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// CreateKey stores a new key.
+func (s *APIKeyStore) CreateKey(ctx context.Context, key *apikey.Key) error {
+	roles, scopes := key.Roles, key.Scopes
+	if roles == nil {
+		roles = []string{}
+	}
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO api_keys (id, name, prefix, key_hash, subject, tenant_id, roles, scopes, service_tier, created_at, expires_at, last_used_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		key.ID, key.Name, key.Prefix, key.Hash, key.Subject, key.TenantID,
+		roles, scopes, key.ServiceTier, key.CreatedAt, key.ExpiresAt, key.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("creating API key: %w", err)
+	}
+	return nil
+}
+
+// GetKey returns the key with the given ID in the caller's tenant.
+func (s *APIKeyStore) GetKey(ctx context.Context, id string) (*apikey.Key, error) {
+	args := []any{id}
+	return s.getKey(ctx, "id = $1"+keyTenantClause(ctx, &args), args)
+}
+
+// GetKeyByHash returns the key with the given hash in any tenant.
+func (s *APIKeyStore) GetKeyByHash(ctx context.Context, hash string) (*apikey.Key, error) {
+	return s.getKey(ctx, "key_hash = $1", []any{hash})
+}
+
+func (s *APIKeyStore) getKey(ctx context.Context, where string, args []any) (*apikey.Key, error) {
+	query := fmt.Sprintf("SELECT %s FROM api_keys WHERE %s", apiKeyColumns, where)
+	key, err := scanAPIKey(s.pool.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying API key: %w", err)
+	}
+	return key, nil
+}
+
+// ListKeys returns all keys in the caller's tenant, oldest first.
+func (s *APIKeyStore) ListKeys(ctx context.Context) ([]*apikey.Key, error) {
+	var args []any
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE TRUE" + keyTenantClause(ctx, &args) + " ORDER BY created_at, id"
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*apikey.Key
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// DeleteKey removes the key with the given ID in the caller's tenant.
+func (s *APIKeyStore) DeleteKey(ctx context.Context, id string) error {
+	args := []any{id}
+	result, err := s.pool.Exec(ctx, "DELETE FROM api_keys WHERE id = $1"+keyTenantClause(ctx, &args), args...)
+	if err != nil {
+		return fmt.Errorf("deleting API key: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// keyTenantClause returns the condition limiting keys to the tenant in
+// ctx, adding its parameter to args, or "" without a tenant.
+func keyTenantClause(ctx context.Context, args *[]any) string {
+	tenantID := storage.GetTenant(ctx)
+	if tenantID == "" {
+		return ""
+	}
+	*args = append(*args, tenantID)
+	return fmt.Sprintf(" AND tenant_id = $%d", len(*args))
+}
+
+// TouchKey records that the key was used at the given Unix time.
+func (s *APIKeyStore) TouchKey(ctx context.Context, id string, usedAt int64) error {
+	result, err := s.pool.Exec(ctx, "UPDATE api_keys SET last_used_at = $1 WHERE id = $2", usedAt, id)
+	if err != nil {
+		return fmt.Errorf("updating API key: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
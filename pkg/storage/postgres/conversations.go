@@ -0,0 +1,356 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/storage"
+	"github.com/rhuss/antwort/pkg/transport"
+)
+
+// Compile-time check.
+var _ transport.ConversationStore = (*ConversationStore)(nil)
+
+// ConversationStore is a PostgreSQL-backed transport.ConversationStore.
+// Conversations and their items live in the conversations and
+// conversation_items tables created by the Store's migrations.
+type ConversationStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewConversationStore creates a conversation store that shares the
+// connection pool of the given response store. The response store must
+// have been created with migrations applied (or applied out of band).
+func NewConversationStore(s *Store) *ConversationStore {
+	return &ConversationStore{pool: s.pool}
+}
+
+// conversationColumns is the column list read by scanConversation.
+const conversationColumns = "id, owner, name, metadata, created_at, updated_at"
+
+// scanConversation reads a single row selected with conversationColumns.
+func scanConversation(row pgx.Row) (*api.Conversation, error) {
+	var conv api.Conversation
+	var metadataJSON *[]byte
+
+	if err := row.Scan(&conv.ID, &conv.UserID, &conv.Name, &metadataJSON, &conv.CreatedAt, &conv.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	conv.Object = "conversation"
+	if metadataJSON != nil {
+		if err := json.Unmarshal(*metadataJSON, &conv.Metadata); err != nil {
+			return nil, fmt.Errorf("unmarshaling metadata: %w", err)
+		}
+	}
+	return &conv, nil
+}
+
+// SaveConversation creates a conversation or updates the name and metadata
+// of an existing one. Updating a conversation that is deleted, belongs to
+// another tenant or is owned by another user returns storage.ErrNotFound.
+func (s *ConversationStore) SaveConversation(ctx context.Context, conv *api.Conversation) error {
+	var metadataJSON []byte
+	if conv.Metadata != nil {
+		var err error
+		metadataJSON, err = json.Marshal(conv.Metadata)
+		if err != nil {
+			return fmt.Errorf("marshaling metadata: %w", err)
+		}
+	}
+
+	var args queryArgs
+	query := fmt.Sprintf(`
+		INSERT INTO conversations (id, tenant_id, owner, name, metadata, created_at, updated_at)
+		VALUES (%s, %s, %s, %s, %s, %s, %s)
+		ON CONFLICT (id) DO UPDATE
+		SET name = EXCLUDED.name, metadata = EXCLUDED.metadata, updated_at = EXCLUDED.updated_at
+		WHERE `,
+		args.add(conv.ID), args.add(storage.GetTenant(ctx)), args.add(storage.GetOwner(ctx)),
+		args.add(conv.Name), args.add(nullJSON(metadataJSON)), args.add(conv.CreatedAt), args.add(conv.UpdatedAt),
+	)
+	query += scopeClause(ctx, "conversations.", &args, true)
+
+	result, err := s.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("saving conversation: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// GetConversation retrieves a conversation by ID, excluding soft-deleted ones.
+func (s *ConversationStore) GetConversation(ctx context.Context, id string) (*api.Conversation, error) {
+	var args queryArgs
+	query := fmt.Sprintf("SELECT %s FROM conversations WHERE id = %s AND %s",
+		conversationColumns, args.add(id), scopeClause(ctx, "", &args, false))
+
+	conv, err := scanConversation(s.pool.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying conversation: %w", err)
+	}
+	return conv, nil
+}
+
+// DeleteConversation soft-deletes a conversation by setting deleted_at.
+// Its items are kept until the row is purged.
+func (s *ConversationStore) DeleteConversation(ctx context.Context, id string) error {
+	var args queryArgs
+	query := fmt.Sprintf("UPDATE conversations SET deleted_at = %s WHERE id = %s AND %s",
+		args.add(time.Now()), args.add(id), scopeClause(ctx, "", &args, false))
+
+	result, err := s.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("deleting conversation: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// ListConversations returns a paginated list of conversations visible to
+// the caller, ordered by creation time with keyset cursors on (created_at, id).
+func (s *ConversationStore) ListConversations(ctx context.Context, opts transport.ListOptions) (*transport.ConversationList, error) {
+	var args queryArgs
+	scope := scopeClause(ctx, "", &args, false)
+	cursor, dir := keysetClause("conversations", scope, opts, &args)
+
+	limit := listLimit(opts.Limit)
+	query := fmt.Sprintf("SELECT %s FROM conversations WHERE %s%s ORDER BY created_at %s, id %s LIMIT %s",
+		conversationColumns, scope, cursor, dir, dir, args.add(limit+1))
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing conversations: %w", err)
+	}
+	defer rows.Close()
+
+	data := []*api.Conversation{}
+	for rows.Next() {
+		conv, err := scanConversation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning conversation: %w", err)
+		}
+		data = append(data, conv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing conversations: %w", err)
+	}
+
+	hasMore := len(data) > limit
+	if hasMore {
+		data = data[:limit]
+	}
+
+	result := &transport.ConversationList{
+		Object:  "list",
+		Data:    data,
+		HasMore: hasMore,
+	}
+	if len(data) > 0 {
+		result.FirstID = data[0].ID
+		result.LastID = data[len(data)-1].ID
+	}
+	return result, nil
+}
+
+// AddItems appends items to a conversation in a single transaction. The
+// conversation row is locked while positions are assigned, so concurrent
+// appends from different replicas never interleave or collide.
+func (s *ConversationStore) AddItems(ctx context.Context, conversationID string, items []api.ConversationItem) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var args queryArgs
+	lockQuery := fmt.Sprintf("SELECT id FROM conversations WHERE id = %s AND %s FOR UPDATE",
+		args.add(conversationID), scopeClause(ctx, "", &args, true))
+	var locked string
+	if err := tx.QueryRow(ctx, lockQuery, args...).Scan(&locked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+		return fmt.Errorf("locking conversation: %w", err)
+	}
+
+	var basePos int
+	if err := tx.QueryRow(ctx,
+		"SELECT COALESCE(MAX(position) + 1, 0) FROM conversation_items WHERE conversation_id = $1",
+		conversationID,
+	).Scan(&basePos); err != nil {
+		return fmt.Errorf("reading item position: %w", err)
+	}
+
+	now := time.Now().Unix()
+	batch := &pgx.Batch{}
+	for i := range items {
+		items[i].Position = basePos + i
+		items[i].ConversationID = conversationID
+		if items[i].CreatedAt == 0 {
+			items[i].CreatedAt = now
+		}
+
+		itemJSON, err := json.Marshal(items[i].Item)
+		if err != nil {
+			return fmt.Errorf("marshaling item: %w", err)
+		}
+		batch.Queue(`
+			INSERT INTO conversation_items (conversation_id, position, item_id, item, created_at)
+			VALUES ($1, $2, $3, $4, $5)`,
+			conversationID, items[i].Position, items[i].Item.ID, itemJSON, items[i].CreatedAt,
+		)
+	}
+	batch.Queue("UPDATE conversations SET updated_at = $1 WHERE id = $2", now, conversationID)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("inserting items: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing items: %w", err)
+	}
+	return nil
+}
+
+// ListItems returns a paginated list of items in a conversation ordered by
+// position. Order "desc" returns the newest items first; the after and
+// before cursors refer to item IDs.
+func (s *ConversationStore) ListItems(ctx context.Context, conversationID string, opts transport.ListOptions) (*transport.ItemList, error) {
+	if err := s.checkVisible(ctx, conversationID); err != nil {
+		return nil, err
+	}
+
+	dir := "ASC"
+	afterOp, beforeOp := ">", "<"
+	if opts.Order == "desc" {
+		dir = "DESC"
+		afterOp, beforeOp = "<", ">"
+	}
+
+	var args queryArgs
+	idArg := args.add(conversationID)
+	query := "SELECT item FROM conversation_items WHERE conversation_id = " + idArg
+
+	cursor := " AND position %s (SELECT position FROM conversation_items WHERE conversation_id = %s AND item_id = %s)"
+	if opts.After != "" {
+		query += fmt.Sprintf(cursor, afterOp, idArg, args.add(opts.After))
+	} else if opts.Before != "" {
+		query += fmt.Sprintf(cursor, beforeOp, idArg, args.add(opts.Before))
+	}
+
+	limit := listLimit(opts.Limit)
+	query += fmt.Sprintf(" ORDER BY position %s LIMIT %s", dir, args.add(limit+1))
+
+	items, err := s.queryItems(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	result := &transport.ItemList{
+		Object:  "list",
+		Data:    items,
+		HasMore: hasMore,
+	}
+	if len(items) > 0 {
+		result.FirstID = items[0].ID
+		result.LastID = items[len(items)-1].ID
+	}
+	return result, nil
+}
+
+// checkVisible returns storage.ErrNotFound unless the conversation exists,
+// is not deleted and is readable by the caller.
+func (s *ConversationStore) checkVisible(ctx context.Context, conversationID string) error {
+	var args queryArgs
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM conversations WHERE id = %s AND %s)",
+		args.add(conversationID), scopeClause(ctx, "", &args, false))
+
+	var exists bool
+	if err := s.pool.QueryRow(ctx, query, args...).Scan(&exists); err != nil {
+		return fmt.Errorf("checking conversation: %w", err)
+	}
+	if !exists {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// queryItems runs a query selecting a single JSONB item column.
+func (s *ConversationStore) queryItems(ctx context.Context, query string, args ...any) ([]api.Item, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing conversation items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []api.Item{}
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("scanning conversation item: %w", err)
+		}
+		var item api.Item
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, fmt.Errorf("unmarshaling conversation item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing conversation items: %w", err)
+	}
+	return items, nil
+}
+
+// AllItems returns all items in a conversation in position order (for
+// engine history reconstruction).
+func (s *ConversationStore) AllItems(ctx context.Context, conversationID string) ([]api.ConversationItem, error) {
+	if err := s.checkVisible(ctx, conversationID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(ctx,
+		"SELECT position, item, created_at FROM conversation_items WHERE conversation_id = $1 ORDER BY position",
+		conversationID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing conversation items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []api.ConversationItem
+	for rows.Next() {
+		ci := api.ConversationItem{ConversationID: conversationID}
+		var raw []byte
+		if err := rows.Scan(&ci.Position, &raw, &ci.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning conversation item: %w", err)
+		}
+		if err := json.Unmarshal(raw, &ci.Item); err != nil {
+			return nil, fmt.Errorf("unmarshaling conversation item: %w", err)
+		}
+		items = append(items, ci)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing conversation items: %w", err)
+	}
+	return items, nil
+}
@@ -0,0 +1,236 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/observability"
+	"github.com/rhuss/antwort/pkg/storage"
+	"github.com/rhuss/antwort/pkg/transport"
+)
+
+// Default and maximum page sizes, matching the in-memory store.
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// listLimit clamps the requested page size to the allowed range.
+func listLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
+
+// queryArgs accumulates positional query parameters.
+type queryArgs []any
+
+// add appends a parameter and returns its placeholder ($1, $2, ...).
+func (a *queryArgs) add(v any) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// scopeClause builds the visibility predicate for rows carrying the
+// deleted_at, tenant_id and owner columns: not soft-deleted, same tenant
+// (when set) and owned by the caller (unless unauthenticated or legacy data
+// with an empty owner). Admins bypass the owner check for read and delete
+// only; writeOp disables the bypass (FR-007). The prefix qualifies column
+// names, e.g. "r." for aliased queries.
+func scopeClause(ctx context.Context, prefix string, args *queryArgs, writeOp bool) string {
+	clause := prefix + "deleted_at IS NULL"
+
+	if tenantID := storage.GetTenant(ctx); tenantID != "" {
+		clause += fmt.Sprintf(" AND %stenant_id = %s", prefix, args.add(tenantID))
+	}
+
+	if owner := storage.GetOwner(ctx); owner != "" && (writeOp || !storage.GetAdmin(ctx)) {
+		clause += fmt.Sprintf(" AND (%sowner = '' OR %sowner = %s)", prefix, prefix, args.add(owner))
+	}
+
+	return clause
+}
+
+// keysetClause builds the cursor predicate and sort direction for lists
+// ordered by (created_at, id). The default order is desc (newest first).
+// The "after" cursor continues in the sort direction; "before" returns rows
+// preceding the cursor. A cursor that is unknown or not visible under scope
+// yields NULL, which matches no rows (same as the in-memory store).
+func keysetClause(table, scope string, opts transport.ListOptions, args *queryArgs) (clause, dir string) {
+	dir = "ASC"
+	afterOp, beforeOp := ">", "<"
+	if opts.Order != "asc" {
+		dir = "DESC"
+		afterOp, beforeOp = "<", ">"
+	}
+
+	cursor := " AND (created_at, id) %s (SELECT created_at, id FROM " + table + " WHERE id = %s AND %s)"
+	if opts.After != "" {
+		clause = fmt.Sprintf(cursor, afterOp, args.add(opts.After), scope)
+	} else if opts.Before != "" {
+		clause = fmt.Sprintf(cursor, beforeOp, args.add(opts.Before), scope)
+	}
+	return clause, dir
+}
+
+// ListResponses returns a paginated list of stored responses filtered by
+// tenant, owner and optionally model, status and background flag. Pagination
+// uses keyset cursors on (created_at, id) so deep pages stay index-backed.
+func (s *Store) ListResponses(ctx context.Context, opts transport.ListOptions) (*transport.ResponseList, error) {
+	start := time.Now()
+
+	var args queryArgs
+	scope := scopeClause(ctx, "", &args, false)
+	where := scope
+
+	if opts.Model != "" {
+		where += " AND model = " + args.add(opts.Model)
+	}
+	if opts.Status != "" {
+		where += " AND status = " + args.add(opts.Status)
+	}
+	if opts.Background != nil {
+		where += " AND background = " + args.add(*opts.Background)
+	}
+
+	cursor, dir := keysetClause("responses", scope, opts, &args)
+	where += cursor
+
+	limit := listLimit(opts.Limit)
+	query := fmt.Sprintf("SELECT %s FROM responses WHERE %s ORDER BY created_at %s, id %s LIMIT %s",
+		responseColumns, where, dir, dir, args.add(limit+1))
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		observability.StorageOperationsTotal.WithLabelValues("postgres", "list", "error").Inc()
+		observability.StorageOperationDuration.WithLabelValues("postgres", "list").Observe(time.Since(start).Seconds())
+		s.recordConnectionsActive()
+		return nil, fmt.Errorf("listing responses: %w", err)
+	}
+	defer rows.Close()
+
+	data := []*api.Response{}
+	for rows.Next() {
+		resp, err := scanResponse(rows)
+		if err != nil {
+			observability.StorageOperationsTotal.WithLabelValues("postgres", "list", "error").Inc()
+			observability.StorageOperationDuration.WithLabelValues("postgres", "list").Observe(time.Since(start).Seconds())
+			s.recordConnectionsActive()
+			return nil, fmt.Errorf("scanning response: %w", err)
+		}
+		data = append(data, resp)
+	}
+	if err := rows.Err(); err != nil {
+		observability.StorageOperationsTotal.WithLabelValues("postgres", "list", "error").Inc()
+		observability.StorageOperationDuration.WithLabelValues("postgres", "list").Observe(time.Since(start).Seconds())
+		s.recordConnectionsActive()
+		return nil, fmt.Errorf("listing responses: %w", err)
+	}
+
+	observability.StorageOperationsTotal.WithLabelValues("postgres", "list", "success").Inc()
+	observability.StorageOperationDuration.WithLabelValues("postgres", "list").Observe(time.Since(start).Seconds())
+	s.recordConnectionsActive()
+
+	hasMore := len(data) > limit
+	if hasMore {
+		data = data[:limit]
+	}
+
+	result := &transport.ResponseList{
+		Object:  "list",
+		Data:    data,
+		HasMore: hasMore,
+	}
+	if len(data) > 0 {
+		result.FirstID = data[0].ID
+		result.LastID = data[len(data)-1].ID
+	}
+
+	return result, nil
+}
+
+// GetInputItems returns a paginated list of input items for a stored
+// response. Items are returned in their original input order; the after
+// and before cursors refer to item IDs. The JSONB input array is expanded
+// in SQL so only the requested page is transferred.
+func (s *Store) GetInputItems(ctx context.Context, responseID string, opts transport.ListOptions) (*transport.ItemList, error) {
+	var args queryArgs
+	idArg := args.add(responseID)
+	scope := scopeClause(ctx, "r.", &args, false)
+
+	query := fmt.Sprintf(`
+		SELECT t.item
+		FROM responses r, jsonb_array_elements(r.input) WITH ORDINALITY AS t(item, pos)
+		WHERE r.id = %s AND %s`, idArg, scope)
+
+	cursorPos := "(SELECT c.pos FROM jsonb_array_elements(r.input) WITH ORDINALITY AS c(item, pos) WHERE c.item->>'id' = %s)"
+	if opts.After != "" {
+		query += " AND t.pos > " + fmt.Sprintf(cursorPos, args.add(opts.After))
+	} else if opts.Before != "" {
+		query += " AND t.pos < " + fmt.Sprintf(cursorPos, args.add(opts.Before))
+	}
+
+	limit := listLimit(opts.Limit)
+	query += " ORDER BY t.pos LIMIT " + args.add(limit+1)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing input items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []api.Item{}
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("scanning input item: %w", err)
+		}
+		var item api.Item
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, fmt.Errorf("unmarshaling input item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing input items: %w", err)
+	}
+
+	// An empty page is ambiguous: the response may exist with no matching
+	// items, or it may not be visible at all.
+	if len(items) == 0 {
+		var existsArgs queryArgs
+		existsQuery := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM responses WHERE id = %s AND %s)",
+			existsArgs.add(responseID), scopeClause(ctx, "", &existsArgs, false))
+		var exists bool
+		if err := s.pool.QueryRow(ctx, existsQuery, existsArgs...).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("checking response: %w", err)
+		}
+		if !exists {
+			return nil, storage.ErrNotFound
+		}
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	result := &transport.ItemList{
+		Object:  "list",
+		Data:    items,
+		HasMore: hasMore,
+	}
+	if len(items) > 0 {
+		result.FirstID = items[0].ID
+		result.LastID = items[len(items)-1].ID
+	}
+
+	return result, nil
+}
@@ -0,0 +1,12 @@
+-- Migration 005: Add composite indexes for cursor-paginated response listing.
+-- ListResponses orders by (created_at, id) and scopes by tenant, owner and
+-- optionally model, so keyset pagination can walk these indexes directly.
+
+CREATE INDEX IF NOT EXISTS idx_responses_list
+    ON responses (tenant_id, created_at DESC, id DESC) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_responses_list_owner
+    ON responses (tenant_id, owner, created_at DESC, id DESC) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_responses_list_model
+    ON responses (tenant_id, model, created_at DESC, id DESC) WHERE deleted_at IS NULL;
@@ -0,0 +1,32 @@
+-- Migration 006: Create conversations and their ordered items (spec 037).
+
+CREATE TABLE IF NOT EXISTS conversations (
+    id         TEXT PRIMARY KEY,
+    tenant_id  TEXT NOT NULL DEFAULT '',
+    owner      TEXT NOT NULL DEFAULT '',
+    name       TEXT NOT NULL DEFAULT '',
+    metadata   JSONB,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversations_list
+    ON conversations (tenant_id, created_at DESC, id DESC) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_conversations_list_owner
+    ON conversations (tenant_id, owner, created_at DESC, id DESC) WHERE deleted_at IS NULL;
+
+-- Items are ordered by a dense per-conversation position assigned on append.
+CREATE TABLE IF NOT EXISTS conversation_items (
+    conversation_id TEXT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    position        INTEGER NOT NULL,
+    item_id         TEXT NOT NULL,
+    item            JSONB NOT NULL,
+    created_at      BIGINT NOT NULL,
+    PRIMARY KEY (conversation_id, position)
+);
+
+-- Cursor lookups resolve an item ID to its position.
+CREATE INDEX IF NOT EXISTS idx_conversation_items_item
+    ON conversation_items (conversation_id, item_id);
@@ -0,0 +1,17 @@
+-- Migration 007: Create shared rate limit counters.
+
+-- One row per limit key ("<subject>:<tier>") and one-minute window. Gateway
+-- replicas add their locally counted usage in batches and read the totals
+-- back to enforce a shared quota.
+CREATE TABLE IF NOT EXISTS rate_limit_windows (
+    limit_key     TEXT NOT NULL,
+    window_start  BIGINT NOT NULL,
+    requests      BIGINT NOT NULL DEFAULT 0,
+    input_tokens  BIGINT NOT NULL DEFAULT 0,
+    output_tokens BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (limit_key, window_start)
+);
+
+-- Expired windows are deleted by window_start.
+CREATE INDEX IF NOT EXISTS idx_rate_limit_windows_start
+    ON rate_limit_windows (window_start);
@@ -0,0 +1,19 @@
+-- Migration 008: Create managed API keys.
+
+-- Keys created through the admin API. Only the SHA-256 hash of a key is
+-- stored; prefix holds its first characters so administrators can tell
+-- keys apart.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id            TEXT PRIMARY KEY,
+    name          TEXT NOT NULL DEFAULT '',
+    prefix        TEXT NOT NULL,
+    key_hash      TEXT NOT NULL UNIQUE,
+    subject       TEXT NOT NULL,
+    tenant_id     TEXT NOT NULL DEFAULT '',
+    roles         TEXT[] NOT NULL DEFAULT '{}',
+    scopes        TEXT[] NOT NULL DEFAULT '{}',
+    service_tier  TEXT NOT NULL DEFAULT '',
+    created_at    BIGINT NOT NULL,
+    expires_at    BIGINT NOT NULL DEFAULT 0,
+    last_used_at  BIGINT NOT NULL DEFAULT 0
+);
@@ -107,13 +107,13 @@ func (s *Store) SaveResponse(ctx context.Context, resp *api.Response) error {
 			id, tenant_id, owner, status, model, previous_response_id,
 			input, output,
 			usage_input_tokens, usage_output_tokens, usage_total_tokens,
-			error, extensions, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			error, extensions, created_at, background
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 	`,
 		resp.ID, tenantID, owner, string(resp.Status), resp.Model, resp.PreviousResponseID,
 		inputJSON, outputJSON,
 		usageIn, usageOut, usageTotal,
-		nullJSON(errorJSON), nullJSON(extensionsJSON), resp.CreatedAt,
+		nullJSON(errorJSON), nullJSON(extensionsJSON), resp.CreatedAt, resp.Background,
 	)
 
 	if err != nil {
@@ -156,14 +156,7 @@ func (s *Store) getResponse(ctx context.Context, id string, excludeDeleted bool)
 	owner := storage.GetOwner(ctx)
 	isAdminCaller := storage.GetAdmin(ctx)
 
-	query := `
-		SELECT id, status, model, previous_response_id,
-		       input, output,
-		       usage_input_tokens, usage_output_tokens, usage_total_tokens,
-		       error, extensions, created_at
-		FROM responses
-		WHERE id = $1
-	`
+	query := "SELECT " + responseColumns + " FROM responses WHERE id = $1"
 	args := []any{id}
 	argIdx := 2
 
@@ -183,19 +176,7 @@ func (s *Store) getResponse(ctx context.Context, id string, excludeDeleted bool)
 		args = append(args, owner)
 	}
 
-	var resp api.Response
-	var status string
-	var prevID *string
-	var inputJSON, outputJSON []byte
-	var errorJSON, extensionsJSON *[]byte
-	var usageIn, usageOut, usageTotal int
-
-	err := s.pool.QueryRow(ctx, query, args...).Scan(
-		&resp.ID, &status, &resp.Model, &prevID,
-		&inputJSON, &outputJSON,
-		&usageIn, &usageOut, &usageTotal,
-		&errorJSON, &extensionsJSON, &resp.CreatedAt,
-	)
+	resp, err := scanResponse(s.pool.QueryRow(ctx, query, args...))
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		observability.StorageOperationsTotal.WithLabelValues("postgres", "get", "error").Inc()
@@ -214,6 +195,33 @@ func (s *Store) getResponse(ctx context.Context, id string, excludeDeleted bool)
 	observability.StorageOperationDuration.WithLabelValues("postgres", "get").Observe(time.Since(start).Seconds())
 	s.recordConnectionsActive()
 
+	return resp, nil
+}
+
+// responseColumns is the column list read by scanResponse.
+const responseColumns = `id, status, model, previous_response_id,
+		input, output,
+		usage_input_tokens, usage_output_tokens, usage_total_tokens,
+		error, extensions, created_at, background`
+
+// scanResponse reads a single row selected with responseColumns into a Response.
+func scanResponse(row pgx.Row) (*api.Response, error) {
+	var resp api.Response
+	var status string
+	var prevID *string
+	var inputJSON, outputJSON []byte
+	var errorJSON, extensionsJSON *[]byte
+	var usageIn, usageOut, usageTotal int
+
+	if err := row.Scan(
+		&resp.ID, &status, &resp.Model, &prevID,
+		&inputJSON, &outputJSON,
+		&usageIn, &usageOut, &usageTotal,
+		&errorJSON, &extensionsJSON, &resp.CreatedAt, &resp.Background,
+	); err != nil {
+		return nil, err
+	}
+
 	resp.Object = "response"
 	resp.Status = api.ResponseStatus(status)
 	resp.PreviousResponseID = prevID
@@ -390,18 +398,6 @@ func nullJSON(b []byte) *[]byte {
 	return &b
 }
 
-// ListResponses returns a paginated list of stored responses.
-// TODO(028): Implement SQL-based listing with cursor pagination.
-func (s *Store) ListResponses(ctx context.Context, opts transport.ListOptions) (*transport.ResponseList, error) {
-	return nil, fmt.Errorf("ListResponses not yet implemented for PostgreSQL")
-}
-
-// GetInputItems returns input items for a stored response.
-// TODO(028): Implement SQL-based input item retrieval with pagination.
-func (s *Store) GetInputItems(ctx context.Context, responseID string, opts transport.ListOptions) (*transport.ItemList, error) {
-	return nil, fmt.Errorf("GetInputItems not yet implemented for PostgreSQL")
-}
-
 // isDuplicateKey checks if the error is a PostgreSQL unique violation (23505).
 func isDuplicateKey(err error) bool {
 	return err != nil && contains(err.Error(), "23505")
@@ -0,0 +1,452 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rhuss/antwort/pkg/auth"
+)
+
+// Compile-time check.
+var _ auth.RateLimiter = (*RateLimiter)(nil)
+
+// rateLimitWindow is the length of one counting window, in seconds.
+const rateLimitWindow = 60
+
+// RateLimiterConfig configures a RateLimiter.
+type RateLimiterConfig struct {
+	// Tiers maps service tiers to their limits (see auth.LimitKey for how
+	// identities are mapped to tiers).
+	Tiers map[string]auth.TierConfig
+
+	// SyncInterval is how often locally counted usage is written to the
+	// database and the shared counters are read back (default: 1 second).
+	SyncInterval time.Duration
+
+	// FailClosed rejects requests with auth.ErrRateLimitUnavailable while
+	// the database cannot be reached. By default the limiter fails open and
+	// keeps deciding on the last known counters plus local usage.
+	FailClosed bool
+}
+
+// RateLimiter is an auth.RateLimiter whose counters are shared between
+// gateway replicas through the rate_limit_windows table.
+//
+// It uses a sliding window approximated from two fixed one-minute windows:
+// the usage of the previous window is weighted by the part of it that still
+// overlaps the sliding window. To keep the write load low, usage is counted
+// locally and written in one batch per SyncInterval, which also refreshes
+// the shared totals. Between syncs, replicas may together exceed a limit by
+// the usage they counted since the last sync.
+type RateLimiter struct {
+	pool *pgxpool.Pool
+	cfg  RateLimiterConfig
+	now  func() time.Time
+
+	// syncMu serializes syncs.
+	syncMu      sync.Mutex
+	lastCleanup int64
+
+	mu       sync.Mutex
+	entries  map[string]*rateEntry
+	pending  map[rateKey]*rateUsage // counted locally, not yet written
+	inflight map[rateKey]*rateUsage // being written by the running sync
+	healthy  bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// rateUsage holds the counters of one window.
+type rateUsage struct {
+	requests     int64
+	inputTokens  int64
+	outputTokens int64
+}
+
+func (u *rateUsage) add(o rateUsage) {
+	u.requests += o.requests
+	u.inputTokens += o.inputTokens
+	u.outputTokens += o.outputTokens
+}
+
+// rateKey identifies the counters of a limit key in one window.
+type rateKey struct {
+	key    string
+	window int64
+}
+
+// rateEntry holds the shared totals of a limit key as of the last sync.
+type rateEntry struct {
+	window     int64 // start of the window counted in curr
+	prev, curr rateUsage
+	lastUsed   time.Time
+}
+
+// NewRateLimiter creates a rate limiter that shares the connection pool
+// of the given store and starts syncing in the background. The store must
+// have been created with migrations applied (or applied out of band).
+// Call Close to stop syncing and write the remaining usage.
+func NewRateLimiter(s *Store, cfg RateLimiterConfig) *RateLimiter {
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = time.Second
+	}
+	l := &RateLimiter{
+		pool:    s.pool,
+		cfg:     cfg,
+		now:     time.Now,
+		entries: make(map[string]*rateEntry),
+		pending: make(map[rateKey]*rateUsage),
+		healthy: true,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Allow checks the request against the shared counters of the identity.
+func (l *RateLimiter) Allow(ctx context.Context, identity *auth.Identity) (*auth.RateLimitStatus, error) {
+	key, tc := auth.LimitKey(l.cfg.Tiers, identity)
+	if tc.Unlimited() {
+		return nil, nil
+	}
+
+	now := l.now()
+	if err := l.load(ctx, key, now); err != nil && l.cfg.FailClosed {
+		return nil, fmt.Errorf("%w: %v", auth.ErrRateLimitUnavailable, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.healthy && l.cfg.FailClosed {
+		return nil, auth.ErrRateLimitUnavailable
+	}
+
+	used, reset := l.usage(key, now)
+	status := rateLimitStatus(tc, used, reset)
+	if exceeded(tc.RequestsPerMinute, used.requests) ||
+		exceeded(tc.InputTokensPerMinute, used.inputTokens) ||
+		exceeded(tc.OutputTokensPerMinute, used.outputTokens) {
+		status.RetryAfter = reset
+		return status, auth.ErrTooManyRequests
+	}
+
+	l.charge(key, now, rateUsage{requests: 1})
+	status.Requests.Remaining--
+	return status, nil
+}
+
+// RecordUsage charges token usage to the identity in ctx.
+func (l *RateLimiter) RecordUsage(ctx context.Context, inputTokens, outputTokens int) {
+	identity := auth.IdentityFromContext(ctx)
+	if identity == nil {
+		return
+	}
+	key, tc := auth.LimitKey(l.cfg.Tiers, identity)
+	if tc.InputTokensPerMinute <= 0 && tc.OutputTokensPerMinute <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.charge(key, l.now(), rateUsage{inputTokens: int64(inputTokens), outputTokens: int64(outputTokens)})
+}
+
+// Close stops the background sync and writes the remaining usage.
+func (l *RateLimiter) Close() error {
+	close(l.stop)
+	<-l.done
+	return nil
+}
+
+// run syncs every SyncInterval until Close is called.
+func (l *RateLimiter) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.cfg.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), l.cfg.SyncInterval)
+			if err := l.sync(ctx); err != nil {
+				slog.Warn("rate limit sync failed", "error", err.Error())
+			}
+			cancel()
+		case <-l.stop:
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := l.sync(ctx); err != nil {
+				slog.Warn("final rate limit sync failed", "error", err.Error())
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// load reads the shared totals of a key that this replica has not seen
+// recently. If the database cannot be reached, the key starts from zero
+// (fail open) or is retried on the next request (fail closed).
+func (l *RateLimiter) load(ctx context.Context, key string, now time.Time) error {
+	l.mu.Lock()
+	_, ok := l.entries[key]
+	l.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	window := windowStart(now)
+	rows, err := l.pool.Query(ctx, `
+		SELECT limit_key, window_start, requests, input_tokens, output_tokens
+		FROM rate_limit_windows
+		WHERE limit_key = $1 AND window_start >= $2
+	`, key, window-rateLimitWindow)
+	var totals map[string]*rateEntry
+	if err == nil {
+		totals, err = scanRateEntries(rows, window)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err != nil {
+		l.healthy = false
+		if l.cfg.FailClosed {
+			return fmt.Errorf("loading rate limit counters: %w", err)
+		}
+		slog.Warn("failed to load rate limit counters, failing open", "error", err.Error())
+	} else {
+		l.healthy = true
+	}
+	if _, ok := l.entries[key]; !ok {
+		e := totals[key]
+		if e == nil {
+			e = &rateEntry{window: window}
+		}
+		e.lastUsed = now
+		l.entries[key] = e
+	}
+	return nil
+}
+
+// sync writes the locally counted usage and refreshes the shared totals of
+// all recently used keys in a single batch. Keys idle for more than two
+// windows are dropped, and expired rows are deleted once per window.
+func (l *RateLimiter) sync(ctx context.Context) error {
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+
+	now := l.now()
+	window := windowStart(now)
+
+	l.mu.Lock()
+	l.inflight, l.pending = l.pending, make(map[rateKey]*rateUsage)
+	keys := make([]string, 0, len(l.entries))
+	for key, e := range l.entries {
+		if now.Sub(e.lastUsed) > 2*rateLimitWindow*time.Second {
+			delete(l.entries, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	batch := &pgx.Batch{}
+	for rk, u := range l.inflight {
+		batch.Queue(`
+			INSERT INTO rate_limit_windows (limit_key, window_start, requests, input_tokens, output_tokens)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (limit_key, window_start) DO UPDATE SET
+				requests = rate_limit_windows.requests + EXCLUDED.requests,
+				input_tokens = rate_limit_windows.input_tokens + EXCLUDED.input_tokens,
+				output_tokens = rate_limit_windows.output_tokens + EXCLUDED.output_tokens
+		`, rk.key, rk.window, u.requests, u.inputTokens, u.outputTokens)
+	}
+	writes := batch.Len()
+	idle := writes == 0 && len(keys) == 0 && l.healthy
+	l.mu.Unlock()
+
+	if idle {
+		return nil
+	}
+
+	cleanup := window > l.lastCleanup
+	if cleanup {
+		batch.Queue(`DELETE FROM rate_limit_windows WHERE window_start < $1`, window-rateLimitWindow)
+	}
+
+	totals, err := l.execSync(ctx, batch, writes, cleanup, keys, window)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err != nil {
+		// Keep the usage for the next attempt.
+		for rk, u := range l.inflight {
+			l.pendingUsage(rk).add(*u)
+		}
+		l.inflight = nil
+		l.healthy = false
+		return err
+	}
+
+	for _, key := range keys {
+		if e, ok := l.entries[key]; ok {
+			t := totals[key]
+			if t == nil {
+				t = &rateEntry{window: window}
+			}
+			e.window, e.prev, e.curr = t.window, t.prev, t.curr
+		}
+	}
+	l.inflight = nil
+	l.healthy = true
+	if cleanup {
+		l.lastCleanup = window
+	}
+	return nil
+}
+
+// execSync sends the sync batch: the usage writes, the optional cleanup
+// and a final read of the totals of keys.
+func (l *RateLimiter) execSync(ctx context.Context, batch *pgx.Batch, writes int, cleanup bool, keys []string, window int64) (map[string]*rateEntry, error) {
+	batch.Queue(`
+		SELECT limit_key, window_start, requests, input_tokens, output_tokens
+		FROM rate_limit_windows
+		WHERE limit_key = ANY($1) AND window_start >= $2
+	`, keys, window-rateLimitWindow)
+
+	br := l.pool.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for i := 0; i < writes; i++ {
+		if _, err := br.Exec(); err != nil {
+			return nil, fmt.Errorf("writing rate limit usage: %w", err)
+		}
+	}
+	if cleanup {
+		if _, err := br.Exec(); err != nil {
+			return nil, fmt.Errorf("deleting expired rate limit windows: %w", err)
+		}
+	}
+	rows, err := br.Query()
+	if err != nil {
+		return nil, fmt.Errorf("reading rate limit counters: %w", err)
+	}
+	return scanRateEntries(rows, window)
+}
+
+// scanRateEntries reads rows of (limit_key, window_start, counters) into
+// entries for the given current window.
+func scanRateEntries(rows pgx.Rows, window int64) (map[string]*rateEntry, error) {
+	defer rows.Close()
+
+	entries := make(map[string]*rateEntry)
+	for rows.Next() {
+		var key string
+		var start int64
+		var u rateUsage
+		if err := rows.Scan(&key, &start, &u.requests, &u.inputTokens, &u.outputTokens); err != nil {
+			return nil, fmt.Errorf("scanning rate limit counters: %w", err)
+		}
+		e := entries[key]
+		if e == nil {
+			e = &rateEntry{window: window}
+			entries[key] = e
+		}
+		switch start {
+		case window:
+			e.curr = u
+		case window - rateLimitWindow:
+			e.prev = u
+		}
+	}
+	return entries, rows.Err()
+}
+
+// usage returns the usage of key in the sliding window ending at now and
+// the time until the current fixed window ends. Must be called with l.mu
+// held.
+func (l *RateLimiter) usage(key string, now time.Time) (rateUsage, time.Duration) {
+	window := windowStart(now)
+
+	var prev, curr rateUsage
+	if e, ok := l.entries[key]; ok {
+		switch e.window {
+		case window:
+			prev, curr = e.prev, e.curr
+		case window - rateLimitWindow:
+			prev = e.curr
+		}
+	}
+	for _, m := range []map[rateKey]*rateUsage{l.inflight, l.pending} {
+		if u, ok := m[rateKey{key, window}]; ok {
+			curr.add(*u)
+		}
+		if u, ok := m[rateKey{key, window - rateLimitWindow}]; ok {
+			prev.add(*u)
+		}
+	}
+
+	end := time.Unix(window+rateLimitWindow, 0)
+	weight := float64(end.Sub(now)) / float64(rateLimitWindow*time.Second)
+	used := rateUsage{
+		requests:     curr.requests + int64(float64(prev.requests)*weight),
+		inputTokens:  curr.inputTokens + int64(float64(prev.inputTokens)*weight),
+		outputTokens: curr.outputTokens + int64(float64(prev.outputTokens)*weight),
+	}
+	return used, end.Sub(now)
+}
+
+// charge adds usage to the pending counters of key. Must be called with
+// l.mu held.
+func (l *RateLimiter) charge(key string, now time.Time, u rateUsage) {
+	l.pendingUsage(rateKey{key, windowStart(now)}).add(u)
+
+	e, ok := l.entries[key]
+	if !ok {
+		// Picked up by the next sync.
+		e = &rateEntry{window: windowStart(now)}
+		l.entries[key] = e
+	}
+	e.lastUsed = now
+}
+
+// pendingUsage returns the pending counters of rk, creating them if
+// needed. Must be called with l.mu held.
+func (l *RateLimiter) pendingUsage(rk rateKey) *rateUsage {
+	u, ok := l.pending[rk]
+	if !ok {
+		u = &rateUsage{}
+		l.pending[rk] = u
+	}
+	return u
+}
+
+// windowStart returns the start of the fixed window containing t, in unix
+// seconds.
+func windowStart(t time.Time) int64 {
+	s := t.Unix()
+	return s - s%rateLimitWindow
+}
+
+// exceeded reports whether an enforced limit has been reached.
+func exceeded(limit int, used int64) bool {
+	return limit > 0 && used >= int64(limit)
+}
+
+// rateLimitStatus builds the header status for the given usage.
+func rateLimitStatus(tc auth.TierConfig, used rateUsage, reset time.Duration) *auth.RateLimitStatus {
+	return &auth.RateLimitStatus{
+		Requests:     auth.RateLimitWindow{Limit: tc.RequestsPerMinute, Remaining: tc.RequestsPerMinute - int(used.requests), Reset: reset},
+		InputTokens:  auth.RateLimitWindow{Limit: tc.InputTokensPerMinute, Remaining: tc.InputTokensPerMinute - int(used.inputTokens), Reset: reset},
+		OutputTokens: auth.RateLimitWindow{Limit: tc.OutputTokensPerMinute, Remaining: tc.OutputTokensPerMinute - int(used.outputTokens), Reset: reset},
+	}
+}
@@ -0,0 +1,366 @@
+package codeinterpreter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/rhuss/antwort/pkg/api"
+)
+
+// maxContainerFileSize limits files uploaded into a container.
+const maxContainerFileSize = 100 * 1024 * 1024
+
+// ContainerFile is a file in a container's working directory. Its ID is
+// derived from the container and path, so it stays the same while the
+// file exists.
+type ContainerFile struct {
+	ID          string `json:"id"`
+	Object      string `json:"object"`
+	ContainerID string `json:"container_id"`
+	Path        string `json:"path"`
+	Bytes       int64  `json:"bytes"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// containerFileID returns the ID of the file at path in a container.
+func containerFileID(containerID, path string) string {
+	sum := sha256.Sum256([]byte(containerID + "/" + path))
+	return "cfile_" + hex.EncodeToString(sum[:])[:24]
+}
+
+// createContainerRequest is the JSON request body for creating a container.
+type createContainerRequest struct {
+	Name         string           `json:"name"`
+	ExpiresAfter *ContainerExpiry `json:"expires_after,omitempty"`
+}
+
+// listResponse is the OpenAI-compatible JSON list envelope.
+type listResponse[T any] struct {
+	Object  string `json:"object"`
+	Data    []T    `json:"data"`
+	FirstID string `json:"first_id"`
+	LastID  string `json:"last_id"`
+	HasMore bool   `json:"has_more"`
+}
+
+// handleCreateContainer handles POST /containers.
+func (p *CodeInterpreterProvider) handleCreateContainer(w http.ResponseWriter, r *http.Request) {
+	var req createContainerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, api.NewInvalidRequestError("body", "invalid request body"))
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		writeAPIError(w, api.NewInvalidRequestError("name", "name is required"))
+		return
+	}
+
+	minutes := 0
+	if req.ExpiresAfter != nil {
+		if req.ExpiresAfter.Anchor != "" && req.ExpiresAfter.Anchor != "last_active_at" {
+			writeAPIError(w, api.NewInvalidRequestError("expires_after.anchor", `anchor must be "last_active_at"`))
+			return
+		}
+		minutes = req.ExpiresAfter.Minutes
+		if minutes < minContainerMinutes || minutes > maxContainerMinutes {
+			writeAPIError(w, api.NewInvalidRequestError("expires_after.minutes",
+				fmt.Sprintf("minutes must be between %d and %d", minContainerMinutes, maxContainerMinutes)))
+			return
+		}
+	}
+
+	c, err := p.containers.create(r.Context(), req.Name, minutes)
+	if errors.Is(err, errTooManyContainers) {
+		writeAPIError(w, api.NewTooManyRequestsError(
+			fmt.Sprintf("at most %d containers per user; delete one first", p.config.MaxContainersPerOwner)))
+		return
+	}
+	if err != nil {
+		slog.Error("code_interpreter: creating container failed", "error", err)
+		writeAPIError(w, api.NewServerError("failed to create container"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c.Container)
+}
+
+// handleListContainers handles GET /containers.
+func (p *CodeInterpreterProvider) handleListContainers(w http.ResponseWriter, r *http.Request) {
+	list := listResponse[Container]{Object: "list", Data: p.containers.list(r.Context())}
+	if n := len(list.Data); n > 0 {
+		list.FirstID, list.LastID = list.Data[0].ID, list.Data[n-1].ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleGetContainer handles GET /containers/{container_id}.
+func (p *CodeInterpreterProvider) handleGetContainer(w http.ResponseWriter, r *http.Request) {
+	c, ok := p.lookupContainer(w, r, false)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.Container)
+}
+
+// handleDeleteContainer handles DELETE /containers/{container_id}.
+func (p *CodeInterpreterProvider) handleDeleteContainer(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("container_id")
+	if err := p.containers.delete(r.Context(), id); err != nil {
+		writeAPIError(w, api.NewNotFoundError("container not found"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":      id,
+		"object":  "container.deleted",
+		"deleted": true,
+	})
+}
+
+// handleListContainerFiles handles GET /containers/{container_id}/files.
+func (p *CodeInterpreterProvider) handleListContainerFiles(w http.ResponseWriter, r *http.Request) {
+	c, ok := p.lookupContainer(w, r, false)
+	if !ok {
+		return
+	}
+	files, err := p.containerFiles(r, c)
+	if err != nil {
+		slog.Error("code_interpreter: listing container files failed", "container", c.ID, "error", err)
+		writeAPIError(w, api.NewServerError("failed to list container files"))
+		return
+	}
+
+	list := listResponse[ContainerFile]{Object: "list", Data: files}
+	if n := len(files); n > 0 {
+		list.FirstID, list.LastID = files[0].ID, files[n-1].ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleUploadContainerFile handles POST /containers/{container_id}/files
+// with a multipart "file" field. The file is written to the container's
+// working directory under its base name.
+func (p *CodeInterpreterProvider) handleUploadContainerFile(w http.ResponseWriter, r *http.Request) {
+	c, ok := p.lookupContainer(w, r, true)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxContainerFileSize+1024) // extra for form fields
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		writeAPIError(w, api.NewInvalidRequestError("Content-Type", "expected multipart/form-data"))
+		return
+	}
+
+	var filename string
+	var content []byte
+	reader := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeAPIError(w, api.NewInvalidRequestError("file", "error reading multipart form"))
+			return
+		}
+		if part.FormName() == "file" {
+			filename = path.Base(part.FileName())
+			content, err = io.ReadAll(part)
+			if err != nil {
+				writeAPIError(w, api.NewInvalidRequestError("file", "error reading file data"))
+				return
+			}
+		}
+		part.Close()
+	}
+
+	if content == nil || filename == "" || filename == "." || filename == "/" {
+		writeAPIError(w, api.NewInvalidRequestError("file", "file is required"))
+		return
+	}
+	if strings.HasPrefix(filename, ".") {
+		writeAPIError(w, api.NewInvalidRequestError("file", "file name must not start with a dot"))
+		return
+	}
+	if len(content) > maxContainerFileSize {
+		writeAPIError(w, api.NewInvalidRequestError("file",
+			fmt.Sprintf("file exceeds maximum size of %d bytes", maxContainerFileSize)))
+		return
+	}
+
+	if err := p.client.WriteFile(r.Context(), c.sandboxURL, c.ID, filename, content); err != nil {
+		slog.Error("code_interpreter: uploading container file failed", "container", c.ID, "error", err)
+		writeAPIError(w, api.NewServerError("failed to store file in container"))
+		return
+	}
+	p.containers.touch(c.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ContainerFile{
+		ID:          containerFileID(c.ID, filename),
+		Object:      "container.file",
+		ContainerID: c.ID,
+		Path:        filename,
+		Bytes:       int64(len(content)),
+		CreatedAt:   p.containers.now().Unix(),
+	})
+}
+
+// handleGetContainerFile handles GET /containers/{container_id}/files/{file_id}.
+func (p *CodeInterpreterProvider) handleGetContainerFile(w http.ResponseWriter, r *http.Request) {
+	c, ok := p.lookupContainer(w, r, false)
+	if !ok {
+		return
+	}
+	file, ok := p.lookupContainerFile(w, r, c)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(file)
+}
+
+// handleGetContainerFileContent handles
+// GET /containers/{container_id}/files/{file_id}/content.
+func (p *CodeInterpreterProvider) handleGetContainerFileContent(w http.ResponseWriter, r *http.Request) {
+	c, ok := p.lookupContainer(w, r, false)
+	if !ok {
+		return
+	}
+	file, ok := p.lookupContainerFile(w, r, c)
+	if !ok {
+		return
+	}
+
+	content, err := p.client.ReadFile(r.Context(), c.sandboxURL, c.ID, file.Path)
+	if errors.Is(err, errSandboxNotFound) {
+		writeAPIError(w, api.NewNotFoundError("file not found"))
+		return
+	}
+	if err != nil {
+		slog.Error("code_interpreter: reading container file failed", "container", c.ID, "error", err)
+		writeAPIError(w, api.NewServerError("failed to retrieve file content"))
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(file.Path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, path.Base(file.Path)))
+	w.Write(content)
+}
+
+// handleDeleteContainerFile handles DELETE /containers/{container_id}/files/{file_id}.
+func (p *CodeInterpreterProvider) handleDeleteContainerFile(w http.ResponseWriter, r *http.Request) {
+	c, ok := p.lookupContainer(w, r, false)
+	if !ok {
+		return
+	}
+	file, ok := p.lookupContainerFile(w, r, c)
+	if !ok {
+		return
+	}
+
+	err := p.client.DeleteFile(r.Context(), c.sandboxURL, c.ID, file.Path)
+	if err != nil && !errors.Is(err, errSandboxNotFound) {
+		slog.Error("code_interpreter: deleting container file failed", "container", c.ID, "error", err)
+		writeAPIError(w, api.NewServerError("failed to delete file"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":      file.ID,
+		"object":  "container.file.deleted",
+		"deleted": true,
+	})
+}
+
+// lookupContainer returns the container named in the request path. On
+// failure it writes the error response.
+func (p *CodeInterpreterProvider) lookupContainer(w http.ResponseWriter, r *http.Request, write bool) (*container, bool) {
+	c, err := p.containers.get(r.Context(), r.PathValue("container_id"), write)
+	if err != nil {
+		writeAPIError(w, api.NewNotFoundError("container not found"))
+		return nil, false
+	}
+	return c, true
+}
+
+// lookupContainerFile returns the container file named in the request
+// path. On failure it writes the error response.
+func (p *CodeInterpreterProvider) lookupContainerFile(w http.ResponseWriter, r *http.Request, c *container) (*ContainerFile, bool) {
+	files, err := p.containerFiles(r, c)
+	if err != nil {
+		slog.Error("code_interpreter: listing container files failed", "container", c.ID, "error", err)
+		writeAPIError(w, api.NewServerError("failed to list container files"))
+		return nil, false
+	}
+	id := r.PathValue("file_id")
+	for i := range files {
+		if files[i].ID == id {
+			return &files[i], true
+		}
+	}
+	writeAPIError(w, api.NewNotFoundError("file not found"))
+	return nil, false
+}
+
+// containerFiles lists the files in the container's working directory.
+func (p *CodeInterpreterProvider) containerFiles(r *http.Request, c *container) ([]ContainerFile, error) {
+	sandboxFiles, err := p.client.ListFiles(r.Context(), c.sandboxURL, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]ContainerFile, 0, len(sandboxFiles))
+	for _, f := range sandboxFiles {
+		files = append(files, ContainerFile{
+			ID:          containerFileID(c.ID, f.Path),
+			Object:      "container.file",
+			ContainerID: c.ID,
+			Path:        f.Path,
+			Bytes:       f.Bytes,
+			CreatedAt:   f.ModifiedAt,
+		})
+	}
+	return files, nil
+}
+
+func writeAPIError(w http.ResponseWriter, apiErr *api.APIError) {
+	w.Header().Set("Content-Type", "application/json")
+	status := http.StatusBadRequest
+	switch apiErr.Type {
+	case api.ErrorTypeNotFound:
+		status = http.StatusNotFound
+	case api.ErrorTypeServerError:
+		status = http.StatusInternalServerError
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{
+			"message": apiErr.Message,
+			"type":    string(apiErr.Type),
+			"param":   apiErr.Param,
+		},
+	})
+}
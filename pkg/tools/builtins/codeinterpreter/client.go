@@ -4,12 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
+// errSandboxNotFound is returned when a session file does not exist.
+var errSandboxNotFound = errors.New("not found in sandbox")
+
 // SandboxClient calls the sandbox server's REST API to execute code.
 type SandboxClient struct {
 	httpClient *http.Client
@@ -63,3 +69,141 @@ func (c *SandboxClient) Execute(ctx context.Context, sandboxURL string, req *San
 
 	return &sandboxResp, nil
 }
+
+// ExecuteStream is like Execute, but passes the output of the code to
+// onOutput while it runs, with the name of its stream ("stdout" or
+// "stderr"). Cancelling ctx closes the connection, which makes the
+// sandbox kill the code. Sandbox servers without the streaming endpoint
+// are called with Execute.
+func (c *SandboxClient) ExecuteStream(ctx context.Context, sandboxURL string, req *SandboxRequest, onOutput func(stream, data string)) (*SandboxResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, sandboxURL+"/execute/stream", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return c.Execute(ctx, sandboxURL, req)
+	case http.StatusTooManyRequests:
+		return nil, fmt.Errorf("sandbox at capacity (HTTP 429)")
+	default:
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("sandbox returned HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var line SandboxStreamLine
+		if err := dec.Decode(&line); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("sandbox stream ended without a result")
+			}
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		switch line.Type {
+		case "stdout", "stderr":
+			onOutput(line.Type, line.Data)
+		case "result":
+			if line.Result == nil {
+				return nil, fmt.Errorf("decode response: result line without a result")
+			}
+			return line.Result, nil
+		}
+	}
+}
+
+// ListFiles lists the files in a session's working directory.
+func (c *SandboxClient) ListFiles(ctx context.Context, sandboxURL, sessionID string) ([]SandboxFile, error) {
+	body, err := c.do(ctx, http.MethodGet, sessionURL(sandboxURL, sessionID)+"/files", nil)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Files []SandboxFile `json:"files"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return list.Files, nil
+}
+
+// ReadFile returns the content of a file in a session.
+func (c *SandboxClient) ReadFile(ctx context.Context, sandboxURL, sessionID, path string) ([]byte, error) {
+	return c.do(ctx, http.MethodGet, sessionFileURL(sandboxURL, sessionID, path), nil)
+}
+
+// WriteFile writes a file into a session, creating the session if needed.
+func (c *SandboxClient) WriteFile(ctx context.Context, sandboxURL, sessionID, path string, content []byte) error {
+	_, err := c.do(ctx, http.MethodPut, sessionFileURL(sandboxURL, sessionID, path), content)
+	return err
+}
+
+// DeleteFile deletes a file from a session.
+func (c *SandboxClient) DeleteFile(ctx context.Context, sandboxURL, sessionID, path string) error {
+	_, err := c.do(ctx, http.MethodDelete, sessionFileURL(sandboxURL, sessionID, path), nil)
+	return err
+}
+
+// DeleteSession deletes a session with its files.
+func (c *SandboxClient) DeleteSession(ctx context.Context, sandboxURL, sessionID string) error {
+	_, err := c.do(ctx, http.MethodDelete, sessionURL(sandboxURL, sessionID), nil)
+	return err
+}
+
+// do sends a session request and returns the response body. A 404
+// response is reported as errSandboxNotFound.
+func (c *SandboxClient) do(ctx context.Context, method, target string, content []byte) ([]byte, error) {
+	var body io.Reader
+	if content != nil {
+		body = bytes.NewReader(content)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errSandboxNotFound
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("sandbox returned HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+func sessionURL(sandboxURL, sessionID string) string {
+	return sandboxURL + "/sessions/" + url.PathEscape(sessionID)
+}
+
+// sessionFileURL escapes each segment of the slash-separated path.
+func sessionFileURL(sandboxURL, sessionID, path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return sessionURL(sandboxURL, sessionID) + "/files/" + strings.Join(segments, "/")
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 	"time"
 )
@@ -133,6 +134,87 @@ func TestSandboxClient_Execute(t *testing.T) {
 	}
 }
 
+func TestSandboxClient_ExecuteStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantErr    bool
+		wantOutput []string
+		wantStdout string
+	}{
+		{
+			name: "output then result",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/execute/stream" {
+					t.Errorf("path = %s, want /execute/stream", r.URL.Path)
+				}
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				enc := json.NewEncoder(w)
+				enc.Encode(SandboxStreamLine{Type: "stdout", Data: "1\n"})
+				enc.Encode(SandboxStreamLine{Type: "stderr", Data: "oops\n"})
+				enc.Encode(SandboxStreamLine{Type: "stdout", Data: "2\n"})
+				enc.Encode(SandboxStreamLine{Type: "result", Result: &SandboxResponse{Status: "success", Stdout: "1\n2\n", Stderr: "oops\n"}})
+			},
+			wantOutput: []string{"stdout:1\n", "stderr:oops\n", "stdout:2\n"},
+			wantStdout: "1\n2\n",
+		},
+		{
+			name: "stream without result",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(SandboxStreamLine{Type: "stdout", Data: "1\n"})
+			},
+			wantErr:    true,
+			wantOutput: []string{"stdout:1\n"},
+		},
+		{
+			name: "sandbox at capacity (429)",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantErr: true,
+		},
+		{
+			name: "sandbox without streaming",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/execute" {
+					http.NotFound(w, r)
+					return
+				}
+				json.NewEncoder(w).Encode(SandboxResponse{Status: "success", Stdout: "42\n"})
+			},
+			wantStdout: "42\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			var output []string
+			client := NewSandboxClient()
+			resp, err := client.ExecuteStream(context.Background(), srv.URL, &SandboxRequest{Code: "print(1)"}, func(stream, data string) {
+				output = append(output, stream+":"+data)
+			})
+			if !slices.Equal(output, tt.wantOutput) {
+				t.Errorf("output = %q, want %q", output, tt.wantOutput)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Stdout != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", resp.Stdout, tt.wantStdout)
+			}
+		})
+	}
+}
+
 func TestSandboxClient_Execute_ContextTimeout(t *testing.T) {
 	// Server that sleeps longer than the context deadline.
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
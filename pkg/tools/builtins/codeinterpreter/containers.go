@@ -0,0 +1,341 @@
+package codeinterpreter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/storage"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// Limits of a container's idle expiry, in minutes.
+const (
+	minContainerMinutes = 1
+	maxContainerMinutes = 24 * 60
+)
+
+var (
+	errContainerNotFound = errors.New("container not found")
+	errTooManyContainers = errors.New("too many containers")
+)
+
+// Container is a sandbox session that keeps its working directory,
+// installed packages and (with a persistent kernel) interpreter state
+// across code executions. It expires after being idle for a while.
+type Container struct {
+	ID           string          `json:"id"`
+	Object       string          `json:"object"`
+	Name         string          `json:"name"`
+	Status       string          `json:"status"`
+	CreatedAt    int64           `json:"created_at"`
+	LastActiveAt int64           `json:"last_active_at"`
+	ExpiresAfter ContainerExpiry `json:"expires_after"`
+}
+
+// ContainerExpiry is the idle time after which a container is removed.
+type ContainerExpiry struct {
+	Anchor  string `json:"anchor"` // always "last_active_at"
+	Minutes int    `json:"minutes"`
+}
+
+// container is a Container with the sandbox it runs in.
+type container struct {
+	Container
+	owner      string
+	sandboxURL string
+	release    func()
+	mounted    map[string]bool // uploaded files copied into the container
+}
+
+// expired reports whether the container has been idle for too long.
+func (c *container) expired(now time.Time) bool {
+	idle := time.Duration(c.ExpiresAfter.Minutes) * time.Minute
+	return now.Sub(time.Unix(c.LastActiveAt, 0)) > idle
+}
+
+// containerManager keeps the containers of all users in memory. Each
+// container holds its sandbox until it is deleted or expires.
+type containerManager struct {
+	acquirer       SandboxAcquirer
+	client         *SandboxClient
+	defaultMinutes int
+	maxPerOwner    int
+	now            func() time.Time
+
+	mu         sync.Mutex
+	containers map[string]*container
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newContainerManager(acquirer SandboxAcquirer, client *SandboxClient, defaultMinutes, maxPerOwner int) *containerManager {
+	return &containerManager{
+		acquirer:       acquirer,
+		client:         client,
+		defaultMinutes: defaultMinutes,
+		maxPerOwner:    maxPerOwner,
+		now:            time.Now,
+		containers:     make(map[string]*container),
+		stop:           make(chan struct{}),
+	}
+}
+
+// run removes expired containers every minute until close is called.
+func (m *containerManager) run() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.expire()
+		}
+	}
+}
+
+// create acquires a sandbox for a new container owned by the caller in
+// ctx. Zero minutes selects the default expiry. Callers that already own
+// the maximum number of containers get errTooManyContainers.
+func (m *containerManager) create(ctx context.Context, name string, minutes int) (*container, error) {
+	if minutes == 0 {
+		minutes = m.defaultMinutes
+	}
+	owner := storage.GetOwner(ctx)
+
+	m.mu.Lock()
+	full := m.full(owner)
+	m.mu.Unlock()
+	if full {
+		return nil, errTooManyContainers
+	}
+
+	sandboxURL, release, err := m.acquirer.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire sandbox: %w", err)
+	}
+
+	now := m.now().Unix()
+	c := &container{
+		Container: Container{
+			ID:           api.NewContainerID(),
+			Object:       "container",
+			Name:         name,
+			Status:       "running",
+			CreatedAt:    now,
+			LastActiveAt: now,
+			ExpiresAfter: ContainerExpiry{Anchor: "last_active_at", Minutes: minutes},
+		},
+		owner:      owner,
+		sandboxURL: sandboxURL,
+		release:    release,
+		mounted:    make(map[string]bool),
+	}
+
+	// Check again, containers may have been created while acquiring.
+	m.mu.Lock()
+	if m.full(owner) {
+		m.mu.Unlock()
+		release()
+		return nil, errTooManyContainers
+	}
+	m.containers[c.ID] = c
+	m.mu.Unlock()
+
+	slog.Debug("code_interpreter: container created", "container", c.ID, "expires_after_minutes", minutes)
+	return c, nil
+}
+
+// full reports whether owner has as many live containers as allowed.
+// The caller must hold m.mu.
+func (m *containerManager) full(owner string) bool {
+	if m.maxPerOwner <= 0 {
+		return false
+	}
+	now := m.now()
+	n := 0
+	for _, c := range m.containers {
+		if c.owner == owner && !c.expired(now) {
+			n++
+		}
+	}
+	return n >= m.maxPerOwner
+}
+
+// get returns a snapshot of the container if the caller in ctx may use
+// it. Callers may read other users' containers only if they are admins
+// and write is false. Containers that are missing, expired or not
+// accessible are reported as errContainerNotFound.
+func (m *containerManager) get(ctx context.Context, id string, write bool) (*container, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.containers[id]
+	if !ok || c.expired(m.now()) || !allowed(ctx, c.owner, write) {
+		return nil, errContainerNotFound
+	}
+	snapshot := *c
+	return &snapshot, nil
+}
+
+// list returns the caller's containers, newest first.
+func (m *containerManager) list(ctx context.Context) []Container {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	list := []Container{}
+	for _, c := range m.containers {
+		if !c.expired(now) && allowed(ctx, c.owner, false) {
+			list = append(list, c.Container)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt != list[j].CreatedAt {
+			return list[i].CreatedAt > list[j].CreatedAt
+		}
+		return list[i].ID > list[j].ID
+	})
+	return list
+}
+
+// touch marks the container as active now.
+func (m *containerManager) touch(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.containers[id]; ok {
+		c.LastActiveAt = m.now().Unix()
+	}
+}
+
+// unmounted returns the files of fileIDs that have not been copied into
+// the container yet.
+func (m *containerManager) unmounted(id string, fileIDs []string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.containers[id]
+	var pending []string
+	for _, fileID := range fileIDs {
+		if !ok || !c.mounted[fileID] {
+			pending = append(pending, fileID)
+		}
+	}
+	return pending
+}
+
+// markMounted records that the files have been copied into the container.
+func (m *containerManager) markMounted(id string, fileIDs []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.containers[id]; ok {
+		for _, fileID := range fileIDs {
+			c.mounted[fileID] = true
+		}
+	}
+}
+
+// bind returns the container of the binding, creating one if the binding
+// names none or its container has expired. An explicit container that
+// is gone is an error.
+func (m *containerManager) bind(ctx context.Context, b *tools.ContainerBinding) (*container, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.ID != "" {
+		c, err := m.get(ctx, b.ID, true)
+		if err == nil {
+			m.touch(c.ID)
+			return c, nil
+		}
+		if b.Explicit {
+			return nil, fmt.Errorf("container %s not found or expired", b.ID)
+		}
+		slog.Debug("code_interpreter: container expired, creating a new one", "container", b.ID)
+	}
+
+	c, err := m.create(ctx, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	b.ID = c.ID
+	return c, nil
+}
+
+// delete removes the container and releases its sandbox.
+func (m *containerManager) delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	c, ok := m.containers[id]
+	if !ok || !allowed(ctx, c.owner, false) {
+		m.mu.Unlock()
+		return errContainerNotFound
+	}
+	delete(m.containers, id)
+	m.mu.Unlock()
+
+	m.destroy(c)
+	return nil
+}
+
+// expire removes the containers that have been idle for too long.
+func (m *containerManager) expire() {
+	now := m.now()
+
+	m.mu.Lock()
+	var expired []*container
+	for id, c := range m.containers {
+		if c.expired(now) {
+			delete(m.containers, id)
+			expired = append(expired, c)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, c := range expired {
+		slog.Debug("code_interpreter: container expired", "container", c.ID)
+		m.destroy(c)
+	}
+}
+
+// close stops expiry and removes all containers.
+func (m *containerManager) close() {
+	m.stopOnce.Do(func() { close(m.stop) })
+
+	m.mu.Lock()
+	all := m.containers
+	m.containers = make(map[string]*container)
+	m.mu.Unlock()
+
+	for _, c := range all {
+		m.destroy(c)
+	}
+}
+
+// destroy deletes the container's session and releases its sandbox.
+func (m *containerManager) destroy(c *container) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := m.client.DeleteSession(ctx, c.sandboxURL, c.ID); err != nil && !errors.Is(err, errSandboxNotFound) {
+		slog.Warn("code_interpreter: deleting container session failed", "container", c.ID, "error", err)
+	}
+	c.release()
+}
+
+// allowed reports whether the caller in ctx may access a container of
+// owner. Without authentication everything is allowed. With it, containers
+// without an owner belong to no caller. Admins may read and delete other
+// users' containers, but not run code in them.
+func allowed(ctx context.Context, owner string, write bool) bool {
+	caller := storage.GetOwner(ctx)
+	if caller == "" || caller == owner {
+		return true
+	}
+	return !write && storage.GetAdmin(ctx)
+}
@@ -0,0 +1,471 @@
+package codeinterpreter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/storage"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// fakeSessionSandbox is an in-memory sandbox server with sessions.
+type fakeSessionSandbox struct {
+	mu       sync.Mutex
+	files    map[string]map[string]string // session -> path -> content
+	executed []string                     // session of each execution
+	inputs   []map[string]string          // input files of each execution
+	deleted  []string                     // deleted sessions
+}
+
+func (f *fakeSessionSandbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.files == nil {
+		f.files = map[string]map[string]string{}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /execute", func(w http.ResponseWriter, r *http.Request) {
+		var req SandboxRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.executed = append(f.executed, req.SessionID)
+		f.inputs = append(f.inputs, req.Files)
+		json.NewEncoder(w).Encode(SandboxResponse{Status: "success", Stdout: "ok\n"})
+	})
+	mux.HandleFunc("GET /sessions/{id}/files", func(w http.ResponseWriter, r *http.Request) {
+		list := []SandboxFile{}
+		for p, content := range f.files[r.PathValue("id")] {
+			list = append(list, SandboxFile{Path: p, Bytes: int64(len(content)), ModifiedAt: 1700000000})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+		json.NewEncoder(w).Encode(map[string]any{"files": list})
+	})
+	mux.HandleFunc("GET /sessions/{id}/files/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		content, ok := f.files[r.PathValue("id")][r.PathValue("path")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, content)
+	})
+	mux.HandleFunc("PUT /sessions/{id}/files/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		id := r.PathValue("id")
+		if f.files[id] == nil {
+			f.files[id] = map[string]string{}
+		}
+		f.files[id][r.PathValue("path")] = string(data)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /sessions/{id}/files/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		delete(f.files[r.PathValue("id")], r.PathValue("path"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("DELETE /sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		delete(f.files, r.PathValue("id"))
+		f.deleted = append(f.deleted, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.ServeHTTP(w, r)
+}
+
+// countingAcquirer hands out one sandbox URL and counts acquisitions
+// and releases.
+type countingAcquirer struct {
+	url      string
+	mu       sync.Mutex
+	acquired int
+	released int
+}
+
+func (a *countingAcquirer) Acquire(_ context.Context) (string, func(), error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acquired++
+	return a.url, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.released++
+	}, nil
+}
+
+func newTestProvider(t *testing.T) (*CodeInterpreterProvider, *fakeSessionSandbox, *countingAcquirer) {
+	t.Helper()
+	sandbox := &fakeSessionSandbox{}
+	server := httptest.NewServer(sandbox)
+	t.Cleanup(server.Close)
+
+	acquirer := &countingAcquirer{url: server.URL}
+	client := NewSandboxClient()
+	p := &CodeInterpreterProvider{
+		acquirer:   acquirer,
+		client:     client,
+		config:     Config{ExecutionTimeout: 30, ContainerMinutes: 20, MaxContainersPerOwner: 10, MaxInputFileSize: 10, MaxInputTotalSize: 16},
+		containers: newContainerManager(acquirer, client, 20, 10),
+	}
+	return p, sandbox, acquirer
+}
+
+// fakeFileSource serves uploaded files of one owner.
+type fakeFileSource struct {
+	owner string
+	files map[string][2]string // file ID -> name, content
+}
+
+func (f *fakeFileSource) ReadFile(ctx context.Context, fileID string, maxBytes int64) (string, []byte, error) {
+	file, ok := f.files[fileID]
+	if !ok || storage.GetOwner(ctx) != f.owner {
+		return "", nil, fmt.Errorf("file %s not found", fileID)
+	}
+	if int64(len(file[1])) > maxBytes {
+		return "", nil, fmt.Errorf("file %s is too large", fileID)
+	}
+	return file[0], []byte(file[1]), nil
+}
+
+func asUser(user string) context.Context {
+	return storage.SetOwner(context.Background(), user)
+}
+
+func TestExecute_ContainerBinding(t *testing.T) {
+	p, sandbox, acquirer := newTestProvider(t)
+	binding := &tools.ContainerBinding{}
+	ctx := tools.SetContainerBinding(asUser("alice"), binding)
+
+	var containers []string
+	for i := 0; i < 2; i++ {
+		result, err := p.Execute(ctx, tools.ToolCall{ID: "call", Name: "code_interpreter", Arguments: `{"code": "x = 1"}`})
+		if err != nil || result.IsError {
+			t.Fatalf("Execute: %v %+v", err, result)
+		}
+		var data struct {
+			ContainerID string `json:"container_id"`
+		}
+		json.Unmarshal([]byte(result.Output), &data)
+		containers = append(containers, data.ContainerID)
+	}
+
+	if binding.ID == "" || containers[0] != binding.ID || containers[1] != binding.ID {
+		t.Errorf("container IDs: outputs %v, binding %q", containers, binding.ID)
+	}
+	if acquirer.acquired != 1 || acquirer.released != 0 {
+		t.Errorf("acquired %d, released %d; want one sandbox held by the container", acquirer.acquired, acquirer.released)
+	}
+	if len(sandbox.executed) != 2 || sandbox.executed[0] != binding.ID || sandbox.executed[1] != binding.ID {
+		t.Errorf("executed in sessions %v, want %s twice", sandbox.executed, binding.ID)
+	}
+
+	// Another user's chain cannot run code in the container; it gets its own.
+	other := &tools.ContainerBinding{ID: binding.ID}
+	if _, err := p.Execute(tools.SetContainerBinding(asUser("bob"), other), tools.ToolCall{ID: "call", Arguments: `{"code": "x"}`}); err != nil {
+		t.Fatal(err)
+	}
+	if other.ID == binding.ID {
+		t.Error("bob reused alice's container")
+	}
+
+	// An explicit container that does not exist is an error.
+	explicit := &tools.ContainerBinding{ID: "cntr_missing", Explicit: true}
+	result, _ := p.Execute(tools.SetContainerBinding(asUser("alice"), explicit), tools.ToolCall{ID: "call", Arguments: `{"code": "x"}`})
+	if !result.IsError || !strings.Contains(result.Output, "cntr_missing") {
+		t.Errorf("explicit missing container: got %+v", result)
+	}
+}
+
+func TestExecute_WithoutBindingReleasesSandbox(t *testing.T) {
+	p, sandbox, acquirer := newTestProvider(t)
+
+	result, err := p.Execute(context.Background(), tools.ToolCall{ID: "call", Arguments: `{"code": "x"}`})
+	if err != nil || result.IsError {
+		t.Fatalf("Execute: %v %+v", err, result)
+	}
+	if acquirer.acquired != 1 || acquirer.released != 1 {
+		t.Errorf("acquired %d, released %d; want the sandbox released", acquirer.acquired, acquirer.released)
+	}
+	if sandbox.executed[0] != "" {
+		t.Errorf("session = %q, want none", sandbox.executed[0])
+	}
+}
+
+func TestContainerManager_Expire(t *testing.T) {
+	p, sandbox, acquirer := newTestProvider(t)
+	m := p.containers
+	now := time.Unix(1700000000, 0)
+	m.now = func() time.Time { return now }
+
+	short, err := m.create(asUser("alice"), "short", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	long, err := m.create(asUser("alice"), "long", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if _, err := m.get(asUser("alice"), short.ID, false); err == nil {
+		t.Error("expired container is still returned")
+	}
+	m.expire()
+
+	if got := m.list(asUser("alice")); len(got) != 1 || got[0].ID != long.ID {
+		t.Errorf("list after expiry = %+v, want only %s", got, long.ID)
+	}
+	if len(sandbox.deleted) != 1 || sandbox.deleted[0] != short.ID {
+		t.Errorf("deleted sessions = %v, want [%s]", sandbox.deleted, short.ID)
+	}
+	if acquirer.released != 1 {
+		t.Errorf("released = %d, want 1", acquirer.released)
+	}
+
+	// Activity postpones expiry.
+	now = now.Add(55 * time.Minute)
+	m.touch(long.ID)
+	now = now.Add(55 * time.Minute)
+	m.expire()
+	if _, err := m.get(asUser("alice"), long.ID, false); err != nil {
+		t.Errorf("active container expired: %v", err)
+	}
+
+	p.Close()
+	if acquirer.released != 2 {
+		t.Errorf("released after Close = %d, want 2", acquirer.released)
+	}
+}
+
+func TestContainerManager_Ownership(t *testing.T) {
+	p, _, _ := newTestProvider(t)
+	m := p.containers
+
+	c, err := m.create(asUser("alice"), "analysis", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ExpiresAfter.Minutes != 20 {
+		t.Errorf("default expiry = %d minutes, want 20", c.ExpiresAfter.Minutes)
+	}
+
+	if _, err := m.get(asUser("bob"), c.ID, false); err == nil {
+		t.Error("bob can read alice's container")
+	}
+	if got := m.list(asUser("bob")); len(got) != 0 {
+		t.Errorf("bob lists %d containers, want 0", len(got))
+	}
+	if err := m.delete(asUser("bob"), c.ID); err == nil {
+		t.Error("bob can delete alice's container")
+	}
+
+	admin := storage.SetAdmin(asUser("root"), true)
+	if _, err := m.get(admin, c.ID, false); err != nil {
+		t.Errorf("admin cannot read: %v", err)
+	}
+	if _, err := m.get(admin, c.ID, true); err == nil {
+		t.Error("admin can run code in another user's container")
+	}
+	if err := m.delete(admin, c.ID); err != nil {
+		t.Errorf("admin cannot delete: %v", err)
+	}
+
+	// Containers created without a caller belong to no authenticated user.
+	orphan, err := m.create(context.Background(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.get(asUser("bob"), orphan.ID, true); err == nil {
+		t.Error("bob can use a container without owner")
+	}
+	if got := m.list(asUser("bob")); len(got) != 0 {
+		t.Errorf("bob lists %d containers, want 0", len(got))
+	}
+	if err := m.delete(asUser("bob"), orphan.ID); err == nil {
+		t.Error("bob can delete a container without owner")
+	}
+}
+
+func TestContainerManager_MaxPerOwner(t *testing.T) {
+	p, _, acquirer := newTestProvider(t)
+	m := p.containers
+	m.maxPerOwner = 3
+
+	for i := 0; i < 3; i++ {
+		if _, err := m.create(asUser("alice"), "", 0); err != nil {
+			t.Fatalf("create %d: %v", i, err)
+		}
+	}
+	if _, err := m.create(asUser("alice"), "", 0); !errors.Is(err, errTooManyContainers) {
+		t.Errorf("fourth container: err = %v, want errTooManyContainers", err)
+	}
+	if acquirer.acquired != 3 {
+		t.Errorf("acquired = %d, want 3", acquirer.acquired)
+	}
+	if _, err := m.create(asUser("bob"), "", 0); err != nil {
+		t.Errorf("bob's first container: %v", err)
+	}
+
+	// Deleting a container makes room for another.
+	if err := m.delete(asUser("alice"), m.list(asUser("alice"))[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.create(asUser("alice"), "", 0); err != nil {
+		t.Errorf("create after delete: %v", err)
+	}
+}
+
+func TestContainerRoutes(t *testing.T) {
+	p, _, _ := newTestProvider(t)
+	mux := http.NewServeMux()
+	for _, route := range p.Routes() {
+		mux.HandleFunc(route.Method+" /v1"+route.Pattern, route.Handler)
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	do := func(method, path, contentType string, body io.Reader, wantStatus int, out any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, body)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != wantStatus {
+			t.Fatalf("%s %s: status %d, want %d: %s", method, path, resp.StatusCode, wantStatus, data)
+		}
+		if out != nil {
+			if s, ok := out.(*string); ok {
+				*s = string(data)
+			} else if err := json.Unmarshal(data, out); err != nil {
+				t.Fatalf("%s %s: decoding %s: %v", method, path, data, err)
+			}
+		}
+	}
+
+	// Create and list.
+	do("POST", "/v1/containers", "application/json", strings.NewReader(`{"name": "x", "expires_after": {"anchor": "last_active_at", "minutes": 0}}`), http.StatusBadRequest, nil)
+	var c Container
+	do("POST", "/v1/containers", "application/json", strings.NewReader(`{"name": "analysis", "expires_after": {"anchor": "last_active_at", "minutes": 5}}`), http.StatusCreated, &c)
+	if c.Object != "container" || c.Name != "analysis" || c.Status != "running" || c.ExpiresAfter.Minutes != 5 || !strings.HasPrefix(c.ID, "cntr_") {
+		t.Errorf("created container = %+v", c)
+	}
+	var containers listResponse[Container]
+	do("GET", "/v1/containers", "", nil, http.StatusOK, &containers)
+	if len(containers.Data) != 1 || containers.FirstID != c.ID {
+		t.Errorf("list = %+v", containers)
+	}
+
+	// Upload, list, read and delete a file.
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "data.csv")
+	io.WriteString(fw, "a,b\n1,2\n")
+	mw.Close()
+	var file ContainerFile
+	do("POST", "/v1/containers/"+c.ID+"/files", mw.FormDataContentType(), &body, http.StatusCreated, &file)
+	if file.Path != "data.csv" || file.Bytes != 8 || file.ContainerID != c.ID || file.Object != "container.file" {
+		t.Errorf("uploaded file = %+v", file)
+	}
+
+	var files listResponse[ContainerFile]
+	do("GET", "/v1/containers/"+c.ID+"/files", "", nil, http.StatusOK, &files)
+	if len(files.Data) != 1 || files.Data[0].ID != file.ID {
+		t.Errorf("files = %+v, want %s", files, file.ID)
+	}
+	var content string
+	do("GET", "/v1/containers/"+c.ID+"/files/"+file.ID+"/content", "", nil, http.StatusOK, &content)
+	if content != "a,b\n1,2\n" {
+		t.Errorf("content = %q", content)
+	}
+	do("DELETE", "/v1/containers/"+c.ID+"/files/"+file.ID, "", nil, http.StatusOK, nil)
+	do("GET", "/v1/containers/"+c.ID+"/files/"+file.ID, "", nil, http.StatusNotFound, nil)
+
+	// Delete the container.
+	do("DELETE", "/v1/containers/"+c.ID, "", nil, http.StatusOK, nil)
+	do("GET", "/v1/containers/"+c.ID, "", nil, http.StatusNotFound, nil)
+}
+
+func TestExecute_InputFiles(t *testing.T) {
+	p, sandbox, _ := newTestProvider(t)
+	p.SetFileSource(&fakeFileSource{owner: "alice", files: map[string][2]string{
+		"file_a":   {"data.csv", "a,b\n1,2\n"},
+		"file_b":   {"data.csv", "x\n"},
+		"file_big": {"big.bin", "0123456789abc"},
+		"file_c":   {"c.txt", "ccccccccc"},
+	}})
+	run := func(ctx context.Context) *tools.ToolResult {
+		t.Helper()
+		result, err := p.Execute(ctx, tools.ToolCall{ID: "call", Name: "code_interpreter", Arguments: `{"code": "x = 1"}`})
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		return result
+	}
+
+	binding := &tools.ContainerBinding{FileIDs: []string{"file_a", "file_b"}}
+	ctx := tools.SetContainerBinding(asUser("alice"), binding)
+	if result := run(ctx); result.IsError {
+		t.Fatalf("Execute: %s", result.Output)
+	}
+	if result := run(ctx); result.IsError {
+		t.Fatalf("second Execute: %s", result.Output)
+	}
+
+	sandbox.mu.Lock()
+	inputs := sandbox.inputs
+	sandbox.mu.Unlock()
+	if len(inputs) != 2 {
+		t.Fatalf("executions = %d, want 2", len(inputs))
+	}
+	want := map[string]string{
+		"data.csv":        base64.StdEncoding.EncodeToString([]byte("a,b\n1,2\n")),
+		"file_b_data.csv": base64.StdEncoding.EncodeToString([]byte("x\n")),
+	}
+	if fmt.Sprint(inputs[0]) != fmt.Sprint(want) {
+		t.Errorf("first execution files = %v, want %v", inputs[0], want)
+	}
+	if len(inputs[1]) != 0 {
+		t.Errorf("files copied again: %v", inputs[1])
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		fileIDs []string
+		want    string
+	}{
+		{"other owner", asUser("bob"), []string{"file_a"}, "file_a not found"},
+		{"file too large", asUser("alice"), []string{"file_big"}, "too large"},
+		{"total too large", asUser("alice"), []string{"file_a", "file_c"}, "exceed the limit of 16 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tools.SetContainerBinding(tt.ctx, &tools.ContainerBinding{FileIDs: tt.fileIDs})
+			result := run(ctx)
+			if !result.IsError || !strings.Contains(result.Output, tt.want) {
+				t.Errorf("result = %+v, want error containing %q", result, tt.want)
+			}
+		})
+	}
+
+	p.SetFileSource(nil)
+	ctx = tools.SetContainerBinding(asUser("alice"), &tools.ContainerBinding{FileIDs: []string{"file_a"}})
+	if result := run(ctx); !result.IsError || !strings.Contains(result.Output, "files API is not enabled") {
+		t.Errorf("result without file source = %+v", result)
+	}
+}
@@ -202,10 +202,153 @@ func TestIntegration_CodeInterpreter_InvalidArguments(t *testing.T) {
 	}
 }
 
-// startSandboxServer builds and starts the real sandbox-server binary as a subprocess.
+func TestIntegration_CodeInterpreter_Container(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name   string
+		env    []string
+		second string // code that relies on the first execution
+	}{
+		{"working directory", nil, "print(open('state.txt').read())"},
+		{"persistent kernel", []string{"SANDBOX_PERSISTENT_KERNEL=true"}, "print(state)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sandboxURL := startSandboxServer(t, tt.env...)
+			provider, err := New(map[string]any{"sandbox_url": sandboxURL})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer provider.Close()
+
+			ctx := tools.SetContainerBinding(context.Background(), &tools.ContainerBinding{})
+			run := func(code string) api.CodeInterpreterCallData {
+				t.Helper()
+				args, _ := json.Marshal(map[string]string{"code": code})
+				result, err := provider.Execute(ctx, tools.ToolCall{ID: "call", Name: "code_interpreter", Arguments: string(args)})
+				if err != nil {
+					t.Fatalf("Execute: %v", err)
+				}
+				if result.IsError {
+					t.Fatalf("unexpected error: %s", result.Output)
+				}
+				var data api.CodeInterpreterCallData
+				json.Unmarshal([]byte(result.Output), &data)
+				return data
+			}
+
+			first := run("state = 'kept'\nopen('state.txt', 'w').write(state)")
+			second := run(tt.second)
+			if first.ContainerID == "" || second.ContainerID != first.ContainerID {
+				t.Errorf("container IDs %q and %q, want the same", first.ContainerID, second.ContainerID)
+			}
+			if len(second.Outputs) == 0 || !strings.Contains(second.Outputs[0].Logs, "kept") {
+				t.Errorf("second execution did not see the state: %+v", second.Outputs)
+			}
+
+			// The file is listed in the container.
+			c, err := provider.containers.get(context.Background(), first.ContainerID, false)
+			if err != nil {
+				t.Fatalf("get container: %v", err)
+			}
+			files, err := provider.client.ListFiles(context.Background(), c.sandboxURL, c.ID)
+			if err != nil || len(files) != 1 || files[0].Path != "state.txt" {
+				t.Errorf("container files = %+v (err %v), want state.txt", files, err)
+			}
+		})
+	}
+}
+
+func TestIntegration_SandboxServer_Limits(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	t.Setenv("SANDBOX_TEST_SECRET", "hunter2")
+	sandboxURL := startSandboxServer(t,
+		"SANDBOX_MEMORY_LIMIT_MB=200",
+		"SANDBOX_CPU_LIMIT_SECONDS=1",
+		"SANDBOX_MAX_OUTPUT_BYTES=100",
+		"SANDBOX_MAX_OUTPUT_FILES=2",
+	)
+	client := NewSandboxClient()
+
+	tests := []struct {
+		name       string
+		code       string
+		wantStatus string
+		wantStderr string
+	}{
+		{"success", "print('hi')", "success", ""},
+		{"clean environment", "import os, sys\nsys.exit('SANDBOX_TEST_SECRET' in os.environ)", "success", ""},
+		{"memory", "x = bytearray(500 * 1024 * 1024)", "memory_limit_exceeded", "memory limit of 200 MiB exceeded"},
+		{"cpu time", "while True:\n    pass", "cpu_limit_exceeded", "CPU time limit of 1 seconds exceeded"},
+		{"output", "print('x' * 500)", "output_limit_exceeded", "output truncated after 100 bytes"},
+		{"files", "import os\nfor i in range(3):\n    open(os.path.join(os.environ['OUTPUT_DIR'], f'{i}.txt'), 'w').write('x')", "file_limit_exceeded", "1 output files not returned"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Execute(context.Background(), sandboxURL, &SandboxRequest{Code: tt.code, TimeoutSeconds: 20})
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if resp.Status != tt.wantStatus || !strings.Contains(resp.Stderr, tt.wantStderr) {
+				t.Errorf("status = %q, stderr = %q, want %q and %q", resp.Status, resp.Stderr, tt.wantStatus, tt.wantStderr)
+			}
+			if len(resp.Stdout) > 100 || len(resp.FilesProduced) > 2 {
+				t.Errorf("limits not applied: %d bytes of stdout, %d files", len(resp.Stdout), len(resp.FilesProduced))
+			}
+		})
+	}
+}
+
+func TestIntegration_SandboxServer_Stream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	for _, env := range [][]string{nil, {"SANDBOX_PERSISTENT_KERNEL=true"}} {
+		t.Run(fmt.Sprint(env), func(t *testing.T) {
+			sandboxURL := startSandboxServer(t, env...)
+			client := NewSandboxClient()
+
+			// Output arrives while the code runs; cancelling kills it.
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var output strings.Builder
+			_, err := client.ExecuteStream(ctx, sandboxURL, &SandboxRequest{
+				Code:           "import time\nprint('first')\ntime.sleep(30)",
+				TimeoutSeconds: 60,
+				SessionID:      "stream",
+			}, func(stream, data string) {
+				// Output may arrive in pieces.
+				if output.WriteString(data); strings.HasSuffix(output.String(), "\n") {
+					cancel()
+				}
+			})
+			if err == nil || output.String() != "first\n" {
+				t.Fatalf("output = %q, err = %v, want first and an error", output.String(), err)
+			}
+
+			// The session is free again once the code is gone.
+			ctx, cancelNext := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancelNext()
+			resp, err := client.ExecuteStream(ctx, sandboxURL, &SandboxRequest{Code: "print(2)", TimeoutSeconds: 5, SessionID: "stream"}, func(string, string) {})
+			if err != nil || resp.Status != "success" || resp.Stdout != "2\n" {
+				t.Errorf("next execution: %+v, err = %v", resp, err)
+			}
+		})
+	}
+}
+
+// startSandboxServer builds and starts the real sandbox-server binary as a subprocess,
+// with extraEnv added to its environment.
 // Returns the base URL (http://localhost:<port>).
 // The server is killed when the test completes.
-func startSandboxServer(t *testing.T) string {
+func startSandboxServer(t *testing.T, extraEnv ...string) string {
 	t.Helper()
 
 	// Check Python is available.
@@ -232,7 +375,9 @@ func startSandboxServer(t *testing.T) string {
 		fmt.Sprintf("SANDBOX_PORT=%d", port),
 		"SANDBOX_MODE=python",
 		"SANDBOX_MAX_CONCURRENT=2",
+		"SANDBOX_SESSION_DIR="+tmpDir+"/sessions",
 	)
+	cmd.Env = append(cmd.Env, extraEnv...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -2,10 +2,12 @@ package codeinterpreter
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -39,6 +41,21 @@ type Config struct {
 
 	// ClaimTimeout is how long to wait for a SandboxClaim to be bound (seconds).
 	ClaimTimeout int
+
+	// ContainerMinutes is the default idle time after which a container
+	// is removed, in minutes.
+	ContainerMinutes int
+
+	// MaxContainersPerOwner limits the live containers of each caller.
+	MaxContainersPerOwner int
+
+	// MaxInputFileSize limits each uploaded file copied into a container,
+	// in bytes.
+	MaxInputFileSize int64
+
+	// MaxInputTotalSize limits the uploaded files copied into a container
+	// by one execution, in bytes.
+	MaxInputTotalSize int64
 }
 
 // SandboxAcquirer abstracts sandbox acquisition. Implementations exist for
@@ -49,19 +66,42 @@ type SandboxAcquirer interface {
 	Acquire(ctx context.Context) (sandboxURL string, release func(), err error)
 }
 
+// FileSaver persists files produced by code executions, so that clients
+// can download them. It is implemented by the Files API provider.
+type FileSaver interface {
+	// SaveOutputFile stores the file for the caller in ctx and returns
+	// its file ID.
+	SaveOutputFile(ctx context.Context, filename string, content []byte) (string, error)
+}
+
+// FileSource reads files uploaded through the Files API, so that they can
+// be copied into containers. It is implemented by the Files API provider.
+type FileSource interface {
+	// ReadFile returns the name and content of a file the caller in ctx
+	// may read. Files larger than maxBytes are an error.
+	ReadFile(ctx context.Context, fileID string, maxBytes int64) (string, []byte, error)
+}
+
 // CodeInterpreterProvider is a FunctionProvider that executes Python code
 // in sandbox pods via the sandbox server REST API.
 type CodeInterpreterProvider struct {
-	acquirer SandboxAcquirer
-	client   *SandboxClient
-	config   Config
+	acquirer   SandboxAcquirer
+	client     *SandboxClient
+	config     Config
+	files      FileSaver
+	inputs     FileSource
+	containers *containerManager
 }
 
 // New creates a new CodeInterpreterProvider from configuration settings.
 func New(settings map[string]any) (*CodeInterpreterProvider, error) {
 	cfg := Config{
-		ExecutionTimeout: 60,
-		ClaimTimeout:     30,
+		ExecutionTimeout:      60,
+		ClaimTimeout:          30,
+		ContainerMinutes:      20,
+		MaxContainersPerOwner: 10,
+		MaxInputFileSize:      16 * 1024 * 1024,
+		MaxInputTotalSize:     32 * 1024 * 1024,
 	}
 
 	if v, ok := settings["sandbox_url"].(string); ok && v != "" {
@@ -79,6 +119,22 @@ func New(settings map[string]any) (*CodeInterpreterProvider, error) {
 	if v, ok := settings["claim_timeout"].(float64); ok && v > 0 {
 		cfg.ClaimTimeout = int(v)
 	}
+	if v, ok := settings["container_expires_after_minutes"].(float64); ok && v > 0 {
+		cfg.ContainerMinutes = int(v)
+	}
+	if v, ok := settings["max_containers_per_owner"].(float64); ok && v > 0 {
+		cfg.MaxContainersPerOwner = int(v)
+	}
+	if v, ok := settings["max_input_file_size"].(float64); ok && v > 0 {
+		cfg.MaxInputFileSize = int64(v)
+	}
+	if v, ok := settings["max_input_total_size"].(float64); ok && v > 0 {
+		cfg.MaxInputTotalSize = int64(v)
+	}
+
+	if cfg.ContainerMinutes < minContainerMinutes || cfg.ContainerMinutes > maxContainerMinutes {
+		return nil, fmt.Errorf("code_interpreter: container_expires_after_minutes must be between %d and %d", minContainerMinutes, maxContainerMinutes)
+	}
 
 	// Validate mutual exclusion.
 	if cfg.SandboxURL != "" && cfg.SandboxTemplate != "" {
@@ -126,13 +182,30 @@ func New(settings map[string]any) (*CodeInterpreterProvider, error) {
 		)
 	}
 
+	client := NewSandboxClient()
+	containers := newContainerManager(acquirer, client, cfg.ContainerMinutes, cfg.MaxContainersPerOwner)
+	go containers.run()
+
 	return &CodeInterpreterProvider{
-		acquirer: acquirer,
-		client:   NewSandboxClient(),
-		config:   cfg,
+		acquirer:   acquirer,
+		client:     client,
+		config:     cfg,
+		containers: containers,
 	}, nil
 }
 
+// SetFileSaver sets where produced files are saved. Without one, produced
+// files are only listed by name.
+func (p *CodeInterpreterProvider) SetFileSaver(s FileSaver) {
+	p.files = s
+}
+
+// SetFileSource sets where uploaded files are read from. Without one,
+// requests that attach files to the code interpreter fail.
+func (p *CodeInterpreterProvider) SetFileSource(s FileSource) {
+	p.inputs = s
+}
+
 // Name returns the provider name.
 func (p *CodeInterpreterProvider) Name() string {
 	return "code_interpreter"
@@ -160,7 +233,7 @@ func (p *CodeInterpreterProvider) Tools() []api.ToolDefinition {
 		{
 			Type:        "function",
 			Name:        "code_interpreter",
-			Description: "Execute Python code in an isolated sandbox. Use this to analyze data, perform calculations, or process files.",
+			Description: "Execute Python code in an isolated sandbox. Use this to analyze data, perform calculations, or process files. Files attached by the user are in the working directory under their file names.",
 			Parameters:  params,
 		},
 	}
@@ -189,23 +262,63 @@ func (p *CodeInterpreterProvider) Execute(ctx context.Context, call tools.ToolCa
 		}, nil
 	}
 
-	// Acquire a sandbox.
-	sandboxURL, release, err := p.acquirer.Acquire(ctx)
-	if err != nil {
-		return &tools.ToolResult{
-			CallID:  call.ID,
-			Output:  fmt.Sprintf("failed to acquire sandbox: %v", err),
-			IsError: true,
-		}, nil
+	// Run in the response's container, or in a fresh sandbox.
+	var sandboxURL, containerID string
+	var mounting []string
+	var inputFiles map[string]string
+	if binding := tools.GetContainerBinding(ctx); binding != nil {
+		c, err := p.containers.bind(ctx, binding)
+		if err != nil {
+			return &tools.ToolResult{
+				CallID:  call.ID,
+				Output:  err.Error(),
+				IsError: true,
+			}, nil
+		}
+		sandboxURL, containerID = c.sandboxURL, c.ID
+		defer p.containers.touch(containerID)
+
+		// Copy attached files into the container once.
+		mounting = p.containers.unmounted(containerID, binding.FileIDs)
+		inputFiles, err = p.readInputFiles(ctx, mounting)
+		if err != nil {
+			return &tools.ToolResult{
+				CallID:  call.ID,
+				Output:  err.Error(),
+				IsError: true,
+			}, nil
+		}
+	} else {
+		url, release, err := p.acquirer.Acquire(ctx)
+		if err != nil {
+			return &tools.ToolResult{
+				CallID:  call.ID,
+				Output:  fmt.Sprintf("failed to acquire sandbox: %v", err),
+				IsError: true,
+			}, nil
+		}
+		defer release()
+		sandboxURL = url
 	}
-	defer release()
 
-	// Execute in sandbox.
-	resp, err := p.client.Execute(ctx, sandboxURL, &SandboxRequest{
+	// Execute in sandbox. The container ID doubles as the sandbox session.
+	// The output is streamed if someone listens to it.
+	req := &SandboxRequest{
 		Code:           args.Code,
 		TimeoutSeconds: p.config.ExecutionTimeout,
 		Requirements:   args.Requirements,
-	})
+		Files:          inputFiles,
+		SessionID:      containerID,
+	}
+	var resp *SandboxResponse
+	var err error
+	if report := tools.GetOutputReporter(ctx); report != nil {
+		resp, err = p.client.ExecuteStream(ctx, sandboxURL, req, func(stream, data string) {
+			report(tools.Output{Stream: stream, Data: data})
+		})
+	} else {
+		resp, err = p.client.Execute(ctx, sandboxURL, req)
+	}
 	if err != nil {
 		slog.Warn("code_interpreter execution failed",
 			"call_id", call.ID,
@@ -218,23 +331,113 @@ func (p *CodeInterpreterProvider) Execute(ctx context.Context, call tools.ToolCa
 		}, nil
 	}
 
-	// Format as code_interpreter_call output.
-	output := formatCodeInterpreterOutput(args.Code, resp)
+	p.containers.markMounted(containerID, mounting)
+
+	// Save produced files and format as code_interpreter_call output.
+	produced := p.saveFiles(ctx, resp.FilesProduced)
+	for i := range produced {
+		produced[i].ContainerID = containerID
+	}
+	output := formatCodeInterpreterOutput(args.Code, containerID, resp, produced)
 
 	return &tools.ToolResult{
 		CallID: call.ID,
 		Output: output,
+		Files:  savedFiles(produced),
 	}, nil
 }
 
+// readInputFiles reads the uploaded files for the sandbox request
+// (base64-encoded content by name). A file whose name is already taken
+// is named after its ID as well.
+func (p *CodeInterpreterProvider) readInputFiles(ctx context.Context, fileIDs []string) (map[string]string, error) {
+	if len(fileIDs) == 0 {
+		return nil, nil
+	}
+	if p.inputs == nil {
+		return nil, fmt.Errorf("cannot attach files: the files API is not enabled")
+	}
+
+	files := make(map[string]string, len(fileIDs))
+	var total int64
+	for _, id := range fileIDs {
+		name, content, err := p.inputs.ReadFile(ctx, id, p.config.MaxInputFileSize)
+		if err != nil {
+			return nil, fmt.Errorf("cannot attach file: %w", err)
+		}
+		total += int64(len(content))
+		if total > p.config.MaxInputTotalSize {
+			return nil, fmt.Errorf("cannot attach files: together they exceed the limit of %d bytes", p.config.MaxInputTotalSize)
+		}
+
+		name = filepath.Base(name)
+		if name == "." || name == "/" || strings.HasPrefix(name, ".") {
+			name = id
+		}
+		if _, taken := files[name]; taken {
+			name = id + "_" + name
+		}
+		files[name] = base64.StdEncoding.EncodeToString(content)
+	}
+	return files, nil
+}
+
+// saveFiles saves the produced files (base64-encoded content by name) and
+// returns them sorted by name. Files that cannot be saved keep an empty
+// FileID.
+func (p *CodeInterpreterProvider) saveFiles(ctx context.Context, produced map[string]string) []tools.OutputFile {
+	names := make([]string, 0, len(produced))
+	for name := range produced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := make([]tools.OutputFile, 0, len(names))
+	for _, name := range names {
+		file := tools.OutputFile{Filename: name}
+		if p.files != nil {
+			content, err := base64.StdEncoding.DecodeString(produced[name])
+			if err == nil {
+				file.FileID, err = p.files.SaveOutputFile(ctx, name, content)
+			}
+			if err != nil {
+				slog.Warn("code_interpreter: saving produced file failed", "file", name, "error", err)
+			}
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// savedFiles returns the files that were saved.
+func savedFiles(files []tools.OutputFile) []tools.OutputFile {
+	var saved []tools.OutputFile
+	for _, f := range files {
+		if f.FileID != "" {
+			saved = append(saved, f)
+		}
+	}
+	return saved
+}
+
 // CanExecute returns true for the code_interpreter tool.
 func (p *CodeInterpreterProvider) CanExecute(toolName string) bool {
 	return toolName == "code_interpreter"
 }
 
-// Routes returns nil (no HTTP management routes for code_interpreter).
+// Routes returns the container management routes.
 func (p *CodeInterpreterProvider) Routes() []registry.Route {
-	return nil
+	return []registry.Route{
+		{Method: "POST", Pattern: "/containers", Handler: p.handleCreateContainer},
+		{Method: "GET", Pattern: "/containers", Handler: p.handleListContainers},
+		{Method: "GET", Pattern: "/containers/{container_id}", Handler: p.handleGetContainer},
+		{Method: "DELETE", Pattern: "/containers/{container_id}", Handler: p.handleDeleteContainer},
+		{Method: "POST", Pattern: "/containers/{container_id}/files", Handler: p.handleUploadContainerFile},
+		{Method: "GET", Pattern: "/containers/{container_id}/files", Handler: p.handleListContainerFiles},
+		{Method: "GET", Pattern: "/containers/{container_id}/files/{file_id}", Handler: p.handleGetContainerFile},
+		{Method: "GET", Pattern: "/containers/{container_id}/files/{file_id}/content", Handler: p.handleGetContainerFileContent},
+		{Method: "DELETE", Pattern: "/containers/{container_id}/files/{file_id}", Handler: p.handleDeleteContainerFile},
+	}
 }
 
 // Collectors returns nil (no custom Prometheus collectors).
@@ -242,14 +445,16 @@ func (p *CodeInterpreterProvider) Collectors() []prometheus.Collector {
 	return nil
 }
 
-// Close releases resources.
+// Close removes all containers and releases their sandboxes.
 func (p *CodeInterpreterProvider) Close() error {
+	p.containers.close()
 	return nil
 }
 
 // formatCodeInterpreterOutput creates a JSON string matching the
-// code_interpreter_call output format.
-func formatCodeInterpreterOutput(code string, resp *SandboxResponse) string {
+// code_interpreter_call output format. Images refer to their saved file;
+// other files are listed with their file ID, if saved.
+func formatCodeInterpreterOutput(code, containerID string, resp *SandboxResponse, files []tools.OutputFile) string {
 	outputs := []api.CodeInterpreterOutput{}
 
 	// Add logs (stdout + stderr).
@@ -268,27 +473,33 @@ func formatCodeInterpreterOutput(code string, resp *SandboxResponse) string {
 	}
 
 	// Add file outputs.
-	for name := range resp.FilesProduced {
-		ext := strings.ToLower(filepath.Ext(name))
-		if ext == ".png" || ext == ".jpg" || ext == ".jpeg" || ext == ".svg" || ext == ".gif" {
+	for _, f := range files {
+		ext := strings.ToLower(filepath.Ext(f.Filename))
+		isImage := ext == ".png" || ext == ".jpg" || ext == ".jpeg" || ext == ".svg" || ext == ".gif"
+		switch {
+		case isImage && f.FileID != "":
+			outputs = append(outputs, api.CodeInterpreterOutput{
+				Type:  "image",
+				Image: &api.CodeInterpreterOutputImage{FileID: f.FileID},
+			})
+		case f.FileID != "":
 			outputs = append(outputs, api.CodeInterpreterOutput{
-				Type: "image",
-				Image: &api.CodeInterpreterOutputImage{
-					FileID: name, // Use filename as file_id for now.
-				},
+				Type: "logs",
+				Logs: fmt.Sprintf("[file: %s, file_id: %s]", f.Filename, f.FileID),
 			})
-		} else {
-			// Non-image files: include as logs with filename prefix.
+		default:
+			// Not saved: include as logs with filename prefix.
 			outputs = append(outputs, api.CodeInterpreterOutput{
 				Type: "logs",
-				Logs: fmt.Sprintf("[file: %s]", name),
+				Logs: fmt.Sprintf("[file: %s]", f.Filename),
 			})
 		}
 	}
 
 	data := api.CodeInterpreterCallData{
-		Code:    code,
-		Outputs: outputs,
+		Code:        code,
+		Outputs:     outputs,
+		ContainerID: containerID,
 	}
 
 	result, _ := json.Marshal(data)
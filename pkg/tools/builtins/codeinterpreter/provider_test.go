@@ -0,0 +1,126 @@
+package codeinterpreter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// fakeFileSaver records saved files. Files named in fail are rejected.
+type fakeFileSaver struct {
+	saved map[string]string // filename -> content
+	fail  map[string]bool
+}
+
+func (f *fakeFileSaver) SaveOutputFile(_ context.Context, filename string, content []byte) (string, error) {
+	if f.fail[filename] {
+		return "", errors.New("storage unavailable")
+	}
+	if f.saved == nil {
+		f.saved = map[string]string{}
+	}
+	f.saved[filename] = string(content)
+	return "file_" + filename, nil
+}
+
+// newFilesSandbox starts a sandbox server that produces the given files.
+func newFilesSandbox(t *testing.T, produced map[string]string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SandboxResponse{
+			Status:        "success",
+			Stdout:        "done\n",
+			FilesProduced: produced,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func executeCode(t *testing.T, p *CodeInterpreterProvider) (*tools.ToolResult, api.CodeInterpreterCallData) {
+	t.Helper()
+	result, err := p.Execute(context.Background(), tools.ToolCall{
+		ID:        "call_1",
+		Name:      "code_interpreter",
+		Arguments: `{"code": "plot()"}`,
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", result.Output)
+	}
+	var data api.CodeInterpreterCallData
+	if err := json.Unmarshal([]byte(result.Output), &data); err != nil {
+		t.Fatalf("decoding output: %v", err)
+	}
+	return result, data
+}
+
+func TestExecute_SavesProducedFiles(t *testing.T) {
+	sandboxURL := newFilesSandbox(t, map[string]string{
+		"chart.png":  "cG5n",         // "png"
+		"result.csv": "YSxiCjEsMg==", // "a,b\n1,2"
+		"broken.txt": "YQ==",
+	})
+	p, err := New(map[string]any{"sandbox_url": sandboxURL})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	saver := &fakeFileSaver{fail: map[string]bool{"broken.txt": true}}
+	p.SetFileSaver(saver)
+
+	result, data := executeCode(t, p)
+
+	if saver.saved["chart.png"] != "png" || saver.saved["result.csv"] != "a,b\n1,2" {
+		t.Errorf("saved files: got %v", saver.saved)
+	}
+
+	// Outputs: logs, then the files sorted by name.
+	want := []api.CodeInterpreterOutput{
+		{Type: "logs", Logs: "done\n"},
+		{Type: "logs", Logs: "[file: broken.txt]"},
+		{Type: "image", Image: &api.CodeInterpreterOutputImage{FileID: "file_chart.png"}},
+		{Type: "logs", Logs: "[file: result.csv, file_id: file_result.csv]"},
+	}
+	if len(data.Outputs) != len(want) {
+		t.Fatalf("outputs: got %d, want %d: %+v", len(data.Outputs), len(want), data.Outputs)
+	}
+	for i, out := range data.Outputs {
+		if out.Type != want[i].Type || out.Logs != want[i].Logs {
+			t.Errorf("output %d: got %+v, want %+v", i, out, want[i])
+		}
+		if want[i].Image != nil && (out.Image == nil || out.Image.FileID != want[i].Image.FileID) {
+			t.Errorf("output %d: image %+v, want %+v", i, out.Image, want[i].Image)
+		}
+	}
+
+	// Only saved files are reported for citation.
+	if len(result.Files) != 2 || result.Files[0].FileID != "file_chart.png" || result.Files[1].Filename != "result.csv" {
+		t.Errorf("result files: got %+v", result.Files)
+	}
+}
+
+func TestExecute_ProducedFilesWithoutSaver(t *testing.T) {
+	sandboxURL := newFilesSandbox(t, map[string]string{"chart.png": "cG5n"})
+	p, err := New(map[string]any{"sandbox_url": sandboxURL})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	result, data := executeCode(t, p)
+
+	if len(data.Outputs) != 2 || data.Outputs[1].Logs != "[file: chart.png]" {
+		t.Errorf("outputs: got %+v", data.Outputs)
+	}
+	if len(result.Files) != 0 {
+		t.Errorf("result files: got %+v, want none", result.Files)
+	}
+}
@@ -8,9 +8,17 @@ type SandboxRequest struct {
 	TimeoutSeconds int               `json:"timeout_seconds"`
 	Requirements   []string          `json:"requirements,omitempty"`
 	Files          map[string]string `json:"files,omitempty"`
+
+	// SessionID runs the code in the session's persistent working
+	// directory. Empty runs it in a fresh directory.
+	SessionID string `json:"session_id,omitempty"`
 }
 
 // SandboxResponse is the response from POST /execute on the sandbox server.
+// Status is "success", "error", or the limit the execution breached:
+// "timeout", "memory_limit_exceeded", "cpu_limit_exceeded",
+// "process_limit_exceeded", "output_limit_exceeded" or
+// "file_limit_exceeded".
 type SandboxResponse struct {
 	Status          string            `json:"status"`
 	Stdout          string            `json:"stdout"`
@@ -19,3 +27,21 @@ type SandboxResponse struct {
 	ExecutionTimeMs int64             `json:"execution_time_ms"`
 	FilesProduced   map[string]string `json:"files_produced,omitempty"`
 }
+
+// SandboxStreamLine is a line of the newline-delimited JSON response of
+// POST /execute/stream on the sandbox server. Lines of type "stdout" and
+// "stderr" carry output in Data as it is written; the last line, of type
+// "result", carries the SandboxResponse.
+type SandboxStreamLine struct {
+	Type   string           `json:"type"`
+	Data   string           `json:"data,omitempty"`
+	Result *SandboxResponse `json:"result,omitempty"`
+}
+
+// SandboxFile is a file in a sandbox session's working directory, as
+// listed by GET /sessions/{id}/files.
+type SandboxFile struct {
+	Path       string `json:"path"`
+	Bytes      int64  `json:"bytes"`
+	ModifiedAt int64  `json:"modified_at"`
+}
@@ -387,11 +387,26 @@ func (p *FileSearchProvider) Execute(ctx context.Context, call tools.ToolCall) (
 		}
 	}
 
+	sources := make([]tools.Source, 0, len(allMatches))
+	for _, m := range allMatches {
+		fileID := m.Metadata["file_id"]
+		if fileID == "" {
+			fileID = m.DocumentID
+		}
+		sources = append(sources, tools.Source{
+			FileID:   fileID,
+			Filename: m.Metadata["filename"],
+			Score:    float64(m.Score),
+			Text:     m.Content,
+		})
+	}
+
 	p.searchCount.WithLabelValues("success").Inc()
 	return &tools.ToolResult{
 		CallID:   call.ID,
 		Output:   output,
 		Metadata: metadata,
+		Sources:  sources,
 	}, nil
 }
 
@@ -191,10 +191,16 @@ func (p *WebSearchProvider) Execute(ctx context.Context, call tools.ToolCall) (*
 		}
 	}
 
+	sources := make([]tools.Source, 0, len(results))
+	for _, r := range results {
+		sources = append(sources, tools.Source{URL: r.URL, Title: r.Title, Text: r.Snippet})
+	}
+
 	return &tools.ToolResult{
 		CallID:   call.ID,
 		Output:   output,
 		Metadata: metadata,
+		Sources:  sources,
 	}, nil
 }
 
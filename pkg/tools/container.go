@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+	"sync"
+)
+
+// ContainerBinding pins the code interpreter container used by the tool
+// calls of a response. The engine sets it before running tools; the code
+// interpreter runs code in the container it names, or creates one and
+// records its ID, so that later calls and later turns share variables,
+// installed packages and files.
+type ContainerBinding struct {
+	// Mutex is held while the container is looked up or created, so that
+	// parallel calls share one container.
+	sync.Mutex
+
+	// ID is the container to use. Empty until a container is created.
+	ID string
+
+	// Explicit is true if the request named the container. An explicit
+	// container that has expired is an error rather than replaced.
+	Explicit bool
+
+	// FileIDs are uploaded files to copy into the container before code
+	// runs: those named by the tool's container and those attached to the
+	// request input as input_file parts.
+	FileIDs []string
+}
+
+// containerBindingKey is a private type for the container binding context key.
+type containerBindingKey struct{}
+
+// SetContainerBinding injects the container binding for the tool calls
+// executed with the context.
+func SetContainerBinding(ctx context.Context, b *ContainerBinding) context.Context {
+	return context.WithValue(ctx, containerBindingKey{}, b)
+}
+
+// GetContainerBinding extracts the container binding from the context.
+// Returns nil if none is set; the code interpreter then runs each call in
+// a fresh sandbox.
+func GetContainerBinding(ctx context.Context) *ContainerBinding {
+	if b, ok := ctx.Value(containerBindingKey{}).(*ContainerBinding); ok {
+		return b
+	}
+	return nil
+}
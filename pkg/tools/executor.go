@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+
+	"github.com/rhuss/antwort/pkg/api"
 )
 
 // ToolKind classifies how a tool is hosted and executed.
@@ -70,4 +72,35 @@ type ToolResult struct {
 	// Metadata carries structured source information for citation generation.
 	// Providers populate this with tool-specific data (e.g., file_id, url, title, content).
 	Metadata map[string]string
+
+	// Sources lists everything a search tool found, best match first.
+	// The engine reports them in the tool's output item.
+	Sources []Source
+
+	// Images holds images the tool returned, as input_image content parts
+	// with inline data. The engine shows them to vision-capable models.
+	Images []api.ContentPart
+
+	// Files lists files the tool produced and saved through the Files API.
+	// The engine cites them in the assistant's reply.
+	Files []OutputFile
+}
+
+// OutputFile is a file a tool produced, such as a chart the code
+// interpreter drew.
+type OutputFile struct {
+	FileID      string
+	Filename    string
+	ContainerID string // container the file was produced in, if any
+}
+
+// Source is a single search hit. Web search fills URL and Title, file
+// search fills FileID, Filename and Score. Text holds the matched content.
+type Source struct {
+	URL      string
+	Title    string
+	FileID   string
+	Filename string
+	Score    float64
+	Text     string
 }
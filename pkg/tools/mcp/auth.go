@@ -2,7 +2,10 @@ package mcp
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +13,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/rhuss/antwort/pkg/auth"
 )
 
 // AuthProvider supplies authentication headers for MCP server connections.
@@ -109,14 +114,19 @@ func (a *OAuthClientCredentialsAuth) fetchToken(ctx context.Context) (string, in
 	if len(a.Scopes) > 0 {
 		data.Set("scope", strings.Join(a.Scopes, " "))
 	}
+	return requestToken(ctx, a.httpClient, a.TokenURL, data)
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.TokenURL, strings.NewReader(data.Encode()))
+// requestToken posts a token request to an OAuth 2.0 token endpoint and
+// returns the access token and its lifetime in seconds.
+func requestToken(ctx context.Context, client *http.Client, tokenURL string, data url.Values) (string, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", 0, fmt.Errorf("creating token request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := a.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", 0, fmt.Errorf("token request failed: %w", err)
 	}
@@ -142,3 +152,163 @@ func (a *OAuthClientCredentialsAuth) fetchToken(ctx context.Context) (string, in
 
 	return tokenResp.AccessToken, tokenResp.ExpiresIn, nil
 }
+
+// ErrNoCallerToken is returned by the per-user auth providers when the
+// caller is authenticated but did not present a token that may be passed
+// on, e.g. because it authenticated with an API key.
+var ErrNoCallerToken = errors.New("caller has no bearer token to pass to the MCP server")
+
+// toolCallKey is a private type for the context key marking tool calls.
+type toolCallKey struct{}
+
+// withToolCall marks ctx as the context of a tools/call request. Only
+// these requests carry the caller's credentials.
+func withToolCall(ctx context.Context) context.Context {
+	return context.WithValue(ctx, toolCallKey{}, true)
+}
+
+// callerToken returns the identity of the caller and the bearer token it
+// authenticated with. The identity is nil for requests other than tool
+// calls, such as session setup, tool discovery and pings, which antwort
+// makes on its own behalf, and for tool calls without authentication.
+func callerToken(ctx context.Context) (*auth.Identity, string, error) {
+	if ctx.Value(toolCallKey{}) == nil {
+		return nil, "", nil
+	}
+	id := auth.IdentityFromContext(ctx)
+	if id == nil {
+		return nil, "", nil
+	}
+	if id.Token == "" {
+		return nil, "", ErrNoCallerToken
+	}
+	return id, id.Token, nil
+}
+
+// BearerForwardAuth passes the caller's bearer token on to the MCP server,
+// which must accept tokens issued for antwort. Other requests carry no
+// Authorization header of their own, so the server's static headers
+// supply the service credential for them.
+type BearerForwardAuth struct{}
+
+// GetHeaders returns an Authorization header with the caller's token.
+func (BearerForwardAuth) GetHeaders(ctx context.Context) (map[string]string, error) {
+	id, token, err := callerToken(ctx)
+	if err != nil || id == nil {
+		return nil, err
+	}
+	return map[string]string{"Authorization": "Bearer " + token}, nil
+}
+
+// Token types and grant type of OAuth 2.0 Token Exchange (RFC 8693).
+const (
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// TokenExchangeAuth exchanges the caller's bearer token for a token issued
+// for the MCP server via OAuth 2.0 Token Exchange (RFC 8693), so that the
+// MCP server sees the caller's identity. Exchanged tokens are cached per
+// subject token and refreshed when 80% of their lifetime has elapsed. Other
+// requests carry antwort's own token, obtained with the client_credentials
+// grant of the same client.
+type TokenExchangeAuth struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Audience     string
+	Scopes       []string
+
+	service    *OAuthClientCredentialsAuth
+	mu         sync.Mutex
+	cache      map[string]exchangedToken
+	httpClient *http.Client
+	nowFunc    func() time.Time // for testing; defaults to time.Now
+}
+
+// exchangedToken is a cached token obtained for one subject token.
+type exchangedToken struct {
+	token     string
+	refreshAt time.Time
+}
+
+// NewTokenExchange creates a TokenExchangeAuth provider.
+func NewTokenExchange(tokenURL, clientID, clientSecret, audience string, scopes []string) *TokenExchangeAuth {
+	return &TokenExchangeAuth{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Audience:     audience,
+		Scopes:       scopes,
+		service:      NewOAuthClientCredentials(tokenURL, clientID, clientSecret, scopes),
+		cache:        make(map[string]exchangedToken),
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		nowFunc:      time.Now,
+	}
+}
+
+// GetHeaders returns an Authorization header with a token exchanged for
+// the caller's token, or with antwort's own token without a caller.
+func (a *TokenExchangeAuth) GetHeaders(ctx context.Context) (map[string]string, error) {
+	id, subjectToken, err := callerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if id == nil {
+		return a.service.GetHeaders(ctx)
+	}
+
+	// Cache by the caller's token rather than its subject, which need not
+	// be unique across issuers.
+	sum := sha256.Sum256([]byte(subjectToken))
+	key := hex.EncodeToString(sum[:])
+
+	a.mu.Lock()
+	cached, ok := a.cache[key]
+	a.mu.Unlock()
+	if ok && a.nowFunc().Before(cached.refreshAt) {
+		return map[string]string{"Authorization": "Bearer " + cached.token}, nil
+	}
+
+	token, expiresIn, err := a.exchange(ctx, subjectToken)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging token for %q: %w", id.Subject, err)
+	}
+
+	// Tokens without a lifetime are exchanged on every request.
+	if expiresIn > 0 {
+		now := a.nowFunc()
+		a.mu.Lock()
+		for k, t := range a.cache {
+			if !now.Before(t.refreshAt) {
+				delete(a.cache, k)
+			}
+		}
+		a.cache[key] = exchangedToken{
+			token:     token,
+			refreshAt: now.Add(time.Duration(float64(expiresIn)*0.8) * time.Second),
+		}
+		a.mu.Unlock()
+	}
+
+	return map[string]string{"Authorization": "Bearer " + token}, nil
+}
+
+// exchange performs the token exchange request for subjectToken.
+func (a *TokenExchangeAuth) exchange(ctx context.Context, subjectToken string) (string, int, error) {
+	data := url.Values{
+		"grant_type":           {grantTypeTokenExchange},
+		"subject_token":        {subjectToken},
+		"subject_token_type":   {tokenTypeAccessToken},
+		"requested_token_type": {tokenTypeAccessToken},
+		"client_id":            {a.ClientID},
+		"client_secret":        {a.ClientSecret},
+	}
+	if a.Audience != "" {
+		data.Set("audience", a.Audience)
+	}
+	if len(a.Scopes) > 0 {
+		data.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	return requestToken(ctx, a.httpClient, a.TokenURL, data)
+}
@@ -3,6 +3,7 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +11,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/rhuss/antwort/pkg/auth"
 )
 
 // mockTokenServer creates an httptest.Server that serves as an OAuth token endpoint.
@@ -297,6 +300,204 @@ func TestOAuthClientCredentials_NoScopesOmitsParam(t *testing.T) {
 	}
 }
 
+// mockExchangeServer creates an httptest.Server that serves as an RFC 8693
+// token exchange endpoint. It issues "exchanged-<subject_token>", and
+// "service-<client_id>" for the client_credentials grant, and records the
+// form of the last request.
+func mockExchangeServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32, func() map[string]string) {
+	t.Helper()
+	callCount := &atomic.Int32{}
+	var mu sync.Mutex
+	var lastForm map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount.Add(1)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "bad form", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		lastForm = make(map[string]string)
+		for k := range r.PostForm {
+			lastForm[k] = r.PostForm.Get(k)
+		}
+		mu.Unlock()
+
+		var token string
+		switch r.FormValue("grant_type") {
+		case grantTypeTokenExchange:
+			token = "exchanged-" + r.FormValue("subject_token")
+		case "client_credentials":
+			token = "service-" + r.FormValue("client_id")
+		default:
+			http.Error(w, "bad grant_type", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokenResponse{
+			AccessToken: token,
+			TokenType:   "bearer",
+			ExpiresIn:   expiresIn,
+		})
+	}))
+	return srv, callCount, func() map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return lastForm
+	}
+}
+
+// callerContext returns the context of a tool call by subject
+// authenticated with token.
+func callerContext(subject, token string) context.Context {
+	return withToolCall(auth.SetIdentity(context.Background(), &auth.Identity{Subject: subject, Token: token}))
+}
+
+func TestTokenExchange_ExchangesCallerToken(t *testing.T) {
+	srv, _, lastForm := mockExchangeServer(t, 3600)
+	defer srv.Close()
+
+	a := NewTokenExchange(srv.URL, "antwort", "secret", "mcp-github", []string{"repo"})
+
+	headers, err := a.GetHeaders(callerContext("alice", "alice-jwt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := headers["Authorization"]; got != "Bearer exchanged-alice-jwt" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer exchanged-alice-jwt")
+	}
+
+	form := lastForm()
+	want := map[string]string{
+		"subject_token":      "alice-jwt",
+		"subject_token_type": tokenTypeAccessToken,
+		"audience":           "mcp-github",
+		"scope":              "repo",
+		"client_id":          "antwort",
+		"client_secret":      "secret",
+	}
+	for k, v := range want {
+		if form[k] != v {
+			t.Errorf("form %s = %q, want %q", k, form[k], v)
+		}
+	}
+}
+
+func TestTokenExchange_CachePerSubject(t *testing.T) {
+	srv, callCount, _ := mockExchangeServer(t, 10)
+	defer srv.Close()
+
+	a := NewTokenExchange(srv.URL, "antwort", "secret", "", nil)
+	now := time.Now()
+	a.nowFunc = func() time.Time { return now }
+
+	for _, subject := range []string{"alice", "alice", "bob", "alice"} {
+		headers, err := a.GetHeaders(callerContext(subject, subject+"-jwt"))
+		if err != nil {
+			t.Fatalf("GetHeaders(%s): %v", subject, err)
+		}
+		if got, want := headers["Authorization"], "Bearer exchanged-"+subject+"-jwt"; got != want {
+			t.Errorf("Authorization for %s = %q, want %q", subject, got, want)
+		}
+	}
+	if got := callCount.Load(); got != 2 {
+		t.Errorf("token endpoint called %d times, want 2 (one per subject)", got)
+	}
+
+	// Past 80% of the lifetime, the token is exchanged again.
+	a.nowFunc = func() time.Time { return now.Add(9 * time.Second) }
+	if _, err := a.GetHeaders(callerContext("alice", "alice-jwt")); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if got := callCount.Load(); got != 3 {
+		t.Errorf("token endpoint called %d times, want 3 (refresh)", got)
+	}
+}
+
+func TestTokenExchange_SameSubjectDifferentTokens(t *testing.T) {
+	srv, callCount, _ := mockExchangeServer(t, 3600)
+	defer srv.Close()
+
+	a := NewTokenExchange(srv.URL, "antwort", "secret", "", nil)
+
+	// Two users of different issuers with the same subject.
+	for _, token := range []string{"issuer-a-jwt", "issuer-b-jwt", "issuer-a-jwt"} {
+		headers, err := a.GetHeaders(callerContext("alice", token))
+		if err != nil {
+			t.Fatalf("GetHeaders(%s): %v", token, err)
+		}
+		if got, want := headers["Authorization"], "Bearer exchanged-"+token; got != want {
+			t.Errorf("Authorization for %s = %q, want %q", token, got, want)
+		}
+	}
+	if got := callCount.Load(); got != 2 {
+		t.Errorf("token endpoint called %d times, want 2 (one per token)", got)
+	}
+}
+
+func TestTokenExchange_NoCaller(t *testing.T) {
+	srv, callCount, _ := mockExchangeServer(t, 3600)
+	defer srv.Close()
+
+	a := NewTokenExchange(srv.URL, "antwort", "secret", "", nil)
+
+	// Requests other than tool calls carry antwort's own token, even
+	// within a caller's request.
+	notToolCall := auth.SetIdentity(context.Background(), &auth.Identity{Subject: "alice", Token: "alice-jwt"})
+	for _, ctx := range []context.Context{context.Background(), notToolCall} {
+		headers, err := a.GetHeaders(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := headers["Authorization"]; got != "Bearer service-antwort" {
+			t.Errorf("Authorization = %q, want antwort's own token", got)
+		}
+	}
+
+	_, err := a.GetHeaders(callerContext("api-key-user", ""))
+	if !errors.Is(err, ErrNoCallerToken) {
+		t.Errorf("error = %v, want ErrNoCallerToken", err)
+	}
+	if got := callCount.Load(); got != 1 {
+		t.Errorf("token endpoint called %d times, want 1 (the cached service token)", got)
+	}
+}
+
+func TestTokenExchange_Failure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	a := NewTokenExchange(srv.URL, "antwort", "secret", "", nil)
+
+	_, err := a.GetHeaders(callerContext("alice", "alice-jwt"))
+	if err == nil || !contains(err.Error(), "invalid_grant") {
+		t.Errorf("error = %v, want the token endpoint's error", err)
+	}
+}
+
+func TestBearerForward(t *testing.T) {
+	var a BearerForwardAuth
+
+	headers, err := a.GetHeaders(callerContext("alice", "alice-jwt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := headers["Authorization"]; got != "Bearer alice-jwt" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer alice-jwt")
+	}
+
+	notToolCall := auth.SetIdentity(context.Background(), &auth.Identity{Subject: "alice", Token: "alice-jwt"})
+	for _, ctx := range []context.Context{context.Background(), notToolCall} {
+		if headers, _ := a.GetHeaders(ctx); len(headers) != 0 {
+			t.Errorf("headers = %v, want none outside of a tool call", headers)
+		}
+	}
+	if _, err := a.GetHeaders(callerContext("api-key-user", "")); !errors.Is(err, ErrNoCallerToken) {
+		t.Errorf("error = %v, want ErrNoCallerToken", err)
+	}
+}
+
 // contains checks if a string contains a substring.
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsSubstr(s, substr))
@@ -2,27 +2,57 @@ package mcp
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/observability"
 	"github.com/rhuss/antwort/pkg/tools"
 )
 
 // MCPClient wraps an MCP SDK Client and ClientSession for a single
 // MCP server connection. It handles connection lifecycle, tool discovery,
-// and tool execution.
+// and tool execution. A client connected from its configuration replaces
+// a lost session with a new one.
 type MCPClient struct {
-	cfg     ServerConfig
-	client  *mcp.Client
-	session *mcp.ClientSession
-
-	mu            sync.Mutex
-	cachedTools   []api.ToolDefinition
-	toolsResolved bool
+	cfg    ServerConfig
+	client *mcp.Client
+
+	// allowURL, if set, must accept every URL the client is redirected to.
+	allowURL func(string) bool
+
+	// sessionMu guards session and reconnectable.
+	sessionMu     sync.RWMutex
+	session       *mcp.ClientSession
+	reconnectable bool
+
+	// reconnectMu serializes replacing a lost session.
+	reconnectMu sync.Mutex
+
+	mu                sync.Mutex
+	cachedTools       []api.ToolDefinition
+	toolsResolved     bool
+	cachedResources   []Resource
+	resourcesResolved bool
+
+	// onToolsChanged, if set, is called when the server announces that its
+	// tool or resource list changed.
+	onToolsChanged func()
+
+	// progressMu guards progress, the reporters of the running tool calls
+	// by progress token, and progressSeq.
+	progressMu  sync.Mutex
+	progress    map[string]func(tools.Progress)
+	progressSeq int
 }
 
 // NewMCPClient creates a new MCPClient for the given server configuration.
@@ -49,9 +79,19 @@ func (c *MCPClient) ConnectWithTransport(ctx context.Context, transport mcp.Tran
 		},
 		&mcp.ClientOptions{
 			Capabilities: &mcp.ClientCapabilities{},
+			ToolListChangedHandler: func(context.Context, *mcp.ToolListChangedRequest) {
+				c.listChanged()
+			},
+			ResourceListChangedHandler: func(context.Context, *mcp.ResourceListChangedRequest) {
+				c.listChanged()
+			},
+			ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+				c.reportProgress(req.Params)
+			},
 		},
 	)
 
+	reconnectable := transport == nil
 	if transport == nil {
 		t, err := c.createTransport()
 		if err != nil {
@@ -64,10 +104,93 @@ func (c *MCPClient) ConnectWithTransport(ctx context.Context, transport mcp.Tran
 	if err != nil {
 		return fmt.Errorf("connecting to MCP server %q: %w", c.cfg.Name, err)
 	}
+
+	c.sessionMu.Lock()
+	old := c.session
 	c.session = session
+	c.reconnectable = reconnectable
+	c.sessionMu.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+
+	// A new session may offer different tools.
+	c.invalidateLists()
+	return nil
+}
+
+// Reconnect replaces the session with a new one. It fails for clients
+// connected with a transport of their own, which cannot be re-opened.
+func (c *MCPClient) Reconnect(ctx context.Context) error {
+	c.sessionMu.RLock()
+	reconnectable := c.reconnectable || c.session == nil
+	c.sessionMu.RUnlock()
+	if !reconnectable {
+		return fmt.Errorf("MCP client %q cannot reconnect", c.cfg.Name)
+	}
+	return c.Connect(ctx)
+}
+
+// replaceSession reconnects unless lost was already replaced by a
+// concurrent caller. The new session is not bound to any request.
+func (c *MCPClient) replaceSession(lost *mcp.ClientSession) error {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+	if c.currentSession() != lost {
+		return nil
+	}
+	if err := c.Reconnect(context.Background()); err != nil {
+		return err
+	}
+	observability.MCPReconnectsTotal.WithLabelValues(c.cfg.Name).Inc()
 	return nil
 }
 
+// Ping checks that the server answers on the current session.
+func (c *MCPClient) Ping(ctx context.Context) error {
+	session := c.currentSession()
+	if session == nil {
+		return fmt.Errorf("MCP client %q not connected", c.cfg.Name)
+	}
+	return session.Ping(ctx, nil)
+}
+
+// currentSession returns the session, or nil if the client is not connected.
+func (c *MCPClient) currentSession() *mcp.ClientSession {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	return c.session
+}
+
+// setToolsChanged sets the function called when the server announces that
+// its tool or resource list changed.
+func (c *MCPClient) setToolsChanged(fn func()) {
+	c.mu.Lock()
+	c.onToolsChanged = fn
+	c.mu.Unlock()
+}
+
+// listChanged handles the server's announcement of a changed tool or
+// resource list.
+func (c *MCPClient) listChanged() {
+	c.invalidateLists()
+	c.mu.Lock()
+	onToolsChanged := c.onToolsChanged
+	c.mu.Unlock()
+	if onToolsChanged != nil {
+		go onToolsChanged()
+	}
+}
+
+// invalidateLists makes the next DiscoverTools and ListResources list the
+// server's tools and resources again.
+func (c *MCPClient) invalidateLists() {
+	c.mu.Lock()
+	c.toolsResolved = false
+	c.resourcesResolved = false
+	c.mu.Unlock()
+}
+
 // createTransport creates an MCP transport based on the server configuration.
 func (c *MCPClient) createTransport() (mcp.Transport, error) {
 	httpClient := c.buildHTTPClient()
@@ -97,7 +220,7 @@ func (c *MCPClient) createTransport() (mcp.Transport, error) {
 }
 
 // buildHTTPClient returns an HTTP client with the appropriate transport
-// for authentication. Returns nil if no auth or custom headers are configured.
+// for authentication. Every client propagates the caller's trace context.
 func (c *MCPClient) buildHTTPClient() *http.Client {
 	var authProvider AuthProvider
 
@@ -109,21 +232,40 @@ func (c *MCPClient) buildHTTPClient() *http.Client {
 			c.cfg.Auth.ClientSecret,
 			c.cfg.Auth.Scopes,
 		)
+	case "oauth_token_exchange":
+		authProvider = NewTokenExchange(
+			c.cfg.Auth.TokenURL,
+			c.cfg.Auth.ClientID,
+			c.cfg.Auth.ClientSecret,
+			c.cfg.Auth.Audience,
+			c.cfg.Auth.Scopes,
+		)
+	case "forward_bearer":
+		authProvider = BearerForwardAuth{}
 	}
 
-	// Build transport chain: static headers + auth provider.
-	hasStaticHeaders := len(c.cfg.Headers) > 0
-	if !hasStaticHeaders && authProvider == nil {
-		return nil
-	}
-
-	return &http.Client{
-		Transport: &authAwareTransport{
-			base:         http.DefaultTransport,
+	// Build transport chain: trace context + static headers + auth provider.
+	base := observability.NewTracingTransport(http.DefaultTransport)
+	httpClient := &http.Client{Transport: base}
+	if len(c.cfg.Headers) > 0 || authProvider != nil {
+		httpClient.Transport = &authAwareTransport{
+			base:         base,
 			headers:      c.cfg.Headers,
 			authProvider: authProvider,
-		},
+		}
+	}
+	if c.allowURL != nil {
+		httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if !c.allowURL(req.URL.String()) {
+				return fmt.Errorf("redirect to %s: %w", req.URL.Redacted(), ErrServerNotAllowed)
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		}
 	}
+	return httpClient
 }
 
 // authAwareTransport is an http.RoundTripper that adds static headers and
@@ -179,12 +321,13 @@ func (c *MCPClient) DiscoverTools(ctx context.Context) ([]api.ToolDefinition, er
 		return c.cachedTools, nil
 	}
 
-	if c.session == nil {
+	session := c.currentSession()
+	if session == nil {
 		return nil, fmt.Errorf("MCP client %q not connected", c.cfg.Name)
 	}
 
 	var toolDefs []api.ToolDefinition
-	for tool, err := range c.session.Tools(ctx, nil) {
+	for tool, err := range session.Tools(ctx, nil) {
 		if err != nil {
 			return nil, fmt.Errorf("listing tools from %q: %w", c.cfg.Name, err)
 		}
@@ -201,8 +344,11 @@ func (c *MCPClient) DiscoverTools(ctx context.Context) ([]api.ToolDefinition, er
 }
 
 // CallTool executes a tool call on the MCP server and returns the result.
+// A call that fails because the server no longer knows the session is
+// retried once on a new session.
 func (c *MCPClient) CallTool(ctx context.Context, call tools.ToolCall) (*tools.ToolResult, error) {
-	if c.session == nil {
+	session := c.currentSession()
+	if session == nil {
 		return nil, fmt.Errorf("MCP client %q not connected", c.cfg.Name)
 	}
 
@@ -222,8 +368,21 @@ func (c *MCPClient) CallTool(ctx context.Context, call tools.ToolCall) (*tools.T
 		Name:      call.Name,
 		Arguments: args,
 	}
+	if report := tools.GetProgressReporter(ctx); report != nil {
+		token := c.watchProgress(report)
+		defer c.unwatchProgress(token)
+		// SetProgressToken drops the token if Meta is nil.
+		params.Meta = mcp.Meta{"progressToken": token}
+	}
 
-	result, err := c.session.CallTool(ctx, params)
+	ctx = withToolCall(ctx)
+	result, err := session.CallTool(ctx, params)
+	if err != nil && sessionLost(err) {
+		slog.Info("MCP session lost, reconnecting", "server", c.cfg.Name, "error", err)
+		if rerr := c.replaceSession(session); rerr == nil {
+			result, err = c.currentSession().CallTool(ctx, params)
+		}
+	}
 	if err != nil {
 		return &tools.ToolResult{
 			CallID:  call.ID,
@@ -235,14 +394,54 @@ func (c *MCPClient) CallTool(ctx context.Context, call tools.ToolCall) (*tools.T
 	return convertResult(call.ID, result), nil
 }
 
+// watchProgress registers a progress reporter and returns the progress
+// token identifying it.
+func (c *MCPClient) watchProgress(report func(tools.Progress)) string {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+	if c.progress == nil {
+		c.progress = make(map[string]func(tools.Progress))
+	}
+	c.progressSeq++
+	token := fmt.Sprintf("%s-%d", c.cfg.Name, c.progressSeq)
+	c.progress[token] = report
+	return token
+}
+
+// unwatchProgress removes the progress reporter of token.
+func (c *MCPClient) unwatchProgress(token string) {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+	delete(c.progress, token)
+}
+
+// reportProgress passes a progress notification to the reporter of its
+// tool call. Notifications for finished calls are dropped; the reporter
+// runs under progressMu, so none arrives after unwatchProgress returned.
+func (c *MCPClient) reportProgress(params *mcp.ProgressNotificationParams) {
+	token, _ := params.ProgressToken.(string)
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+	if report := c.progress[token]; report != nil {
+		report(tools.Progress{Progress: params.Progress, Total: params.Total, Message: params.Message})
+	}
+}
+
 // Close closes the MCP session.
 func (c *MCPClient) Close() error {
-	if c.session != nil {
-		return c.session.Close()
+	if session := c.currentSession(); session != nil {
+		return session.Close()
 	}
 	return nil
 }
 
+// sessionLost reports whether a request failed without reaching the
+// server because the session is gone: it was closed, or the server
+// (e.g. after a restart) no longer knows it.
+func sessionLost(err error) bool {
+	return errors.Is(err, mcp.ErrConnectionClosed) || strings.Contains(err.Error(), "session not found")
+}
+
 // convertTool converts an MCP Tool to an api.ToolDefinition.
 func convertTool(t *mcp.Tool) (api.ToolDefinition, error) {
 	var params json.RawMessage
@@ -265,20 +464,68 @@ func convertTool(t *mcp.Tool) (api.ToolDefinition, error) {
 
 // convertResult converts an MCP CallToolResult to a tools.ToolResult.
 func convertResult(callID string, result *mcp.CallToolResult) *tools.ToolResult {
-	// Extract text content from the result.
-	var output string
+	// Extract text content from the result. Embedded resources contribute
+	// their text, resource links a reference; both are recorded as sources.
+	// Images are kept as input_image parts and noted in the text.
+	var parts []string
+	var sources []tools.Source
+	var images []api.ContentPart
 	for _, content := range result.Content {
-		if tc, ok := content.(*mcp.TextContent); ok {
-			if output != "" {
-				output += "\n"
+		switch c := content.(type) {
+		case *mcp.TextContent:
+			parts = append(parts, c.Text)
+		case *mcp.ImageContent:
+			parts = append(parts, fmt.Sprintf("[image %d (%s)]", len(images)+1, c.MIMEType))
+			images = append(images, api.ContentPart{
+				Type:      "input_image",
+				Data:      base64.StdEncoding.EncodeToString(c.Data),
+				MediaType: c.MIMEType,
+			})
+		case *mcp.EmbeddedResource:
+			if c.Resource == nil {
+				continue
 			}
-			output += tc.Text
+			parts = append(parts, c.Resource.Text)
+			sources = append(sources, tools.Source{URL: c.Resource.URI, Text: c.Resource.Text})
+		case *mcp.ResourceLink:
+			parts = append(parts, fmt.Sprintf("[resource %s: %s]", c.URI, c.Name))
+			sources = append(sources, tools.Source{URL: c.URI, Title: c.Name})
 		}
 	}
 
-	return &tools.ToolResult{
+	// Structured content is kept as JSON. Servers usually repeat it as
+	// text, in which case the text is replaced.
+	if result.StructuredContent != nil {
+		if data, err := json.Marshal(result.StructuredContent); err == nil {
+			parts = slices.DeleteFunc(parts, func(p string) bool { return sameJSON(p, data) })
+			parts = append(parts, string(data))
+		}
+	}
+
+	tr := &tools.ToolResult{
 		CallID:  callID,
-		Output:  output,
+		Output:  strings.Join(parts, "\n"),
 		IsError: result.IsError,
+		Sources: sources,
+		Images:  images,
+	}
+	if len(sources) > 0 {
+		// Annotations cite the first resource of the result.
+		tr.Metadata = map[string]string{
+			"tool":    "mcp_resource",
+			"url":     sources[0].URL,
+			"title":   sources[0].Title,
+			"content": tr.Output,
+		}
+	}
+	return tr
+}
+
+// sameJSON reports whether text is a JSON encoding of the same value as data.
+func sameJSON(text string, data []byte) bool {
+	var a, b any
+	if json.Unmarshal([]byte(text), &a) != nil || json.Unmarshal(data, &b) != nil {
+		return false
 	}
+	return reflect.DeepEqual(a, b)
 }
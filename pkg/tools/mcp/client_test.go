@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+func TestConvertResult_Image(t *testing.T) {
+	result := convertResult("call_1", &mcp.CallToolResult{Content: []mcp.Content{
+		&mcp.TextContent{Text: "Rendered the chart."},
+		&mcp.ImageContent{Data: []byte("png-bytes"), MIMEType: "image/png"},
+	}})
+
+	if result.Output != "Rendered the chart.\n[image 1 (image/png)]" {
+		t.Errorf("output = %q", result.Output)
+	}
+	if len(result.Images) != 1 {
+		t.Fatalf("images = %+v, want 1", result.Images)
+	}
+	img := result.Images[0]
+	if img.Type != "input_image" || img.MediaType != "image/png" || img.Data != base64.StdEncoding.EncodeToString([]byte("png-bytes")) {
+		t.Errorf("image = %+v", img)
+	}
+}
+
+func TestConvertResult_StructuredContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []mcp.Content
+		want    string
+	}{
+		{"no text", nil, `{"count":2,"status":"ok"}`},
+		{"text repeats it", []mcp.Content{&mcp.TextContent{Text: `{"status": "ok", "count": 2}`}}, `{"count":2,"status":"ok"}`},
+		{"text differs", []mcp.Content{&mcp.TextContent{Text: "Two items."}}, "Two items.\n" + `{"count":2,"status":"ok"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := convertResult("call_1", &mcp.CallToolResult{
+				Content:           tt.content,
+				StructuredContent: map[string]any{"status": "ok", "count": 2},
+			})
+			if result.Output != tt.want {
+				t.Errorf("output = %q, want %q", result.Output, tt.want)
+			}
+		})
+	}
+}
+
+func TestMCPClient_CallToolProgress(t *testing.T) {
+	// The tool finishes once the client has seen its progress, since
+	// notifications arriving after the result are dropped.
+	received := make(chan struct{})
+	server := mcp.NewServer(&mcp.Implementation{Name: "slow", Version: "1.0.0"}, nil)
+	server.AddTool(
+		&mcp.Tool{Name: "index", InputSchema: map[string]any{"type": "object"}},
+		func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			for i := 1; i <= 2; i++ {
+				_ = req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+					ProgressToken: req.Params.GetProgressToken(),
+					Progress:      float64(i),
+					Total:         2,
+					Message:       "indexing",
+				})
+			}
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+			}
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil
+		},
+	)
+	client := connectServer(t, "slow", server)
+
+	var got []tools.Progress
+	ctx := tools.SetProgressReporter(context.Background(), func(p tools.Progress) {
+		got = append(got, p)
+		if len(got) == 2 {
+			close(received)
+		}
+	})
+
+	result, err := client.CallTool(ctx, tools.ToolCall{ID: "call_1", Name: "index", Arguments: `{}`})
+	if err != nil || result.Output != "done" {
+		t.Fatalf("CallTool = %+v, %v", result, err)
+	}
+	want := []tools.Progress{
+		{Progress: 1, Total: 2, Message: "indexing"},
+		{Progress: 2, Total: 2, Message: "indexing"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("progress = %+v, want %+v", got, want)
+	}
+}
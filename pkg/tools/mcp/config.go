@@ -1,5 +1,7 @@
 package mcp
 
+import "github.com/rhuss/antwort/pkg/api"
+
 // Config holds the configuration for all MCP server connections.
 type Config struct {
 	// Servers is the list of MCP server configurations to connect to.
@@ -27,14 +29,21 @@ type ServerConfig struct {
 	// If set, the auth provider is used instead of static Headers for
 	// authentication. Static Headers are still sent alongside auth headers.
 	Auth MCPAuthConfig `json:"auth,omitempty"`
+
+	// RequireApproval selects the tools whose calls wait for the user's
+	// approval. Nil means calls run without approval.
+	RequireApproval *api.MCPApprovalPolicy `json:"require_approval,omitempty"`
 }
 
 // MCPAuthConfig describes the authentication configuration for an MCP server.
 type MCPAuthConfig struct {
-	// Type is the authentication method: "static" or "oauth_client_credentials".
+	// Type is the authentication method: "static", "oauth_client_credentials",
+	// "oauth_token_exchange" (RFC 8693 exchange of the caller's token), or
+	// "forward_bearer" (the caller's token as is).
 	Type string `json:"type" yaml:"type"`
 
-	// TokenURL is the OAuth 2.0 token endpoint URL (for oauth_client_credentials).
+	// TokenURL is the OAuth 2.0 token endpoint URL (for oauth_client_credentials
+	// and oauth_token_exchange).
 	TokenURL string `json:"token_url,omitempty" yaml:"token_url"`
 
 	// ClientID is the OAuth 2.0 client identifier.
@@ -51,4 +60,7 @@ type MCPAuthConfig struct {
 
 	// Scopes is the list of OAuth 2.0 scopes to request.
 	Scopes []string `json:"scopes,omitempty" yaml:"scopes"`
+
+	// Audience is the audience of exchanged tokens (for oauth_token_exchange).
+	Audience string `json:"audience,omitempty" yaml:"audience"`
 }
@@ -10,4 +10,9 @@
 // Configuration is provided via ServerConfig structs, which specify the
 // server name, transport type (SSE or streamable-http), URL, and optional
 // authentication headers.
+//
+// Requests can also declare MCP servers with tools of type "mcp". Pool
+// connects to them if their URL is on the configured allowlist, shares
+// connections between requests, and RequestServers executes their tools
+// for the duration of one request.
 package mcp
@@ -4,37 +4,113 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/observability"
 	"github.com/rhuss/antwort/pkg/tools"
 )
 
+// Server health states reported by MCPExecutor.Health.
+const (
+	HealthConnecting = "connecting"
+	HealthHealthy    = "healthy"
+	HealthUnhealthy  = "unhealthy"
+)
+
+// Default intervals of the health checks started by MCPExecutor.Start.
+const (
+	DefaultHealthCheckInterval = 30 * time.Second
+	DefaultToolRefreshInterval = 5 * time.Minute
+)
+
+// HealthConfig controls how MCPExecutor.Start keeps servers connected.
+type HealthConfig struct {
+	// CheckInterval is the time between pings of a connected server.
+	// Defaults to DefaultHealthCheckInterval.
+	CheckInterval time.Duration
+
+	// RefreshInterval is the time between tool list refreshes, in addition
+	// to the refreshes announced by servers. Defaults to
+	// DefaultToolRefreshInterval.
+	RefreshInterval time.Duration
+}
+
+// ServerHealth reports the connection state of one MCP server.
+type ServerHealth struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Tools     int    `json:"tools"`
+	LastError string `json:"last_error,omitempty"`
+	CheckedAt int64  `json:"checked_at,omitempty"`
+}
+
 // MCPExecutor implements tools.ToolExecutor for MCP server tools.
 // It manages connections to multiple MCP servers, discovers their tools,
-// and routes tool calls to the appropriate server.
+// and routes tool calls to the appropriate server. A tool name offered by
+// more than one server is exposed as "<server>__<tool>" for each of them.
+// If servers offer resources, the model can read them with the
+// read_resource tool.
 type MCPExecutor struct {
 	mu sync.RWMutex
 
 	// clients maps server name to MCPClient.
 	clients map[string]*MCPClient
 
-	// toolToServer maps tool name to the server name that provides it.
-	toolToServer map[string]string
+	// routes maps exposed tool name to the server and tool it calls.
+	routes map[string]toolRoute
+
+	// toolDefs lists the tools under their exposed names.
+	toolDefs []api.ToolDefinition
+
+	// resources lists the resources offered by the servers.
+	resources []Resource
 
 	// discovered tracks whether tools have been discovered.
 	discovered bool
+
+	// health tracks the state of each server once Start was called.
+	health map[string]*ServerHealth
+
+	// refreshMu serializes tool list refreshes.
+	refreshMu sync.Mutex
+
+	// backoffMin and backoffMax bound the delay between connection attempts.
+	backoffMin time.Duration
+	backoffMax time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// toolRoute records the server and server-side name of an exposed tool.
+// The read_resource tool has a route of its own, with resource set.
+type toolRoute struct {
+	server   string
+	tool     string
+	resource bool
 }
 
 // Ensure MCPExecutor implements tools.ToolExecutor at compile time.
 var _ tools.ToolExecutor = (*MCPExecutor)(nil)
 
 // NewMCPExecutor creates a new MCPExecutor with the given MCP clients.
+// Connected clients are used as they are; call Start to connect and
+// supervise clients in the background.
 func NewMCPExecutor(clients map[string]*MCPClient) *MCPExecutor {
-	return &MCPExecutor{
-		clients:      clients,
-		toolToServer: make(map[string]string),
+	e := &MCPExecutor{
+		clients:    clients,
+		routes:     make(map[string]toolRoute),
+		backoffMin: time.Second,
+		backoffMax: time.Minute,
+	}
+	for _, client := range clients {
+		client.setToolsChanged(func() { e.refresh(context.Background()) })
 	}
+	return e
 }
 
 // Kind returns ToolKindMCP.
@@ -49,7 +125,7 @@ func (e *MCPExecutor) CanExecute(toolName string) bool {
 
 	e.mu.RLock()
 	defer e.mu.RUnlock()
-	_, ok := e.toolToServer[toolName]
+	_, ok := e.routes[toolName]
 	return ok
 }
 
@@ -58,7 +134,7 @@ func (e *MCPExecutor) Execute(ctx context.Context, call tools.ToolCall) (*tools.
 	e.ensureDiscovered()
 
 	e.mu.RLock()
-	serverName, ok := e.toolToServer[call.Name]
+	route, ok := e.routes[call.Name]
 	if !ok {
 		e.mu.RUnlock()
 		return &tools.ToolResult{
@@ -67,12 +143,35 @@ func (e *MCPExecutor) Execute(ctx context.Context, call tools.ToolCall) (*tools.
 			IsError: true,
 		}, nil
 	}
-	client := e.clients[serverName]
+	client := e.clients[route.server]
 	e.mu.RUnlock()
 
+	if route.resource {
+		return e.readResource(ctx, call)
+	}
+	call.Name = route.tool
 	return client.CallTool(ctx, call)
 }
 
+// RequiresApproval reports whether calls to the named tool wait for the
+// user's approval, and the name of the server that provides it.
+func (e *MCPExecutor) RequiresApproval(toolName string) (string, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	route, ok := e.routes[toolName]
+	if !ok || route.resource {
+		return "", false
+	}
+	return route.server, e.clients[route.server].cfg.RequireApproval.Requires(route.tool)
+}
+
+// ServerLabel returns the name of the server that provides the named tool.
+func (e *MCPExecutor) ServerLabel(toolName string) string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.routes[toolName].server
+}
+
 // DiscoveredTools returns all tools discovered from connected MCP servers.
 // This is useful for the engine to merge MCP tools into the request's
 // tool definitions.
@@ -81,18 +180,173 @@ func (e *MCPExecutor) DiscoveredTools() []api.ToolDefinition {
 
 	e.mu.RLock()
 	defer e.mu.RUnlock()
+	return slices.Clone(e.toolDefs)
+}
+
+// Start connects to the servers in the background and keeps them
+// connected: a server that cannot be reached is retried with exponential
+// backoff, connected servers are pinged every CheckInterval and
+// reconnected when they stop answering, and tool lists are refreshed
+// every RefreshInterval and whenever a server announces a change. Tools
+// of unreachable servers are not offered.
+func (e *MCPExecutor) Start(cfg HealthConfig) {
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = DefaultHealthCheckInterval
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = DefaultToolRefreshInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	e.mu.Lock()
+	e.cancel = cancel
+	e.discovered = true
+	e.health = make(map[string]*ServerHealth, len(e.clients))
+	for name := range e.clients {
+		e.health[name] = &ServerHealth{Name: name, Status: HealthConnecting}
+		observability.MCPServerUp.WithLabelValues(name).Set(0)
+	}
+	e.mu.Unlock()
 
-	var allTools []api.ToolDefinition
-	for _, client := range e.clients {
-		client.mu.Lock()
-		allTools = append(allTools, client.cachedTools...)
-		client.mu.Unlock()
+	for name, client := range e.clients {
+		e.wg.Add(1)
+		go e.supervise(ctx, name, client, cfg)
 	}
-	return allTools
 }
 
-// Close closes all MCP client connections.
+// supervise keeps one server connected until ctx is cancelled.
+func (e *MCPExecutor) supervise(ctx context.Context, name string, client *MCPClient, cfg HealthConfig) {
+	defer e.wg.Done()
+
+	backoff := e.backoffMin
+	connected := client.currentSession() != nil
+	everConnected := connected
+	if connected {
+		e.setHealth(name, nil)
+		e.refresh(ctx)
+	}
+	for ctx.Err() == nil {
+		if !connected {
+			if err := client.Reconnect(ctx); err != nil {
+				slog.Warn("failed to connect to MCP server",
+					"server", name,
+					"retry_in", backoff,
+					"error", err,
+				)
+				e.setHealth(name, err)
+				if !sleep(ctx, backoff) {
+					return
+				}
+				backoff = min(backoff*2, e.backoffMax)
+				continue
+			}
+			if everConnected {
+				observability.MCPReconnectsTotal.WithLabelValues(name).Inc()
+			}
+			connected, everConnected = true, true
+			backoff = e.backoffMin
+			slog.Info("MCP server connected", "server", name)
+			e.setHealth(name, nil)
+			e.refresh(ctx)
+		}
+
+		lastRefresh := time.Now()
+		for connected {
+			if !sleep(ctx, cfg.CheckInterval) {
+				return
+			}
+			pingCtx, cancel := context.WithTimeout(ctx, cfg.CheckInterval)
+			err := client.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				slog.Warn("MCP server health check failed, reconnecting",
+					"server", name,
+					"error", err,
+				)
+				connected = false
+				e.setHealth(name, err)
+				e.refresh(ctx)
+				break
+			}
+			e.setHealth(name, nil)
+			if time.Since(lastRefresh) >= cfg.RefreshInterval {
+				client.invalidateLists()
+				e.refresh(ctx)
+				lastRefresh = time.Now()
+			}
+		}
+	}
+}
+
+// sleep waits for d and reports false if ctx was cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// setHealth records the outcome of a connection attempt or health check.
+func (e *MCPExecutor) setHealth(name string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	h, ok := e.health[name]
+	if !ok {
+		return
+	}
+	h.CheckedAt = time.Now().Unix()
+	if err != nil {
+		h.Status = HealthUnhealthy
+		h.LastError = err.Error()
+		observability.MCPServerUp.WithLabelValues(name).Set(0)
+		return
+	}
+	h.Status = HealthHealthy
+	h.LastError = ""
+	observability.MCPServerUp.WithLabelValues(name).Set(1)
+}
+
+// Health returns the state of each server, sorted by name. Before Start
+// is called, all servers are reported as healthy.
+func (e *MCPExecutor) Health() []ServerHealth {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	result := make([]ServerHealth, 0, len(e.clients))
+	for name := range e.clients {
+		h := ServerHealth{Name: name, Status: HealthHealthy}
+		if tracked, ok := e.health[name]; ok {
+			h = *tracked
+		}
+		for _, route := range e.routes {
+			if route.server == name {
+				h.Tools++
+			}
+		}
+		result = append(result, h)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Close stops the health checks and closes all MCP client connections.
 func (e *MCPExecutor) Close() error {
+	e.mu.Lock()
+	cancel := e.cancel
+	e.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		e.wg.Wait()
+	}
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
@@ -109,23 +363,52 @@ func (e *MCPExecutor) Close() error {
 // ensureDiscovered triggers tool discovery if it hasn't been done yet.
 func (e *MCPExecutor) ensureDiscovered() {
 	e.mu.RLock()
-	if e.discovered {
-		e.mu.RUnlock()
+	discovered := e.discovered
+	e.mu.RUnlock()
+	if discovered {
 		return
 	}
-	e.mu.RUnlock()
 
-	e.mu.Lock()
-	defer e.mu.Unlock()
+	e.refreshMu.Lock()
+	defer e.refreshMu.Unlock()
 
-	// Double-check after acquiring write lock.
-	if e.discovered {
+	// Double-check after acquiring the refresh lock.
+	e.mu.RLock()
+	discovered = e.discovered
+	e.mu.RUnlock()
+	if discovered {
 		return
 	}
+	e.rebuild(context.Background())
+}
 
-	ctx := context.Background()
-	for name, client := range e.clients {
-		toolDefs, err := client.DiscoverTools(ctx)
+// refresh lists the tools of all healthy servers again and rebuilds the
+// routing table.
+func (e *MCPExecutor) refresh(ctx context.Context) {
+	e.refreshMu.Lock()
+	defer e.refreshMu.Unlock()
+	e.rebuild(ctx)
+}
+
+// rebuild discovers the tools of all healthy servers and replaces the
+// routing table. The caller must hold refreshMu.
+func (e *MCPExecutor) rebuild(ctx context.Context) {
+	e.mu.RLock()
+	names := make([]string, 0, len(e.clients))
+	for name := range e.clients {
+		if h, ok := e.health[name]; !ok || h.Status == HealthHealthy {
+			names = append(names, name)
+		}
+	}
+	e.mu.RUnlock()
+	sort.Strings(names)
+
+	// Discover without holding the lock, so that tool calls continue.
+	serverTools := make(map[string][]api.ToolDefinition, len(names))
+	providers := make(map[string]int)
+	var resources []Resource
+	for _, name := range names {
+		toolDefs, err := e.clients[name].DiscoverTools(ctx)
 		if err != nil {
 			slog.Error("failed to discover tools from MCP server",
 				"server", name,
@@ -133,23 +416,75 @@ func (e *MCPExecutor) ensureDiscovered() {
 			)
 			continue
 		}
-
+		serverResources, err := e.clients[name].ListResources(ctx)
+		if err != nil {
+			slog.Warn("failed to list resources from MCP server",
+				"server", name,
+				"error", err,
+			)
+		}
+		resources = append(resources, serverResources...)
+		serverTools[name] = toolDefs
 		for _, td := range toolDefs {
-			if _, exists := e.toolToServer[td.Name]; exists {
+			providers[td.Name]++
+		}
+		slog.Info("discovered MCP tools",
+			"server", name,
+			"count", len(toolDefs),
+		)
+	}
+
+	routes := make(map[string]toolRoute)
+	var toolDefs []api.ToolDefinition
+	for _, name := range names {
+		count := 0
+		for _, td := range serverTools[name] {
+			exposed := td.Name
+			if providers[td.Name] > 1 {
+				exposed = name + "__" + td.Name
+			}
+			if _, exists := routes[exposed]; exists {
 				slog.Warn("duplicate MCP tool name, using first provider",
-					"tool", td.Name,
+					"tool", exposed,
 					"server", name,
 				)
 				continue
 			}
-			e.toolToServer[td.Name] = name
+			routes[exposed] = toolRoute{server: name, tool: td.Name}
+			td.Name = exposed
+			toolDefs = append(toolDefs, td)
+			count++
+		}
+		observability.MCPServerTools.WithLabelValues(name).Set(float64(count))
+	}
+	for name := range e.clients {
+		if _, ok := serverTools[name]; !ok {
+			observability.MCPServerTools.WithLabelValues(name).Set(0)
 		}
+	}
 
-		slog.Info("discovered MCP tools",
-			"server", name,
-			"count", len(toolDefs),
-		)
+	if len(resources) > 0 {
+		if _, exists := routes[ReadResourceTool]; exists {
+			slog.Warn("MCP server offers a tool named read_resource, resources are not offered")
+		} else {
+			routes[ReadResourceTool] = toolRoute{tool: ReadResourceTool, resource: true}
+			toolDefs = append(toolDefs, readResourceDefinition(resources))
+		}
 	}
 
+	for tool, n := range providers {
+		if n > 1 {
+			slog.Warn("MCP tool offered by several servers, prefixing with server names",
+				"tool", tool,
+				"servers", n,
+			)
+		}
+	}
+
+	e.mu.Lock()
+	e.routes = routes
+	e.toolDefs = toolDefs
+	e.resources = resources
 	e.discovered = true
+	e.mu.Unlock()
 }
@@ -3,7 +3,12 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rhuss/antwort/pkg/tools"
@@ -280,3 +285,173 @@ func TestMCPExecutor_Kind(t *testing.T) {
 		t.Errorf("expected ToolKindMCP, got %v", executor.Kind())
 	}
 }
+
+// textTool returns a tool handler answering with text.
+func textTool(text string) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: text}}}, nil
+	}
+}
+
+// eventually polls cond until it holds or the timeout expires.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// switchableServer serves the handler set last, so that tests can take an
+// MCP server down and restart it under the same URL.
+type switchableServer struct {
+	handler atomic.Pointer[http.Handler]
+	url     string
+}
+
+func newSwitchableServer(t *testing.T) *switchableServer {
+	t.Helper()
+	s := &switchableServer{}
+	s.set(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(*s.handler.Load()).ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	s.url = ts.URL + "/mcp"
+	return s
+}
+
+func (s *switchableServer) set(h http.Handler) { s.handler.Store(&h) }
+
+func TestMCPExecutor_NameCollision(t *testing.T) {
+	clientA := setupTestServer(t, map[string]mcp.ToolHandler{
+		"search": textTool("search A"),
+		"tool_a": textTool("from server A"),
+	})
+	clientB := setupTestServer(t, map[string]mcp.ToolHandler{
+		"search": textTool("search B"),
+	})
+
+	executor := NewMCPExecutor(map[string]*MCPClient{
+		"server-a": clientA,
+		"server-b": clientB,
+	})
+	defer executor.Close()
+
+	var names []string
+	for _, td := range executor.DiscoveredTools() {
+		names = append(names, td.Name)
+	}
+	slices.Sort(names)
+	want := []string{"server-a__search", "server-b__search", "tool_a"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("tools = %v, want %v", names, want)
+	}
+
+	if executor.CanExecute("search") {
+		t.Error("CanExecute should return false for the ambiguous name")
+	}
+	if got := executor.ServerLabel("server-b__search"); got != "server-b" {
+		t.Errorf("ServerLabel = %q, want %q", got, "server-b")
+	}
+
+	result, err := executor.Execute(context.Background(), tools.ToolCall{ID: "call_1", Name: "server-b__search"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.Output != "search B" {
+		t.Errorf("Output = %q, want %q", result.Output, "search B")
+	}
+}
+
+func TestMCPExecutor_ToolListChanged(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+	server.AddTool(&mcp.Tool{Name: "first", InputSchema: map[string]any{"type": "object"}}, textTool("first"))
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	go func() { _ = server.Run(context.Background(), serverTransport) }()
+
+	client := &MCPClient{cfg: ServerConfig{Name: "test-server"}}
+	if err := client.ConnectWithTransport(context.Background(), clientTransport); err != nil {
+		t.Fatalf("ConnectWithTransport failed: %v", err)
+	}
+	executor := NewMCPExecutor(map[string]*MCPClient{"test-server": client})
+	defer executor.Close()
+
+	if !executor.CanExecute("first") {
+		t.Fatal("CanExecute should return true for first")
+	}
+
+	// Adding a tool makes the server announce a changed tool list.
+	server.AddTool(&mcp.Tool{Name: "second", InputSchema: map[string]any{"type": "object"}}, textTool("second"))
+	eventually(t, "the new tool", func() bool { return executor.CanExecute("second") })
+}
+
+func TestMCPExecutor_StartUnreachableServer(t *testing.T) {
+	srv := newSwitchableServer(t)
+
+	executor := NewMCPExecutor(map[string]*MCPClient{
+		"flaky": NewMCPClient(ServerConfig{Name: "flaky", URL: srv.url}),
+	})
+	executor.backoffMin = 10 * time.Millisecond
+	executor.backoffMax = 20 * time.Millisecond
+	executor.Start(HealthConfig{CheckInterval: 20 * time.Millisecond, RefreshInterval: time.Hour})
+	defer executor.Close()
+
+	eventually(t, "the server to be reported unhealthy", func() bool {
+		h := executor.Health()
+		return h[0].Status == HealthUnhealthy && h[0].LastError != ""
+	})
+	if executor.CanExecute("get_weather") {
+		t.Error("CanExecute should return false while the server is unreachable")
+	}
+
+	// The server comes up: it is connected and its tools are offered.
+	srv.set(httpToolHandler("get_weather"))
+	eventually(t, "the server to be healthy", func() bool {
+		h := executor.Health()
+		return h[0].Status == HealthHealthy && h[0].Tools == 1
+	})
+	if !executor.CanExecute("get_weather") {
+		t.Error("CanExecute should return true once the server is connected")
+	}
+
+	// The server restarts and forgets the session; calls still succeed.
+	srv.set(httpToolHandler("get_weather"))
+	result, err := executor.Execute(context.Background(), tools.ToolCall{ID: "call_1", Name: "get_weather"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.IsError || result.Output != "called get_weather" {
+		t.Errorf("result = %+v, want the tool's output", result)
+	}
+}
+
+func TestMCPExecutor_HealthCheckReconnects(t *testing.T) {
+	srv := newSwitchableServer(t)
+	srv.set(httpToolHandler("get_weather"))
+
+	executor := NewMCPExecutor(map[string]*MCPClient{
+		"flaky": NewMCPClient(ServerConfig{Name: "flaky", URL: srv.url}),
+	})
+	executor.backoffMin = 10 * time.Millisecond
+	executor.backoffMax = 20 * time.Millisecond
+	executor.Start(HealthConfig{CheckInterval: 20 * time.Millisecond, RefreshInterval: time.Hour})
+	defer executor.Close()
+
+	eventually(t, "the tool", func() bool { return executor.CanExecute("get_weather") })
+
+	// The server restarts with a different tool list.
+	srv.set(httpToolHandler("get_forecast"))
+	eventually(t, "the new tool list", func() bool {
+		return executor.CanExecute("get_forecast") && !executor.CanExecute("get_weather")
+	})
+	if h := executor.Health(); h[0].Status != HealthHealthy {
+		t.Errorf("Status = %q, want %q", h[0].Status, HealthHealthy)
+	}
+}
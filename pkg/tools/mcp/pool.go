@@ -0,0 +1,235 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrServerNotAllowed is returned when a request declares an MCP server
+// whose URL does not match the configured allowlist.
+var ErrServerNotAllowed = errors.New("MCP server URL is not allowed")
+
+// PoolConfig configures the connections to MCP servers declared per
+// request.
+type PoolConfig struct {
+	// AllowedURLs lists the server URLs requests may connect to. An entry
+	// ending in "/*" matches every URL with the same scheme and host whose
+	// path is below the entry's path; other entries must match exactly. An
+	// empty list rejects every server, which guards against requests
+	// probing internal endpoints (SSRF).
+	AllowedURLs []string
+
+	// IdleTimeout is how long a connection is kept open after its last
+	// request finished, so follow-up requests can reuse it. Zero closes
+	// connections as soon as they are released.
+	IdleTimeout time.Duration
+}
+
+// Pool shares MCPClient connections to servers declared in requests.
+// Clients are keyed by URL, transport, and headers, so requests carrying
+// different credentials never share a session.
+type Pool struct {
+	cfg PoolConfig
+
+	// connect opens a client; replaced in tests.
+	connect func(ctx context.Context, cfg ServerConfig) (*MCPClient, error)
+
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+	closed  bool
+}
+
+type pooledClient struct {
+	key    string
+	client *MCPClient
+	refs   int
+	idle   *time.Timer
+}
+
+// NewPool creates a pool for per-request MCP servers.
+func NewPool(cfg PoolConfig) *Pool {
+	p := &Pool{
+		cfg:     cfg,
+		clients: make(map[string]*pooledClient),
+	}
+	p.connect = p.connectClient
+	return p
+}
+
+// connectClient creates and connects a client for cfg. Redirects must stay
+// within the allowlist.
+func (p *Pool) connectClient(ctx context.Context, cfg ServerConfig) (*MCPClient, error) {
+	client := NewMCPClient(cfg)
+	client.allowURL = p.Allowed
+	if err := client.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Allowed reports whether rawURL matches the allowlist.
+func (p *Pool) Allowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	for _, pattern := range p.cfg.AllowedURLs {
+		if matchURL(pattern, u) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchURL reports whether u matches an allowlist entry. Scheme and host
+// must be equal; a trailing "*" only extends the path, after a "/".
+func matchURL(pattern string, u *url.URL) bool {
+	prefix, wildcard := strings.CutSuffix(pattern, "*")
+	p, err := url.Parse(prefix)
+	if err != nil || p.Scheme != u.Scheme || !strings.EqualFold(p.Host, u.Host) {
+		return false
+	}
+	if !wildcard {
+		return strings.TrimSuffix(u.Path, "/") == strings.TrimSuffix(p.Path, "/") && u.RawQuery == p.RawQuery
+	}
+	// Dot segments must not lead out of the pattern's path.
+	return strings.HasSuffix(p.Path, "/") && strings.HasPrefix(path.Clean("/"+u.Path), p.Path)
+}
+
+// Acquire returns a connected client for cfg, reusing a pooled connection
+// when one exists. Every successful call must be paired with Release.
+func (p *Pool) Acquire(ctx context.Context, cfg ServerConfig) (*MCPClient, error) {
+	if !p.Allowed(cfg.URL) {
+		return nil, fmt.Errorf("%w: %s", ErrServerNotAllowed, cfg.URL)
+	}
+	key := poolKey(cfg)
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errors.New("MCP client pool is closed")
+	}
+	if pc, ok := p.clients[key]; ok {
+		p.retain(pc)
+		p.mu.Unlock()
+		return pc.client, nil
+	}
+	p.mu.Unlock()
+
+	// Connect without holding the lock. The session outlives the request
+	// that opened it, so it must not be bound to the request's deadline.
+	client, err := p.connect(context.WithoutCancel(ctx), cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		_ = client.Close()
+		return nil, errors.New("MCP client pool is closed")
+	}
+	if pc, ok := p.clients[key]; ok {
+		// Another request connected concurrently; use its client.
+		_ = client.Close()
+		p.retain(pc)
+		return pc.client, nil
+	}
+	p.clients[key] = &pooledClient{key: key, client: client, refs: 1}
+	return client, nil
+}
+
+// Release returns a client obtained from Acquire. When no request uses it
+// any more, it is closed after the idle timeout.
+func (p *Pool) Release(client *MCPClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var pc *pooledClient
+	for _, c := range p.clients {
+		if c.client == client {
+			pc = c
+			break
+		}
+	}
+	if pc == nil {
+		return
+	}
+	pc.refs--
+	if pc.refs > 0 {
+		return
+	}
+	if p.cfg.IdleTimeout <= 0 {
+		p.evict(pc)
+		return
+	}
+	pc.idle = time.AfterFunc(p.cfg.IdleTimeout, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if pc.refs == 0 && p.clients[pc.key] == pc {
+			p.evict(pc)
+		}
+	})
+}
+
+// Close closes all pooled connections. Clients still in use are closed
+// as well; later Acquire calls fail.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for _, pc := range p.clients {
+		p.evict(pc)
+	}
+	return nil
+}
+
+// retain marks a pooled client as in use. Must be called with p.mu held.
+func (p *Pool) retain(pc *pooledClient) {
+	pc.refs++
+	if pc.idle != nil {
+		pc.idle.Stop()
+		pc.idle = nil
+	}
+}
+
+// evict removes and closes a pooled client. Must be called with p.mu held.
+func (p *Pool) evict(pc *pooledClient) {
+	delete(p.clients, pc.key)
+	if pc.idle != nil {
+		pc.idle.Stop()
+	}
+	if err := pc.client.Close(); err != nil {
+		slog.Warn("failed to close MCP client", "server", pc.client.cfg.Name, "error", err)
+	}
+}
+
+// poolKey identifies connections that can be shared between requests.
+func poolKey(cfg ServerConfig) string {
+	names := make([]string, 0, len(cfg.Headers))
+	for name := range cfg.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(cfg.Transport)
+	b.WriteByte('\n')
+	b.WriteString(cfg.URL)
+	for _, name := range names {
+		b.WriteByte('\n')
+		b.WriteString(strings.ToLower(name))
+		b.WriteByte(':')
+		b.WriteString(cfg.Headers[name])
+	}
+	return b.String()
+}
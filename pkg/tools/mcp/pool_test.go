@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// httpToolHandler returns a handler serving a new MCP server offering the
+// named tools over streamable HTTP. Each tool echoes its name.
+func httpToolHandler(toolNames ...string) http.Handler {
+	server := mcp.NewServer(&mcp.Implementation{Name: "http-test-server", Version: "1.0.0"}, nil)
+	for _, name := range toolNames {
+		server.AddTool(
+			&mcp.Tool{Name: name, Description: "Test tool: " + name, InputSchema: map[string]any{"type": "object"}},
+			func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "called " + name}}}, nil
+			},
+		)
+	}
+	return mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+}
+
+// startHTTPServer serves an MCP server offering the named tools over
+// streamable HTTP. It returns the endpoint URL.
+func startHTTPServer(t *testing.T, toolNames ...string) string {
+	t.Helper()
+
+	ts := httptest.NewServer(httpToolHandler(toolNames...))
+	t.Cleanup(ts.Close)
+	return ts.URL + "/mcp"
+}
+
+func TestPool_Allowed(t *testing.T) {
+	pool := NewPool(PoolConfig{AllowedURLs: []string{
+		"https://mcp.example.com/tools",
+		"https://tenants.example.com/*",
+		"https://scoped.example.com/teams/*",
+		// Invalid: the wildcard must follow a "/" after the host.
+		"https://unscoped.example.com*",
+	}})
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://mcp.example.com/tools", true},
+		{"https://mcp.example.com/tools/", true},
+		{"https://mcp.example.com/other", false},
+		{"https://tenants.example.com/alice/mcp", true},
+		{"https://tenants.example.com", true},
+		{"https://tenants.example.com.evil.com/mcp", false},
+		{"https://tenants.example.com:8443/alice/mcp", false},
+		{"https://tenants.example.com@evil.com/mcp", false},
+		{"http://tenants.example.com/alice/mcp", false},
+		{"https://mcp.example.com/tools/../admin", false},
+		{"https://scoped.example.com/teams/a/mcp", true},
+		{"https://scoped.example.com/teams", false},
+		{"https://scoped.example.com/teams-evil/mcp", false},
+		{"https://scoped.example.com/teams/../admin", false},
+		{"https://scoped.example.com/teams/%2e%2e/admin", false},
+		{"https://unscoped.example.com.evil.com/mcp", false},
+		{"file:///etc/passwd", false},
+		{"not a url", false},
+	}
+	for _, tt := range tests {
+		if got := pool.Allowed(tt.url); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	if NewPool(PoolConfig{}).Allowed("https://mcp.example.com/tools") {
+		t.Error("empty allowlist should reject every URL")
+	}
+}
+
+func TestPool_AcquireRelease(t *testing.T) {
+	url := startHTTPServer(t, "echo")
+	pool := NewPool(PoolConfig{AllowedURLs: []string{url}})
+	defer pool.Close()
+	ctx := context.Background()
+
+	a, err := pool.Acquire(ctx, ServerConfig{Name: "a", URL: url, Headers: map[string]string{"X-Token": "alice"}})
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	b, err := pool.Acquire(ctx, ServerConfig{Name: "b", URL: url, Headers: map[string]string{"X-Token": "alice"}})
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if a != b {
+		t.Error("requests with the same URL and headers should share a client")
+	}
+
+	c, err := pool.Acquire(ctx, ServerConfig{Name: "c", URL: url, Headers: map[string]string{"X-Token": "bob"}})
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if c == a {
+		t.Error("requests with different headers must not share a client")
+	}
+
+	pool.Release(a)
+	pool.Release(c)
+	if got := len(pool.clients); got != 1 {
+		t.Fatalf("pooled clients after partial release = %d, want 1", got)
+	}
+	pool.Release(b)
+	if got := len(pool.clients); got != 0 {
+		t.Errorf("pooled clients after release = %d, want 0 (no idle timeout)", got)
+	}
+
+	if _, err := pool.Acquire(ctx, ServerConfig{URL: "https://elsewhere.example.com/mcp"}); !errors.Is(err, ErrServerNotAllowed) {
+		t.Errorf("Acquire with disallowed URL: err = %v, want ErrServerNotAllowed", err)
+	}
+}
+
+func TestOpenRequestServers(t *testing.T) {
+	url := startHTTPServer(t, "search", "delete_all")
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL + "/mcp"
+	down.Close()
+
+	pool := NewPool(PoolConfig{AllowedURLs: []string{url, downURL}})
+	defer pool.Close()
+	ctx := context.Background()
+
+	rs, err := pool.OpenRequestServers(ctx, []api.ToolDefinition{
+		{Type: "function", Name: "local"},
+		{Type: "mcp", ServerLabel: "docs", ServerURL: url, AllowedTools: []string{"search"}},
+		{Type: "mcp", ServerLabel: "down", ServerURL: downURL},
+	})
+	if err != nil {
+		t.Fatalf("OpenRequestServers: %v", err)
+	}
+	defer rs.Close()
+
+	items := rs.ListToolsItems()
+	if len(items) != 2 {
+		t.Fatalf("expected 2 mcp_list_tools items, got %d", len(items))
+	}
+	docs := items[0].MCPListTools
+	if items[0].Type != api.ItemTypeMCPListTools || docs.ServerLabel != "docs" {
+		t.Errorf("items[0] = %s %+v, want mcp_list_tools for docs", items[0].Type, docs)
+	}
+	if len(docs.Tools) != 1 || docs.Tools[0].Name != "search" {
+		t.Errorf("docs tools = %+v, want only search", docs.Tools)
+	}
+	if items[1].MCPListTools.Error == "" {
+		t.Error("expected an error for the unreachable server")
+	}
+
+	if !rs.CanExecute("search") {
+		t.Error("CanExecute(search) = false, want true")
+	}
+	if rs.CanExecute("delete_all") {
+		t.Error("tools outside allowed_tools must not be executable")
+	}
+	result, err := rs.Execute(ctx, tools.ToolCall{ID: "call_1", Name: "search", Arguments: "{}"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if result.Output != "called search" || result.IsError {
+		t.Errorf("result = %+v, want output %q", result, "called search")
+	}
+
+	_, err = pool.OpenRequestServers(ctx, []api.ToolDefinition{
+		{Type: "mcp", ServerLabel: "internal", ServerURL: "http://169.254.169.254/latest"},
+	})
+	if !errors.Is(err, ErrServerNotAllowed) {
+		t.Errorf("err = %v, want ErrServerNotAllowed", err)
+	}
+}
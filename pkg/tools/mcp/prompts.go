@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// PromptScheme prefixes references to MCP prompts ("mcp://<server>/<prompt>"),
+// which agent profiles can use as instructions.
+const PromptScheme = "mcp://"
+
+// ErrInvalidPrompt is returned when a prompt reference cannot be rendered
+// because of the reference or the variables given for it.
+var ErrInvalidPrompt = errors.New("invalid MCP prompt")
+
+// ParsePromptRef splits a prompt reference into the server and prompt name.
+// It returns false if ref is not a prompt reference.
+func ParsePromptRef(ref string) (server, name string, ok bool) {
+	rest, ok := strings.CutPrefix(ref, PromptScheme)
+	if !ok {
+		return "", "", false
+	}
+	server, name, ok = strings.Cut(rest, "/")
+	if !ok || server == "" || name == "" {
+		return "", "", false
+	}
+	return server, name, true
+}
+
+// GetPrompt renders the named prompt with the given variables and returns
+// the text of its messages. Only variables the prompt declares as
+// arguments are passed; a missing required argument is an error.
+func (c *MCPClient) GetPrompt(ctx context.Context, name string, variables map[string]string) (string, error) {
+	session := c.currentSession()
+	if session == nil {
+		return "", fmt.Errorf("MCP client %q not connected", c.cfg.Name)
+	}
+
+	var prompt *mcp.Prompt
+	for p, err := range session.Prompts(ctx, nil) {
+		if err != nil {
+			return "", fmt.Errorf("listing prompts from %q: %w", c.cfg.Name, err)
+		}
+		if p.Name == name {
+			prompt = p
+			break
+		}
+	}
+	if prompt == nil {
+		return "", fmt.Errorf("%w: server %q has no prompt %q", ErrInvalidPrompt, c.cfg.Name, name)
+	}
+
+	args := make(map[string]string, len(prompt.Arguments))
+	for _, arg := range prompt.Arguments {
+		value, ok := variables[arg.Name]
+		if !ok {
+			if arg.Required {
+				return "", fmt.Errorf("%w: prompt %q requires variable %q", ErrInvalidPrompt, name, arg.Name)
+			}
+			continue
+		}
+		args[arg.Name] = value
+	}
+
+	result, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: name, Arguments: args})
+	if err != nil {
+		return "", fmt.Errorf("getting prompt %q from %q: %w", name, c.cfg.Name, err)
+	}
+
+	var parts []string
+	for _, msg := range result.Messages {
+		switch content := msg.Content.(type) {
+		case *mcp.TextContent:
+			parts = append(parts, content.Text)
+		case *mcp.EmbeddedResource:
+			if content.Resource != nil && content.Resource.Text != "" {
+				parts = append(parts, content.Resource.Text)
+			}
+		}
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// RenderPrompt renders the prompt referenced by ref ("mcp://<server>/<prompt>")
+// with the given variables.
+func (e *MCPExecutor) RenderPrompt(ctx context.Context, ref string, variables map[string]string) (string, error) {
+	server, name, ok := ParsePromptRef(ref)
+	if !ok {
+		return "", fmt.Errorf("%w: malformed reference %q", ErrInvalidPrompt, ref)
+	}
+
+	e.mu.RLock()
+	client := e.clients[server]
+	e.mu.RUnlock()
+	if client == nil {
+		return "", fmt.Errorf("%w: unknown MCP server %q", ErrInvalidPrompt, server)
+	}
+	return client.GetPrompt(ctx, name, variables)
+}
@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestParsePromptRef(t *testing.T) {
+	tests := []struct {
+		ref          string
+		server, name string
+		ok           bool
+	}{
+		{"mcp://docs/support", "docs", "support", true},
+		{"mcp://docs/team/support", "docs", "team/support", true},
+		{"mcp://docs", "", "", false},
+		{"mcp:///support", "", "", false},
+		{"You are a helpful assistant.", "", "", false},
+	}
+	for _, tt := range tests {
+		server, name, ok := ParsePromptRef(tt.ref)
+		if server != tt.server || name != tt.name || ok != tt.ok {
+			t.Errorf("ParsePromptRef(%q) = %q, %q, %v; want %q, %q, %v",
+				tt.ref, server, name, ok, tt.server, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestMCPExecutor_RenderPrompt(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "docs", Version: "1.0.0"}, nil)
+	var got map[string]string
+	server.AddPrompt(
+		&mcp.Prompt{
+			Name: "support",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "product", Required: true},
+				{Name: "tone"},
+			},
+		},
+		func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			got = req.Params.Arguments
+			return &mcp.GetPromptResult{Messages: []*mcp.PromptMessage{
+				{Role: "user", Content: &mcp.TextContent{Text: "You support " + req.Params.Arguments["product"] + "."}},
+				{Role: "user", Content: &mcp.TextContent{Text: "Be concise."}},
+			}}, nil
+		},
+	)
+	executor := NewMCPExecutor(map[string]*MCPClient{"docs": connectServer(t, "docs", server)})
+	defer executor.Close()
+
+	text, err := executor.RenderPrompt(context.Background(), "mcp://docs/support",
+		map[string]string{"product": "antwort", "user": "alice"})
+	if err != nil {
+		t.Fatalf("RenderPrompt failed: %v", err)
+	}
+	if text != "You support antwort.\n\nBe concise." {
+		t.Errorf("text = %q", text)
+	}
+	if len(got) != 1 || got["product"] != "antwort" {
+		t.Errorf("arguments = %v, want only the declared product", got)
+	}
+
+	for _, tt := range []struct {
+		ref       string
+		variables map[string]string
+	}{
+		{"mcp://docs/support", nil},
+		{"mcp://docs/missing", nil},
+		{"mcp://other/support", map[string]string{"product": "antwort"}},
+	} {
+		if _, err := executor.RenderPrompt(context.Background(), tt.ref, tt.variables); !errors.Is(err, ErrInvalidPrompt) {
+			t.Errorf("RenderPrompt(%q, %v) error = %v, want ErrInvalidPrompt", tt.ref, tt.variables, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// RequestServers holds the MCP servers one request declared with tools of
+// type "mcp". It implements tools.ToolExecutor for the tools they offer and
+// returns its pooled connections when closed.
+type RequestServers struct {
+	pool    *Pool
+	clients []*MCPClient
+
+	// toolToServer maps tool name to the server that provides it.
+	toolToServer map[string]requestTool
+	tools        []api.ToolDefinition
+	listings     []api.Item
+}
+
+// requestTool records the server a tool of a RequestServers belongs to.
+type requestTool struct {
+	client  *MCPClient
+	label   string
+	approve *api.MCPApprovalPolicy
+}
+
+// Ensure RequestServers implements tools.ToolExecutor at compile time.
+var _ tools.ToolExecutor = (*RequestServers)(nil)
+
+// OpenRequestServers connects to the servers declared by the "mcp" tool
+// definitions in defs and lists their tools, keeping only those named in
+// allowed_tools when it is set. A server that cannot be reached is
+// reported in its mcp_list_tools item rather than failing the request;
+// a server URL outside the allowlist fails with ErrServerNotAllowed.
+func (p *Pool) OpenRequestServers(ctx context.Context, defs []api.ToolDefinition) (*RequestServers, error) {
+	rs := &RequestServers{
+		pool:         p,
+		toolToServer: make(map[string]requestTool),
+	}
+
+	for _, def := range defs {
+		if def.Type != "mcp" {
+			continue
+		}
+		if !p.Allowed(def.ServerURL) {
+			rs.Close()
+			return nil, fmt.Errorf("%w: %s", ErrServerNotAllowed, def.ServerURL)
+		}
+
+		listing := &api.MCPListToolsData{ServerLabel: def.ServerLabel, Tools: []api.MCPToolInfo{}}
+		rs.listings = append(rs.listings, api.Item{
+			ID:           api.NewItemID(),
+			Type:         api.ItemTypeMCPListTools,
+			Status:       api.ItemStatusCompleted,
+			MCPListTools: listing,
+		})
+
+		toolDefs, err := rs.connect(ctx, def)
+		if err != nil {
+			if errors.Is(err, ErrServerNotAllowed) {
+				rs.Close()
+				return nil, err
+			}
+			slog.Warn("failed to list tools from MCP server",
+				"server", def.ServerLabel,
+				"error", err,
+			)
+			listing.Error = err.Error()
+			continue
+		}
+
+		for _, td := range toolDefs {
+			listing.Tools = append(listing.Tools, api.MCPToolInfo{
+				Name:        td.Name,
+				Description: td.Description,
+				InputSchema: td.Parameters,
+			})
+		}
+	}
+	return rs, nil
+}
+
+// connect acquires a client for the server of def and registers the tools
+// the request may use.
+func (rs *RequestServers) connect(ctx context.Context, def api.ToolDefinition) ([]api.ToolDefinition, error) {
+	client, err := rs.pool.Acquire(ctx, ServerConfig{
+		Name:    def.ServerLabel,
+		URL:     def.ServerURL,
+		Headers: def.Headers,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rs.clients = append(rs.clients, client)
+
+	discovered, err := client.DiscoverTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed []api.ToolDefinition
+	for _, td := range discovered {
+		if len(def.AllowedTools) > 0 && !slices.Contains(def.AllowedTools, td.Name) {
+			continue
+		}
+		if _, exists := rs.toolToServer[td.Name]; exists {
+			slog.Warn("duplicate MCP tool name, using first provider",
+				"tool", td.Name,
+				"server", def.ServerLabel,
+			)
+			continue
+		}
+		rs.toolToServer[td.Name] = requestTool{client: client, label: def.ServerLabel, approve: def.RequireApproval}
+		rs.tools = append(rs.tools, td)
+		allowed = append(allowed, td)
+	}
+	return allowed, nil
+}
+
+// Kind returns ToolKindMCP.
+func (rs *RequestServers) Kind() tools.ToolKind {
+	return tools.ToolKindMCP
+}
+
+// CanExecute returns true if one of the request's servers provides the
+// named tool and the request allows it.
+func (rs *RequestServers) CanExecute(toolName string) bool {
+	_, ok := rs.toolToServer[toolName]
+	return ok
+}
+
+// RequiresApproval reports whether calls to the named tool wait for the
+// user's approval, and the label of the server that provides it.
+func (rs *RequestServers) RequiresApproval(toolName string) (string, bool) {
+	tool, ok := rs.toolToServer[toolName]
+	if !ok {
+		return "", false
+	}
+	return tool.label, tool.approve.Requires(toolName)
+}
+
+// ServerLabel returns the label of the server that provides the named tool.
+func (rs *RequestServers) ServerLabel(toolName string) string {
+	return rs.toolToServer[toolName].label
+}
+
+// Execute routes the tool call to the server that provides the tool.
+func (rs *RequestServers) Execute(ctx context.Context, call tools.ToolCall) (*tools.ToolResult, error) {
+	tool, ok := rs.toolToServer[call.Name]
+	if !ok {
+		return &tools.ToolResult{
+			CallID:  call.ID,
+			Output:  fmt.Sprintf("no MCP server provides tool %q", call.Name),
+			IsError: true,
+		}, nil
+	}
+	return tool.client.CallTool(ctx, call)
+}
+
+// DiscoveredTools returns the function definitions of the tools the
+// request may use.
+func (rs *RequestServers) DiscoveredTools() []api.ToolDefinition {
+	return rs.tools
+}
+
+// ListToolsItems returns one mcp_list_tools output item per declared server.
+func (rs *RequestServers) ListToolsItems() []api.Item {
+	return rs.listings
+}
+
+// Close releases the request's connections back to the pool.
+func (rs *RequestServers) Close() {
+	for _, client := range rs.clients {
+		rs.pool.Release(client)
+	}
+	rs.clients = nil
+}
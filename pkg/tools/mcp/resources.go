@@ -0,0 +1,190 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// ReadResourceTool is the name of the tool through which the model reads
+// the resources of the configured MCP servers.
+const ReadResourceTool = "read_resource"
+
+// maxListedResources caps the resources listed in the description of the
+// read_resource tool.
+const maxListedResources = 50
+
+// Resource describes a resource offered by an MCP server.
+type Resource struct {
+	Server      string
+	URI         string
+	Name        string
+	Description string
+	MIMEType    string
+}
+
+// ListResources returns the resources the server offers, or none if the
+// server does not support resources. The list is cached like the tools.
+func (c *MCPClient) ListResources(ctx context.Context) ([]Resource, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.resourcesResolved {
+		return c.cachedResources, nil
+	}
+
+	session := c.currentSession()
+	if session == nil {
+		return nil, fmt.Errorf("MCP client %q not connected", c.cfg.Name)
+	}
+
+	var resources []Resource
+	if caps := session.InitializeResult().Capabilities; caps != nil && caps.Resources != nil {
+		for r, err := range session.Resources(ctx, nil) {
+			if err != nil {
+				return nil, fmt.Errorf("listing resources from %q: %w", c.cfg.Name, err)
+			}
+			resources = append(resources, Resource{
+				Server:      c.cfg.Name,
+				URI:         r.URI,
+				Name:        r.Name,
+				Description: r.Description,
+				MIMEType:    r.MIMEType,
+			})
+		}
+	}
+
+	c.cachedResources = resources
+	c.resourcesResolved = true
+	return resources, nil
+}
+
+// ReadResource reads the resource with the given URI and returns its
+// contents as the result of call. Binary contents are described rather
+// than included. The result records the resource as a source, so that
+// annotations can cite it.
+func (c *MCPClient) ReadResource(ctx context.Context, call tools.ToolCall, uri, title string) (*tools.ToolResult, error) {
+	session := c.currentSession()
+	if session == nil {
+		return nil, fmt.Errorf("MCP client %q not connected", c.cfg.Name)
+	}
+
+	result, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		return &tools.ToolResult{
+			CallID:  call.ID,
+			Output:  fmt.Sprintf("MCP resource read error: %v", err),
+			IsError: true,
+		}, nil
+	}
+
+	var parts []string
+	for _, content := range result.Contents {
+		if content.Text != "" || content.Blob == nil {
+			parts = append(parts, content.Text)
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("[binary content of %s (%s), %d bytes]", content.URI, content.MIMEType, len(content.Blob)))
+	}
+	output := strings.Join(parts, "\n")
+
+	return &tools.ToolResult{
+		CallID:  call.ID,
+		Output:  output,
+		Sources: []tools.Source{{URL: uri, Title: title, Text: output}},
+		Metadata: map[string]string{
+			"tool":    "mcp_resource",
+			"url":     uri,
+			"title":   title,
+			"content": output,
+		},
+	}, nil
+}
+
+// readResourceDefinition returns the definition of the read_resource tool
+// for the given resources.
+func readResourceDefinition(resources []Resource) api.ToolDefinition {
+	var b strings.Builder
+	b.WriteString("Read a resource offered by an MCP server. Available resources:")
+	var servers []string
+	for _, r := range resources {
+		if !slices.Contains(servers, r.Server) {
+			servers = append(servers, r.Server)
+		}
+	}
+	for i, r := range resources {
+		if i == maxListedResources {
+			fmt.Fprintf(&b, "\n- ... and %d more", len(resources)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s (server %s): %s", r.URI, r.Server, r.Name)
+		if r.Description != "" {
+			fmt.Fprintf(&b, ", %s", r.Description)
+		}
+	}
+
+	params, _ := json.Marshal(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"uri": map[string]any{
+				"type":        "string",
+				"description": "URI of the resource to read.",
+			},
+			"server": map[string]any{
+				"type":        "string",
+				"enum":        servers,
+				"description": "MCP server offering the resource. Optional if the URI is listed.",
+			},
+		},
+		"required": []string{"uri"},
+	})
+
+	return api.ToolDefinition{
+		Type:        "function",
+		Name:        ReadResourceTool,
+		Description: b.String(),
+		Parameters:  params,
+	}
+}
+
+// readResource executes a call of the read_resource tool.
+func (e *MCPExecutor) readResource(ctx context.Context, call tools.ToolCall) (*tools.ToolResult, error) {
+	var args struct {
+		URI    string `json:"uri"`
+		Server string `json:"server"`
+	}
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil || args.URI == "" {
+		return &tools.ToolResult{
+			CallID:  call.ID,
+			Output:  "read_resource requires a \"uri\" argument",
+			IsError: true,
+		}, nil
+	}
+
+	e.mu.RLock()
+	var title string
+	server := args.Server
+	for _, r := range e.resources {
+		if r.URI == args.URI && (server == "" || server == r.Server) {
+			server, title = r.Server, r.Name
+			break
+		}
+	}
+	client := e.clients[server]
+	e.mu.RUnlock()
+
+	if client == nil {
+		return &tools.ToolResult{
+			CallID:  call.ID,
+			Output:  fmt.Sprintf("no MCP server offers resource %q", args.URI),
+			IsError: true,
+		}, nil
+	}
+	return client.ReadResource(ctx, call, args.URI, title)
+}
@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rhuss/antwort/pkg/tools"
+)
+
+// connectServer connects a client named name to server via in-memory
+// transports.
+func connectServer(t *testing.T, name string, server *mcp.Server) *MCPClient {
+	t.Helper()
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	go func() {
+		_ = server.Run(context.Background(), serverTransport)
+	}()
+
+	client := &MCPClient{cfg: ServerConfig{Name: name}}
+	if err := client.ConnectWithTransport(context.Background(), clientTransport); err != nil {
+		t.Fatalf("ConnectWithTransport failed: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// newResourceServer returns a server offering a text resource and a tool
+// that returns an embedded resource.
+func newResourceServer() *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "docs", Version: "1.0.0"}, nil)
+	server.AddResource(
+		&mcp.Resource{URI: "docs://handbook", Name: "Handbook", Description: "Team handbook", MIMEType: "text/plain"},
+		func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+			return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{
+				{URI: req.Params.URI, MIMEType: "text/plain", Text: "Vacation requests need two weeks notice."},
+			}}, nil
+		},
+	)
+	server.AddTool(
+		&mcp.Tool{Name: "lookup", InputSchema: map[string]any{"type": "object"}},
+		func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{
+				&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "docs://policy", Text: "Expenses are reimbursed monthly."}},
+			}}, nil
+		},
+	)
+	return server
+}
+
+func TestMCPExecutor_ReadResource(t *testing.T) {
+	executor := NewMCPExecutor(map[string]*MCPClient{
+		"docs": connectServer(t, "docs", newResourceServer()),
+	})
+	defer executor.Close()
+
+	var def string
+	for _, td := range executor.DiscoveredTools() {
+		if td.Name == ReadResourceTool {
+			def = td.Description
+		}
+	}
+	if !strings.Contains(def, "docs://handbook") {
+		t.Fatalf("read_resource description = %q, want the handbook listed", def)
+	}
+	if _, ok := executor.RequiresApproval(ReadResourceTool); ok {
+		t.Error("read_resource should not require approval")
+	}
+
+	result, err := executor.Execute(context.Background(), tools.ToolCall{
+		ID:        "call_1",
+		Name:      ReadResourceTool,
+		Arguments: `{"uri":"docs://handbook"}`,
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.IsError || result.Output != "Vacation requests need two weeks notice." {
+		t.Fatalf("result = %+v", result)
+	}
+	if result.Metadata["tool"] != "mcp_resource" || result.Metadata["url"] != "docs://handbook" || result.Metadata["title"] != "Handbook" {
+		t.Errorf("metadata = %v", result.Metadata)
+	}
+
+	result, err = executor.Execute(context.Background(), tools.ToolCall{
+		ID:        "call_2",
+		Name:      ReadResourceTool,
+		Arguments: `{"uri":"docs://missing","server":"other"}`,
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !result.IsError {
+		t.Errorf("expected an error for an unknown server, got %+v", result)
+	}
+}
+
+func TestMCPExecutor_NoResources(t *testing.T) {
+	executor := NewMCPExecutor(map[string]*MCPClient{
+		"test-server": setupTestServer(t, map[string]mcp.ToolHandler{"ping": textTool("pong")}),
+	})
+	defer executor.Close()
+
+	if executor.CanExecute(ReadResourceTool) {
+		t.Error("read_resource should not be offered without resources")
+	}
+}
+
+func TestMCPClient_EmbeddedResourceMetadata(t *testing.T) {
+	client := connectServer(t, "docs", newResourceServer())
+
+	result, err := client.CallTool(context.Background(), tools.ToolCall{ID: "call_1", Name: "lookup", Arguments: `{}`})
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if result.Output != "Expenses are reimbursed monthly." {
+		t.Errorf("output = %q", result.Output)
+	}
+	if len(result.Sources) != 1 || result.Sources[0].URL != "docs://policy" {
+		t.Errorf("sources = %+v", result.Sources)
+	}
+	if result.Metadata["tool"] != "mcp_resource" || result.Metadata["url"] != "docs://policy" {
+		t.Errorf("metadata = %v", result.Metadata)
+	}
+}
@@ -0,0 +1,29 @@
+package tools
+
+import "context"
+
+// Output is output that a running tool call produced, such as what code
+// run by the code interpreter wrote. Stream names where it was written,
+// e.g. "stdout" or "stderr".
+type Output struct {
+	Stream string
+	Data   string
+}
+
+// outputReporterKey is a private type for the output reporter context key.
+type outputReporterKey struct{}
+
+// SetOutputReporter injects a function that receives the output of the
+// tool call executed with the context while it runs.
+func SetOutputReporter(ctx context.Context, report func(Output)) context.Context {
+	return context.WithValue(ctx, outputReporterKey{}, report)
+}
+
+// GetOutputReporter extracts the output reporter from the context.
+// Returns nil if no reporter is set.
+func GetOutputReporter(ctx context.Context) func(Output) {
+	if report, ok := ctx.Value(outputReporterKey{}).(func(Output)); ok {
+		return report
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package tools
+
+import "context"
+
+// Progress is a progress notification from a running tool call.
+// Total is zero if the tool does not know the total amount of work.
+type Progress struct {
+	Progress float64
+	Total    float64
+	Message  string
+}
+
+// progressReporterKey is a private type for the progress reporter context key.
+type progressReporterKey struct{}
+
+// SetProgressReporter injects a function that receives the progress
+// notifications of the tool call executed with the context.
+func SetProgressReporter(ctx context.Context, report func(Progress)) context.Context {
+	return context.WithValue(ctx, progressReporterKey{}, report)
+}
+
+// GetProgressReporter extracts the progress reporter from the context.
+// Returns nil if no reporter is set.
+func GetProgressReporter(ctx context.Context) func(Progress) {
+	if report, ok := ctx.Value(progressReporterKey{}).(func(Progress)); ok {
+		return report
+	}
+	return nil
+}
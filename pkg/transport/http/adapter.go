@@ -10,6 +10,7 @@ import (
 
 	"github.com/rhuss/antwort/pkg/api"
 	"github.com/rhuss/antwort/pkg/audit"
+	"github.com/rhuss/antwort/pkg/auth/apikey"
 	"github.com/rhuss/antwort/pkg/storage"
 	"github.com/rhuss/antwort/pkg/transport"
 )
@@ -32,6 +33,10 @@ type Adapter struct {
 	config          Config
 	auditLogger     *audit.Logger
 	bgCanceller     BackgroundCanceller            // nil if no background worker
+	models          *modelCache                    // nil if model listing disabled
+	agentModels     bool                           // list agent profiles as virtual models
+	streams         *transport.StreamRegistry      // nil if stream resumption disabled
+	keyStore        apikey.Store                   // nil if key management disabled
 }
 
 // Config holds configuration for the HTTP adapter.
@@ -77,6 +82,10 @@ func NewAdapter(creator transport.ResponseCreator, store transport.ResponseStore
 	// Agent profile listing (Spec 038).
 	a.mux.HandleFunc("GET /v1/agents", a.handleListAgents)
 
+	// Model listing (OpenAI-compatible).
+	a.mux.HandleFunc("GET /v1/models", a.handleListModels)
+	a.mux.HandleFunc("GET /v1/models/{id...}", a.handleGetModel)
+
 	// Conversation endpoints (Spec 037).
 	a.mux.HandleFunc("POST /v1/conversations", a.handleCreateConversation)
 	a.mux.HandleFunc("GET /v1/conversations", a.handleListConversations)
@@ -85,6 +94,12 @@ func NewAdapter(creator transport.ResponseCreator, store transport.ResponseStore
 	a.mux.HandleFunc("GET /v1/conversations/{id}", a.handleGetConversation)
 	a.mux.HandleFunc("DELETE /v1/conversations/{id}", a.handleDeleteConversation)
 
+	// API key management (admin only).
+	a.mux.HandleFunc("POST /v1/admin/api_keys", a.handleCreateAPIKey)
+	a.mux.HandleFunc("GET /v1/admin/api_keys", a.handleListAPIKeys)
+	a.mux.HandleFunc("GET /v1/admin/api_keys/{id}", a.handleGetAPIKey)
+	a.mux.HandleFunc("DELETE /v1/admin/api_keys/{id}", a.handleDeleteAPIKey)
+
 	return a
 }
 
@@ -225,14 +240,30 @@ func (a *Adapter) handleCreateResponse(w http.ResponseWriter, r *http.Request) {
 
 // handleStreamingResponse handles streaming POST requests (stream: true).
 func (a *Adapter) handleStreamingResponse(w http.ResponseWriter, r *http.Request, req *api.CreateResponseRequest) {
-	ctx, cancel := context.WithCancel(r.Context())
+	parent := r.Context()
+	if a.streams != nil {
+		// Keep generating when the client disconnects, so that it can
+		// resume the stream. DELETE still cancels via the in-flight registry.
+		parent = context.WithoutCancel(parent)
+	}
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
 	var registeredID string
-	rw := newSSEResponseWriter(w, func(id string) {
+	sse := newSSEResponseWriter(w, func(id string) {
 		registeredID = id
 		a.inflight.Register(id, cancel)
 	})
+	var rw eventWriter = sse
+	if a.streams != nil {
+		resumable := &resumableWriter{
+			sseResponseWriter: sse,
+			streams:           a.streams,
+			owner:             storage.GetOwner(r.Context()),
+		}
+		defer resumable.close()
+		rw = resumable
+	}
 
 	err := a.creator.CreateResponse(ctx, req, rw)
 
@@ -250,8 +281,14 @@ func (a *Adapter) handleStreamingResponse(w http.ResponseWriter, r *http.Request
 	}
 }
 
-// handleGetResponse handles GET /v1/responses/{id}.
+// handleGetResponse handles GET /v1/responses/{id}. With stream=true it
+// resumes the response's event stream instead.
 func (a *Adapter) handleGetResponse(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("stream") == "true" {
+		a.handleResumeStream(w, r)
+		return
+	}
+
 	if a.store == nil {
 		transport.WriteErrorResponse(w,
 			api.NewInvalidRequestError("", "response retrieval is not available (no store configured)"),
@@ -489,10 +526,16 @@ func parseListOptions(r *http.Request) (transport.ListOptions, *api.APIError) {
 	return opts, nil
 }
 
+// eventWriter is a ResponseWriter that knows whether streaming has started.
+type eventWriter interface {
+	transport.ResponseWriter
+	hasStartedStreaming() bool
+}
+
 // writeHandlerError writes an error response from the handler. If streaming
 // has already started, it sends a response.failed event. Otherwise it writes
 // a standard JSON error response.
-func (a *Adapter) writeHandlerError(w http.ResponseWriter, rw *sseResponseWriter, err error) {
+func (a *Adapter) writeHandlerError(w http.ResponseWriter, rw eventWriter, err error) {
 	var apiErr *api.APIError
 	if !errors.As(err, &apiErr) {
 		apiErr = api.NewServerError(err.Error())
@@ -0,0 +1,179 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/auth/apikey"
+	"github.com/rhuss/antwort/pkg/storage"
+	"github.com/rhuss/antwort/pkg/transport"
+)
+
+// apiKeyObject is the API representation of a managed key. Secret holds
+// the plaintext key and is only set in the response to its creation.
+type apiKeyObject struct {
+	Object string `json:"object"`
+	*apikey.Key
+	Secret string `json:"key,omitempty"`
+}
+
+// SetKeyStore enables the /v1/admin/api_keys endpoints on the adapter.
+func (a *Adapter) SetKeyStore(store apikey.Store) {
+	a.keyStore = store
+}
+
+// checkKeyAdmin writes an error and returns false unless key management is
+// enabled and the caller is an admin.
+func (a *Adapter) checkKeyAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if a.keyStore == nil {
+		transport.WriteErrorResponse(w,
+			api.NewInvalidRequestError("", "API key management is not enabled"),
+			http.StatusNotImplemented,
+		)
+		return false
+	}
+	if !storage.GetAdmin(r.Context()) {
+		transport.WriteErrorResponse(w,
+			&api.APIError{Type: "forbidden", Message: "managing API keys requires the admin role"},
+			http.StatusForbidden,
+		)
+		return false
+	}
+	return true
+}
+
+func (a *Adapter) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !a.checkKeyAdmin(w, r) {
+		return
+	}
+
+	var req struct {
+		Name        string   `json:"name"`
+		Subject     string   `json:"subject"`
+		TenantID    string   `json:"tenant_id"`
+		Roles       []string `json:"roles"`
+		Scopes      []string `json:"scopes"`
+		ServiceTier string   `json:"service_tier"`
+		ExpiresAt   int64    `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		transport.WriteAPIError(w, api.NewInvalidRequestError("body", "invalid request body"))
+		return
+	}
+	if req.Subject == "" {
+		transport.WriteAPIError(w, api.NewInvalidRequestError("subject", "subject is required"))
+		return
+	}
+	// Admins of a tenant create keys for their own tenant only.
+	if tenantID := storage.GetTenant(r.Context()); tenantID != "" {
+		if req.TenantID != "" && req.TenantID != tenantID {
+			transport.WriteAPIError(w, api.NewInvalidRequestError("tenant_id", "tenant_id must be the caller's tenant"))
+			return
+		}
+		req.TenantID = tenantID
+	}
+	now := time.Now().Unix()
+	if req.ExpiresAt != 0 && req.ExpiresAt <= now {
+		transport.WriteAPIError(w, api.NewInvalidRequestError("expires_at", "expires_at must be in the future"))
+		return
+	}
+
+	plaintext, err := apikey.GenerateKey()
+	if err != nil {
+		transport.WriteAPIError(w, api.NewServerError(err.Error()))
+		return
+	}
+	key := &apikey.Key{
+		ID:          api.NewAPIKeyID(),
+		Name:        req.Name,
+		Prefix:      apikey.KeyPrefix(plaintext),
+		Hash:        apikey.HashKey(plaintext),
+		Subject:     req.Subject,
+		TenantID:    req.TenantID,
+		Roles:       req.Roles,
+		Scopes:      req.Scopes,
+		ServiceTier: req.ServiceTier,
+		CreatedAt:   now,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	if err := a.keyStore.CreateKey(r.Context(), key); err != nil {
+		transport.WriteAPIError(w, api.NewServerError(err.Error()))
+		return
+	}
+
+	a.auditLogger.Log(r.Context(), "resource.created", "resource_type", "api_key", "resource_id", key.ID, "key_subject", key.Subject)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(apiKeyObject{Object: "api_key", Key: key, Secret: plaintext})
+}
+
+func (a *Adapter) handleGetAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !a.checkKeyAdmin(w, r) {
+		return
+	}
+
+	key, err := a.keyStore.GetKey(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			transport.WriteAPIError(w, api.NewNotFoundError("API key not found"))
+		} else {
+			transport.WriteAPIError(w, api.NewServerError(err.Error()))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apiKeyObject{Object: "api_key", Key: key})
+}
+
+func (a *Adapter) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if !a.checkKeyAdmin(w, r) {
+		return
+	}
+
+	keys, err := a.keyStore.ListKeys(r.Context())
+	if err != nil {
+		transport.WriteAPIError(w, api.NewServerError(err.Error()))
+		return
+	}
+
+	data := make([]apiKeyObject, 0, len(keys))
+	for _, key := range keys {
+		data = append(data, apiKeyObject{Object: "api_key", Key: key})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"object": "list",
+		"data":   data,
+	})
+}
+
+func (a *Adapter) handleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !a.checkKeyAdmin(w, r) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := a.keyStore.DeleteKey(r.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			transport.WriteAPIError(w, api.NewNotFoundError("API key not found"))
+		} else {
+			transport.WriteAPIError(w, api.NewServerError(err.Error()))
+		}
+		return
+	}
+
+	a.auditLogger.Log(r.Context(), "resource.deleted", "resource_type", "api_key", "resource_id", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      id,
+		"object":  "api_key",
+		"deleted": true,
+	})
+}
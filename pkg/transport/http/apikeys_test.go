@@ -0,0 +1,199 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/auth/apikey"
+	"github.com/rhuss/antwort/pkg/storage"
+	"github.com/rhuss/antwort/pkg/storage/memory"
+)
+
+// asAdmin marks every request as coming from an admin, like the auth
+// middleware does for identities with the admin role.
+func asAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(storage.SetAdmin(r.Context(), true)))
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	adapter := newTestAdapter(&mockCreator{}, &mockStore{})
+	ac, logger := newAuditCapture()
+	adapter.SetAuditLogger(logger)
+	keys := memory.NewAPIKeyStore()
+	adapter.SetKeyStore(keys)
+
+	srv := httptest.NewServer(asAdmin(adapter.Handler()))
+	defer srv.Close()
+
+	// Create a key; the plaintext key is only returned here.
+	body := `{"name":"ci","subject":"build-bot","roles":["ops"],"scopes":["responses:create"],"service_tier":"batch"}`
+	resp, err := http.Post(srv.URL+"/v1/admin/api_keys", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST error: %v", err)
+	}
+	var created map[string]any
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+	id, _ := created["id"].(string)
+	secret, _ := created["key"].(string)
+	if created["object"] != "api_key" || !strings.HasPrefix(id, "key_") || secret == "" {
+		t.Fatalf("created = %v", created)
+	}
+	if created["prefix"] != apikey.KeyPrefix(secret) || created["subject"] != "build-bot" {
+		t.Errorf("created = %v", created)
+	}
+
+	// Only the hash is stored.
+	stored, err := keys.GetKeyByHash(t.Context(), apikey.HashKey(secret))
+	if err != nil || stored.ID != id {
+		t.Fatalf("GetKeyByHash = %v, %v", stored, err)
+	}
+
+	// Get and list do not reveal the key.
+	resp, _ = http.Get(srv.URL + "/v1/admin/api_keys/" + id)
+	var got map[string]any
+	json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if got["id"] != id || got["key"] != nil {
+		t.Errorf("get = %v", got)
+	}
+
+	resp, _ = http.Get(srv.URL + "/v1/admin/api_keys")
+	var list struct {
+		Object string           `json:"object"`
+		Data   []map[string]any `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if list.Object != "list" || len(list.Data) != 1 || list.Data[0]["key"] != nil {
+		t.Errorf("list = %+v", list)
+	}
+
+	// Revoke it.
+	req, _ := http.NewRequest("DELETE", srv.URL+"/v1/admin/api_keys/"+id, nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("delete status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	resp, _ = http.Get(srv.URL + "/v1/admin/api_keys/" + id)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("get after delete status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	var events []string
+	for _, e := range ac.entries() {
+		if e["resource_type"] == "api_key" && e["resource_id"] == id {
+			events = append(events, e["event"].(string))
+		}
+	}
+	if strings.Join(events, ",") != "resource.created,resource.deleted" {
+		t.Errorf("audit events = %v", events)
+	}
+}
+
+func TestAPIKeysErrors(t *testing.T) {
+	past := time.Now().Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name     string
+		keyStore bool
+		admin    bool
+		method   string
+		path     string
+		body     string
+		want     int
+	}{
+		{"disabled", false, true, "GET", "/v1/admin/api_keys", "", http.StatusNotImplemented},
+		{"not admin", true, false, "GET", "/v1/admin/api_keys", "", http.StatusForbidden},
+		{"not admin create", true, false, "POST", "/v1/admin/api_keys", `{"subject":"x"}`, http.StatusForbidden},
+		{"missing subject", true, true, "POST", "/v1/admin/api_keys", `{"name":"x"}`, http.StatusBadRequest},
+		{"expired", true, true, "POST", "/v1/admin/api_keys", `{"subject":"x","expires_at":` + strconv.FormatInt(past, 10) + `}`, http.StatusBadRequest},
+		{"invalid body", true, true, "POST", "/v1/admin/api_keys", `{`, http.StatusBadRequest},
+		{"unknown key", true, true, "DELETE", "/v1/admin/api_keys/key_missing", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter := newTestAdapter(&mockCreator{}, &mockStore{})
+			if tt.keyStore {
+				adapter.SetKeyStore(memory.NewAPIKeyStore())
+			}
+			handler := adapter.Handler()
+			if tt.admin {
+				handler = asAdmin(handler)
+			}
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIKeysTenantAdmin(t *testing.T) {
+	adapter := newTestAdapter(&mockCreator{}, &mockStore{})
+	keys := memory.NewAPIKeyStore()
+	adapter.SetKeyStore(keys)
+	other := &apikey.Key{ID: "key_other", Hash: "other", Subject: "bot", TenantID: "tenant-b", Roles: []string{"admin"}}
+	if err := keys.CreateKey(t.Context(), other); err != nil {
+		t.Fatal(err)
+	}
+
+	// An admin of tenant-a.
+	handler := asAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		adapter.Handler().ServeHTTP(w, r.WithContext(storage.SetTenant(r.Context(), "tenant-a")))
+	}))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do("POST", "/v1/admin/api_keys", `{"subject":"x","tenant_id":"tenant-b","roles":["admin"]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("create for another tenant: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec := do("POST", "/v1/admin/api_keys", `{"subject":"x"}`)
+	var created map[string]any
+	json.NewDecoder(rec.Body).Decode(&created)
+	if rec.Code != http.StatusCreated || created["tenant_id"] != "tenant-a" {
+		t.Errorf("create = %d %v, want a key of tenant-a", rec.Code, created)
+	}
+
+	if rec := do("GET", "/v1/admin/api_keys/key_other", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get other tenant's key: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := do("DELETE", "/v1/admin/api_keys/key_other", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("delete other tenant's key: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	rec = do("GET", "/v1/admin/api_keys", "")
+	var list struct {
+		Data []map[string]any `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Data) != 1 || list.Data[0]["tenant_id"] != "tenant-a" {
+		t.Errorf("list = %v, want only tenant-a's key", list.Data)
+	}
+	if _, err := keys.GetKey(t.Context(), "key_other"); err != nil {
+		t.Errorf("other tenant's key is gone: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/agent"
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/provider"
+	"github.com/rhuss/antwort/pkg/transport"
+)
+
+// ModelLister lists the models served by the inference backend.
+// provider.Provider satisfies this interface.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]provider.ModelInfo, error)
+}
+
+// modelCache caches the backend model list for a fixed TTL so that
+// GET /v1/models does not hit the backend on every call. One request at a
+// time refreshes the list; the others are served the previous list
+// meanwhile, and when a refresh fails, until the next successful one.
+type modelCache struct {
+	lister ModelLister
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	models    []provider.ModelInfo
+	fetchedAt time.Time
+	refresh   chan struct{} // closed when the running refresh ends; nil if none
+	err       error         // error of the last refresh
+}
+
+// list returns the cached model list, refreshing it when expired.
+func (c *modelCache) list(ctx context.Context) ([]provider.ModelInfo, error) {
+	c.mu.Lock()
+	cached := !c.fetchedAt.IsZero()
+	if cached && time.Since(c.fetchedAt) < c.ttl {
+		defer c.mu.Unlock()
+		return c.models, nil
+	}
+	if done := c.refresh; done != nil {
+		if cached {
+			defer c.mu.Unlock()
+			return c.models, nil
+		}
+		// There is nothing to serve before the first refresh ends.
+		c.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.fetchedAt.IsZero() {
+			return nil, c.err
+		}
+		return c.models, nil
+	}
+	done := make(chan struct{})
+	c.refresh = done
+	c.mu.Unlock()
+
+	// Call the backend without holding the lock.
+	models, err := c.lister.ListModels(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refresh = nil
+	close(done)
+	c.err = err
+	if err != nil {
+		if c.fetchedAt.IsZero() {
+			return nil, err
+		}
+		slog.Warn("failed to refresh model list, serving cached models", "error", err.Error())
+		return c.models, nil
+	}
+
+	c.models = models
+	c.fetchedAt = time.Now()
+	return models, nil
+}
+
+// SetModelLister enables GET /v1/models and GET /v1/models/{id}, serving
+// the lister's models cached for ttl. A non-positive ttl disables caching.
+func (a *Adapter) SetModelLister(lister ModelLister, ttl time.Duration) {
+	a.models = &modelCache{lister: lister, ttl: ttl}
+}
+
+// SetAgentModels controls whether agent profiles are listed as virtual
+// models ("agent/<name>") on GET /v1/models. Requires a profile resolver.
+func (a *Adapter) SetAgentModels(enabled bool) {
+	a.agentModels = enabled
+}
+
+// handleListModels handles GET /v1/models.
+func (a *Adapter) handleListModels(w http.ResponseWriter, r *http.Request) {
+	models, ok := a.listModels(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"object": "list",
+		"data":   models,
+	})
+}
+
+// handleGetModel handles GET /v1/models/{id}.
+func (a *Adapter) handleGetModel(w http.ResponseWriter, r *http.Request) {
+	// Model IDs may contain slashes (e.g. "meta-llama/Llama-3.1-8B"); the
+	// route uses {id...} to capture the rest of the path.
+	id := r.PathValue("id")
+
+	models, ok := a.listModels(w, r)
+	if !ok {
+		return
+	}
+
+	for _, m := range models {
+		if m.ID == id {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(m)
+			return
+		}
+	}
+	transport.WriteAPIError(w, api.NewNotFoundError("model "+id+" not found"))
+}
+
+// listModels returns the backend models followed by the virtual agent
+// models (if enabled), sorted by ID. It writes an error response and
+// returns false on failure.
+func (a *Adapter) listModels(w http.ResponseWriter, r *http.Request) ([]provider.ModelInfo, bool) {
+	if a.models == nil {
+		transport.WriteErrorResponse(w,
+			api.NewInvalidRequestError("", "model listing is not configured"),
+			http.StatusNotImplemented,
+		)
+		return nil, false
+	}
+
+	backendModels, err := a.models.list(r.Context())
+	if err != nil {
+		slog.Warn("failed to list models", "error", err.Error())
+		transport.WriteAPIError(w, api.NewServerError("failed to list models"))
+		return nil, false
+	}
+
+	models := make([]provider.ModelInfo, 0, len(backendModels))
+	for _, m := range backendModels {
+		if m.Object == "" {
+			m.Object = "model"
+		}
+		models = append(models, m)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+
+	if a.agentModels {
+		if lister, ok := a.profileResolver.(*agent.ConfigResolver); ok {
+			summaries := lister.List()
+			sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+			for _, s := range summaries {
+				models = append(models, provider.ModelInfo{
+					ID:      agent.ModelID(s.Name),
+					Object:  "model",
+					OwnedBy: "antwort",
+				})
+			}
+		}
+	}
+
+	return models, true
+}
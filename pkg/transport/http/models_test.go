@@ -0,0 +1,236 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/agent"
+	"github.com/rhuss/antwort/pkg/config"
+	"github.com/rhuss/antwort/pkg/provider"
+)
+
+// mockModelLister counts ListModels calls and returns a fixed result.
+type mockModelLister struct {
+	models []provider.ModelInfo
+	err    error
+	calls  int
+}
+
+func (m *mockModelLister) ListModels(_ context.Context) ([]provider.ModelInfo, error) {
+	m.calls++
+	return m.models, m.err
+}
+
+type modelList struct {
+	Object string               `json:"object"`
+	Data   []provider.ModelInfo `json:"data"`
+}
+
+func getModels(t *testing.T, srv *httptest.Server, path string) (*http.Response, modelList) {
+	t.Helper()
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s error: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	var list modelList
+	if resp.StatusCode == http.StatusOK && path == "/v1/models" {
+		if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+	}
+	return resp, list
+}
+
+func TestListModels(t *testing.T) {
+	lister := &mockModelLister{models: []provider.ModelInfo{
+		{ID: "meta-llama/Llama-3.1-8B", OwnedBy: "vllm"},
+		{ID: "gpt-4o", Object: "model", OwnedBy: "openai"},
+	}}
+	adapter := newTestAdapter(&mockCreator{}, nil)
+	adapter.SetModelLister(lister, time.Minute)
+	srv := httptest.NewServer(adapter.Handler())
+	defer srv.Close()
+
+	resp, list := getModels(t, srv, "/v1/models")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if list.Object != "list" || len(list.Data) != 2 {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	if list.Data[0].ID != "gpt-4o" || list.Data[1].Object != "model" {
+		t.Errorf("models not sorted or object not defaulted: %+v", list.Data)
+	}
+
+	// Second call is served from the cache.
+	getModels(t, srv, "/v1/models")
+	if lister.calls != 1 {
+		t.Errorf("ListModels called %d times, want 1 (cached)", lister.calls)
+	}
+}
+
+func TestGetModel(t *testing.T) {
+	lister := &mockModelLister{models: []provider.ModelInfo{{ID: "meta-llama/Llama-3.1-8B"}}}
+	adapter := newTestAdapter(&mockCreator{}, nil)
+	adapter.SetModelLister(lister, time.Minute)
+	srv := httptest.NewServer(adapter.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/models/meta-llama/Llama-3.1-8B")
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	var model provider.ModelInfo
+	json.NewDecoder(resp.Body).Decode(&model)
+	if model.ID != "meta-llama/Llama-3.1-8B" {
+		t.Errorf("model id = %q", model.ID)
+	}
+
+	resp, _ = getModels(t, srv, "/v1/models/unknown")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown model status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestListModelsWithAgents(t *testing.T) {
+	resolver, err := agent.NewConfigResolver(map[string]config.AgentProfileConfig{
+		"devops": {Model: "gpt-4o"},
+	})
+	if err != nil {
+		t.Fatalf("NewConfigResolver: %v", err)
+	}
+	adapter := newTestAdapter(&mockCreator{}, nil)
+	adapter.SetModelLister(&mockModelLister{models: []provider.ModelInfo{{ID: "gpt-4o"}}}, time.Minute)
+	adapter.SetProfileResolver(resolver)
+	adapter.SetAgentModels(true)
+	srv := httptest.NewServer(adapter.Handler())
+	defer srv.Close()
+
+	_, list := getModels(t, srv, "/v1/models")
+	if len(list.Data) != 2 || list.Data[1].ID != "agent/devops" {
+		t.Errorf("expected agent virtual model, got %+v", list.Data)
+	}
+}
+
+func TestListModelsErrors(t *testing.T) {
+	// Not configured.
+	srv := httptest.NewServer(newTestAdapter(&mockCreator{}, nil).Handler())
+	resp, _ := getModels(t, srv, "/v1/models")
+	srv.Close()
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("unconfigured status = %d, want 501", resp.StatusCode)
+	}
+
+	// Backend failure without a cached list.
+	lister := &mockModelLister{err: errors.New("unreachable")}
+	adapter := newTestAdapter(&mockCreator{}, nil)
+	adapter.SetModelLister(lister, 0)
+	srv = httptest.NewServer(adapter.Handler())
+	defer srv.Close()
+
+	resp, _ = getModels(t, srv, "/v1/models")
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("backend failure status = %d, want 500", resp.StatusCode)
+	}
+
+	// Backend failure with a cached list serves the stale list.
+	lister.err = nil
+	lister.models = []provider.ModelInfo{{ID: "m"}}
+	getModels(t, srv, "/v1/models")
+	lister.err = errors.New("unreachable")
+	resp, list := getModels(t, srv, "/v1/models")
+	if resp.StatusCode != http.StatusOK || len(list.Data) != 1 {
+		t.Errorf("stale list: status = %d, data = %+v", resp.StatusCode, list.Data)
+	}
+}
+
+// blockingModelLister returns models once release is closed and reports
+// each call on started.
+type blockingModelLister struct {
+	models  []provider.ModelInfo
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *blockingModelLister) ListModels(ctx context.Context) ([]provider.ModelInfo, error) {
+	m.started <- struct{}{}
+	select {
+	case <-m.release:
+		return m.models, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestModelCacheServesStaleDuringRefresh(t *testing.T) {
+	lister := &blockingModelLister{
+		models:  []provider.ModelInfo{{ID: "new"}},
+		started: make(chan struct{}, 2),
+		release: make(chan struct{}),
+	}
+	cache := &modelCache{lister: lister, ttl: time.Minute}
+	cache.models = []provider.ModelInfo{{ID: "old"}}
+	cache.fetchedAt = time.Now().Add(-2 * time.Minute)
+
+	refreshed := make(chan []provider.ModelInfo)
+	go func() {
+		models, _ := cache.list(context.Background())
+		refreshed <- models
+	}()
+	<-lister.started
+
+	// While the backend is slow, other requests get the stale list.
+	models, err := cache.list(context.Background())
+	if err != nil || len(models) != 1 || models[0].ID != "old" {
+		t.Errorf("list() during refresh = %+v, %v, want the stale list", models, err)
+	}
+	if n := len(lister.started); n != 0 {
+		t.Errorf("%d more ListModels calls during a refresh, want 0", n)
+	}
+
+	close(lister.release)
+	if models := <-refreshed; len(models) != 1 || models[0].ID != "new" {
+		t.Errorf("refreshed list = %+v, want the new list", models)
+	}
+	if models, _ := cache.list(context.Background()); len(models) != 1 || models[0].ID != "new" {
+		t.Errorf("list() after refresh = %+v, want the new list", models)
+	}
+}
+
+func TestModelCacheFirstRefreshShared(t *testing.T) {
+	lister := &blockingModelLister{
+		models:  []provider.ModelInfo{{ID: "m"}},
+		started: make(chan struct{}, 2),
+		release: make(chan struct{}),
+	}
+	cache := &modelCache{lister: lister, ttl: time.Minute}
+
+	results := make(chan []provider.ModelInfo, 2)
+	for range 2 {
+		go func() {
+			models, _ := cache.list(context.Background())
+			results <- models
+		}()
+	}
+	<-lister.started
+	close(lister.release)
+	for range 2 {
+		if models := <-results; len(models) != 1 {
+			t.Errorf("list() = %+v, want the fetched list", models)
+		}
+	}
+	if n := len(lister.started); n != 0 {
+		t.Errorf("ListModels called %d more times, want 1 call in total", n)
+	}
+}
@@ -0,0 +1,212 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/storage"
+	"github.com/rhuss/antwort/pkg/transport"
+)
+
+// queuedPollInterval is how often a resuming client re-checks the status of
+// a queued background response that has not started streaming yet.
+const queuedPollInterval = time.Second
+
+// SetStreamRegistry enables stream resumption. Events of streaming
+// responses are buffered in the registry and can be replayed with
+// GET /v1/responses/{id}?stream=true. Streaming responses keep running
+// when the client disconnects; DELETE still cancels them.
+func (a *Adapter) SetStreamRegistry(r *transport.StreamRegistry) {
+	a.streams = r
+}
+
+// resumableWriter records the events of a streaming response in a
+// StreamBuffer before forwarding them to the client. Once the client has
+// gone away, events are only buffered, so generation continues and the
+// client can resume the stream later.
+type resumableWriter struct {
+	*sseResponseWriter
+	streams  *transport.StreamRegistry
+	owner    string
+	buf      *transport.StreamBuffer
+	detached bool
+}
+
+// WriteEvent buffers the event and forwards it to the client if it is
+// still connected.
+func (w *resumableWriter) WriteEvent(ctx context.Context, event api.StreamEvent) error {
+	if w.buf == nil && event.Type == api.EventResponseCreated && event.Response != nil {
+		w.buf = w.streams.Open(event.Response.ID, w.owner)
+	}
+	if w.buf == nil {
+		return w.sseResponseWriter.WriteEvent(ctx, event)
+	}
+
+	event = w.buf.Append(event)
+	if w.detached {
+		return nil
+	}
+	if err := w.sseResponseWriter.WriteEvent(ctx, event); err != nil {
+		// The client is gone; keep buffering for a later resume.
+		w.detached = true
+	}
+	return nil
+}
+
+// close marks the buffered stream as finished.
+func (w *resumableWriter) close() {
+	if w.buf != nil {
+		w.buf.Close()
+	}
+}
+
+// handleResumeStream handles GET /v1/responses/{id}?stream=true. It replays
+// the buffered events after the sequence number given by starting_after
+// (or the Last-Event-ID header) and then follows the stream until it ends.
+func (a *Adapter) handleResumeStream(w http.ResponseWriter, r *http.Request) {
+	if a.streams == nil {
+		transport.WriteErrorResponse(w,
+			api.NewInvalidRequestError("stream", "stream resumption is not enabled"),
+			http.StatusNotImplemented,
+		)
+		return
+	}
+
+	id := r.PathValue("id")
+	if !api.ValidateResponseID(id) {
+		transport.WriteErrorResponse(w,
+			api.NewInvalidRequestError("id", "malformed response ID"),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	after, apiErr := parseStartingAfter(r)
+	if apiErr != nil {
+		transport.WriteAPIError(w, apiErr)
+		return
+	}
+
+	buf, apiErr := a.resumableBuffer(r.Context(), id)
+	if apiErr != nil {
+		transport.WriteAPIError(w, apiErr)
+		return
+	}
+
+	events, done, changed, err := buf.Since(after)
+	if err != nil {
+		transport.WriteAPIError(w, api.NewInvalidRequestError("starting_after",
+			fmt.Sprintf("events after sequence number %d are no longer available", after)))
+		return
+	}
+
+	// Send the headers right away; the next event may take a while.
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	rw := newSSEResponseWriter(w, nil)
+	rw.Flush()
+
+	for {
+		for _, ev := range events {
+			if err := rw.WriteEvent(r.Context(), ev); err != nil {
+				return
+			}
+			after = ev.SequenceNumber
+			if terminalEvents[ev.Type] {
+				return
+			}
+		}
+		if done {
+			// The terminal event lies before starting_after, or the
+			// stream ended without one.
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+		if events, done, changed, err = buf.Since(after); err != nil {
+			// The reader fell behind the buffer; the client can retry.
+			return
+		}
+	}
+}
+
+// resumableBuffer returns the event buffer of a response the caller may
+// access. Stored responses are authorized by the store; responses that are
+// still streaming and not stored yet must belong to the caller. For queued
+// background responses it waits until the worker starts streaming.
+func (a *Adapter) resumableBuffer(ctx context.Context, id string) (*transport.StreamBuffer, *api.APIError) {
+	notFound := api.NewNotFoundError("response " + id + " not found")
+
+	var resp *api.Response
+	if a.store != nil {
+		var err error
+		resp, err = a.store.GetResponse(ctx, id)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			var apiErr *api.APIError
+			if errors.As(err, &apiErr) {
+				return nil, apiErr
+			}
+			return nil, api.NewServerError(err.Error())
+		}
+	}
+
+	for {
+		opened := a.streams.Opened()
+		if buf := a.streams.Get(id); buf != nil {
+			if resp == nil && buf.Owner() != storage.GetOwner(ctx) && !storage.GetAdmin(ctx) {
+				return nil, notFound
+			}
+			return buf, nil
+		}
+		if resp == nil {
+			return nil, notFound
+		}
+		if resp.Status != api.ResponseStatusQueued {
+			return nil, api.NewNotFoundError("no resumable stream for response " + id)
+		}
+
+		select {
+		case <-opened:
+		case <-time.After(queuedPollInterval):
+			// The response may have been claimed by a worker in another
+			// process, in which case it never streams here.
+			latest, err := a.store.GetResponse(ctx, id)
+			if err != nil {
+				return nil, notFound
+			}
+			resp = latest
+		case <-ctx.Done():
+			return nil, api.NewServerError(ctx.Err().Error())
+		}
+	}
+}
+
+// parseStartingAfter returns the sequence number after which events are
+// replayed, from the starting_after parameter or the Last-Event-ID header.
+// It returns -1 (replay everything) when neither is set.
+func parseStartingAfter(r *http.Request) (int, *api.APIError) {
+	param, value := "starting_after", r.URL.Query().Get("starting_after")
+	if value == "" {
+		param, value = "Last-Event-ID", r.Header.Get("Last-Event-ID")
+	}
+	if value == "" {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, api.NewInvalidRequestError(param, param+" must be a non-negative integer")
+	}
+	return n, nil
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/api"
+	"github.com/rhuss/antwort/pkg/storage"
+	"github.com/rhuss/antwort/pkg/transport"
+)
+
+const resumeTestID = "resp_resumetestABCD5678901234"
+
+// readUntil reads SSE lines until one equals want.
+func readUntil(t *testing.T, r *bufio.Reader, want string) {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended before %q: %v", want, err)
+		}
+		if strings.TrimSpace(line) == want {
+			return
+		}
+	}
+}
+
+func getStream(t *testing.T, srv *httptest.Server, query string, header http.Header) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/responses/"+resumeTestID+"?stream=true"+query, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	return resp
+}
+
+func TestResumeStream(t *testing.T) {
+	release := make(chan struct{})
+	finished := make(chan error, 1)
+	creator := transport.ResponseCreatorFunc(func(ctx context.Context, req *api.CreateResponseRequest, w transport.ResponseWriter) error {
+		resp := &api.Response{ID: resumeTestID, Status: api.ResponseStatusInProgress}
+		w.WriteEvent(ctx, api.StreamEvent{Type: api.EventResponseCreated, SequenceNumber: 0, Response: resp})
+		w.WriteEvent(ctx, api.StreamEvent{Type: api.EventOutputTextDelta, SequenceNumber: 1, Delta: "Hello"})
+		<-release
+		w.WriteEvent(ctx, api.StreamEvent{Type: api.EventOutputTextDelta, SequenceNumber: 2, Delta: " world"})
+		err := w.WriteEvent(ctx, api.StreamEvent{Type: api.EventResponseCompleted, SequenceNumber: 3, Response: resp})
+		finished <- ctx.Err()
+		return err
+	})
+
+	adapter := newTestAdapter(creator, nil)
+	adapter.SetStreamRegistry(transport.NewStreamRegistry(100, time.Minute))
+	srv := httptest.NewServer(adapter.Handler())
+	defer srv.Close()
+
+	// Start a stream and drop the connection after the first delta.
+	ctx, disconnect := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/v1/responses",
+		strings.NewReader(`{"model":"test","stream":true,"input":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST error: %v", err)
+	}
+	readUntil(t, bufio.NewReader(resp.Body), "id: 1")
+	disconnect()
+	resp.Body.Close()
+
+	// Reattach after the last received event and let generation continue.
+	resumed := getStream(t, srv, "&starting_after=1", nil)
+	defer resumed.Body.Close()
+	close(release)
+
+	body, _ := io.ReadAll(resumed.Body)
+	for _, want := range []string{"id: 2\nevent: response.output_text.delta\n", "event: response.completed\n", "data: [DONE]\n"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("resumed stream missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(string(body), "Hello") {
+		t.Error("resumed stream replayed events before starting_after")
+	}
+	if err := <-finished; err != nil {
+		t.Errorf("generation was cancelled by the client disconnect: %v", err)
+	}
+
+	// A finished stream can still be replayed via Last-Event-ID.
+	replay := getStream(t, srv, "", http.Header{"Last-Event-ID": {"2"}})
+	body, _ = io.ReadAll(replay.Body)
+	replay.Body.Close()
+	if strings.Contains(string(body), "world") || !strings.Contains(string(body), "event: response.completed\n") ||
+		strings.Count(string(body), "[DONE]") != 1 {
+		t.Errorf("unexpected replay after Last-Event-ID:\n%s", body)
+	}
+}
+
+func TestResumeStreamErrors(t *testing.T) {
+	adapter := newTestAdapter(&mockCreator{}, nil)
+	srv := httptest.NewServer(adapter.Handler())
+	resp := getStream(t, srv, "", nil)
+	resp.Body.Close()
+	srv.Close()
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("disabled: status = %d, want 501", resp.StatusCode)
+	}
+
+	streams := transport.NewStreamRegistry(2, time.Minute)
+	adapter.SetStreamRegistry(streams)
+	srv = httptest.NewServer(adapter.Handler())
+	defer srv.Close()
+
+	resp = getStream(t, srv, "", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown response: status = %d, want 404", resp.StatusCode)
+	}
+
+	buf := streams.Open(resumeTestID, "")
+	for seq := 0; seq < 3; seq++ {
+		buf.Append(api.StreamEvent{Type: api.EventOutputTextDelta, SequenceNumber: seq})
+	}
+
+	resp = getStream(t, srv, "&starting_after=-1", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("negative starting_after: status = %d, want 400", resp.StatusCode)
+	}
+
+	// Sequence number 0 has been dropped from the buffer.
+	resp = getStream(t, srv, "", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("evicted events: status = %d, want 400", resp.StatusCode)
+	}
+
+	// Unstored streams can only be resumed by their owner.
+	const aliceID = "resp_resumetestALICE678901234"
+	owned := streams.Open(aliceID, "alice")
+	ctx := storage.SetOwner(context.Background(), "bob")
+	if _, apiErr := adapter.resumableBuffer(ctx, aliceID); apiErr == nil || apiErr.Type != api.ErrorTypeNotFound {
+		t.Errorf("other owner: error = %v, want not found", apiErr)
+	}
+	ctx = storage.SetOwner(context.Background(), "alice")
+	if b, apiErr := adapter.resumableBuffer(ctx, aliceID); apiErr != nil || b != owned {
+		t.Errorf("owner: buffer = %v, error = %v", b, apiErr)
+	}
+}
@@ -60,6 +60,7 @@ func newSSEResponseWriter(w http.ResponseWriter, onCreated func(id string)) *sse
 
 // WriteEvent sends a single SSE event. The event is formatted as:
 //
+//	id: {sequence_number}\n
 //	event: {type}\n
 //	data: {json}\n
 //	\n
@@ -97,7 +98,8 @@ func (s *sseResponseWriter) WriteEvent(ctx context.Context, event api.StreamEven
 	}
 
 	// Write SSE format.
-	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+	// The id field lets clients resume with the Last-Event-ID header.
+	if _, err := fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", event.SequenceNumber, event.Type, data); err != nil {
 		return fmt.Errorf("failed to write event: %w", err)
 	}
 
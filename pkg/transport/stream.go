@@ -0,0 +1,167 @@
+package transport
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/api"
+)
+
+// ErrEventsEvicted is returned by StreamBuffer.Since when the requested
+// events have already been dropped from the buffer.
+var ErrEventsEvicted = errors.New("requested events are no longer buffered")
+
+// StreamRegistry retains the most recent events of streaming responses so
+// that clients can reattach after a dropped connection and replay what
+// they missed. A buffer is kept for a retention period after its stream
+// has finished.
+//
+// All methods are safe for concurrent access.
+type StreamRegistry struct {
+	size      int
+	retention time.Duration
+
+	mu      sync.Mutex
+	buffers map[string]*StreamBuffer
+	opened  chan struct{} // closed and replaced whenever a buffer is opened
+}
+
+// NewStreamRegistry creates a registry that keeps up to size events per
+// response and removes buffers retention after their stream has finished.
+func NewStreamRegistry(size int, retention time.Duration) *StreamRegistry {
+	return &StreamRegistry{
+		size:      size,
+		retention: retention,
+		buffers:   make(map[string]*StreamBuffer),
+		opened:    make(chan struct{}),
+	}
+}
+
+// Open creates the buffer for a response. owner is the subject that may
+// resume the stream without the response being found in the store; it is
+// empty when authentication is disabled or the response is stored.
+func (r *StreamRegistry) Open(id, owner string) *StreamBuffer {
+	b := &StreamBuffer{
+		owner:   owner,
+		size:    r.size,
+		lastSeq: -1,
+		changed: make(chan struct{}),
+	}
+	b.onClose = func() {
+		time.AfterFunc(r.retention, func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if r.buffers[id] == b {
+				delete(r.buffers, id)
+			}
+		})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buffers[id] = b
+	close(r.opened)
+	r.opened = make(chan struct{})
+	return b
+}
+
+// Get returns the buffer of a response, or nil if there is none.
+func (r *StreamRegistry) Get(id string) *StreamBuffer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buffers[id]
+}
+
+// Opened returns a channel that is closed the next time a buffer is
+// opened. It lets readers wait for a queued response to start streaming.
+func (r *StreamRegistry) Opened() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.opened
+}
+
+// StreamBuffer holds the most recent events of one streaming response.
+// Writers append events as they are produced; readers replay the events
+// after a sequence number and wait for more.
+type StreamBuffer struct {
+	owner   string
+	size    int
+	onClose func()
+
+	mu      sync.Mutex
+	events  []api.StreamEvent // oldest first
+	lastSeq int
+	done    bool
+	changed chan struct{} // closed and replaced on every append and on close
+}
+
+// Owner returns the subject recorded when the buffer was opened.
+func (b *StreamBuffer) Owner() string {
+	return b.owner
+}
+
+// Append adds an event to the buffer, dropping the oldest event when the
+// buffer is full. Events whose sequence number does not follow the last
+// one (such as errors written by the transport) are renumbered. It returns
+// the event as buffered.
+func (b *StreamBuffer) Append(event api.StreamEvent) api.StreamEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if event.SequenceNumber <= b.lastSeq {
+		event.SequenceNumber = b.lastSeq + 1
+	}
+	b.lastSeq = event.SequenceNumber
+
+	b.events = append(b.events, event)
+	if b.size > 0 && len(b.events) > b.size {
+		b.events = b.events[len(b.events)-b.size:]
+	}
+	b.notify()
+	return event
+}
+
+// Close marks the stream as finished. Waiting readers are woken up and
+// the buffer is removed from its registry after the retention period.
+func (b *StreamBuffer) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.done {
+		return
+	}
+	b.done = true
+	b.notify()
+	if b.onClose != nil {
+		b.onClose()
+	}
+}
+
+// Since returns the buffered events with a sequence number greater than
+// after, whether the stream has finished, and a channel that is closed
+// when the buffer changes. It returns ErrEventsEvicted if some of those
+// events have already been dropped.
+func (b *StreamBuffer) Since(after int) ([]api.StreamEvent, bool, <-chan struct{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.events) > 0 && b.events[0].SequenceNumber > after+1 {
+		return nil, b.done, b.changed, ErrEventsEvicted
+	}
+
+	var events []api.StreamEvent
+	for i, ev := range b.events {
+		if ev.SequenceNumber > after {
+			events = append(events, b.events[i:]...)
+			break
+		}
+	}
+	return events, b.done, b.changed, nil
+}
+
+// notify wakes up readers waiting for changes. Must be called with b.mu held.
+func (b *StreamBuffer) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
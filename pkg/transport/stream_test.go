@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rhuss/antwort/pkg/api"
+)
+
+func TestStreamBuffer_Since(t *testing.T) {
+	r := NewStreamRegistry(3, time.Minute)
+	b := r.Open("resp_1", "alice")
+
+	for seq := 0; seq < 2; seq++ {
+		b.Append(api.StreamEvent{Type: api.EventOutputTextDelta, SequenceNumber: seq})
+	}
+	// Events without a fresh sequence number are renumbered.
+	if ev := b.Append(api.StreamEvent{Type: api.EventResponseFailed}); ev.SequenceNumber != 2 {
+		t.Errorf("renumbered sequence = %d, want 2", ev.SequenceNumber)
+	}
+
+	events, done, changed, err := b.Since(0)
+	if err != nil || done || len(events) != 2 || events[0].SequenceNumber != 1 {
+		t.Fatalf("Since(0) = %v, %v, %v", events, done, err)
+	}
+
+	b.Close()
+	select {
+	case <-changed:
+	default:
+		t.Error("Close did not wake up readers")
+	}
+	if _, done, _, _ := b.Since(2); !done {
+		t.Error("buffer should be done after Close")
+	}
+
+	// The oldest event is dropped once the buffer is full.
+	b.Append(api.StreamEvent{SequenceNumber: 3})
+	if _, _, _, err := b.Since(-1); !errors.Is(err, ErrEventsEvicted) {
+		t.Errorf("Since(-1) error = %v, want ErrEventsEvicted", err)
+	}
+	if events, _, _, err := b.Since(0); err != nil || len(events) != 3 {
+		t.Errorf("Since(0) = %v, %v", events, err)
+	}
+}
+
+func TestStreamRegistry_Retention(t *testing.T) {
+	r := NewStreamRegistry(10, 10*time.Millisecond)
+
+	opened := r.Opened()
+	b := r.Open("resp_1", "")
+	select {
+	case <-opened:
+	default:
+		t.Error("Open did not signal waiting readers")
+	}
+	if r.Get("resp_1") != b {
+		t.Fatal("Get did not return the opened buffer")
+	}
+
+	b.Close()
+	deadline := time.Now().Add(time.Second)
+	for r.Get("resp_1") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("buffer not removed after retention")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -1,11 +1,17 @@
 package integration
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	gomcp "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rhuss/antwort/pkg/auth"
 	"github.com/rhuss/antwort/pkg/tools"
 	"github.com/rhuss/antwort/pkg/tools/mcp"
 )
@@ -195,3 +201,75 @@ func TestMCPExecutorKind(t *testing.T) {
 		t.Errorf("Kind() = %v, want ToolKindMCP", executor.Kind())
 	}
 }
+
+// TestMCPForwardBearerOnToolCalls verifies that with forward_bearer auth
+// only tool calls carry the caller's token, while session setup and tool
+// discovery carry the configured service credential.
+func TestMCPForwardBearerOnToolCalls(t *testing.T) {
+	server := gomcp.NewServer(
+		&gomcp.Implementation{Name: "integration-test-server", Version: "1.0.0"},
+		nil,
+	)
+	server.AddTool(
+		&gomcp.Tool{Name: "whoami", InputSchema: map[string]any{"type": "object"}},
+		func(ctx context.Context, req *gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+			return &gomcp.CallToolResult{
+				Content: []gomcp.Content{&gomcp.TextContent{Text: "ok"}},
+			}, nil
+		},
+	)
+	handler := gomcp.NewStreamableHTTPHandler(func(*http.Request) *gomcp.Server { return server }, nil)
+
+	// Record the Authorization header of each JSON-RPC method.
+	var mu sync.Mutex
+	headers := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			var msg struct {
+				Method string `json:"method"`
+			}
+			if json.Unmarshal(body, &msg) == nil && msg.Method != "" {
+				mu.Lock()
+				headers[msg.Method] = r.Header.Get("Authorization")
+				mu.Unlock()
+			}
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	client := mcp.NewMCPClient(mcp.ServerConfig{
+		Name:    "forward",
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "Bearer service-key"},
+		Auth:    mcp.MCPAuthConfig{Type: "forward_bearer"},
+	})
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("connecting MCP client: %v", err)
+	}
+	defer client.Close()
+
+	ctx := auth.SetIdentity(context.Background(), &auth.Identity{Subject: "alice", Token: "alice-jwt"})
+	if _, err := client.DiscoverTools(ctx); err != nil {
+		t.Fatalf("DiscoverTools failed: %v", err)
+	}
+	result, err := client.CallTool(ctx, tools.ToolCall{ID: "call_1", Name: "whoami", Arguments: `{}`})
+	if err != nil || result.IsError {
+		t.Fatalf("CallTool = %+v, %v", result, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]string{
+		"initialize": "Bearer service-key",
+		"tools/list": "Bearer service-key",
+		"tools/call": "Bearer alice-jwt",
+	}
+	for method, wantAuth := range want {
+		if got, ok := headers[method]; !ok || got != wantAuth {
+			t.Errorf("%s Authorization = %q, want %q", method, got, wantAuth)
+		}
+	}
+}